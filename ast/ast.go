@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package ast
+
+import (
+	"io"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// Kind tells what a Node represents.
+type Kind int
+
+const (
+	// Element nodes have a name, attributes and children.
+	Element Kind = iota
+	// Text nodes only have a Value.
+	Text
+	// Comment nodes only have a Value.
+	Comment
+)
+
+// String returns a readable name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case Element:
+		return "element"
+	case Text:
+		return "text"
+	case Comment:
+		return "comment"
+	default:
+		return "unknown"
+	}
+}
+
+// BlockType describes with what brackets the children of an element were surrounded.
+// The values match parser.BlockType.
+type BlockType string
+
+const (
+	// BlockNone is used for elements without brackets around their children.
+	BlockNone BlockType = ""
+	// BlockNormal is used for children in curly brackets.
+	BlockNormal BlockType = "{}"
+	// BlockGroup is used for children in parentheses.
+	BlockGroup BlockType = "()"
+	// BlockGeneric is used for children in angle brackets.
+	BlockGeneric BlockType = "<>"
+)
+
+// Attribute is a key-value pair on an element.
+type Attribute struct {
+	Key   string
+	Value string
+	// ValueKind tells whether the value was written as a string, number or boolean.
+	ValueKind token.ValueKind
+	Range     token.Position
+}
+
+// Node is an element, text or comment in a document.
+type Node struct {
+	Kind Kind
+	// Name is the name of an element. It is empty for texts and comments.
+	Name string
+	// Value is the content of a text or comment. It is empty for elements.
+	Value string
+	// Attributes are in source order, with forwarded attributes first. Keys are unique, unless the
+	// parser keeps duplicates with parser.CollectDuplicateAttributes.
+	Attributes []Attribute
+	Children   []*Node
+	Block      BlockType
+	// Range spans all tokens that were processed to build this node.
+	Range token.Position
+}
+
+// Attribute returns the first attribute with the given key, or nil if it does not exist.
+// Use AllAttributes to get duplicates.
+func (n *Node) Attribute(key string) *Attribute {
+	for i := range n.Attributes {
+		if n.Attributes[i].Key == key {
+			return &n.Attributes[i]
+		}
+	}
+
+	return nil
+}
+
+// AllAttributes returns all attributes with the given key in order, which are multiple ones only
+// for duplicates that the parser kept with parser.CollectDuplicateAttributes.
+func (n *Node) AllAttributes(key string) []*Attribute {
+	var result []*Attribute
+
+	for i := range n.Attributes {
+		if n.Attributes[i].Key == key {
+			result = append(result, &n.Attributes[i])
+		}
+	}
+
+	return result
+}
+
+// Parse reads a document and returns its root element.
+func Parse(filename string, r io.Reader, opts ...parser.ParserOption) (*Node, error) {
+	tree, err := parser.NewParser(filename, r, opts...).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return FromTree(tree), nil
+}
+
+// FromTree converts a parsed tree into a Node. The result does not share memory with the tree.
+func FromTree(tree *parser.TreeNode) *Node {
+	node := &Node{
+		Name:  tree.Name,
+		Block: BlockType(tree.BlockType),
+		Range: tree.Range,
+	}
+
+	switch {
+	case tree.IsText():
+		node.Kind = Text
+		node.Value = *tree.Text
+	case tree.IsComment():
+		node.Kind = Comment
+		node.Value = *tree.Comment
+	default:
+		node.Kind = Element
+	}
+
+	if count := tree.Attributes.Len(); count > 0 {
+		node.Attributes = make([]Attribute, 0, count)
+
+		for i := 0; i < count; i++ {
+			attr := tree.Attributes.GetAt(i)
+			node.Attributes = append(node.Attributes, Attribute{
+				Key:       attr.Key,
+				Value:     attr.Value,
+				ValueKind: attr.Kind,
+				Range:     attr.Range,
+			})
+		}
+	}
+
+	if len(tree.Children) > 0 {
+		node.Children = make([]*Node, 0, len(tree.Children))
+		for _, child := range tree.Children {
+			node.Children = append(node.Children, FromTree(child))
+		}
+	}
+
+	return node
+}
+
+// Tree converts the node back into a parser.TreeNode, e.g. to encode it.
+// The result does not share memory with the node.
+func (n *Node) Tree() *parser.TreeNode {
+	tree := parser.NewNode(n.Name)
+
+	switch n.Kind {
+	case Text:
+		value := n.Value
+		tree.Text = &value
+	case Comment:
+		value := n.Value
+		tree.Comment = &value
+	case Element:
+		// Elements only need their name, which is already set.
+	}
+
+	tree.BlockType = parser.BlockType(n.Block)
+	tree.Range = n.Range
+
+	for _, attr := range n.Attributes {
+		tree.Attributes.Set(util.Attribute{
+			Key:   attr.Key,
+			Value: attr.Value,
+			Range: attr.Range,
+			Kind:  attr.ValueKind,
+		})
+	}
+
+	if len(n.Children) > 0 {
+		tree.Children = make([]*parser.TreeNode, 0, len(n.Children))
+		for _, child := range n.Children {
+			tree.AddChildren(child.Tree())
+		}
+	}
+
+	return tree
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package ast_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/ast"
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	root, err := Parse("test", strings.NewReader(`#? note
+#server @host{localhost} {#port 80}
+#! fn @public=true (x)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Kind != Element || root.Name != "root" || root.Block != BlockNormal || len(root.Children) != 3 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+
+	comment, server, fn := root.Children[0], root.Children[1], root.Children[2]
+
+	if comment.Kind != Comment || comment.Value != "note\n" {
+		t.Errorf("unexpected comment: %+v", comment)
+	}
+
+	if host := server.Attribute("host"); host == nil || host.Value != "localhost" || host.Range.BeginPos.Line != 2 {
+		t.Errorf("unexpected host attribute: %+v", host)
+	}
+
+	if server.Block != BlockNormal || server.Children[0].Name != "port" {
+		t.Errorf("unexpected server: %+v", server)
+	}
+
+	if text := server.Children[0].Children[0]; text.Kind != Text || text.Value != "80" {
+		t.Errorf("unexpected text: %+v", text)
+	}
+
+	if public := fn.Attribute("public"); public == nil || public.ValueKind != token.KindBool {
+		t.Errorf("unexpected public attribute: %+v", public)
+	}
+
+	if fn.Block != BlockGroup || server.Attribute("missing") != nil {
+		t.Errorf("unexpected fn: %+v", fn)
+	}
+}
+
+func TestDuplicateAttributes(t *testing.T) {
+	t.Parallel()
+
+	root, err := Parse("test", strings.NewReader(`#item @tag{a} @tag{b}`),
+		parser.WithDuplicateAttributes(parser.CollectDuplicateAttributes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := root.Children[0]
+
+	if tag := item.Attribute("tag"); tag == nil || tag.Value != "a" {
+		t.Errorf("expected the first attribute, but got %+v", tag)
+	}
+
+	tags := item.AllAttributes("tag")
+	if len(tags) != 2 || tags[0].Value != "a" || tags[1].Value != "b" {
+		t.Errorf("expected both attributes in order, but got %+v", tags)
+	}
+
+	if missing := item.AllAttributes("missing"); len(missing) != 0 {
+		t.Errorf("expected no attributes, but got %+v", missing)
+	}
+}
+
+func TestTreeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	root, err := Parse("test", strings.NewReader(`#! list @@id="1" item {
+	// comment
+	entry @count=3 "text",
+	generic <x>
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := root.Tree()
+	if again := FromTree(tree); !reflect.DeepEqual(root, again) {
+		t.Errorf("converting to a tree and back changed the node:\n%+v\n%+v", root, again)
+	}
+
+	var writer bytes.Buffer
+	if err := encoder.NewDymlEncoder(&writer).Encode(tree); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "#! list item @id=\"1\" {\n"; !strings.HasPrefix(writer.String(), want) {
+		t.Errorf("expected encoded tree to start with '%s', but got '%s'", want, writer.String())
+	}
+
+	// Modifying the tree must not change the node it was created from.
+	tree.Children[0].Children[0].Name = "changed"
+	tree.Children[0].Children[0].Attributes.Remove("id")
+
+	if item := root.Children[0].Children[0]; item.Name != "item" || item.Attribute("id") == nil {
+		t.Errorf("modifying the tree changed the node: %+v", item)
+	}
+}
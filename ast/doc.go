@@ -0,0 +1,7 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ast contains a plain representation of parsed dyml documents for downstream tools.
+// Unlike parser.TreeNode, it has no state that is only needed while parsing, so it can be relied
+// upon as a stable contract. Use FromTree and Node.Tree to convert between both representations.
+package ast
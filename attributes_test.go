@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+)
+
+type flaggedButton struct {
+	Disabled bool     `dyml:"disabled,attr"`
+	Hidden   bool     `dyml:"hidden,attr"`
+	Classes  []string `dyml:"class,attr,oneof=primary large small"`
+	Weights  []int    `dyml:"weight,attr"`
+	Label    string   `dyml:"label"`
+}
+
+func TestUnmarshalAttributeFlagsAndLists(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		strict  bool
+		want    flaggedButton
+		wantErr bool
+	}{
+		{
+			name: "g1 flags",
+			text: "#button @disabled @class{large} @weight{3} {#label OK}",
+			want: flaggedButton{Disabled: true, Classes: []string{"large"}, Weights: []int{3}, Label: "OK"},
+		},
+		{
+			name: "g2 flags",
+			text: `#! button @hidden @class="primary" { label "OK" }`,
+			want: flaggedButton{Hidden: true, Classes: []string{"primary"}, Label: "OK"},
+		},
+		{
+			name: "repeated attributes",
+			text: "#button @class{primary} @weight{1} @class{large} @weight{2}",
+			want: flaggedButton{Classes: []string{"primary", "large"}, Weights: []int{1, 2}},
+		},
+		{
+			name:    "invalid element of list",
+			text:    "#button @class{primary} @class{huge}",
+			wantErr: true,
+		},
+		{
+			name:    "flag for a number",
+			text:    "#button @weight",
+			wantErr: true,
+		},
+		{
+			name:    "strict without list",
+			text:    "#button @disabled @hidden {#label OK}",
+			strict:  true,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got struct {
+				Button flaggedButton `dyml:"button"`
+			}
+
+			decoder := NewDecoder("", strings.NewReader(test.text), test.strict,
+				WithParserOptions(parser.WithDuplicateAttributes(parser.CollectDuplicateAttributes)))
+
+			err := decoder.Decode(&got)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got %+v", got.Button)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.Button, test.want) {
+				t.Errorf("expected %+v, but got %+v", test.want, got.Button)
+			}
+		})
+	}
+}
+
+func TestMarshalAttributeList(t *testing.T) {
+	t.Parallel()
+
+	type Document struct {
+		Button flaggedButton `dyml:"button"`
+	}
+
+	want := Document{Button: flaggedButton{Disabled: true, Classes: []string{"primary", "large"}, Weights: []int{1, 2}}}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), `@class="primary" @class="large"`) {
+		t.Errorf("expected an attribute for each element, but got %s", buf.String())
+	}
+
+	var got Document
+
+	collect := parser.WithDuplicateAttributes(parser.CollectDuplicateAttributes)
+	decoder := NewDecoder("", &buf, false, WithParserOptions(collect))
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml"
+)
+
+type benchServer struct {
+	Name    string            `dyml:"name,attr"`
+	Port    int               `dyml:"port,attr"`
+	Enabled bool              `dyml:"enabled"`
+	Tags    []string          `dyml:"tag"`
+	Labels  map[string]string `dyml:"labels"`
+	Comment string            `dyml:",inner"`
+}
+
+type benchConfig struct {
+	Title   string        `dyml:"title"`
+	Servers []benchServer `dyml:"server"`
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	var sb strings.Builder
+
+	sb.WriteString("#title Benchmark\n")
+
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&sb, "#server @name{s%d} @port{%d} {#enabled true #tag a #tag b #labels{#env{prod}} text}\n", i, i)
+	}
+
+	tree, err := dyml.ParseString("bench", sb.String())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var config benchConfig
+		if err := dyml.UnmarshalTree(tree, &config, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
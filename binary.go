@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/util"
+)
+
+// base64Option is the tag option of []byte fields, whose element or attribute contains base64 encoded data.
+const base64Option = "base64"
+
+// encodingAttribute is the attribute that names the encoding of the binary content of an element.
+const encodingAttribute = "encoding"
+
+// binaryEncoding returns the encoding of the content of node, if value should be decoded from it.
+// The encoding is set by the 'base64' tag option or, for []byte values, by the 'encoding' attribute.
+func binaryEncoding(node *parser.TreeNode, value reflect.Value, tags []string) (string, bool) {
+	if len(tags) > 1 && containsString(tags[1:], base64Option) {
+		return base64Option, true
+	}
+
+	if node.IsNode() && isBytes(value.Type()) {
+		if attr := node.Attributes.Get(encodingAttribute); attr != nil {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// doBinary decodes the text of node with the given encoding into value, which must be a []byte.
+func (u *unmarshaler) doBinary(node *parser.TreeNode, value reflect.Value, encoding string) error {
+	if encoding != base64Option {
+		return NewUnmarshalError(node, fmt.Sprintf("unsupported encoding '%s', expected 'base64'", encoding), nil)
+	}
+
+	value = allocate(value)
+	if !isBytes(value.Type()) {
+		return NewUnmarshalError(node, fmt.Sprintf("'base64' requires a []byte, not '%s'", value.Type()), nil)
+	}
+
+	text, err := u.findText(node)
+	if err != nil {
+		return NewUnmarshalError(node, "expected base64 encoded text", err)
+	}
+
+	data, err := decodeBase64(text)
+	if err != nil {
+		return NewUnmarshalError(node, "invalid base64 encoded text", err)
+	}
+
+	value.SetBytes(data)
+
+	return nil
+}
+
+// doBinaryAttribute decodes the base64 encoded value of the attribute into value, which must be a []byte.
+// node is the node the attribute belongs to.
+func (u *unmarshaler) doBinaryAttribute(node *parser.TreeNode, attr *util.Attribute, value reflect.Value) error {
+	value = allocate(value)
+	if !isBytes(value.Type()) {
+		return NewUnmarshalError(node, fmt.Sprintf("'base64' requires a []byte, not '%s'", value.Type()), nil)
+	}
+
+	data, err := decodeBase64(attr.Value)
+	if err != nil {
+		return NewUnmarshalError(node, fmt.Sprintf("invalid base64 encoded attribute '%s'", attr.Key), err)
+	}
+
+	value.SetBytes(data)
+
+	return nil
+}
+
+// decodeBase64 decodes standard base64 encoded text. Whitespace is ignored, so that long payloads
+// can be split into multiple lines.
+func decodeBase64(text string) ([]byte, error) {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, text)
+
+	return base64.StdEncoding.DecodeString(text)
+}
+
+// marshalBinary returns the base64 encoded text of value, which must be a []byte.
+func marshalBinary(value reflect.Value) (string, error) {
+	value = reflect.Indirect(value)
+	if !isBytes(value.Type()) {
+		return "", fmt.Errorf("'base64' requires a []byte, not '%s'", value.Type())
+	}
+
+	return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+}
+
+// isBytes returns true if t is a slice of bytes.
+func isBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
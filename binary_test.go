@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+)
+
+type Blob struct {
+	Data     []byte  `dyml:"data,base64"`
+	Checksum []byte  `dyml:"checksum,attr,base64"`
+	Key      *[]byte `dyml:",base64"`
+	Payload  []byte
+}
+
+func TestUnmarshalBase64(t *testing.T) {
+	key := []byte("key")
+
+	tests := []struct {
+		name    string
+		text    string
+		want    Blob
+		wantErr bool
+	}{
+		{
+			name: "tagged fields",
+			text: `#Blob @checksum{AAE=} {#data{SGVsbG8=} #Key{a2V5}}`,
+			want: Blob{Data: []byte("Hello"), Checksum: []byte{0, 1}, Key: &key},
+		},
+		{
+			name: "whitespace is ignored",
+			text: "#! Blob {\n  data \"SGVs\n    bG8=\"\n}",
+			want: Blob{Data: []byte("Hello")},
+		},
+		{
+			name: "encoding attribute",
+			text: `#Blob {#Payload @encoding{base64} {SGVsbG8=}}`,
+			want: Blob{Payload: []byte("Hello")},
+		},
+		{
+			name:    "invalid text",
+			text:    `#Blob {#data{not base64!}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid attribute",
+			text:    `#Blob @checksum{%%}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported encoding",
+			text:    `#Blob {#Payload @encoding{hex} {48656c6c6f}}`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var value struct {
+				Blob Blob
+			}
+
+			err := Unmarshal(strings.NewReader(test.text), &value, false)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got %+v", value.Blob)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(value.Blob, test.want) {
+				t.Errorf("expected %+v, but got %+v", test.want, value.Blob)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBase64RequiresBytes(t *testing.T) {
+	t.Parallel()
+
+	var value struct {
+		Data string `dyml:"data,base64"`
+	}
+
+	if err := Unmarshal(strings.NewReader(`#data{SGVsbG8=}`), &value, false); err == nil {
+		t.Error("expected an error for a string field")
+	}
+}
+
+func TestMarshalBase64(t *testing.T) {
+	t.Parallel()
+
+	key := []byte{0xff, 0xfe}
+	type Document struct {
+		Blob Blob
+	}
+
+	want := Document{Blob{Data: []byte("Hello"), Checksum: []byte{0, 1}, Key: &key, Payload: []byte{1, 2}}}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	text := buf.String()
+	for _, s := range []string{`data @encoding="base64" "SGVsbG8="`, `@checksum="AAE="`, `"//4="`} {
+		if !strings.Contains(text, s) {
+			t.Errorf("expected '%s' in the output, but got:\n%s", s, text)
+		}
+	}
+
+	var got Document
+	if err := Unmarshal(strings.NewReader(text), &got, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes, which is written with a unit like "10MiB" or "1.5GB" in documents.
+// Units with an "i" are powers of 1024, the others powers of 1000. Units are not case-sensitive and
+// a number without a unit is a number of bytes.
+type ByteSize uint64
+
+// Units of ByteSize.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+	EB ByteSize = 1000 * PB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+	EiB ByteSize = 1024 * PiB
+)
+
+// byteSizeUnits are all units ordered from largest to smallest, binary before decimal units of the same magnitude.
+//
+//nolint:gochecknoglobals // A constant table that is never modified.
+var byteSizeUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"EiB", EiB}, {"EB", EB}, {"PiB", PiB}, {"PB", PB}, {"TiB", TiB}, {"TB", TB},
+	{"GiB", GiB}, {"GB", GB}, {"MiB", MiB}, {"MB", MB}, {"KiB", KiB}, {"KB", KB}, {"B", Byte},
+}
+
+// ParseByteSize parses a size like "10MiB", "1.5 GB" or "512".
+func ParseByteSize(s string) (ByteSize, error) {
+	text := strings.TrimSpace(s)
+
+	end := len(text)
+	for end > 0 && (text[end-1] < '0' || text[end-1] > '9') && text[end-1] != '.' {
+		end--
+	}
+
+	number, unit := strings.TrimSpace(text[:end]), strings.TrimSpace(text[end:])
+
+	size := Byte
+
+	if unit != "" {
+		found := false
+
+		for _, u := range byteSizeUnits {
+			if strings.EqualFold(unit, u.name) {
+				size, found = u.size, true
+
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("unknown unit '%s' in byte size '%s'", unit, s)
+		}
+	}
+
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(size) {
+			return 0, fmt.Errorf("byte size '%s' is too large", s)
+		}
+
+		return ByteSize(n) * size, nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size '%s'", s)
+	}
+
+	bytes := math.Round(f * float64(size))
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size '%s' is too large", s)
+	}
+
+	return ByteSize(bytes), nil
+}
+
+// String returns the size with the largest unit that represents it exactly, e.g. "10MiB" or "1500B".
+// The result can be parsed with ParseByteSize.
+func (b ByteSize) String() string {
+	for _, u := range byteSizeUnits {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+
+	return "0B"
+}
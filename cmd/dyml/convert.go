@@ -0,0 +1,345 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Formats that can be converted.
+const (
+	formatDyml = "dyml"
+	formatXML  = "xml"
+	formatJSON = "json"
+)
+
+// runConvert converts the file in args into dyml.
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "format of the input, dyml, xml or json (default from the file extension)")
+	to := flags.String("to", formatDyml, "format of the output, only dyml is supported")
+	indent := flags.Int("indent", 4, "number of spaces per level of indentation")
+	unwrap := flags.Bool("unwrap", false, "convert the children of the XML root element instead of the element itself")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "convert requires exactly one file\n\n%s", usage)
+
+		return exitError
+	}
+
+	filename := flags.Arg(0)
+
+	if *from == "" {
+		*from = formatOf(filename)
+	}
+
+	if *to != formatDyml {
+		fmt.Fprintf(stderr, "cannot convert to '%s', only %s is supported\n", *to, formatDyml)
+
+		return exitError
+	}
+
+	if *indent < 0 {
+		fmt.Fprintf(stderr, "invalid indent %d\n", *indent)
+
+		return exitError
+	}
+
+	var input io.Reader = os.Stdin
+
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+
+			return exitError
+		}
+
+		defer file.Close()
+
+		input = file
+	}
+
+	enc := encoder.NewDymlEncoder(stdout, encoder.WithDymlIndent(strings.Repeat(" ", *indent)))
+
+	if err := convert(*from, filename, input, enc, *unwrap); err != nil {
+		fmt.Fprintf(stderr, "cannot convert '%s': %v\n", filename, err)
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// formatOf returns the format of a file by its extension, which is dyml for unknown extensions.
+func formatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xml":
+		return formatXML
+	case ".json":
+		return formatJSON
+	default:
+		return formatDyml
+	}
+}
+
+// convert reads r in the given format and writes it with enc. XML and dyml are written while they
+// are read, JSON in the form of parser.TreeNode.MarshalJSON is read completely first.
+// The root element of XML is a top-level element of the output, unless unwrap is true, which
+// is useful for XML that was written by an encoder.XMLEncoder.
+func convert(format, filename string, r io.Reader, enc *encoder.DymlEncoder, unwrap bool) error {
+	switch format {
+	case formatDyml:
+		visitor := parser.NewVisitor(filename, r)
+		visitor.SetVisitable(&childStream{encoder: enc})
+
+		return visitor.Run()
+	case formatXML:
+		var opts []encoder.XMLDecoderOption
+		if !unwrap {
+			opts = append(opts, encoder.WithDocumentElement())
+		}
+
+		decoder := encoder.NewXMLDecoder(filename, r, opts...)
+		decoder.SetVisitable(&childStream{encoder: enc})
+
+		return decoder.Run()
+	case formatJSON:
+		var tree parser.TreeNode
+		if err := json.NewDecoder(r).Decode(&tree); err != nil {
+			return err
+		}
+
+		return enc.Encode(&tree)
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+// childStream is a parser.Visitable that writes each child of the root with a DymlEncoder as soon as
+// it is complete, so that only a single child has to be kept in memory.
+type childStream struct {
+	encoder *encoder.DymlEncoder
+	// recorder contains the events of the current child within a root, so that they can be turned into a tree.
+	recorder parser.Recorder
+	// depth is the number of open elements, including the root.
+	depth int
+	// forwardDepth is the number of open forwarded nodes and nodes within them.
+	forwardDepth int
+	// forwarded is true while forwards on the top level wait for the element that they are forwarded to.
+	forwarded bool
+}
+
+// recording returns true if a child is being recorded.
+func (s *childStream) recording() bool {
+	return len(s.recorder.Events()) > 0
+}
+
+// begin starts recording a new child, unless one is recorded already.
+func (s *childStream) begin() error {
+	if s.recording() {
+		return nil
+	}
+
+	if err := s.recorder.Open(token.Identifier{Value: parser.DefaultRootName}); err != nil {
+		return err
+	}
+
+	return s.recorder.SetBlockType(parser.BlockNormal)
+}
+
+// end writes the recorded children, once all of their elements are closed.
+func (s *childStream) end() error {
+	if s.depth > 1 || s.forwarded || !s.recording() {
+		return nil
+	}
+
+	if err := s.recorder.Close(); err != nil {
+		return err
+	}
+
+	if err := s.recorder.Finalize(); err != nil {
+		return err
+	}
+
+	tree, err := s.recorder.Tree()
+	if err != nil {
+		return err
+	}
+
+	s.recorder.Reset()
+
+	for _, child := range tree.Children {
+		if err := s.encoder.EncodeChild(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// topLevel returns true if the next event belongs to the root itself and nothing is recorded.
+func (s *childStream) topLevel() bool {
+	return s.depth == 1 && s.forwardDepth == 0 && !s.recording()
+}
+
+func (s *childStream) Open(name token.Identifier) error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth++
+
+		return s.recorder.Open(name)
+	}
+
+	s.depth++
+
+	if s.depth == 1 {
+		return nil
+	}
+
+	s.forwarded = false
+
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	return s.recorder.Open(name)
+}
+
+func (s *childStream) Comment(comment token.CharData) error {
+	if s.topLevel() {
+		return s.encoder.EncodeChild(parser.NewStringCommentNode(comment.Value))
+	}
+
+	return s.recorder.Comment(comment)
+}
+
+func (s *childStream) Text(text token.CharData) error {
+	if s.topLevel() {
+		return s.encoder.EncodeChild(parser.NewStringNode(text.Value))
+	}
+
+	return s.recorder.Text(text)
+}
+
+func (s *childStream) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth++
+
+		return s.recorder.OpenReturnArrow(arrow, name)
+	}
+
+	s.depth++
+	s.forwarded = false
+
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	return s.recorder.OpenReturnArrow(arrow, name)
+}
+
+func (s *childStream) CloseReturnArrow() error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth--
+
+		return s.recorder.CloseReturnArrow()
+	}
+
+	s.depth--
+
+	if err := s.recorder.CloseReturnArrow(); err != nil {
+		return err
+	}
+
+	return s.end()
+}
+
+func (s *childStream) SetBlockType(blockType parser.BlockType) error {
+	if s.depth == 1 && s.forwardDepth == 0 {
+		return nil
+	}
+
+	return s.recorder.SetBlockType(blockType)
+}
+
+func (s *childStream) OpenForward(name token.Identifier) error {
+	s.forwardDepth++
+
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	return s.recorder.OpenForward(name)
+}
+
+func (s *childStream) TextForward(text token.CharData) error {
+	s.forwarded = s.forwarded || s.depth == 1
+
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	return s.recorder.TextForward(text)
+}
+
+func (s *childStream) Close() error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth--
+		s.forwarded = s.forwarded || (s.forwardDepth == 0 && s.depth == 1)
+
+		return s.recorder.Close()
+	}
+
+	s.depth--
+
+	if s.depth == 0 {
+		return nil
+	}
+
+	if err := s.recorder.Close(); err != nil {
+		return err
+	}
+
+	return s.end()
+}
+
+func (s *childStream) Attribute(key token.Identifier, value token.CharData) error {
+	if s.depth == 1 && s.forwardDepth == 0 {
+		return token.NewPosError(key, fmt.Sprintf("attribute '%s' of the root element cannot be converted", key.Value))
+	}
+
+	return s.recorder.Attribute(key, value)
+}
+
+func (s *childStream) AttributeForward(key token.Identifier, value token.CharData) error {
+	s.forwarded = s.forwarded || s.depth == 1
+
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	return s.recorder.AttributeForward(key, value)
+}
+
+func (s *childStream) Finalize() error {
+	// Forwards without an element to forward them to are still written, so that the parser reports them.
+	s.forwarded = false
+
+	return s.end()
+}
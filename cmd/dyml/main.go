@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Command dyml provides tools to work with dyml documents.
+//
+// Usage:
+//
+//	dyml convert [-from format] [-to dyml] [-indent n] file
+//	dyml diff a.dyml b.dyml
+//	dyml gen [-package name] [-type name] example.dyml
+//	dyml lint [-allow names] [-attrs keys] [-disable rules] files...
+//	dyml tokens file
+//
+// convert reads a document in the format dyml, xml or json and prints it as dyml with the DymlEncoder,
+// see package encoder. The format is taken from the file extension unless -from is given, "-" reads
+// from standard input. XML and dyml are converted while they are read, so large files do not need
+// to fit into memory, JSON has to be in the form written by parser.TreeNode.MarshalJSON.
+//
+// diff prints the structural differences between two documents, one per line.
+// It exits with 0 if the documents are equal, 1 if they differ and 2 on errors.
+//
+// gen prints Go structs with dyml tags for documents like the given example, see package dymlgen.
+//
+// lint prints the findings of the rules in package lint for all files, one per line.
+// It exits with 0 if there are no findings, 1 if there are findings and 2 on errors.
+//
+// tokens prints the tokens of a document with their positions, one per line, see token.Dump.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golangee/dyml"
+	"github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/dymlgen"
+	"github.com/golangee/dyml/lint"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Exit codes of the command.
+const (
+	exitOK = iota
+	exitDifferent
+	exitError
+	// exitFindings is returned by lint if any rule reported a problem.
+	exitFindings = exitDifferent
+)
+
+// usage describes all available commands.
+const usage = `usage: dyml <command> [arguments]
+
+commands:
+    convert <file>  print a document as dyml, "-" reads from standard input
+        -from       format of the input, dyml, xml or json (default from the file extension)
+        -to         format of the output, only dyml is supported (default "dyml")
+        -indent     number of spaces per level of indentation (default 4)
+        -unwrap     convert the children of the XML root element instead of the element itself
+    diff <a> <b>    print structural differences between two documents
+    gen <example>   print Go structs for documents like the example
+        -package    package of the generated code (default "main")
+        -type       name of the struct for the whole document (default "Document")
+    lint <files>    print style problems in documents
+        -allow      comma separated names of elements that may be repeated
+        -attrs      comma separated keys of known attributes
+        -disable    comma separated names of rules to skip
+    tokens <file>   print the tokens of a document
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given in args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+
+		return exitError
+	}
+
+	switch args[0] {
+	case "convert":
+		return runConvert(args[1:], stdout, stderr)
+	case "diff":
+		return runDiff(args[1:], stdout, stderr)
+	case "gen":
+		return runGen(args[1:], stdout, stderr)
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "tokens":
+		return runTokens(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command '%s'\n\n%s", args[0], usage)
+
+		return exitError
+	}
+}
+
+// runDiff prints the differences between the two files in args.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintf(stderr, "diff requires exactly two files\n\n%s", usage)
+
+		return exitError
+	}
+
+	a, err := parseFile(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	b, err := parseFile(args[1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	changes := compare.Diff(a, b)
+	for _, change := range changes {
+		pos := change.ToRange.BeginPos
+		if change.Type == compare.Removed {
+			pos = change.FromRange.BeginPos
+		}
+
+		fmt.Fprintf(stdout, "%s: %s\n", pos, change)
+	}
+
+	if len(changes) > 0 {
+		return exitDifferent
+	}
+
+	return exitOK
+}
+
+// runGen prints the Go structs generated for the example file in args.
+func runGen(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	packageName := flags.String("package", "main", "package of the generated code")
+	typeName := flags.String("type", "Document", "name of the struct for the whole document")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "gen requires exactly one file\n\n%s", usage)
+
+		return exitError
+	}
+
+	tree, err := parseFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	src, err := dymlgen.Generate(tree, dymlgen.WithPackageName(*packageName), dymlgen.WithTypeName(*typeName))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	if _, err := stdout.Write(src); err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// runLint prints the findings of all lint rules for the files in args.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	allow := flags.String("allow", "", "comma separated names of elements that may be repeated")
+	attrs := flags.String("attrs", "", "comma separated keys of known attributes")
+	disable := flags.String("disable", "", "comma separated names of rules to skip")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintf(stderr, "lint requires at least one file\n\n%s", usage)
+
+		return exitError
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range splitList(*disable) {
+		disabled[name] = true
+	}
+
+	var rules []lint.Rule
+
+	for _, rule := range []lint.Rule{
+		lint.DuplicateSiblings(splitList(*allow)...),
+		lint.EmptyBlocks(),
+		lint.MixedBlockTypes(),
+		lint.AttributeTypos(splitList(*attrs)...),
+	} {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	code := exitOK
+
+	for _, filename := range flags.Args() {
+		tree, err := parseFile(filename)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+
+			return exitError
+		}
+
+		for _, finding := range lint.Lint(tree, rules...) {
+			fmt.Fprintf(stdout, "%s: %s\n", finding.Range.BeginPos, finding)
+
+			code = exitFindings
+		}
+	}
+
+	return code
+}
+
+// splitList returns the trimmed, non-empty values of a comma separated list.
+func splitList(list string) []string {
+	var values []string
+
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// runTokens prints the tokens of the file in args.
+func runTokens(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(stderr, "tokens requires exactly one file\n\n%s", usage)
+
+		return exitError
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+
+		return exitError
+	}
+
+	defer file.Close()
+
+	if err := token.Dump(file, stdout); err != nil {
+		fmt.Fprintf(stderr, "cannot read tokens of '%s': %v\n", args[0], err)
+
+		return exitError
+	}
+
+	return exitOK
+}
+
+// parseFile parses the file with the given name.
+func parseFile(filename string) (*parser.TreeNode, error) {
+	tree, err := dyml.ParseFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse '%s': %w", filename, err)
+	}
+
+	return tree, nil
+}
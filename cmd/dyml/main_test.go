@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConvert(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"doc.xml":  `<root><!-- c --><book id="1"><title>A title</title></book><toc/></root>`,
+		"doc.dyml": "#? c\n#! book @id=\"1\" {\n  title \"A title\"\n}\n#toc",
+		"doc.json": `{"name": "root", "block": "{}", "children": [{"name": "book", "attrs": [{"key": "id", "value": "1"}]}]}`,
+		// Forwarded attributes on the top level wait for the element that they belong to.
+		"forward.dyml": `#! @@id="1" book`,
+		"root.xml":     `<root version="1"/>`,
+		"cfg.xml":      `<cfg version="1"><db host="localhost"/></cfg>`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"-indent", "2", "-unwrap", "doc.xml"},
+			want: "#? c\n#! book @id=\"1\" {\n  title {\n    \"A title\"\n  }\n}\n#! toc,\n",
+		},
+		{
+			args: []string{"cfg.xml"},
+			want: "#! cfg @version=\"1\" {\n    db @host=\"localhost\",\n}\n",
+		},
+		{
+			args: []string{"root.xml"},
+			want: "#! root @version=\"1\",\n",
+		},
+		{
+			args: []string{"-from", "dyml", "doc.dyml"},
+			want: "#? c\n#! book @id=\"1\" {\n    title \"A title\"\n}\n#! toc,\n",
+		},
+		{
+			args: []string{"doc.json"},
+			want: "#! book @id=\"1\",\n",
+		},
+		{
+			args: []string{"forward.dyml"},
+			want: "#! book @id=\"1\",\n",
+		},
+	}
+
+	for _, test := range tests {
+		args := append([]string{"convert"}, test.args...)
+		args[len(args)-1] = filepath.Join(dir, args[len(args)-1])
+
+		var stdout, stderr bytes.Buffer
+
+		if code := run(args, &stdout, &stderr); code != exitOK {
+			t.Errorf("%v: expected success, got %d: %s", test.args, code, stderr.String())
+
+			continue
+		}
+
+		if stdout.String() != test.want {
+			t.Errorf("%v: expected output\n%s\nbut got\n%s", test.args, test.want, stdout.String())
+		}
+	}
+
+	for _, args := range [][]string{
+		{"convert", "-unwrap", filepath.Join(dir, "root.xml")},
+		{"convert", "-from", "yaml", filepath.Join(dir, "doc.dyml")},
+		{"convert", "-to", "xml", filepath.Join(dir, "doc.dyml")},
+		{"convert", "-indent", "-1", filepath.Join(dir, "doc.dyml")},
+		{"convert"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != exitError {
+			t.Errorf("%v: expected an error, got %d", args, code)
+		}
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.dyml")
+	b := filepath.Join(dir, "b.dyml")
+
+	if err := os.WriteFile(a, []byte("#name{Gopher}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(b, []byte("#name{Ferris}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"diff", a, a}, &stdout, &stderr); code != exitOK || stdout.Len() > 0 {
+		t.Errorf("expected no differences, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+
+	if code := run([]string{"diff", a, b}, &stdout, &stderr); code != exitDifferent {
+		t.Errorf("expected differences, got %d: %s", code, stderr.String())
+	}
+
+	want := b + `:1:7: ~ root/name/#text "Gopher" -> "Ferris"` + "\n"
+	if stdout.String() != want {
+		t.Errorf("expected output '%s', but got '%s'", want, stdout.String())
+	}
+
+	if code := run([]string{"diff", a}, &stdout, &stderr); code != exitError {
+		t.Errorf("expected an error for missing arguments, got %d", code)
+	}
+}
+
+func TestRunGen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	example := filepath.Join(dir, "example.dyml")
+
+	if err := os.WriteFile(example, []byte("#server @host{localhost}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"gen", "-package", "config", "-type", "Config", example}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected success, got %d: %s", code, stderr.String())
+	}
+
+	for _, want := range []string{"package config", "type Config struct", "Host string `dyml:\"host,attr\"`"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected output to contain '%s', but got '%s'", want, stdout.String())
+		}
+	}
+
+	if code := run([]string{"gen"}, &stdout, &stderr); code != exitError {
+		t.Errorf("expected an error for missing arguments, got %d", code)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.dyml")
+	problems := filepath.Join(dir, "problems.dyml")
+
+	if err := os.WriteFile(clean, []byte("#server @host{a} #server @host{b}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(problems, []byte("#a{} #a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"lint", "-allow", "server", clean}, &stdout, &stderr); code != exitOK || stdout.Len() > 0 {
+		t.Errorf("expected no findings, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+
+	if code := run([]string{"lint", clean, problems}, &stdout, &stderr); code != exitFindings {
+		t.Errorf("expected findings, got %d: %s", code, stderr.String())
+	}
+
+	want := clean + ":1:19: 'server' is already defined at " + clean + ":1:2 (duplicate-siblings)\n" +
+		problems + ":1:2: 'a' has an empty block (empty-blocks)\n" +
+		problems + ":1:7: 'a' is already defined at " + problems + ":1:2 (duplicate-siblings)\n"
+	if stdout.String() != want {
+		t.Errorf("expected '%s', but got '%s'", want, stdout.String())
+	}
+
+	stdout.Reset()
+
+	if code := run([]string{"lint", "-disable", "duplicate-siblings,empty-blocks", problems}, &stdout, &stderr); code != exitOK {
+		t.Errorf("expected disabled rules to be skipped, got %d: %s", code, stdout.String())
+	}
+
+	if code := run([]string{"lint"}, &stdout, &stderr); code != exitError {
+		t.Errorf("expected an error for missing arguments, got %d", code)
+	}
+}
+
+func TestRunTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "doc.dyml")
+
+	if err := os.WriteFile(file, []byte("#name{Gopher}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"tokens", file}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected success, got %d: %s", code, stderr.String())
+	}
+
+	want := `1:1-1:2     DefineElement
+1:2-1:6     Identifier       "name"
+1:6-1:7     BlockStart
+1:7-1:13    CharData         "Gopher"
+1:13-1:14   BlockEnd
+`
+	if stdout.String() != want {
+		t.Errorf("expected output\n%s\nbut got\n%s", want, stdout.String())
+	}
+
+	if code := run([]string{"tokens", filepath.Join(dir, "missing.dyml")}, &stdout, &stderr); code != exitError {
+		t.Errorf("expected an error for a missing file, got %d", code)
+	}
+}
@@ -0,0 +1,359 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// ChangeType describes what happened to a node or attribute.
+type ChangeType string
+
+const (
+	// Added nodes or attributes only exist in the new tree.
+	Added ChangeType = "added"
+	// Removed nodes or attributes only exist in the old tree.
+	Removed ChangeType = "removed"
+	// Changed nodes or attributes exist in both trees, but their value differs.
+	Changed ChangeType = "changed"
+)
+
+// Change is a single difference between two trees.
+type Change struct {
+	Type ChangeType
+	// Path identifies the node in the tree, e.g. "root/server[1]/port".
+	// Should there be multiple siblings with the same name, the index among them is added in brackets.
+	// Text and comment nodes are named "#text" and "#comment".
+	// For removed nodes the path is relative to the old tree, otherwise it is relative to the new tree.
+	Path string
+	// Attribute is the key of the attribute that changed, or empty if the change is about the node itself.
+	Attribute string
+	// From is the old value, which is the text, comment, attribute value or block type.
+	// It is empty for added nodes and attributes.
+	From string
+	// To is the new value, just like From. It is empty for removed nodes and attributes.
+	To string
+	// FromRange is the position of the node or attribute in the old tree.
+	FromRange token.Position
+	// ToRange is the position of the node or attribute in the new tree.
+	ToRange token.Position
+}
+
+// String returns a single line describing the change.
+func (c Change) String() string {
+	path := c.Path
+	if c.Attribute != "" {
+		path += "@" + c.Attribute
+	}
+
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s%s", path, quoteValue(c.To))
+	case Removed:
+		return fmt.Sprintf("- %s%s", path, quoteValue(c.From))
+	case Changed:
+		return fmt.Sprintf("~ %s %s -> %s", path, strconv.Quote(c.From), strconv.Quote(c.To))
+	default:
+		return fmt.Sprintf("? %s", path)
+	}
+}
+
+// quoteValue returns the quoted value with a leading space, or nothing if the value is empty.
+func quoteValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return " " + strconv.Quote(value)
+}
+
+// Diff returns all changes that are needed to turn tree a into tree b.
+// Children are matched by their kind and name, so that inserting an element only results in a single
+// change and not in changes to all following siblings. Matched text and comment nodes with a different
+// value are reported as Changed. Positions and the order of attributes are ignored.
+func Diff(a, b *parser.TreeNode) []Change {
+	var changes []Change
+
+	diffNode(a, b, nodeName(a), nodeName(b), &changes)
+
+	return changes
+}
+
+// DiffNormalized works like Diff, but normalizes both trees with opts first, e.g. to ignore differences
+// in whitespace. The ranges of the changes are kept, unless opts.ClearRanges is set.
+func DiffNormalized(a, b *parser.TreeNode, opts parser.NormalizeOptions) []Change {
+	return Diff(parser.Normalize(a, opts), parser.Normalize(b, opts))
+}
+
+// diffNode compares the two nodes, which have been matched and therefore are of the same kind.
+// pathA and pathB are the paths of the nodes in their respective trees.
+func diffNode(a, b *parser.TreeNode, pathA, pathB string, changes *[]Change) {
+	// Elements have no value, but their block type might have changed.
+	from, to := nodeValue(a), nodeValue(b)
+	if a.IsNode() {
+		from, to = string(a.BlockType), string(b.BlockType)
+	}
+
+	if from != to {
+		*changes = append(*changes, Change{
+			Type:      Changed,
+			Path:      pathB,
+			From:      from,
+			To:        to,
+			FromRange: a.Range,
+			ToRange:   b.Range,
+		})
+	}
+
+	diffAttributes(a, b, pathA, pathB, changes)
+
+	childPathsA := childPaths(a, pathA)
+	childPathsB := childPaths(b, pathB)
+
+	// Walk both lists of children in order, using the matching pairs as anchors.
+	i, j := 0, 0
+
+	for _, pair := range matchChildren(a.Children, b.Children) {
+		for ; i < pair[0]; i++ {
+			*changes = append(*changes, removed(a.Children[i], childPathsA[i]))
+		}
+
+		for ; j < pair[1]; j++ {
+			*changes = append(*changes, added(b.Children[j], childPathsB[j]))
+		}
+
+		diffNode(a.Children[i], b.Children[j], childPathsA[i], childPathsB[j], changes)
+
+		i++
+		j++
+	}
+
+	for ; i < len(a.Children); i++ {
+		*changes = append(*changes, removed(a.Children[i], childPathsA[i]))
+	}
+
+	for ; j < len(b.Children); j++ {
+		*changes = append(*changes, added(b.Children[j], childPathsB[j]))
+	}
+}
+
+// diffAttributes compares the attributes of the two nodes.
+func diffAttributes(a, b *parser.TreeNode, pathA, pathB string, changes *[]Change) {
+	attributesA := attributeMap(a.Attributes)
+	attributesB := attributeMap(b.Attributes)
+
+	for _, key := range sortedKeys(attributesA) {
+		attrA := attributesA[key]
+
+		attrB, ok := attributesB[key]
+		if !ok {
+			*changes = append(*changes, Change{
+				Type:      Removed,
+				Path:      pathA,
+				Attribute: key,
+				From:      attrA.Value,
+				FromRange: attrA.Range,
+			})
+		} else if attrA.Value != attrB.Value {
+			*changes = append(*changes, Change{
+				Type:      Changed,
+				Path:      pathB,
+				Attribute: key,
+				From:      attrA.Value,
+				To:        attrB.Value,
+				FromRange: attrA.Range,
+				ToRange:   attrB.Range,
+			})
+		}
+	}
+
+	for _, key := range sortedKeys(attributesB) {
+		if attrB := attributesB[key]; attributesA[key] == nil {
+			*changes = append(*changes, Change{
+				Type:      Added,
+				Path:      pathB,
+				Attribute: key,
+				To:        attrB.Value,
+				ToRange:   attrB.Range,
+			})
+		}
+	}
+}
+
+// matchChildren finds the longest common subsequence of the two lists of children, where two children
+// match if they are of the same kind and have the same name. Equal children are preferred.
+// The result contains pairs of indices into a and b in ascending order.
+func matchChildren(a, b []*parser.TreeNode) [][2]int {
+	// lcs[i][j] contains the score of the best match of a[i:] and b[j:].
+	// A matching pair scores 2, or 3 if the children are equal.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	score := func(x, y *parser.TreeNode) int {
+		if nodeName(x) != nodeName(y) {
+			return 0
+		}
+
+		if equal(x, y) {
+			return 3
+		}
+
+		return 2
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			best := lcs[i+1][j]
+			if lcs[i][j+1] > best {
+				best = lcs[i][j+1]
+			}
+
+			if s := score(a[i], b[j]); s > 0 && lcs[i+1][j+1]+s > best {
+				best = lcs[i+1][j+1] + s
+			}
+
+			lcs[i][j] = best
+		}
+	}
+
+	var pairs [][2]int
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch s := score(a[i], b[j]); {
+		case s > 0 && lcs[i][j] == lcs[i+1][j+1]+s:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lcs[i][j] == lcs[i+1][j]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return pairs
+}
+
+// equal returns true if both trees have the same content, ignoring positions and the order of attributes.
+func equal(a, b *parser.TreeNode) bool {
+	if nodeName(a) != nodeName(b) || nodeValue(a) != nodeValue(b) || a.BlockType != b.BlockType ||
+		len(a.Children) != len(b.Children) || a.Attributes.Len() != b.Attributes.Len() {
+		return false
+	}
+
+	attributesB := attributeMap(b.Attributes)
+	for key, attr := range attributeMap(a.Attributes) {
+		if other := attributesB[key]; other == nil || other.Value != attr.Value {
+			return false
+		}
+	}
+
+	for i := range a.Children {
+		if !equal(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// removed creates a change for a node that only exists in the old tree.
+func removed(node *parser.TreeNode, path string) Change {
+	return Change{
+		Type:      Removed,
+		Path:      path,
+		From:      nodeValue(node),
+		FromRange: node.Range,
+	}
+}
+
+// added creates a change for a node that only exists in the new tree.
+func added(node *parser.TreeNode, path string) Change {
+	return Change{
+		Type:    Added,
+		Path:    path,
+		To:      nodeValue(node),
+		ToRange: node.Range,
+	}
+}
+
+// childPaths returns the paths for all children of node.
+// An index is only added if there are multiple siblings with the same name.
+func childPaths(node *parser.TreeNode, path string) []string {
+	counts := map[string]int{}
+	for _, child := range node.Children {
+		counts[nodeName(child)]++
+	}
+
+	indices := map[string]int{}
+	paths := make([]string, len(node.Children))
+
+	for i, child := range node.Children {
+		name := nodeName(child)
+		paths[i] = path + "/" + name
+
+		if counts[name] > 1 {
+			paths[i] += fmt.Sprintf("[%d]", indices[name])
+		}
+
+		indices[name]++
+	}
+
+	return paths
+}
+
+// nodeName returns the name of an element or "#text" and "#comment" for text and comment nodes.
+func nodeName(node *parser.TreeNode) string {
+	switch {
+	case node.IsText():
+		return "#text"
+	case node.IsComment():
+		return "#comment"
+	default:
+		return node.Name
+	}
+}
+
+// nodeValue returns the text of text nodes, the comment of comment nodes and an empty string for elements.
+func nodeValue(node *parser.TreeNode) string {
+	switch {
+	case node.IsText():
+		return *node.Text
+	case node.IsComment():
+		return *node.Comment
+	default:
+		return ""
+	}
+}
+
+// attributeMap returns all attributes in the list by their key.
+func attributeMap(list util.AttributeList) map[string]*util.Attribute {
+	result := map[string]*util.Attribute{}
+
+	for attr := list.Pop(); attr != nil; attr = list.Pop() {
+		result[attr.Key] = attr
+	}
+
+	return result
+}
+
+// sortedKeys returns the keys of the map in ascending order.
+func sortedKeys(m map[string]*util.Attribute) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package compare_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    `#! server @host="localhost" { port "80", port "443" }`,
+			b: `#! server @host="localhost" {
+					port "80"
+					port "443"
+				}`,
+			want: nil,
+		},
+		{
+			name: "changed text",
+			a:    `#name{Gopher}`,
+			b:    `#name{Ferris}`,
+			want: []string{`~ root/name/#text "Gopher" -> "Ferris"`},
+		},
+		{
+			name: "attributes",
+			a:    `#server @host{localhost} @port{80}`,
+			b:    `#server @port{8080} @tls{true}`,
+			want: []string{
+				`- root/server@host "localhost"`,
+				`~ root/server@port "80" -> "8080"`,
+				`+ root/server@tls "true"`,
+			},
+		},
+		{
+			name: "inserted element",
+			a:    `#! list { item "a", item "c" }`,
+			b:    `#! list { item "a", item "b", item "c" }`,
+			want: []string{`+ root/list/item[1]`},
+		},
+		{
+			name: "removed element",
+			a:    `#! list { a, b, c }`,
+			b:    `#! list { a, c }`,
+			want: []string{`- root/list/b`},
+		},
+		{
+			name: "block type",
+			a:    `#! fn (a)`,
+			b:    `#! fn <a>`,
+			want: []string{`~ root/fn "()" -> "<>"`},
+		},
+		{
+			name: "comments",
+			a: `#! x { // old comment
+					y }`,
+			b:    `#! x { y }`,
+			want: []string{`- root/x/#comment "old comment"`},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := parser.NewParser("a", strings.NewReader(test.a)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := parser.NewParser("b", strings.NewReader(test.b)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, change := range Diff(a, b) {
+				got = append(got, change.String())
+			}
+
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("wanted changes\n%s\nbut got\n%s", strings.Join(test.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffPositions(t *testing.T) {
+	t.Parallel()
+
+	a, err := parser.NewParser("a", strings.NewReader("#a @key{1}")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parser.NewParser("b", strings.NewReader("#!\n\na @key=\"2\",")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("expected one change, but got %v", changes)
+	}
+
+	if changes[0].FromRange.BeginPos.Line != 1 || changes[0].ToRange.BeginPos.Line != 3 {
+		t.Errorf("unexpected positions %v and %v", changes[0].FromRange, changes[0].ToRange)
+	}
+}
+
+func TestDiffNormalized(t *testing.T) {
+	t.Parallel()
+
+	a, err := parser.NewParser("a", strings.NewReader("#name{some text} #port 80")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parser.NewParser("b", strings.NewReader("#name{ some\n  text }\n#port 81")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, change := range DiffNormalized(a, b, parser.NormalizeOptions{CollapseWhitespace: true}) {
+		got = append(got, change.ToRange.BeginPos.String()+": "+change.String())
+	}
+
+	want := []string{`b:3:7: ~ root/port/#text "80" -> "81"`}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wanted changes\n%s\nbut got\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
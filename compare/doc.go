@@ -0,0 +1,6 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package compare computes structural differences between two parsed dyml trees,
+// e.g. to review changes to a configuration.
+package compare
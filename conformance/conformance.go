@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package conformance
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// corpus contains all documents and golden files.
+//
+//go:embed testdata
+var corpus embed.FS
+
+const (
+	// documentExt is the file extension of documents in the corpus.
+	documentExt = ".dyml"
+	// goldenExt is the file extension of golden files in the corpus.
+	goldenExt = ".json"
+)
+
+// Node is a parsed tree in a form that does not depend on a specific implementation.
+// Golden files contain it as JSON. Positions are not part of it.
+type Node struct {
+	// Name is the name of an element.
+	Name string `json:"name,omitempty"`
+	// Block is the block type of an element, e.g. "{}".
+	Block string `json:"block,omitempty"`
+	// Attributes of an element in the order they were defined.
+	Attributes []Attribute `json:"attributes,omitempty"`
+	// Text is set for text nodes.
+	Text *string `json:"text,omitempty"`
+	// Comment is set for comment nodes.
+	Comment  *string `json:"comment,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Attribute is a key-value pair of a Node.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// FromTree converts a tree of the parser of this module into a Node.
+func FromTree(tree *parser.TreeNode) *Node {
+	node := &Node{
+		Name:    tree.Name,
+		Block:   string(tree.BlockType),
+		Text:    tree.Text,
+		Comment: tree.Comment,
+	}
+
+	for i := 0; i < tree.Attributes.Len(); i++ {
+		attr := tree.Attributes.GetAt(i)
+		node.Attributes = append(node.Attributes, Attribute{Key: attr.Key, Value: attr.Value})
+	}
+
+	for _, child := range tree.Children {
+		node.Children = append(node.Children, FromTree(child))
+	}
+
+	return node
+}
+
+// Implementation parses a document into a tree. An error must be returned for invalid documents.
+type Implementation func(filename string, r io.Reader) (*Node, error)
+
+// Reference is the Implementation that uses the parser of this module.
+func Reference(filename string, r io.Reader) (*Node, error) {
+	tree, err := parser.NewParser(filename, r).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return FromTree(tree), nil
+}
+
+// Case is a single document of the corpus.
+type Case struct {
+	// Name is the path of the document within the corpus without extension, e.g. "valid/attributes".
+	Name string
+	// Document is the dyml input.
+	Document []byte
+	// Valid is true if the document must be accepted.
+	Valid bool
+	// Golden is the expected result for valid documents in JSON.
+	Golden []byte
+}
+
+// Want returns the expected tree of a valid document.
+func (c Case) Want() (*Node, error) {
+	var node Node
+
+	if err := json.Unmarshal(c.Golden, &node); err != nil {
+		return nil, fmt.Errorf("invalid golden file of '%s': %w", c.Name, err)
+	}
+
+	return &node, nil
+}
+
+// Corpus returns all cases, sorted by their name.
+func Corpus() ([]Case, error) {
+	var cases []Case
+
+	for _, dir := range []string{"valid", "invalid"} {
+		entries, err := fs.ReadDir(corpus, path.Join("testdata", dir))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), documentExt) {
+				continue
+			}
+
+			c := Case{
+				Name:  path.Join(dir, strings.TrimSuffix(entry.Name(), documentExt)),
+				Valid: dir == "valid",
+			}
+
+			c.Document, err = corpus.ReadFile(path.Join("testdata", c.Name+documentExt))
+			if err != nil {
+				return nil, err
+			}
+
+			if c.Valid {
+				c.Golden, err = corpus.ReadFile(path.Join("testdata", c.Name+goldenExt))
+				if err != nil {
+					return nil, fmt.Errorf("valid document '%s' has no golden file: %w", c.Name, err)
+				}
+			}
+
+			cases = append(cases, c)
+		}
+	}
+
+	return cases, nil
+}
+
+// RunConformance checks impl against every case of the corpus in its own subtest.
+// Valid documents must result in the tree of their golden file, invalid documents in an error.
+func RunConformance(t *testing.T, impl Implementation) {
+	t.Helper()
+
+	cases, err := Corpus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := impl(c.Name+documentExt, bytes.NewReader(c.Document))
+
+			if !c.Valid {
+				if err == nil {
+					t.Fatalf("invalid document was accepted:\n%s", c.Document)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("valid document was rejected: %v", err)
+			}
+
+			want, err := c.Want()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotJSON, wantJSON := MarshalGolden(got), MarshalGolden(want)
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Fatalf("expected\n%s\nbut got\n%s", wantJSON, gotJSON)
+			}
+		})
+	}
+}
+
+// MarshalGolden returns the content of a golden file for node.
+func MarshalGolden(node *Node) []byte {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(node); err != nil {
+		// A Node only contains strings and slices, which can always be encoded.
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package conformance_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/conformance"
+	"github.com/golangee/dyml/parser"
+)
+
+// update is set with -update to regenerate the golden files instead of comparing with them.
+//
+//nolint:gochecknoglobals // Test flags have to be registered before the tests run.
+var update = flag.Bool("update", false, "write the golden files of the corpus with the reference implementation")
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	if *update {
+		updateGolden(t)
+
+		// The corpus is embedded, so the new golden files are only checked by the next run.
+		return
+	}
+
+	RunConformance(t, Reference)
+}
+
+// updateGolden writes the golden files of all valid documents.
+func updateGolden(t *testing.T) {
+	t.Helper()
+
+	documents, err := filepath.Glob(filepath.Join("testdata", "valid", "*.dyml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, document := range documents {
+		buf, err := os.ReadFile(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Reference(document, bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%s: %v", document, err)
+		}
+
+		golden := strings.TrimSuffix(document, ".dyml") + ".json"
+		if err := os.WriteFile(golden, MarshalGolden(got), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConformanceRecorder(t *testing.T) {
+	t.Parallel()
+
+	// Replaying recorded events must build the same trees as parsing.
+	RunConformance(t, func(filename string, r io.Reader) (*Node, error) {
+		recorder, err := parser.Record(filename, r)
+		if err != nil {
+			return nil, err
+		}
+
+		tree, err := recorder.Tree()
+		if err != nil {
+			return nil, err
+		}
+
+		return FromTree(tree), nil
+	})
+}
+
+func TestCorpus(t *testing.T) {
+	t.Parallel()
+
+	cases, err := Corpus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var valid, invalid int
+
+	for _, c := range cases {
+		if c.Valid {
+			valid++
+
+			if _, err := c.Want(); err != nil {
+				t.Error(err)
+			}
+		} else {
+			invalid++
+		}
+	}
+
+	if valid == 0 || invalid == 0 {
+		t.Fatalf("expected valid and invalid documents, got %d and %d", valid, invalid)
+	}
+}
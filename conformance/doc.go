@@ -0,0 +1,16 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package conformance contains a corpus of valid and invalid dyml documents together with
+// golden files of their parse trees, which acts as the specification of the grammar.
+// RunConformance checks any parser implementation against it:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.RunConformance(t, myParser)
+//	}
+//
+// The corpus is located in testdata. Each valid document "name.dyml" in testdata/valid has a
+// golden file "name.json" with its expected tree, documents in testdata/invalid must be rejected.
+// New edge cases are added by creating a document and running "go test ./conformance -update",
+// which writes the golden files with the parser of this module.
+package conformance
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// ContentKind tells what a Content is.
+type ContentKind int
+
+const (
+	// ContentText is a text, whose value is in Content.Text.
+	ContentText ContentKind = iota
+	// ContentElement is a child element, which is in Content.Element.
+	ContentElement
+	// ContentComment is a comment, whose value is in Content.Text.
+	ContentComment
+)
+
+// Content is a child of an element, which is collected by fields with a 'children' tag in document order,
+// so that texts keep their place between the elements of markup documents.
+type Content struct {
+	Kind ContentKind
+	// Text is the value of texts and comments.
+	Text string
+	// Element is set for child elements.
+	Element *parser.TreeNode
+}
+
+// contentSliceType is the type of fields with a 'children' tag.
+//
+//nolint:gochecknoglobals // Computed once instead of for every field with a 'children' tag.
+var contentSliceType = reflect.TypeOf([]Content{})
+
+// doChildren sets the slice of Content in value to all children of node.
+func (u *unmarshaler) doChildren(node *parser.TreeNode, value reflect.Value) error {
+	slice := allocate(value)
+	if slice.Type() != contentSliceType {
+		return NewUnmarshalError(node,
+			fmt.Sprintf("'children' struct tag requires a []dyml.Content, not '%s'", slice.Type()), nil)
+	}
+
+	contents := make([]Content, 0, len(node.Children))
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsText():
+			contents = append(contents, Content{Kind: ContentText, Text: *child.Text})
+		case child.IsComment():
+			contents = append(contents, Content{Kind: ContentComment, Text: *child.Comment})
+		default:
+			contents = append(contents, Content{Kind: ContentElement, Element: child})
+		}
+
+		u.pushPath(fmt.Sprintf("[%d]", len(contents)-1))
+		u.record(child.Range)
+		u.popPath()
+	}
+
+	slice.Set(reflect.ValueOf(contents))
+
+	return nil
+}
+
+// doMarshalChildren adds the contents in value, which belongs to a 'children' field, as children to node.
+func (m *marshaler) doMarshalChildren(node *parser.TreeNode, value reflect.Value) error {
+	value = reflect.Indirect(value)
+	if value.Type() != contentSliceType {
+		return fmt.Errorf("'children' struct tag requires a []dyml.Content, not '%s'", value.Type())
+	}
+
+	for _, content := range value.Interface().([]Content) { //nolint:forcetypeassert
+		switch content.Kind {
+		case ContentText:
+			node.AddChildren(parser.NewStringNode(content.Text))
+		case ContentComment:
+			node.AddChildren(parser.NewStringCommentNode(content.Text))
+		case ContentElement:
+			if content.Element == nil {
+				return errors.New("content of kind element has no element")
+			}
+
+			node.AddChildren(content.Element.Clone())
+		default:
+			return fmt.Errorf("invalid content kind %d", content.Kind)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// UnmarshalOption can be used to change the behavior of the unmarshalling process.
+type UnmarshalOption func(u *unmarshaler)
+
+// WithProvenance enables recording the source position of every populated field.
+// The result can be retrieved with Decoder.Provenance after decoding.
+func WithProvenance() UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.provenance = Provenance{}
+	}
+}
+
+// WithPreprocessor adds a stage that transforms the tree before it is unmarshalled,
+// e.g. preprocess.Variables. Preprocessors are applied in the order they are given
+// to a copy of the tree, so that trees passed to DecodeTree are not modified.
+func WithPreprocessor(preprocessor func(tree *parser.TreeNode) error) UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.preprocessors = append(u.preprocessors, preprocessor)
+	}
+}
+
+// WithParserOptions configures the parser that reads the input, e.g. to set limits
+// with parser.WithMaxDepth when decoding untrusted input.
+func WithParserOptions(opts ...parser.ParserOption) UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.parserOptions = append(u.parserOptions, opts...)
+	}
+}
+
+// WithNamingStrategy converts the names of all fields without a rename tag with strategy, e.g. SnakeCase,
+// so that documents can follow their own naming conventions without tagging every field.
+func WithNamingStrategy(strategy NamingStrategy) UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.naming = strategy
+	}
+}
+
+// WithCaseInsensitiveNames matches the names of elements and attributes with those of the fields without
+// regard to case, so that "#port" is read into a field named "Port".
+func WithCaseInsensitiveNames() UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.caseInsensitive = true
+	}
+}
+
+// Provenance maps the path of each populated field to the position in the source it was read from.
+// Paths consist of the go field names separated by dots. Elements of slices and maps
+// are denoted by their index or key in brackets, e.g. "Server.Ports[1]" or "Users[admin].Name".
+type Provenance map[string]token.Position
+
+// Decoder reads a dyml document from an input stream and unmarshals it into go values.
+type Decoder struct {
+	filename   string
+	reader     io.Reader
+	strict     bool
+	opts       []UnmarshalOption
+	provenance Provenance
+}
+
+// NewDecoder creates a new Decoder reading from r. The filename is used for positional information.
+// See Unmarshal for the meaning of strict.
+func NewDecoder(filename string, r io.Reader, strict bool, opts ...UnmarshalOption) *Decoder {
+	return &Decoder{
+		filename: filename,
+		reader:   r,
+		strict:   strict,
+		opts:     opts,
+	}
+}
+
+// Decode parses the input and unmarshals it into the given value, just like Unmarshal.
+func (d *Decoder) Decode(into interface{}) error {
+	return d.DecodeContext(context.Background(), into)
+}
+
+// DecodeContext works like Decode, but stops with an error wrapping ctx.Err() once ctx is done
+// while parsing the input.
+func (d *Decoder) DecodeContext(ctx context.Context, into interface{}) error {
+	return d.decodePath(ctx, nil, into)
+}
+
+// DecodePath works like Decode, but only unmarshals the first element at path into the given value,
+// like UnmarshalPath.
+func (d *Decoder) DecodePath(path string, into interface{}) error {
+	return d.decodePath(context.Background(), splitPath(path), into)
+}
+
+// decodePath parses the input and unmarshals the first element at path, or the whole document
+// if path is empty, into the given value.
+func (d *Decoder) decodePath(ctx context.Context, path []string, into interface{}) error {
+	if into == nil {
+		return fmt.Errorf("cannot unmarshal into nil")
+	}
+
+	parserOptions := newUnmarshaler(d.strict, d.opts...).parserOptions
+
+	// The source text is only kept if it is needed, as it doubles the memory used for the input.
+	if hasRawFields(reflect.TypeOf(into)) {
+		parserOptions = append(parserOptions[:len(parserOptions):len(parserOptions)], parser.WithSourceMap())
+	}
+
+	if len(path) > 0 {
+		parserOptions = append(parserOptions[:len(parserOptions):len(parserOptions)], parser.WithSelection(path...))
+	}
+
+	p := parser.NewParser(d.filename, d.reader, parserOptions...)
+
+	tree, err := p.ParseContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	selected := parser.Select(tree, path...)
+	if selected == nil {
+		return fmt.Errorf("no element found at path '%s'", strings.Join(path, "/"))
+	}
+
+	return d.decodeTree(selected, into, p.SourceMap())
+}
+
+// splitPath splits a path like "server/http" into the names of its elements.
+func splitPath(path string) []string {
+	var names []string
+
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// DecodeTree works like Decode, but processes an already parsed tree.
+// Fields with a 'raw' tag cannot be read from parsed trees.
+func (d *Decoder) DecodeTree(tree *parser.TreeNode, into interface{}) error {
+	return d.decodeTree(tree, into, nil)
+}
+
+// decodeTree unmarshals tree into the given value. source is nil, unless the value has 'raw' fields.
+func (d *Decoder) decodeTree(tree *parser.TreeNode, into interface{}, source *parser.SourceMap) error {
+	unmarshal := newUnmarshaler(d.strict, d.opts...)
+	unmarshal.source = source
+
+	if len(unmarshal.preprocessors) > 0 {
+		tree = tree.Clone()
+
+		for _, preprocessor := range unmarshal.preprocessors {
+			if err := preprocessor(tree); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := unmarshal.doAny(tree, reflect.ValueOf(into))
+	d.provenance = unmarshal.provenance
+
+	return err
+}
+
+// Provenance returns where each field of the most recently decoded value was defined.
+// This is nil, unless the Decoder was created with the WithProvenance option.
+func (d *Decoder) Provenance() Provenance {
+	return d.provenance
+}
@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/preprocess"
+	"github.com/golangee/dyml/token"
+)
+
+func TestDecoderProvenance(t *testing.T) {
+	t.Parallel()
+
+	type Server struct {
+		Host  string `dyml:"host,attr"`
+		Ports []int  `dyml:"port"`
+	}
+
+	type Config struct {
+		Name   string `dyml:"name"`
+		Server Server `dyml:"server"`
+	}
+
+	input := `#name Example
+#server @host{localhost} {
+	#port 80
+	#port 443
+}`
+
+	var config Config
+
+	decoder := NewDecoder("config.dyml", strings.NewReader(input), false, WithProvenance())
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	// want maps field paths to the line they are expected on.
+	want := map[string]int{
+		"Name":            1,
+		"Server":          2,
+		"Server.Host":     2,
+		"Server.Ports":    2,
+		"Server.Ports[0]": 3,
+		"Server.Ports[1]": 4,
+	}
+
+	provenance := decoder.Provenance()
+	if len(provenance) != len(want) {
+		t.Errorf("expected %d recorded fields, but got %d: %v", len(want), len(provenance), provenance)
+	}
+
+	for path, line := range want {
+		pos, ok := provenance[path]
+		if !ok {
+			t.Errorf("no provenance recorded for '%s'", path)
+
+			continue
+		}
+
+		if pos.Begin().Line != line || pos.Begin().File != "config.dyml" {
+			t.Errorf("expected '%s' to be defined at config.dyml:%d, but got %s", path, line, pos.Begin())
+		}
+	}
+}
+
+func TestDecoderWithoutProvenance(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Name string `dyml:"name"`
+	}
+
+	var config Config
+
+	decoder := NewDecoder("", strings.NewReader("#name Example"), false)
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoder.Provenance() != nil {
+		t.Error("provenance should not be recorded by default")
+	}
+}
+
+func TestDecoderWithPreprocessor(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Host string
+	}
+
+	input := `#define @name{host} {example.com}
+#! Host "$(host)"`
+
+	tree, err := parser.NewParser("", strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config Config
+
+	decoder := NewDecoder("", nil, false, WithPreprocessor(preprocess.Variables))
+	if err := decoder.DecodeTree(tree, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Host != "example.com" {
+		t.Errorf("expected variable to be resolved, but got '%s'", config.Host)
+	}
+
+	if tree.Children[0].Name != "define" {
+		t.Error("the tree passed to DecodeTree must not be modified")
+	}
+}
+
+func TestDecoderWithInterpolation(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Server struct {
+			URL  string `dyml:"url,attr"`
+			Port int
+		}
+	}
+
+	env := map[string]string{"HOST": "example.com", "PORT": "8080"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+
+		return value, ok
+	}
+
+	var config Config
+
+	decoder := NewDecoder("", strings.NewReader(`#! Server @url="https://${HOST}/" { Port "${PORT}" }`), false,
+		WithPreprocessor(preprocess.Interpolate(lookup)))
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Server.URL != "https://example.com/" || config.Server.Port != 8080 {
+		t.Errorf("expected references to be resolved, but got %+v", config)
+	}
+
+	decoder = NewDecoder("", strings.NewReader(`#! Server { Port "${MISSING}" }`), false,
+		WithPreprocessor(preprocess.Interpolate(lookup)))
+	if err := decoder.Decode(&config); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
+
+func TestUnmarshalContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var value struct {
+		Name string
+	}
+
+	err := UnmarshalContext(ctx, strings.NewReader(`#Name{test}`), &value, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, but got %v", err)
+	}
+
+	if err := UnmarshalContext(context.Background(), strings.NewReader(`#Name{test}`), &value, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if value.Name != "test" {
+		t.Errorf("expected 'test', but got '%s'", value.Name)
+	}
+}
+
+func TestDecoderWithParserOptions(t *testing.T) {
+	t.Parallel()
+
+	var value struct {
+		Name string
+	}
+
+	decoder := NewDecoder("", strings.NewReader(`#Name{test}`), false,
+		WithParserOptions(parser.WithMaxTextLength(2)))
+
+	var limitErr token.LimitError
+	if err := decoder.Decode(&value); !errors.As(err, &limitErr) {
+		t.Errorf("expected a limit error, but got %v", err)
+	}
+}
+
+func TestDecoderWithLiterals(t *testing.T) {
+	type Server struct {
+		Port    int
+		Ratio   float64
+		TLS     bool
+		Name    string
+		Aliases []string
+		Offsets map[string]int
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    Server
+		wantErr bool
+	}{
+		{
+			name: "literals",
+			text: `#! Server { Port 8080, Ratio -0.5, TLS true, Name 42, Aliases { 1, "b" } Offsets { a -1, b 2 } }`,
+			want: Server{
+				Port: 8080, Ratio: -0.5, TLS: true, Name: "42", Aliases: []string{"1", "b"},
+				Offsets: map[string]int{"a": -1, "b": 2},
+			},
+		},
+		{
+			name:    "boolean for a number",
+			text:    `#! Server { Port true }`,
+			wantErr: true,
+		},
+		{
+			name:    "number for a boolean",
+			text:    `#! Server { TLS 1 }`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var value struct {
+				Server Server
+			}
+
+			decoder := NewDecoder("", strings.NewReader(test.text), true,
+				WithParserOptions(parser.WithLexerOptions(token.WithLiterals())))
+
+			err := decoder.Decode(&value)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, but got %+v", value.Server)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(value.Server, test.want) {
+				t.Errorf("expected %+v, but got %+v", test.want, value.Server)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPath(t *testing.T) {
+	t.Parallel()
+
+	type HTTP struct {
+		Port   int    `dyml:"port,attr"`
+		Host   string `dyml:"host"`
+		Script string `dyml:"script,raw"`
+	}
+
+	text := `#database @url{postgres://} {#pool 10}
+	#server {
+		#grpc @port{9000}
+		#http @port{8080} {
+			#host{localhost}
+			#script {#echo hello}
+		}
+	}`
+
+	var value HTTP
+	if err := UnmarshalPath(strings.NewReader(text), "/server/http", &value, true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := HTTP{Port: 8080, Host: "localhost", Script: "#echo hello"}
+	if value != want {
+		t.Errorf("expected %+v, but got %+v", want, value)
+	}
+
+	err := UnmarshalPath(strings.NewReader(text), "server/https", &value, true)
+	if err == nil || !strings.Contains(err.Error(), "server/https") {
+		t.Errorf("expected an error about the missing element, but got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/internal/docsnippet"
+)
+
+// TestDocSnippets makes sure that all dyml examples in the documentation of this package are valid.
+func TestDocSnippets(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		snippets, err := docsnippet.ExtractFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, snippet := range snippets {
+			count++
+
+			if err := snippet.Verify(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	if count == 0 {
+		t.Error("no snippets found in the documentation")
+	}
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dymlgen generates Go struct definitions with dyml tags from an example document,
+// so that the document can be read with dyml.Unmarshal without writing the tags by hand.
+//
+// The structure is inferred from the example:
+//
+//   - Attributes become fields with an 'attr' tag. Attributes that were written as numbers or
+//     booleans in G2 become int, float64 or bool fields, all others become strings.
+//   - Elements that only contain text become string fields.
+//   - Elements with attributes or child elements become structs. Their text is read into
+//     a field with an 'inner' tag.
+//   - Elements that occur multiple times in the same parent become slices.
+//   - All occurrences of an element in the same place are merged, so the example
+//     should contain every attribute and element at least once.
+//   - A comment directly before an element becomes the documentation of its field.
+//
+// Everything that cannot be inferred can be annotated with the AnnotationAttribute, whose value is
+// a comma separated list of directives. The attribute is not part of the generated structs.
+//
+//	#server @dymlgen{slice, name=Host, @port=int} @port{8080} {
+//	    #env @dymlgen{map} {#HOME{/root} #PATH{/bin}}
+//	    #level @dymlgen{type=LogLevel} debug
+//	}
+//
+// The directives are:
+//
+//   - slice generates a slice, even if the element occurs only once.
+//   - map generates a map with the names of the child elements as keys.
+//   - type=T uses the Go type T for the element instead of generating one. T must be declared
+//     in the same package, imports are not added.
+//   - name=N sets the name of the generated struct.
+//   - @key=T uses the Go type T for the attribute key.
+package dymlgen
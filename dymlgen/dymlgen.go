@@ -0,0 +1,272 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dymlgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// initialisms are words that are written in upper case in Go names.
+//
+//nolint:gochecknoglobals // A constant lookup table that is never modified.
+var initialisms = map[string]bool{
+	"api": true, "cpu": true, "css": true, "dns": true, "html": true, "http": true, "https": true, "id": true,
+	"ip": true, "json": true, "sql": true, "tcp": true, "tls": true, "ttl": true, "udp": true, "uri": true,
+	"url": true, "uuid": true, "xml": true,
+}
+
+// Option can be passed to Generate to configure the generated code.
+type Option func(g *generator)
+
+// WithPackageName sets the name of the package of the generated code, which is "main" by default.
+func WithPackageName(name string) Option {
+	return func(g *generator) {
+		g.packageName = name
+	}
+}
+
+// WithTypeName sets the name of the struct for the whole document, which is "Document" by default.
+func WithTypeName(name string) Option {
+	return func(g *generator) {
+		g.typeName = name
+	}
+}
+
+// generator collects the struct declarations for a document.
+type generator struct {
+	packageName string
+	typeName    string
+	decls       []*decl
+	typeNames   map[string]bool
+}
+
+// decl is a struct declaration.
+type decl struct {
+	name    string
+	element string
+	fields  []field
+}
+
+// field is a field of a struct declaration.
+type field struct {
+	name   string
+	goType string
+	tag    string
+	doc    string
+}
+
+// Generate returns formatted Go source code with struct definitions for documents like root,
+// which is usually the parsed example document. See the package documentation for how
+// types are inferred.
+func Generate(root *parser.TreeNode, opts ...Option) ([]byte, error) {
+	g := &generator{
+		packageName: "main",
+		typeName:    "Document",
+		typeNames:   map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if !isGoIdentifier(g.packageName) {
+		return nil, fmt.Errorf("invalid package name '%s'", g.packageName)
+	}
+
+	if !isGoIdentifier(g.typeName) {
+		return nil, fmt.Errorf("invalid type name '%s'", g.typeName)
+	}
+
+	s, err := collect(root)
+	if err != nil {
+		return nil, err
+	}
+
+	g.typeNames[g.typeName] = true
+
+	if err := g.structType(s, g.typeName); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by dymlgen. DO NOT EDIT.\n\npackage %s\n", g.packageName)
+
+	for i, d := range g.decls {
+		if i == 0 {
+			fmt.Fprintf(&buf, "\n// %s represents a document.\n", d.name)
+		} else {
+			fmt.Fprintf(&buf, "\n// %s represents the element '%s'.\n", d.name, d.element)
+		}
+
+		fmt.Fprintf(&buf, "type %s struct {\n", d.name)
+
+		for _, f := range d.fields {
+			if f.doc != "" {
+				for _, line := range strings.Split(f.doc, "\n") {
+					fmt.Fprintf(&buf, "// %s\n", strings.TrimSpace(line))
+				}
+			}
+
+			fmt.Fprintf(&buf, "%s %s `dyml:\"%s\"`\n", f.name, f.goType, f.tag)
+		}
+
+		buf.WriteString("}\n")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// structType adds a struct declaration with the given name for s.
+func (g *generator) structType(s *shape, name string) error {
+	d := &decl{name: name, element: s.name}
+	g.decls = append(g.decls, d)
+
+	fieldNames := map[string]bool{}
+	unique := func(name string) string {
+		candidate := name
+		for i := 2; fieldNames[candidate]; i++ {
+			candidate = name + strconv.Itoa(i)
+		}
+
+		fieldNames[candidate] = true
+
+		return candidate
+	}
+
+	for _, attr := range s.attrs {
+		goType := attr.goType
+		if t, ok := s.directives.attrTypes[attr.key]; ok {
+			goType = t
+		}
+
+		d.fields = append(d.fields, field{name: unique(goName(attr.key)), goType: goType, tag: attr.key + ",attr"})
+	}
+
+	for _, child := range s.children {
+		goType, err := g.valueType(child.shape, name, goName(child.shape.name))
+		if err != nil {
+			return err
+		}
+
+		if child.repeated || child.shape.directives.slice {
+			goType = "[]" + goType
+		}
+
+		d.fields = append(d.fields, field{
+			name:   unique(goName(child.shape.name)),
+			goType: goType,
+			tag:    child.shape.name,
+			doc:    child.doc,
+		})
+	}
+
+	if s.hasText {
+		d.fields = append(d.fields, field{name: unique("Text"), goType: "string", tag: ",inner"})
+	}
+
+	return nil
+}
+
+// valueType returns the Go type for the elements described by s. parent is the name of the struct that
+// contains the elements, candidate is the preferred name for a new struct.
+func (g *generator) valueType(s *shape, parent, candidate string) (string, error) {
+	switch {
+	case s.directives.goType != "":
+		return s.directives.goType, nil
+	case s.directives.isMap:
+		value := &shape{name: s.name}
+		for _, child := range s.children {
+			if err := value.merge(child.shape); err != nil {
+				return "", err
+			}
+		}
+
+		goType, err := g.valueType(value, parent, candidate+"Value")
+		if err != nil {
+			return "", err
+		}
+
+		return "map[string]" + goType, nil
+	case s.isText():
+		return "string", nil
+	default:
+		name, err := g.newTypeName(s, parent, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		return name, g.structType(s, name)
+	}
+}
+
+// newTypeName reserves a name for the struct for s. Unless the name is set with a directive,
+// the name of the parent or a number is added to candidate to make it unique.
+func (g *generator) newTypeName(s *shape, parent, candidate string) (string, error) {
+	if name := s.directives.typeName; name != "" {
+		if g.typeNames[name] {
+			return "", token.NewPosError(s.directives.pos, fmt.Sprintf("type name '%s' is used twice", name))
+		}
+
+		g.typeNames[name] = true
+
+		return name, nil
+	}
+
+	if g.typeNames[candidate] {
+		candidate = parent + candidate
+	}
+
+	name := candidate
+	for i := 2; g.typeNames[name]; i++ {
+		name = candidate + strconv.Itoa(i)
+	}
+
+	g.typeNames[name] = true
+
+	return name, nil
+}
+
+// goName converts a dyml name into an exported Go identifier, e.g. "server-url" becomes "ServerURL".
+func goName(name string) string {
+	var sb strings.Builder
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		if initialisms[strings.ToLower(word)] {
+			sb.WriteString(strings.ToUpper(word))
+
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(word)
+		sb.WriteRune(unicode.ToUpper(r))
+		sb.WriteString(word[size:])
+	}
+
+	result := sb.String()
+	if r, _ := utf8.DecodeRuneInString(result); !unicode.IsUpper(r) {
+		result = "X" + result
+	}
+
+	return result
+}
+
+// isGoIdentifier returns true if s can be used as a name in Go.
+func isGoIdentifier(s string) bool {
+	return gotoken.IsIdentifier(s)
+}
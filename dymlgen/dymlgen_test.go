@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dymlgen_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml"
+	"github.com/golangee/dyml/dymlgen"
+	"github.com/golangee/dyml/parser"
+)
+
+// header is the start of all generated files in package main.
+const header = "// Code generated by dymlgen. DO NOT EDIT.\n\npackage main\n"
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		opts []dymlgen.Option
+		want string
+	}{
+		{
+			name: "text elements",
+			text: `#name Gopher #city{Berlin}`,
+			want: header + `
+// Document represents a document.
+type Document struct {
+	Name string ` + "`dyml:\"name\"`" + `
+	City string ` + "`dyml:\"city\"`" + `
+}
+`,
+		},
+		{
+			name: "attributes and inner text",
+			text: `#! g2 { link @href="example.com" @id=1 @weight=0.5 @new=true "Example", link @id=2.5 "More" }`,
+			opts: []dymlgen.Option{dymlgen.WithPackageName("web"), dymlgen.WithTypeName("Page")},
+			want: `// Code generated by dymlgen. DO NOT EDIT.
+
+package web
+
+// Page represents a document.
+type Page struct {
+	G2 G2 ` + "`dyml:\"g2\"`" + `
+}
+
+// G2 represents the element 'g2'.
+type G2 struct {
+	Link []Link ` + "`dyml:\"link\"`" + `
+}
+
+// Link represents the element 'link'.
+type Link struct {
+	Href   string  ` + "`dyml:\"href,attr\"`" + `
+	ID     float64 ` + "`dyml:\"id,attr\"`" + `
+	Weight float64 ` + "`dyml:\"weight,attr\"`" + `
+	New    bool    ` + "`dyml:\"new,attr\"`" + `
+	Text   string  ` + "`dyml:\",inner\"`" + `
+}
+`,
+		},
+		{
+			name: "merged occurrences",
+			text: `#item @a{1} #item{#sub @b{2}} #? doc of other
+#other_item{#sub{x}}`,
+			want: header + `
+// Document represents a document.
+type Document struct {
+	Item []Item ` + "`dyml:\"item\"`" + `
+	// doc of other
+	OtherItem OtherItem ` + "`dyml:\"other_item\"`" + `
+}
+
+// Item represents the element 'item'.
+type Item struct {
+	A   string ` + "`dyml:\"a,attr\"`" + `
+	Sub Sub    ` + "`dyml:\"sub\"`" + `
+}
+
+// Sub represents the element 'sub'.
+type Sub struct {
+	B string ` + "`dyml:\"b,attr\"`" + `
+}
+
+// OtherItem represents the element 'other_item'.
+type OtherItem struct {
+	Sub string ` + "`dyml:\"sub\"`" + `
+}
+`,
+		},
+		{
+			name: "name collisions",
+			text: `#a{#b @x{1}} #c{#b @y{2}} #d @text{t} @Text{u} text`,
+			want: header + `
+// Document represents a document.
+type Document struct {
+	A A ` + "`dyml:\"a\"`" + `
+	C C ` + "`dyml:\"c\"`" + `
+	D D ` + "`dyml:\"d\"`" + `
+}
+
+// A represents the element 'a'.
+type A struct {
+	B B ` + "`dyml:\"b\"`" + `
+}
+
+// B represents the element 'b'.
+type B struct {
+	X string ` + "`dyml:\"x,attr\"`" + `
+}
+
+// C represents the element 'c'.
+type C struct {
+	B CB ` + "`dyml:\"b\"`" + `
+}
+
+// CB represents the element 'b'.
+type CB struct {
+	Y string ` + "`dyml:\"y,attr\"`" + `
+}
+
+// D represents the element 'd'.
+type D struct {
+	Text  string ` + "`dyml:\"text,attr\"`" + `
+	Text2 string ` + "`dyml:\"Text,attr\"`" + `
+	Text3 string ` + "`dyml:\",inner\"`" + `
+}
+`,
+		},
+		{
+			name: "directives",
+			text: `#server @dymlgen{slice, name=Host, @port=int} @port{80} {
+				#env @dymlgen{map} {#HOME{/root} #PATH{/bin}}
+				#users @dymlgen{map} {#alice @admin{yes} #bob}
+				#level @dymlgen{type=LogLevel} debug
+			}`,
+			want: header + `
+// Document represents a document.
+type Document struct {
+	Server []Host ` + "`dyml:\"server\"`" + `
+}
+
+// Host represents the element 'server'.
+type Host struct {
+	Port  int                   ` + "`dyml:\"port,attr\"`" + `
+	Env   map[string]string     ` + "`dyml:\"env\"`" + `
+	Users map[string]UsersValue ` + "`dyml:\"users\"`" + `
+	Level LogLevel              ` + "`dyml:\"level\"`" + `
+}
+
+// UsersValue represents the element 'users'.
+type UsersValue struct {
+	Admin string ` + "`dyml:\"admin,attr\"`" + `
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			src, err := dymlgen.Generate(tree, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(src) != test.want {
+				t.Errorf("expected\n%s\nbut got\n%s", test.want, src)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		opts []dymlgen.Option
+	}{
+		{name: "unknown directive", text: `#a @dymlgen{list}`},
+		{name: "invalid type", text: `#a @dymlgen{type=map[}`},
+		{name: "invalid name", text: `#a @dymlgen{name=a b}`},
+		{name: "conflicting types", text: `#a @dymlgen{type=int} #a @dymlgen{type=string}`},
+		{name: "duplicate type name", text: `#a @dymlgen{name=X} @x{1} #b @dymlgen{name=X} @y{2}`},
+		{name: "invalid package", text: `#a`, opts: []dymlgen.Option{dymlgen.WithPackageName("func")}},
+		{name: "invalid type name", text: `#a`, opts: []dymlgen.Option{dymlgen.WithTypeName("")}},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if src, err := dymlgen.Generate(tree, test.opts...); err == nil {
+				t.Errorf("expected an error, but got\n%s", src)
+			}
+		})
+	}
+}
+
+// Config, Server and DatabaseValue are the structs generated for configExample.
+type Config struct {
+	Name     string                   `dyml:"name"`
+	Server   []Server                 `dyml:"server"`
+	Database map[string]DatabaseValue `dyml:"database"`
+}
+
+type Server struct {
+	Host string `dyml:"host,attr"`
+	Port int    `dyml:"port,attr"`
+	TLS  bool   `dyml:"tls,attr"`
+	Text string `dyml:",inner"`
+}
+
+type DatabaseValue struct {
+	URL  string `dyml:"url,attr"`
+	Pool string `dyml:"pool"`
+}
+
+const configExample = `#name{example}
+#server @dymlgen{@port=int, @tls=bool} @host{localhost} @port{80} @tls{false} {plain}
+#server @host{example.com} @port{443} @tls{true} {secure}
+#database @dymlgen{map} {
+	#main @url{postgres://main} {#pool 10}
+	#cache @url{redis://cache} {#pool 2}
+}`
+
+func TestGeneratedTypesUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	tree, err := parser.NewParser("example", strings.NewReader(configExample)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := dymlgen.Generate(tree, dymlgen.WithTypeName("Config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Server   []Server                 `dyml:\"server\"`",
+		"Database map[string]DatabaseValue `dyml:\"database\"`",
+		"TLS  bool   `dyml:\"tls,attr\"`",
+		"URL  string `dyml:\"url,attr\"`",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected generated code to contain '%s', but got\n%s", want, src)
+		}
+	}
+
+	var config Config
+	if err := dyml.UnmarshalTree(tree, &config, true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		Name: "example",
+		Server: []Server{
+			{Host: "localhost", Port: 80, TLS: false, Text: "plain"},
+			{Host: "example.com", Port: 443, TLS: true, Text: "secure"},
+		},
+		Database: map[string]DatabaseValue{
+			"main":  {URL: "postgres://main", Pool: "10"},
+			"cache": {URL: "redis://cache", Pool: "2"},
+		},
+	}
+
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("expected %+v, but got %+v", want, config)
+	}
+}
@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dymlgen
+
+import (
+	"fmt"
+	goparser "go/parser"
+	"strconv"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// AnnotationAttribute is the name of the attribute that contains the directives for an element.
+const AnnotationAttribute = "dymlgen"
+
+// shape collects the attributes and children of all occurrences of an element.
+type shape struct {
+	name       string
+	attrs      []*attrShape
+	children   []*childShape
+	hasText    bool
+	directives directives
+}
+
+// attrShape is an attribute of a shape with the Go type for its values.
+type attrShape struct {
+	key    string
+	goType string
+}
+
+// childShape is a child element of a shape.
+type childShape struct {
+	shape *shape
+	// repeated is true if the element occurs multiple times in one parent.
+	repeated bool
+	// doc is the comment in front of the first occurrence.
+	doc string
+}
+
+// directives are the parsed value of the AnnotationAttribute.
+type directives struct {
+	slice     bool
+	isMap     bool
+	goType    string
+	typeName  string
+	attrTypes map[string]string
+	// pos is the position of the annotation, which is used for errors.
+	pos token.Node
+}
+
+// collect returns the shape of node. Children with the same name are merged.
+func collect(node *parser.TreeNode) (*shape, error) {
+	s := &shape{name: node.Name}
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attr := node.Attributes.GetAt(i)
+
+		if attr.Key == AnnotationAttribute {
+			dirs, err := parseDirectives(attr)
+			if err != nil {
+				return nil, err
+			}
+
+			s.directives = dirs
+
+			continue
+		}
+
+		s.attrs = append(s.attrs, &attrShape{key: attr.Key, goType: attributeType(attr)})
+	}
+
+	var doc string
+
+	seen := map[string]bool{}
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsComment():
+			doc = strings.TrimSpace(*child.Comment)
+		case child.IsText():
+			if strings.TrimSpace(*child.Text) != "" {
+				s.hasText = true
+			}
+		case child.IsNode():
+			childShape, err := collect(child)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.addChild(childShape, seen[child.Name], doc); err != nil {
+				return nil, err
+			}
+
+			seen[child.Name] = true
+			doc = ""
+		}
+	}
+
+	return s, nil
+}
+
+// addChild merges the shape of a child element into s.
+func (s *shape) addChild(child *shape, repeated bool, doc string) error {
+	for _, existing := range s.children {
+		if existing.shape.name == child.name {
+			existing.repeated = existing.repeated || repeated
+
+			if existing.doc == "" {
+				existing.doc = doc
+			}
+
+			return existing.shape.merge(child)
+		}
+	}
+
+	s.children = append(s.children, &childShape{shape: child, repeated: repeated, doc: doc})
+
+	return nil
+}
+
+// merge adds the attributes, children and directives of other to s.
+func (s *shape) merge(other *shape) error {
+	if err := s.directives.merge(other.directives, other.name); err != nil {
+		return err
+	}
+
+	for _, attr := range other.attrs {
+		if existing := s.attr(attr.key); existing != nil {
+			existing.goType = mergeTypes(existing.goType, attr.goType)
+		} else {
+			copied := *attr
+			s.attrs = append(s.attrs, &copied)
+		}
+	}
+
+	for _, child := range other.children {
+		if err := s.addChild(child.shape, child.repeated, child.doc); err != nil {
+			return err
+		}
+	}
+
+	s.hasText = s.hasText || other.hasText
+
+	return nil
+}
+
+// attr returns the attribute with the given key or nil.
+func (s *shape) attr(key string) *attrShape {
+	for _, attr := range s.attrs {
+		if attr.key == key {
+			return attr
+		}
+	}
+
+	return nil
+}
+
+// isText returns true if the element has neither attributes nor child elements.
+func (s *shape) isText() bool {
+	return len(s.attrs) == 0 && len(s.children) == 0
+}
+
+// merge adds the directives of other to d. Directives that are set in both must be equal.
+func (d *directives) merge(other directives, name string) error {
+	conflict := func(what string) error {
+		return token.NewPosError(other.pos, fmt.Sprintf("conflicting %s for '%s'", what, name))
+	}
+
+	d.slice = d.slice || other.slice
+	d.isMap = d.isMap || other.isMap
+
+	if other.goType != "" {
+		if d.goType != "" && d.goType != other.goType {
+			return conflict("types")
+		}
+
+		d.goType = other.goType
+	}
+
+	if other.typeName != "" {
+		if d.typeName != "" && d.typeName != other.typeName {
+			return conflict("names")
+		}
+
+		d.typeName = other.typeName
+	}
+
+	for key, goType := range other.attrTypes {
+		if existing, ok := d.attrTypes[key]; ok && existing != goType {
+			return conflict(fmt.Sprintf("types of attribute '%s'", key))
+		}
+
+		if d.attrTypes == nil {
+			d.attrTypes = map[string]string{}
+		}
+
+		d.attrTypes[key] = goType
+	}
+
+	if d.pos == nil {
+		d.pos = other.pos
+	}
+
+	return nil
+}
+
+// parseDirectives parses the value of the AnnotationAttribute.
+func parseDirectives(attr *util.Attribute) (directives, error) {
+	dirs := directives{pos: attr.Range}
+
+	for _, directive := range strings.Split(attr.Value, ",") {
+		directive = strings.TrimSpace(directive)
+		key, value := directive, ""
+
+		if i := strings.IndexByte(directive, '='); i >= 0 {
+			key, value = strings.TrimSpace(directive[:i]), strings.TrimSpace(directive[i+1:])
+		}
+
+		switch {
+		case directive == "":
+			continue
+		case key == "slice" && value == "":
+			dirs.slice = true
+		case key == "map" && value == "":
+			dirs.isMap = true
+		case key == "type" && isGoType(value):
+			dirs.goType = value
+		case key == "name" && isGoIdentifier(value):
+			dirs.typeName = value
+		case strings.HasPrefix(key, "@") && len(key) > 1 && isGoType(value):
+			if dirs.attrTypes == nil {
+				dirs.attrTypes = map[string]string{}
+			}
+
+			dirs.attrTypes[key[1:]] = value
+		default:
+			return dirs, token.NewPosError(attr.Range, fmt.Sprintf("invalid directive '%s'", directive))
+		}
+	}
+
+	return dirs, nil
+}
+
+// attributeType returns the Go type for the value of attr.
+func attributeType(attr *util.Attribute) string {
+	switch attr.Kind {
+	case token.KindNumber:
+		if _, err := strconv.ParseInt(attr.Value, 10, 64); err == nil {
+			return "int"
+		}
+
+		return "float64"
+	case token.KindBool:
+		return "bool"
+	case token.KindString:
+		return "string"
+	default:
+		return "string"
+	}
+}
+
+// mergeTypes returns a Go type that can hold the values of attributes with the types a and b.
+func mergeTypes(a, b string) string {
+	switch {
+	case a == b:
+		return a
+	case (a == "int" && b == "float64") || (a == "float64" && b == "int"):
+		return "float64"
+	default:
+		return "string"
+	}
+}
+
+// isGoType returns true if s is a valid Go type expression.
+func isGoType(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	_, err := goparser.ParseExpr(s)
+
+	return err == nil
+}
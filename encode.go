@@ -0,0 +1,497 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/util"
+)
+
+// Marshaler can be implemented to define custom marshalling behavior, symmetric to Unmarshaler.
+// The attributes, children and BlockType of the returned node become the content of the element
+// that represents the value, its name is ignored.
+type Marshaler interface {
+	MarshalDyml() (*parser.TreeNode, error)
+}
+
+// MarshalOption can be used to change the behavior of the marshalling process.
+type MarshalOption func(m *marshaler)
+
+// WithMarshalNamingStrategy converts the names of all fields without a rename tag with strategy,
+// e.g. SnakeCase, so that the written elements and attributes follow a consistent naming convention.
+// It is the counterpart to WithNamingStrategy.
+func WithMarshalNamingStrategy(strategy NamingStrategy) MarshalOption {
+	return func(m *marshaler) {
+		m.naming = strategy
+	}
+}
+
+// marshaler holds the options of the marshalling process.
+type marshaler struct {
+	// naming converts the names of fields without a rename tag, if it is set.
+	naming NamingStrategy
+}
+
+// Encoder writes go values as dyml documents to an output stream.
+type Encoder struct {
+	writer io.Writer
+	opts   []MarshalOption
+}
+
+// NewEncoder creates a new Encoder writing to w.
+func NewEncoder(w io.Writer, opts ...MarshalOption) *Encoder {
+	return &Encoder{
+		writer: w,
+		opts:   opts,
+	}
+}
+
+// Encode writes v as dyml, just like Marshal.
+func (e *Encoder) Encode(v interface{}) error {
+	tree, err := MarshalTree(v, e.opts...)
+	if err != nil {
+		return err
+	}
+
+	return encoder.NewDymlEncoder(e.writer).Encode(tree)
+}
+
+// Marshal writes v as dyml to w. See MarshalTree for how values are converted.
+func Marshal(w io.Writer, v interface{}, opts ...MarshalOption) error {
+	return NewEncoder(w, opts...).Encode(v)
+}
+
+// MarshalTree converts v into a tree, so that unmarshalling the tree into a value of the same type
+// results in v again. v must be a struct or a pointer to a struct.
+// The same struct tags as for Unmarshal are supported:
+// Fields are written as elements with the field name or the renamed name, 'attr' fields
+// are written as attributes and 'inner' fields are written into the surrounding element.
+// Slices with a rename tag are written as one element per slice element, untagged slices are written as
+// an element that contains the slice elements. Maps are written with one element per key, sorted by key.
+// The text of 'raw' fields is parsed and written as the children of their element.
+// The elements of 'any' fields are written as children, which must be parser.TreeNode, *parser.TreeNode
+// or implement Marshaler. In contrast to other values, the name of the node returned by MarshalDyml is used.
+// The contents of 'children' fields are written as children in their order.
+// Embedded structs and 'squash' fields are written into the surrounding element.
+// Names of fields without a rename tag are converted by the strategy given with WithMarshalNamingStrategy.
+// Nil pointers are omitted. As the root element cannot have attributes in dyml, v itself
+// must not have 'attr' fields when writing it with Marshal.
+func MarshalTree(v interface{}, opts ...MarshalOption) (*parser.TreeNode, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot marshal '%T', a struct is required", v)
+	}
+
+	m := &marshaler{}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	root := parser.NewNode(parser.DefaultRootName).Block(parser.BlockNormal)
+	root.Synthetic = true
+
+	if err := m.doMarshalAny(root, value, nil); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// doMarshalAny writes the given value into node, which is the element that represents the value.
+// tags are any field tags that may be relevant to process the value.
+func (m *marshaler) doMarshalAny(node *parser.TreeNode, value reflect.Value, tags []string) error {
+	if custom, ok := asMarshaler(value); ok {
+		custom, err := custom.MarshalDyml()
+		if err != nil {
+			return fmt.Errorf("cannot marshal '%s': %w", node.Name, err)
+		}
+
+		if custom != nil {
+			node.Attributes = custom.Attributes
+			node.BlockType = custom.BlockType
+			node.AddChildren(custom.Children...)
+		}
+
+		return nil
+	}
+
+	// Types with their own text format are written like primitives, even if they are structs.
+	if _, ok := textValues[value.Type()]; ok {
+		text, err := marshalPrimitive(value)
+		if err != nil {
+			return fmt.Errorf("cannot marshal '%s': %w", node.Name, err)
+		}
+
+		node.AddChildren(parser.NewStringNode(text))
+
+		return nil
+	}
+
+	if len(tags) > 1 && containsString(tags[1:], base64Option) && value.Kind() != reflect.Ptr {
+		text, err := marshalBinary(value)
+		if err != nil {
+			return fmt.Errorf("cannot marshal '%s': %w", node.Name, err)
+		}
+
+		node.AddAttribute(encodingAttribute, base64Option)
+		node.AddChildren(parser.NewStringNode(text))
+
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+
+		return m.doMarshalAny(node, value.Elem(), tags)
+	case reflect.Struct:
+		if value.Type() == reflect.TypeOf(parser.TreeNode{}) {
+			tree := value.Interface().(parser.TreeNode) //nolint:forcetypeassert
+			node.Attributes = tree.Attributes
+			node.BlockType = tree.BlockType
+			node.AddChildren(tree.Children...)
+
+			return nil
+		}
+
+		return m.doMarshalStruct(node, value)
+	case reflect.Slice, reflect.Array:
+		return m.doMarshalSlice(node, value)
+	case reflect.Map:
+		return m.doMarshalMap(node, value)
+	default:
+		text, err := marshalPrimitive(value)
+		if err != nil {
+			return fmt.Errorf("cannot marshal '%s': %w", node.Name, err)
+		}
+
+		node.AddChildren(parser.NewStringNode(text))
+
+		return nil
+	}
+}
+
+// doMarshalStruct writes all exported fields of the struct in value into node.
+func (m *marshaler) doMarshalStruct(node *parser.TreeNode, value reflect.Value) error {
+	for _, info := range cachedStructType(value.Type()).fields {
+		field := value.Field(info.index)
+
+		// Fields of embedded structs are written to the current node, unless the struct is renamed.
+		if info.embedded && indirectType(field.Type()).Kind() == reflect.Struct {
+			for field.Kind() == reflect.Ptr && !field.IsNil() {
+				field = field.Elem()
+			}
+
+			if field.Kind() == reflect.Struct {
+				if err := m.doMarshalStruct(node, field); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if info.squash {
+			return fmt.Errorf("cannot squash field '%s', it is not a struct", info.goName)
+		}
+
+		if !info.exported {
+			// Unexported fields can neither be marshalled nor unmarshalled.
+			continue
+		}
+
+		if info.invalidAs != "" {
+			return fmt.Errorf("cannot marshal '%s', field type '%s' invalid", info.goName, info.invalidAs)
+		}
+
+		fieldName := info.documentName(m.naming)
+		tags := info.tags
+
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			continue
+		}
+
+		switch info.as {
+		case unmarshalNormal:
+			// Renamed slices are written as repeated elements with that name.
+			if info.filtered {
+				element := reflect.Indirect(field)
+				for j := 0; j < element.Len(); j++ {
+					child := parser.NewNode(fieldName)
+					if err := m.doMarshalAny(child, element.Index(j), nil); err != nil {
+						return err
+					}
+
+					node.AddChildren(child)
+				}
+
+				continue
+			}
+
+			child := parser.NewNode(fieldName)
+			if err := m.doMarshalAny(child, field, tags); err != nil {
+				return err
+			}
+
+			node.AddChildren(child)
+		case unmarshalAttribute:
+			if isAttributeList(info, field) {
+				if err := marshalAttributeList(node, fieldName, field); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			marshal := marshalAttribute
+			if info.base64 {
+				marshal = marshalBinary
+			}
+
+			text, err := marshal(field)
+			if err != nil {
+				return fmt.Errorf("cannot marshal attribute '%s': %w", fieldName, err)
+			}
+
+			node.AddAttribute(fieldName, text)
+		case unmarshalInner:
+			if err := m.doMarshalAny(node, field, nil); err != nil {
+				return err
+			}
+		case unmarshalAny:
+			if err := m.doMarshalUnknownChildren(node, field); err != nil {
+				return fmt.Errorf("cannot marshal '%s': %w", info.goName, err)
+			}
+		case unmarshalChildren:
+			if err := m.doMarshalChildren(node, field); err != nil {
+				return fmt.Errorf("cannot marshal '%s': %w", info.goName, err)
+			}
+		case unmarshalRaw:
+			child, err := marshalRaw(fieldName, field)
+			if err != nil {
+				return fmt.Errorf("cannot marshal '%s': %w", info.goName, err)
+			}
+
+			node.AddChildren(child)
+		default:
+			// Should never happen. We provide a helpful message just in case.
+			return fmt.Errorf("marshal in invalid state: marshalType=%v. this is a bug", info.as)
+		}
+	}
+
+	return nil
+}
+
+// marshalRaw returns an element with the given name, whose children are parsed from the text in value,
+// which belongs to a 'raw' field. The text is parsed as G1.
+func marshalRaw(name string, value reflect.Value) (*parser.TreeNode, error) {
+	value = reflect.Indirect(value)
+
+	var text string
+
+	switch {
+	case value.Kind() == reflect.String:
+		text = value.String()
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		text = string(value.Bytes())
+	default:
+		return nil, fmt.Errorf("'raw' struct tag requires a string or []byte, not '%s'", value.Type())
+	}
+
+	tree, err := parser.NewParser(name, strings.NewReader(text)).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.NewNode(name).Block(parser.BlockNormal).AddChildren(tree.Children...), nil
+}
+
+// doMarshalUnknownChildren adds the elements of the slice in value, which belongs to an 'any' field,
+// as children to node.
+func (m *marshaler) doMarshalUnknownChildren(node *parser.TreeNode, value reflect.Value) error {
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Slice {
+		return fmt.Errorf("'any' struct tag requires a slice, not '%s'", value.Type())
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		element := value.Index(i)
+
+		switch tree := element.Interface().(type) {
+		case *parser.TreeNode:
+			if tree != nil {
+				node.AddChildren(tree.Clone())
+			}
+		case parser.TreeNode:
+			node.AddChildren(tree.Clone())
+		default:
+			custom, ok := asMarshaler(element)
+			if !ok {
+				return fmt.Errorf("type '%s' must be a parser.TreeNode or implement Marshaler", element.Type())
+			}
+
+			child, err := custom.MarshalDyml()
+			if err != nil {
+				return err
+			}
+
+			if child != nil {
+				node.AddChildren(child)
+			}
+		}
+	}
+
+	return nil
+}
+
+// doMarshalSlice writes all elements of the slice or array in value as children of node.
+// Primitives are written as text, everything else as an element named "item".
+func (m *marshaler) doMarshalSlice(node *parser.TreeNode, value reflect.Value) error {
+	isPrimitive := (&unmarshaler{}).isPrimitive(indirectType(value.Type().Elem()))
+
+	for i := 0; i < value.Len(); i++ {
+		element := value.Index(i)
+
+		if isPrimitive {
+			if element.Kind() == reflect.Ptr && element.IsNil() {
+				continue
+			}
+
+			text, err := marshalPrimitive(reflect.Indirect(element))
+			if err != nil {
+				return fmt.Errorf("cannot marshal element of '%s': %w", node.Name, err)
+			}
+
+			node.AddChildren(parser.NewStringNode(text))
+
+			continue
+		}
+
+		child := parser.NewNode("item")
+		if err := m.doMarshalAny(child, element, nil); err != nil {
+			return err
+		}
+
+		node.AddChildren(child)
+	}
+
+	return nil
+}
+
+// doMarshalMap writes every entry of the map in value as an element named after the key.
+// Entries are sorted by their key, so that the output is stable.
+func (m *marshaler) doMarshalMap(node *parser.TreeNode, value reflect.Value) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, value.Len())
+
+	iter := value.MapRange()
+	for iter.Next() {
+		key, err := marshalPrimitive(iter.Key())
+		if err != nil {
+			return fmt.Errorf("cannot marshal map key of '%s': %w", node.Name, err)
+		}
+
+		if !encoder.IsIdentifier(key) {
+			return fmt.Errorf("cannot marshal map key '%s' of '%s', it is not a valid identifier", key, node.Name)
+		}
+
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	for _, e := range entries {
+		child := parser.NewNode(e.key)
+		if err := m.doMarshalAny(child, e.value, nil); err != nil {
+			return err
+		}
+
+		node.AddChildren(child)
+	}
+
+	return nil
+}
+
+// marshalAttribute returns the text for an attribute value, which must be a primitive type.
+func marshalAttribute(value reflect.Value) (string, error) {
+	return marshalPrimitive(reflect.Indirect(value))
+}
+
+// marshalAttributeList adds an attribute with the given name to node for each element of the slice value.
+func marshalAttributeList(node *parser.TreeNode, name string, value reflect.Value) error {
+	for i := 0; i < value.Len(); i++ {
+		text, err := marshalAttribute(value.Index(i))
+		if err != nil {
+			return fmt.Errorf("cannot marshal attribute '%s': %w", name, err)
+		}
+
+		node.Attributes.Add(util.Attribute{Key: name, Value: text})
+	}
+
+	return nil
+}
+
+// marshalPrimitive formats the value, which must be a primitive type, as text
+// that can be unmarshalled again.
+func marshalPrimitive(value reflect.Value) (string, error) {
+	if tv, ok := textValues[value.Type()]; ok {
+		return tv.format(value), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported type '%s'", value.Type())
+	}
+}
+
+// asMarshaler returns the Marshaler implemented by value or a pointer to it.
+func asMarshaler(value reflect.Value) (Marshaler, bool) {
+	if !value.CanInterface() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return nil, false
+	}
+
+	if custom, ok := value.Interface().(Marshaler); ok {
+		return custom, true
+	}
+
+	if value.CanAddr() {
+		if custom, ok := value.Addr().Interface().(Marshaler); ok {
+			return custom, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+)
+
+// MarshalDyml is the counterpart to CustomUnmarshal.UnmarshalDyml and writes the sum as a single "Add".
+func (c CustomUnmarshal) MarshalDyml() (*parser.TreeNode, error) {
+	if c.Sum < 0 {
+		return nil, fmt.Errorf("negative sum %d", c.Sum)
+	}
+
+	return parser.NewNode("").AddChildren(
+		parser.NewNode("Add").AddChildren(parser.NewStringNode(strconv.Itoa(c.Sum))),
+	), nil
+}
+
+func ExampleMarshal() {
+	type Animal struct {
+		Name string `dyml:"name,attr"`
+		Legs int
+	}
+
+	type Zoo struct {
+		Title   string
+		Animals []Animal `dyml:"animal"`
+	}
+
+	zoo := Zoo{
+		Title: `The "best" zoo`,
+		Animals: []Animal{
+			{Name: "Gopher", Legs: 4},
+			{Name: "Ostrich", Legs: 2},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, zoo); err != nil {
+		panic(err)
+	}
+
+	fmt.Print(buf.String())
+	// Output: #! Title "The \"best\" zoo"
+	// #! animal @name="Gopher" Legs "4"
+	// #! animal @name="Ostrich" Legs "2"
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type Inner struct {
+		ID      uint   `dyml:"id,attr"`
+		Enabled bool   `dyml:"enabled,attr"`
+		Text    string `dyml:",inner"`
+	}
+
+	type Base struct {
+		Version float64
+	}
+
+	type Timestamps struct {
+		Created int
+		Updated int
+	}
+
+	type Everything struct {
+		Base
+		Times    Timestamps `dyml:",squash"`
+		Name     string
+		Pointer  *int
+		Missing  *int
+		Numbers  []int
+		Inners   []Inner `dyml:"inner"`
+		Array    [2]string
+		Lookup   map[string]int
+		Groups   map[string][]string
+		Nested   map[string]Inner
+		Custom   CustomUnmarshal
+		Children [][]int
+	}
+
+	pointer := 42
+
+	want := Everything{
+		Base:    Base{Version: 1.5},
+		Times:   Timestamps{Created: 1, Updated: 2},
+		Name:    "with \"quotes\", \\backslashes\\ and\nnewlines",
+		Pointer: &pointer,
+		Numbers: []int{1, -2, 3},
+		Inners:  []Inner{{ID: 1, Enabled: true, Text: "first"}, {ID: 2, Text: "second"}},
+		Array:   [2]string{"a", "b"},
+		Lookup:  map[string]int{"b": 2, "a": 1},
+		Groups:  map[string][]string{"x": {"1", "2"}, "y": {"3"}},
+		Nested:  map[string]Inner{"n": {ID: 7, Text: "seven"}},
+		Custom:  CustomUnmarshal{Sum: 12},
+		Children: [][]int{
+			{1, 2},
+			{3},
+		},
+	}
+
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Everything
+	if err := Unmarshal(&buf, &got, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip failed:\nwant %#v\ngot  %#v", want, got)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{
+			name:  "not a struct",
+			value: 123,
+		},
+		{
+			name: "unsupported type",
+			value: struct {
+				Func func()
+			}{},
+		},
+		{
+			name: "attribute not primitive",
+			value: struct {
+				Attr []map[string]int `dyml:"attr,attr"`
+			}{Attr: []map[string]int{{}}},
+		},
+		{
+			name: "invalid map key",
+			value: struct {
+				Map map[string]int
+			}{Map: map[string]int{"no spaces": 1}},
+		},
+		{
+			name: "any field without nodes",
+			value: struct {
+				Unknown []int `dyml:",any"`
+			}{Unknown: []int{1}},
+		},
+		{
+			name: "squashed field not a struct",
+			value: struct {
+				Name string `dyml:",squash"`
+			}{},
+		},
+		{
+			name: "custom marshaler error",
+			value: struct {
+				Custom CustomUnmarshal
+			}{Custom: CustomUnmarshal{Sum: -1}},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := Marshal(&buf, test.value); err == nil {
+				t.Errorf("expected an error, but got output '%s'", buf.String())
+			}
+		})
+	}
+}
+
+func TestMarshalAny(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Name    string
+		Unknown []*parser.TreeNode `dyml:",any"`
+	}
+
+	text := `#Name{x} #color{blue} #size @unit{cm} {3}`
+
+	var config Config
+	if err := Unmarshal(strings.NewReader(text), &config, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, config); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "#! Name \"x\"\n#! color {\n    \"blue\"\n}\n#! size @unit=\"cm\" {\n    \"3\"\n}\n"
+	if buf.String() != want {
+		t.Errorf("expected '%s', but got '%s'", want, buf.String())
+	}
+}
+
+func TestMarshalChildren(t *testing.T) {
+	t.Parallel()
+
+	type Paragraph struct {
+		Content []Content `dyml:",children"`
+	}
+
+	tree, err := MarshalTree(Paragraph{Content: []Content{
+		{Kind: ContentText, Text: "Some"},
+		{Kind: ContentElement, Element: parser.NewNode("b").AddChildren(parser.NewStringNode("bold"))},
+		{Kind: ContentComment, Text: "remark"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree.Children) != 3 || !tree.Children[0].IsText() || tree.Children[1].Name != "b" ||
+		!tree.Children[2].IsComment() {
+		t.Errorf("expected the contents as children in their order, got\n%s", tree.Dump())
+	}
+
+	if _, err := MarshalTree(Paragraph{Content: []Content{{Kind: ContentElement}}}); err == nil {
+		t.Error("expected an error for an element content without element")
+	}
+}
+
+func TestMarshalRaw(t *testing.T) {
+	t.Parallel()
+
+	type Page struct {
+		Body  string `dyml:"body,raw"`
+		Bytes []byte `dyml:"bytes,raw"`
+	}
+
+	tree, err := MarshalTree(Page{Body: "Some #b{bold} text", Bytes: []byte("#x @y{z}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, bytesNode := tree.Children[0], tree.Children[1]
+	if len(body.Children) != 3 || body.Children[1].Name != "b" {
+		t.Errorf("expected the body to be parsed, got %d children", len(body.Children))
+	}
+
+	if len(bytesNode.Children) != 1 || bytesNode.Children[0].Attributes.Get("y") == nil {
+		t.Errorf("expected the bytes to be parsed, got %d children", len(bytesNode.Children))
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, Page{Body: "#unclosed{"}); err == nil {
+		t.Errorf("expected an error for invalid raw text, got '%s'", buf.String())
+	}
+}
+
+func TestMarshalNamingStrategy(t *testing.T) {
+	t.Parallel()
+
+	type Server struct {
+		ListenAddr string `dyml:",attr"`
+		MaxConns   int
+		Retries    int `dyml:"tries"`
+	}
+
+	type Config struct {
+		ServerName string
+		MainServer Server
+	}
+
+	want := Config{ServerName: "main", MainServer: Server{ListenAddr: ":80", MaxConns: 5, Retries: 3}}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, WithMarshalNamingStrategy(SnakeCase)).Encode(want); err != nil {
+		t.Fatal(err)
+	}
+
+	text := "#! server_name \"main\"\n#! main_server @listen_addr=\":80\" {\n    max_conns \"5\"\n    tries \"3\"\n}\n"
+	if buf.String() != text {
+		t.Errorf("expected '%s', but got '%s'", text, buf.String())
+	}
+
+	var got Config
+
+	decoder := NewDecoder("", &buf, true, WithNamingStrategy(SnakeCase))
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip failed:\nwant %#v\ngot  %#v", want, got)
+	}
+
+	tree, err := MarshalTree(want, WithMarshalNamingStrategy(KebabCase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := tree.Children[1].Name; name != "main-server" {
+		t.Errorf("expected 'main-server', but got '%s'", name)
+	}
+
+	if err := Marshal(&buf, want, WithMarshalNamingStrategy(KebabCase)); err == nil {
+		t.Error("expected an error for names that are not valid in dyml")
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+)
+
+// sectionReader generates a document with a number of sections while it is read,
+// so that the input does not need to be kept in memory.
+type sectionReader struct {
+	sections int
+	current  strings.Reader
+}
+
+func (r *sectionReader) Read(p []byte) (int, error) {
+	if r.current.Len() == 0 {
+		if r.sections == 0 {
+			return 0, io.EOF
+		}
+
+		r.sections--
+		r.current.Reset(fmt.Sprintf(`#section @id{s%[1]d} {
+  #title Section %[1]d with <special> & "quoted" text
+  ##note @@important{yes} #p{A paragraph with #b{bold} text.}
+}
+#! impl%[1]d {
+  @@visibility="public"
+  func Run(x int, y string) -> (int, error)
+}
+`, r.sections))
+	}
+
+	return r.current.Read(p)
+}
+
+// BenchmarkXMLEncode encodes documents of different sizes. As the encoder only keeps open
+// elements in memory, the bytes allocated per section stay the same for all sizes.
+func BenchmarkXMLEncode(b *testing.B) {
+	for _, size := range []int{10, 1000} {
+		sections := size
+
+		b.Run(fmt.Sprintf("sections=%d", sections), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				reader := &sectionReader{sections: sections}
+				if err := encoder.NewXMLEncoder("bench", reader, io.Discard).Encode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCBORDecode compares reading a cached tree to parsing the document again.
+func BenchmarkCBORDecode(b *testing.B) {
+	text, err := io.ReadAll(&sectionReader{sections: 100})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tree, err := parser.NewParser("bench", bytes.NewReader(text)).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var cached bytes.Buffer
+	if err := encoder.NewCBOREncoder(&cached, encoder.WithCBORRanges()).Encode(tree); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := parser.NewParser("bench", bytes.NewReader(text)).Parse(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cbor", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := encoder.NewCBORDecoder(bytes.NewReader(cached.Bytes())).Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
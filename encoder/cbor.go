@@ -0,0 +1,550 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// cborVersion is the version of the layout written by the CBOREncoder.
+// Version 2 added the UTF-16 columns to ranges, ranges of version 1 can still be read.
+const cborVersion = 2
+
+// Major types of CBOR data items, as defined in RFC 8949.
+const (
+	cborUint  = 0
+	cborText  = 3
+	cborArray = 4
+	// cborNull is the data item for null, which has the major type 7.
+	cborNull = 0xf6
+)
+
+// Kinds of nodes in the CBOR layout.
+const (
+	cborElement = iota
+	cborTextNode
+	cborComment
+)
+
+// cborNodeChunk is the number of nodes that a CBORDecoder allocates at once.
+const cborNodeChunk = 256
+
+// ErrInvalidCBOR is the cause of all errors about input that was not written by a CBOREncoder.
+var ErrInvalidCBOR = errors.New("invalid cbor tree")
+
+// CBOREncoder writes a parsed tree in CBOR (RFC 8949), e.g. to cache documents that are expensive
+// to parse. The tree can be read again with a CBORDecoder. The layout is an array with the version,
+// the list of files in ranges (or null without ranges) and the root node. Each node is an array
+// with its kind, its name or text, its block type, its attributes, its children and, if enabled,
+// its range. Ranges refer to files by their index.
+type CBOREncoder struct {
+	writer *bufio.Writer
+	// ranges is true if ranges should be written.
+	ranges bool
+	// files maps file names to their index in the list of files.
+	files map[string]int
+}
+
+// CBOROption can be passed to NewCBOREncoder to configure the output.
+type CBOROption func(e *CBOREncoder)
+
+// WithCBORRanges writes the Range of all nodes and attributes, which is not written by default.
+func WithCBORRanges() CBOROption {
+	return func(e *CBOREncoder) {
+		e.ranges = true
+	}
+}
+
+// NewCBOREncoder creates a new CBOREncoder that writes to w.
+func NewCBOREncoder(w io.Writer, opts ...CBOROption) *CBOREncoder {
+	e := &CBOREncoder{
+		writer: bufio.NewWriter(w),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Encode writes the given tree to the writer.
+func (e *CBOREncoder) Encode(root *parser.TreeNode) error {
+	e.writeHead(cborArray, 3)
+	e.writeHead(cborUint, cborVersion)
+
+	if e.ranges {
+		var files []string
+
+		e.files = map[string]int{}
+		e.collectFiles(root, &files)
+
+		e.writeHead(cborArray, uint64(len(files)))
+
+		for _, file := range files {
+			e.writeString(file)
+		}
+	} else {
+		_ = e.writer.WriteByte(cborNull)
+	}
+
+	e.writeNode(root)
+
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write cbor: %w", err)
+	}
+
+	return nil
+}
+
+// collectFiles adds all files that are used in ranges of node and its children to files.
+func (e *CBOREncoder) collectFiles(node *parser.TreeNode, files *[]string) {
+	add := func(file string) {
+		if _, ok := e.files[file]; !ok {
+			e.files[file] = len(*files)
+			*files = append(*files, file)
+		}
+	}
+
+	add(node.Range.BeginPos.File)
+	add(node.Range.EndPos.File)
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attr := node.Attributes.GetAt(i)
+		add(attr.Range.BeginPos.File)
+		add(attr.Range.EndPos.File)
+	}
+
+	for _, child := range node.Children {
+		e.collectFiles(child, files)
+	}
+}
+
+// writeNode writes a node with all its children.
+func (e *CBOREncoder) writeNode(node *parser.TreeNode) {
+	fields := uint64(5)
+	if e.ranges {
+		fields++
+	}
+
+	e.writeHead(cborArray, fields)
+
+	switch {
+	case node.IsText():
+		e.writeHead(cborUint, cborTextNode)
+		e.writeString(*node.Text)
+	case node.IsComment():
+		e.writeHead(cborUint, cborComment)
+		e.writeString(*node.Comment)
+	default:
+		e.writeHead(cborUint, cborElement)
+		e.writeString(node.Name)
+	}
+
+	e.writeString(string(node.BlockType))
+
+	e.writeHead(cborArray, uint64(node.Attributes.Len()))
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attr := node.Attributes.GetAt(i)
+		e.writeHead(cborArray, fields-2)
+		e.writeString(attr.Key)
+		e.writeString(attr.Value)
+		e.writeHead(cborUint, uint64(attr.Kind))
+
+		if e.ranges {
+			e.writeRange(attr.Range)
+		}
+	}
+
+	e.writeHead(cborArray, uint64(len(node.Children)))
+
+	for _, child := range node.Children {
+		e.writeNode(child)
+	}
+
+	if e.ranges {
+		e.writeRange(node.Range)
+	}
+}
+
+// writeRange writes a position as an array of the file index, line, column, offset and UTF-16 column
+// of the beginning and end.
+func (e *CBOREncoder) writeRange(rng token.Position) {
+	e.writeHead(cborArray, 10)
+
+	for _, pos := range []token.Pos{rng.BeginPos, rng.EndPos} {
+		e.writeHead(cborUint, uint64(e.files[pos.File]))
+		e.writeHead(cborUint, uint64(pos.Line))
+		e.writeHead(cborUint, uint64(pos.Col))
+		e.writeHead(cborUint, uint64(pos.Offset))
+		e.writeHead(cborUint, uint64(pos.UTF16Col))
+	}
+}
+
+// writeString writes a text string.
+func (e *CBOREncoder) writeString(s string) {
+	e.writeHead(cborText, uint64(len(s)))
+	_, _ = e.writer.WriteString(s)
+}
+
+// writeHead writes the initial bytes of a data item, which contain its major type and
+// its argument, e.g. the length of a string. Errors are reported by Flush.
+func (e *CBOREncoder) writeHead(major byte, arg uint64) {
+	major <<= 5
+
+	switch {
+	case arg < 24:
+		_ = e.writer.WriteByte(major | byte(arg))
+	case arg <= 0xff:
+		_, _ = e.writer.Write([]byte{major | 24, byte(arg)})
+	case arg <= 0xffff:
+		_, _ = e.writer.Write([]byte{major | 25, byte(arg >> 8), byte(arg)})
+	case arg <= 0xffffffff:
+		_, _ = e.writer.Write([]byte{major | 26, byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)})
+	default:
+		_ = e.writer.WriteByte(major | 27)
+
+		for shift := 56; shift >= 0; shift -= 8 {
+			_ = e.writer.WriteByte(byte(arg >> shift))
+		}
+	}
+}
+
+// CBORDecoder reads trees that were written by a CBOREncoder.
+type CBORDecoder struct {
+	reader io.Reader
+	// data is the complete input and pos the offset of the next byte to read.
+	data []byte
+	pos  int
+	// files are the file names that ranges refer to.
+	files []string
+	// ranges is true if the input contains ranges.
+	ranges bool
+	// version is the version of the layout of the input.
+	version uint64
+	// nodes is a chunk of memory for new nodes, so that they do not need to be allocated one by one.
+	nodes []parser.TreeNode
+}
+
+// NewCBORDecoder creates a new CBORDecoder that reads from r.
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{
+		reader: r,
+	}
+}
+
+// Decode reads a tree. Errors about input that was not written by a CBOREncoder wrap ErrInvalidCBOR.
+func (d *CBORDecoder) Decode() (*parser.TreeNode, error) {
+	data, err := io.ReadAll(d.reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cbor: %w", err)
+	}
+
+	d.data, d.pos = data, 0
+
+	if err := d.expectArray(3); err != nil {
+		return nil, err
+	}
+
+	version, err := d.readUint()
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 || version > cborVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCBOR, version)
+	}
+
+	d.version = version
+
+	if err := d.readFiles(); err != nil {
+		return nil, err
+	}
+
+	return d.readNode()
+}
+
+// readFiles reads the list of files, which is null if there are no ranges.
+func (d *CBORDecoder) readFiles() error {
+	if d.pos < len(d.data) && d.data[d.pos] == cborNull {
+		d.pos++
+		d.ranges = false
+
+		return nil
+	}
+
+	count, err := d.readLength(cborArray)
+	if err != nil {
+		return err
+	}
+
+	d.ranges = true
+	d.files = make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		file, err := d.readString()
+		if err != nil {
+			return err
+		}
+
+		d.files = append(d.files, file)
+	}
+
+	return nil
+}
+
+// newNode returns a pointer to a new zero TreeNode.
+func (d *CBORDecoder) newNode() *parser.TreeNode {
+	if len(d.nodes) == 0 {
+		d.nodes = make([]parser.TreeNode, cborNodeChunk)
+	}
+
+	node := &d.nodes[0]
+	d.nodes = d.nodes[1:]
+
+	return node
+}
+
+// readNode reads a node with all its children.
+func (d *CBORDecoder) readNode() (*parser.TreeNode, error) {
+	fields := uint64(5)
+	if d.ranges {
+		fields++
+	}
+
+	if err := d.expectArray(fields); err != nil {
+		return nil, err
+	}
+
+	kind, err := d.readUint()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	node := d.newNode()
+
+	switch kind {
+	case cborElement:
+		node.Name = value
+	case cborTextNode:
+		node.Text = &value
+	case cborComment:
+		node.Comment = &value
+	default:
+		return nil, fmt.Errorf("%w: unknown kind of node %d", ErrInvalidCBOR, kind)
+	}
+
+	blockType, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	node.BlockType = parser.BlockType(blockType)
+
+	if err := d.readAttributes(node, fields-2); err != nil {
+		return nil, err
+	}
+
+	children, err := d.readLength(cborArray)
+	if err != nil {
+		return nil, err
+	}
+
+	if children > 0 {
+		node.Children = make([]*parser.TreeNode, 0, children)
+	}
+
+	for i := 0; i < children; i++ {
+		child, err := d.readNode()
+		if err != nil {
+			return nil, err
+		}
+
+		node.AddChildren(child)
+	}
+
+	if d.ranges {
+		if node.Range, err = d.readRange(); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+// readAttributes reads the attributes of node, which are arrays with the given number of fields.
+func (d *CBORDecoder) readAttributes(node *parser.TreeNode, fields uint64) error {
+	count, err := d.readLength(cborArray)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		if err := d.expectArray(fields); err != nil {
+			return err
+		}
+
+		var attr util.Attribute
+
+		if attr.Key, err = d.readString(); err != nil {
+			return err
+		}
+
+		if attr.Value, err = d.readString(); err != nil {
+			return err
+		}
+
+		kind, err := d.readUint()
+		if err != nil {
+			return err
+		}
+
+		attr.Kind = token.ValueKind(kind)
+
+		if d.ranges {
+			if attr.Range, err = d.readRange(); err != nil {
+				return err
+			}
+		}
+
+		node.Attributes.Add(attr)
+	}
+
+	return nil
+}
+
+// readRange reads a position that was written by writeRange.
+func (d *CBORDecoder) readRange() (token.Position, error) {
+	var rng token.Position
+
+	var values [5]uint64
+
+	// Ranges of version 1 have no UTF-16 columns.
+	n := len(values)
+	if d.version == 1 {
+		n--
+	}
+
+	if err := d.expectArray(uint64(2 * n)); err != nil {
+		return rng, err
+	}
+
+	for _, pos := range [2]*token.Pos{&rng.BeginPos, &rng.EndPos} {
+		for i := 0; i < n; i++ {
+			v, err := d.readUint()
+			if err != nil {
+				return rng, err
+			}
+
+			values[i] = v
+		}
+
+		if values[0] >= uint64(len(d.files)) {
+			return rng, fmt.Errorf("%w: unknown file %d", ErrInvalidCBOR, values[0])
+		}
+
+		pos.File = d.files[values[0]]
+		pos.Line, pos.Col, pos.Offset, pos.UTF16Col = int(values[1]), int(values[2]), int(values[3]), int(values[4])
+	}
+
+	return rng, nil
+}
+
+// expectArray reads the head of an array and returns an error if it does not have the given length.
+func (d *CBORDecoder) expectArray(length uint64) error {
+	n, err := d.readHead(cborArray)
+	if err != nil {
+		return err
+	}
+
+	if n != length {
+		return fmt.Errorf("%w: expected an array of length %d, but got %d", ErrInvalidCBOR, length, n)
+	}
+
+	return nil
+}
+
+// readUint reads an unsigned integer.
+func (d *CBORDecoder) readUint() (uint64, error) {
+	return d.readHead(cborUint)
+}
+
+// readString reads a text string.
+func (d *CBORDecoder) readString() (string, error) {
+	n, err := d.readLength(cborText)
+	if err != nil {
+		return "", err
+	}
+
+	s := string(d.data[d.pos : d.pos+n])
+	d.pos += n
+
+	return s, nil
+}
+
+// readLength reads the head of a string or an array and returns its length. As every byte
+// of a string and every element of an array take at least one byte, the length cannot be
+// larger than the rest of the input.
+func (d *CBORDecoder) readLength(major byte) (int, error) {
+	n, err := d.readHead(major)
+	if err != nil {
+		return 0, err
+	}
+
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, fmt.Errorf("%w: length %d exceeds the input", ErrInvalidCBOR, n)
+	}
+
+	return int(n), nil
+}
+
+// readHead reads the initial bytes of a data item with the given major type and returns its argument.
+func (d *CBORDecoder) readHead(major byte) (uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("%w: unexpected end of input", ErrInvalidCBOR)
+	}
+
+	b := d.data[d.pos]
+	d.pos++
+
+	if b>>5 != major {
+		return 0, fmt.Errorf("%w: expected major type %d, but got %d", ErrInvalidCBOR, major, b>>5)
+	}
+
+	info := b & 0x1f
+	if info < 24 {
+		return uint64(info), nil
+	}
+
+	if info > 27 {
+		return 0, fmt.Errorf("%w: unsupported additional information %d", ErrInvalidCBOR, info)
+	}
+
+	size := 1 << (info - 24)
+	if d.pos+size > len(d.data) {
+		return 0, fmt.Errorf("%w: unexpected end of input", ErrInvalidCBOR)
+	}
+
+	var arg uint64
+	for _, b := range d.data[d.pos : d.pos+size] {
+		arg = arg<<8 | uint64(b)
+	}
+
+	d.pos += size
+
+	return arg, nil
+}
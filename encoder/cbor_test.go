@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/ast"
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		`#? comment
+		#book @id{1} @title{A long title that needs more than 23 bytes} {
+			#chapter Some text #b{bold}
+		}`,
+		`#! g2 {
+			fn @public=true @@order=2 Run(x int) -> result (int, error)
+			name: string
+		}`,
+		strings.Repeat("#item{text} ", 300),
+	}
+
+	t.Parallel()
+
+	for _, in := range inputs {
+		input := in
+
+		for _, ranges := range []bool{false, true} {
+			withRanges := ranges
+
+			t.Run(input, func(t *testing.T) {
+				t.Parallel()
+
+				tree, err := parser.NewParser("file.dyml", strings.NewReader(input)).Parse()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var opts []encoder.CBOROption
+				if withRanges {
+					opts = append(opts, encoder.WithCBORRanges())
+				}
+
+				var buf bytes.Buffer
+				if err := encoder.NewCBOREncoder(&buf, opts...).Encode(tree); err != nil {
+					t.Fatal(err)
+				}
+
+				decoded, err := encoder.NewCBORDecoder(&buf).Decode()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want, got := ast.FromTree(tree), ast.FromTree(decoded)
+				if !withRanges {
+					clearRanges(want)
+				}
+
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("decoded tree differs:\n%+v\n%+v", want, got)
+				}
+			})
+		}
+	}
+}
+
+// clearRanges removes the ranges of all nodes and attributes.
+func clearRanges(node *ast.Node) {
+	node.Range = token.Position{}
+
+	for i := range node.Attributes {
+		node.Attributes[i].Range = token.Position{}
+	}
+
+	for _, child := range node.Children {
+		clearRanges(child)
+	}
+}
+
+func TestCBORDecodeInvalid(t *testing.T) {
+	tree, err := parser.NewParser("test", strings.NewReader("#a @b{c} {#d{text}}")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := encoder.NewCBOREncoder(&buf, encoder.WithCBORRanges()).Encode(tree); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty", input: nil},
+		{name: "truncated", input: valid[:len(valid)/2]},
+		{name: "wrong version", input: append([]byte{0x83, 0x03}, valid[2:]...)},
+		{name: "not an array", input: []byte{0x63, 'a', 'b', 'c'}},
+		{name: "huge string", input: []byte{0x83, 0x01, 0xf6, 0x85, 0x00, 0x7b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := encoder.NewCBORDecoder(bytes.NewReader(test.input)).Decode(); !errors.Is(err, encoder.ErrInvalidCBOR) {
+				t.Errorf("expected ErrInvalidCBOR, but got %v", err)
+			}
+		})
+	}
+}
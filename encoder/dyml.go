@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// DymlEncoder writes a parsed tree as dyml text.
+// All elements are written in G2, as quoted strings in G2 preserve text exactly.
+// Parsing the output will result in the same tree again, with the exception of
+// comments on the top level, which will get a trailing newline.
+type DymlEncoder struct {
+	writer *bufio.Writer
+	// indent is the current level of indentation.
+	indent uint
+	// indentUnit is written once per level of indentation.
+	indentUnit string
+}
+
+// DymlEncoderOption configures a DymlEncoder.
+type DymlEncoderOption func(e *DymlEncoder)
+
+// WithDymlIndent sets the string that is written once per level of indentation, e.g. "\t".
+// The default are four spaces.
+func WithDymlIndent(indent string) DymlEncoderOption {
+	return func(e *DymlEncoder) {
+		e.indentUnit = indent
+	}
+}
+
+// NewDymlEncoder creates a new DymlEncoder that writes to w.
+func NewDymlEncoder(w io.Writer, opts ...DymlEncoderOption) *DymlEncoder {
+	e := &DymlEncoder{
+		writer:     bufio.NewWriter(w),
+		indentUnit: "    ",
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Encode writes the given tree to the writer. The root node itself is not written,
+// as it is implicitly created when parsing dyml, only its children are.
+func (e *DymlEncoder) Encode(root *parser.TreeNode) error {
+	if root.Attributes.Len() > 0 {
+		return fmt.Errorf("attributes of the root element '%s' cannot be encoded", root.Name)
+	}
+
+	for _, child := range root.Children {
+		if err := e.writeTopLevel(child); err != nil {
+			return err
+		}
+	}
+
+	return e.flush()
+}
+
+// EncodeChild writes a single child of the root, like Encode does for each of them, and flushes it.
+// Calling it for one child after another allows writing documents that do not fit into memory as a whole.
+func (e *DymlEncoder) EncodeChild(child *parser.TreeNode) error {
+	if err := e.writeTopLevel(child); err != nil {
+		return err
+	}
+
+	return e.flush()
+}
+
+// writeTopLevel writes a child of the root in its own line.
+func (e *DymlEncoder) writeTopLevel(child *parser.TreeNode) error {
+	switch {
+	case child.IsComment():
+		// The newline that ends a comment is part of it, so it is not written twice.
+		comment := strings.TrimSuffix(*child.Comment, "\n")
+
+		return e.writeString(fmt.Sprintf("#? %s\n", escapeG1Comment(comment)))
+	case child.IsText():
+		return e.writeString(fmt.Sprintf("#! %s\n", quoteG2(*child.Text)))
+	default:
+		if err := e.writeString("#! "); err != nil {
+			return err
+		}
+
+		if err := e.writeElement(child); err != nil {
+			return err
+		}
+
+		return e.writeString("\n")
+	}
+}
+
+// flush writes all buffered output.
+func (e *DymlEncoder) flush() error {
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush written dyml: %w", err)
+	}
+
+	return nil
+}
+
+// writeElement writes a regular node with all of its attributes and children.
+func (e *DymlEncoder) writeElement(node *parser.TreeNode) error {
+	if !IsIdentifier(node.Name) {
+		return fmt.Errorf("'%s' is not a valid element name", node.Name)
+	}
+
+	var tag strings.Builder
+
+	tag.WriteString(node.Name)
+
+	attributes := node.Attributes
+
+	for {
+		attr := attributes.Pop()
+		if attr == nil {
+			break
+		}
+
+		if !IsIdentifier(attr.Key) {
+			return fmt.Errorf("'%s' is not a valid attribute name", attr.Key)
+		}
+
+		value := attr.Value
+		if attr.Kind == token.KindString {
+			value = quoteG2(value)
+		}
+
+		tag.WriteString(fmt.Sprintf(" @%s=%s", attr.Key, value))
+	}
+
+	if err := e.writeString(tag.String()); err != nil {
+		return err
+	}
+
+	// Nodes without a block are written inline if that does not change how they are parsed.
+	if node.BlockType == parser.BlockNone {
+		switch {
+		case len(node.Children) == 0:
+			// The comma prevents following elements from nesting.
+			return e.writeString(",")
+		case len(node.Children) == 1 && node.Children[0].IsText():
+			return e.writeString(" " + quoteG2(*node.Children[0].Text))
+		case len(node.Children) == 1 && node.Children[0].IsNode():
+			if err := e.writeString(" "); err != nil {
+				return err
+			}
+
+			return e.writeElement(node.Children[0])
+		}
+	}
+
+	return e.writeBlock(node)
+}
+
+// writeBlock writes all children of node enclosed in the brackets of its BlockType.
+func (e *DymlEncoder) writeBlock(node *parser.TreeNode) error {
+	blockType := node.BlockType
+	if blockType == parser.BlockNone {
+		blockType = parser.BlockNormal
+	}
+
+	brackets := string(blockType)
+
+	if len(node.Children) == 0 {
+		return e.writeString(" " + brackets)
+	}
+
+	if err := e.writeString(" " + brackets[:1] + "\n"); err != nil {
+		return err
+	}
+
+	e.indent++
+
+	for _, child := range node.Children {
+		var err error
+
+		switch {
+		case child.IsComment():
+			for _, line := range strings.Split(*child.Comment, "\n") {
+				if err = e.writeString(fmt.Sprintf("%s// %s\n", e.indentString(), line)); err != nil {
+					break
+				}
+			}
+		case child.IsText():
+			err = e.writeString(fmt.Sprintf("%s%s\n", e.indentString(), quoteG2(*child.Text)))
+		default:
+			if err = e.writeString(e.indentString()); err == nil {
+				err = e.writeElement(child)
+			}
+
+			if err == nil {
+				err = e.writeString("\n")
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	e.indent--
+
+	return e.writeString(e.indentString() + brackets[1:])
+}
+
+// writeString is a convenience method to write strings to the underlying writer.
+func (e *DymlEncoder) writeString(s string) error {
+	_, err := e.writer.WriteString(s)
+
+	return err
+}
+
+// indentString returns the indentation of the current level.
+func (e *DymlEncoder) indentString() string {
+	return strings.Repeat(e.indentUnit, int(e.indent))
+}
+
+// IsIdentifier returns true if s is a valid name for elements and attributes.
+// Identifiers consist of the characters allowed by token.UnicodeIdentChar and may be separated by dots.
+func IsIdentifier(s string) bool {
+	for _, part := range strings.Split(s, ".") {
+		if len(part) == 0 {
+			return false
+		}
+
+		for _, r := range part {
+			if !token.UnicodeIdentChar(r) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// quoteG2 returns s as a quoted string for G2, escaping quotes and backslashes.
+func quoteG2(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// escapeG1Comment escapes all characters that would end a G1 comment.
+func escapeG1Comment(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `#`, `\#`)
+
+	return replacer.Replace(s)
+}
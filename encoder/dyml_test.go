@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestDymlEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		tree *parser.TreeNode
+		opts []encoder.DymlEncoderOption
+		want string
+	}{
+		{
+			name: "empty",
+			tree: parser.NewNode("root").Block(parser.BlockNormal),
+			want: "",
+		},
+		{
+			name: "top level text and comments",
+			tree: parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+				parser.NewStringCommentNode("a #comment"),
+				parser.NewStringNode(`say "hello"`),
+			),
+			want: "#? a \\#comment\n#! \"say \\\"hello\\\"\"\n",
+		},
+		{
+			name: "elements",
+			tree: parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+				parser.NewNode("empty"),
+				parser.NewNode("text").AddAttribute("key", "value").AddChildren(parser.NewStringNode("content")),
+				parser.NewNode("nested").AddChildren(parser.NewNode("child")),
+			),
+			want: "#! empty,\n#! text @key=\"value\" \"content\"\n#! nested child,\n",
+		},
+		{
+			name: "blocks",
+			tree: parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+				parser.NewNode("fn").Block(parser.BlockGroup).AddChildren(
+					parser.NewStringCommentNode("first\nsecond"),
+					parser.NewNode("a"),
+					parser.NewNode("b").Block(parser.BlockGeneric),
+				),
+			),
+			want: "#! fn (\n    // first\n    // second\n    a,\n    b <>\n)\n",
+		},
+		{
+			name: "indent",
+			tree: parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+				parser.NewNode("a").Block(parser.BlockNormal).AddChildren(
+					parser.NewNode("b").Block(parser.BlockNormal).AddChildren(parser.NewStringNode("x")),
+				),
+			),
+			opts: []encoder.DymlEncoderOption{encoder.WithDymlIndent("\t")},
+			want: "#! a {\n\tb {\n\t\t\"x\"\n\t}\n}\n",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var writer bytes.Buffer
+			if err := encoder.NewDymlEncoder(&writer, test.opts...).Encode(test.tree); err != nil {
+				t.Fatal(err)
+			}
+
+			if writer.String() != test.want {
+				t.Errorf("wanted '%s', got '%s'", test.want, writer.String())
+			}
+		})
+	}
+}
+
+func TestDymlEncodeRoundTrip(t *testing.T) {
+	inputs := []string{
+		`#! list {
+			item1 key "value",
+			@@id="1"
+			item2,
+			item3 @key="va\"lue",
+		}`,
+		`#! g2 {
+			hello(string) -> (int)
+			fn x<y> -> <z>
+			a b c d
+		}`,
+		`#! server @port=8080 @ratio=-0.5 @tls=false @name="8080"`,
+		`#! "text \\ with \" escapes"`,
+		`#! a {
+			// some comment
+			b { "x" "y" }
+		}`,
+	}
+
+	t.Parallel()
+
+	for _, in := range inputs {
+		input := in
+
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := parser.NewParser("want", strings.NewReader(input)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var writer bytes.Buffer
+			if err := encoder.NewDymlEncoder(&writer).Encode(want); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parser.NewParser("got", &writer).Parse()
+			if err != nil {
+				t.Fatalf("cannot parse encoded output: %v", err)
+			}
+
+			if !sameTree(want, got) {
+				t.Errorf("encoded tree differs from input:\n%s", writer.String())
+			}
+		})
+	}
+}
+
+func TestDymlEncodeTwice(t *testing.T) {
+	inputs := []string{
+		"#? note\n#x",
+		"#? first\n#? second\n\n#x{text}",
+		"#! a {\n// some comment\nb}",
+	}
+
+	t.Parallel()
+
+	for _, in := range inputs {
+		input := in
+
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			first := encodeDyml(t, input)
+			if second := encodeDyml(t, first); second != first {
+				t.Errorf("encoding the output again changed it from\n%s\nto\n%s", first, second)
+			}
+		})
+	}
+}
+
+// encodeDyml parses the input and encodes it as dyml again.
+func encodeDyml(t *testing.T, input string) string {
+	t.Helper()
+
+	tree, err := parser.NewParser("input", strings.NewReader(input)).Parse()
+	if err != nil {
+		t.Fatalf("cannot parse '%s': %v", input, err)
+	}
+
+	var writer bytes.Buffer
+	if err := encoder.NewDymlEncoder(&writer).Encode(tree); err != nil {
+		t.Fatal(err)
+	}
+
+	return writer.String()
+}
+
+// sameTree compares two trees, ignoring the positions of nodes and attributes.
+func sameTree(a, b *parser.TreeNode) bool {
+	if a.Name != b.Name || a.BlockType != b.BlockType ||
+		!reflect.DeepEqual(a.Text, b.Text) || !reflect.DeepEqual(a.Comment, b.Comment) ||
+		len(a.Children) != len(b.Children) || a.Attributes.Len() != b.Attributes.Len() {
+		return false
+	}
+
+	attrsA, attrsB := a.Attributes, b.Attributes
+	for attr := attrsA.Pop(); attr != nil; attr = attrsA.Pop() {
+		other := attrsB.Pop()
+		if attr.Key != other.Key || attr.Value != other.Value || attr.Kind != other.Kind {
+			return false
+		}
+	}
+
+	for i := range a.Children {
+		if !sameTree(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,356 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// MarkdownElement describes how an element is converted into Markdown.
+type MarkdownElement struct {
+	// Block elements are separated from other blocks by an empty line. Texts and inline elements
+	// between blocks are combined into paragraphs.
+	Block bool
+	// Section elements increase the level of the headings within them. Their children are converted
+	// as blocks, the children of all other elements are converted inline.
+	Section bool
+	// Render returns the Markdown of the element. content is the already converted Markdown of
+	// its children and level is the number of enclosing sections, starting at 1.
+	// If Render is nil, only the content is written.
+	Render func(node *parser.TreeNode, content string, level int) (string, error)
+}
+
+// MarkdownElements returns the elements that are known to a MarkdownEncoder by default:
+//
+//   - #title is a heading, whose level depends on the number of enclosing sections.
+//   - #section contains a part of the document, its titles are one level deeper.
+//   - #p is a paragraph.
+//   - #bold and #italic emphasize their text.
+//   - #image is an image with its location in @src and optionally @title. Its text or @alt is the
+//     alternative text.
+//   - #link is a link to @href with its text, or the URL itself if there is no text.
+func MarkdownElements() map[string]MarkdownElement {
+	return map[string]MarkdownElement{
+		"title": {
+			Block: true,
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				if level > 6 {
+					level = 6
+				}
+
+				return strings.Repeat("#", level) + " " + content, nil
+			},
+		},
+		"section": {
+			Block:   true,
+			Section: true,
+		},
+		"p": {
+			Block: true,
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				return escapeBlockStart(content), nil
+			},
+		},
+		"bold": {
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				return emphasize(content, "**"), nil
+			},
+		},
+		"italic": {
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				return emphasize(content, "*"), nil
+			},
+		},
+		"image": {
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				src := node.Attributes.Get("src")
+				if src == nil {
+					return "", token.NewPosError(node.Range, "image needs a @src attribute")
+				}
+
+				if alt := node.Attributes.Get("alt"); alt != nil {
+					content = escapeMarkdown(alt.Value)
+				}
+
+				destination := markdownDestination(src.Value, node.Attributes.Get("title"))
+
+				return fmt.Sprintf("![%s](%s)", content, destination), nil
+			},
+		},
+		"link": {
+			Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+				href := node.Attributes.Get("href")
+				if href == nil {
+					return "", token.NewPosError(node.Range, "link needs a @href attribute")
+				}
+
+				if content == "" {
+					return "<" + href.Value + ">", nil
+				}
+
+				destination := markdownDestination(href.Value, node.Attributes.Get("title"))
+
+				return fmt.Sprintf("[%s](%s)", content, destination), nil
+			},
+		},
+	}
+}
+
+// MarkdownOption is used to configure a MarkdownEncoder.
+type MarkdownOption func(e *MarkdownEncoder)
+
+// WithMarkdownElement adds an element to the mapping of a MarkdownEncoder or replaces a default one.
+func WithMarkdownElement(name string, element MarkdownElement) MarkdownOption {
+	return func(e *MarkdownEncoder) {
+		e.elements[name] = element
+	}
+}
+
+// MarkdownEncoder writes a parsed text-centric document, as it is usually written in G1, as Markdown,
+// e.g. to use dyml as the source of a documentation pipeline. Elements are converted as described by
+// MarkdownElements. The content of unknown elements is written without any markup.
+// Whitespace in texts is collapsed into single spaces and comments are ignored.
+type MarkdownEncoder struct {
+	writer   io.Writer
+	elements map[string]MarkdownElement
+}
+
+// NewMarkdownEncoder creates a new MarkdownEncoder that writes to w.
+func NewMarkdownEncoder(w io.Writer, opts ...MarkdownOption) *MarkdownEncoder {
+	e := &MarkdownEncoder{
+		writer:   w,
+		elements: MarkdownElements(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Encode writes the given tree as Markdown to the writer. The root is treated like a section,
+// so that top-level titles are headings of the first level.
+func (e *MarkdownEncoder) Encode(root *parser.TreeNode) error {
+	blocks, err := e.blocks(root.Children, 1)
+	if err != nil {
+		return err
+	}
+
+	if blocks == "" {
+		return nil
+	}
+
+	_, err = io.WriteString(e.writer, blocks+"\n")
+
+	return err
+}
+
+// blocks converts the children of a section, joining block elements and paragraphs with empty lines.
+func (e *MarkdownEncoder) blocks(children []*parser.TreeNode, level int) (string, error) {
+	var blocks []string
+
+	// paragraph contains the index of the first child of the current paragraph.
+	paragraph := 0
+
+	endParagraph := func(end int) error {
+		text, err := e.inlines(children[paragraph:end], level)
+		if err != nil {
+			return err
+		}
+
+		if text = strings.TrimSpace(text); text != "" {
+			blocks = append(blocks, escapeBlockStart(text))
+		}
+
+		paragraph = end + 1
+
+		return nil
+	}
+
+	for i, child := range children {
+		if !child.IsNode() || !e.elements[child.Name].Block {
+			continue
+		}
+
+		if err := endParagraph(i); err != nil {
+			return "", err
+		}
+
+		block, err := e.element(child, level)
+		if err != nil {
+			return "", err
+		}
+
+		if block = strings.TrimSpace(block); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+
+	if err := endParagraph(len(children)); err != nil {
+		return "", err
+	}
+
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// inlines converts texts, comments and elements that are part of a paragraph.
+func (e *MarkdownEncoder) inlines(nodes []*parser.TreeNode, level int) (string, error) {
+	var sb strings.Builder
+
+	for i, node := range nodes {
+		// Whitespace after an element is not part of the following text, so it is restored from the ranges.
+		if i > 0 && node.IsText() && nodes[i-1].IsNode() &&
+			node.Range.BeginPos.Offset > nodes[i-1].Range.EndPos.Offset && !strings.HasSuffix(sb.String(), " ") {
+			sb.WriteByte(' ')
+		}
+
+		inline, err := e.inline(node, level)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(inline)
+	}
+
+	return sb.String(), nil
+}
+
+// inline converts a text, comment or element that is part of a paragraph.
+func (e *MarkdownEncoder) inline(node *parser.TreeNode, level int) (string, error) {
+	switch {
+	case node.IsComment():
+		return "", nil
+	case node.IsText():
+		return escapeMarkdown(collapseSpaces(*node.Text)), nil
+	default:
+		return e.element(node, level)
+	}
+}
+
+// element converts an element with all of its children.
+func (e *MarkdownEncoder) element(node *parser.TreeNode, level int) (string, error) {
+	element := e.elements[node.Name]
+
+	content, err := e.content(node, element, level)
+	if err != nil {
+		return "", err
+	}
+
+	if element.Render == nil {
+		return content, nil
+	}
+
+	return element.Render(node, content, level)
+}
+
+// content converts the children of an element, as blocks for sections and inline for all other elements.
+func (e *MarkdownEncoder) content(node *parser.TreeNode, element MarkdownElement, level int) (string, error) {
+	if element.Section {
+		return e.blocks(node.Children, level+1)
+	}
+
+	content, err := e.inlines(node.Children, level)
+	if err != nil {
+		return "", err
+	}
+
+	if element.Block {
+		return strings.TrimSpace(content), nil
+	}
+
+	return content, nil
+}
+
+// emphasize surrounds the content with the marker. Whitespace at the borders is moved outside,
+// as Markdown does not allow emphasis to begin or end with whitespace.
+func emphasize(content, marker string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return content
+	}
+
+	start := strings.Index(content, trimmed)
+
+	return content[:start] + marker + trimmed + marker + content[start+len(trimmed):]
+}
+
+// markdownDestination returns the destination of a link or image with an optional title.
+func markdownDestination(url string, title *util.Attribute) string {
+	url = "<" + strings.NewReplacer("<", `\<`, ">", `\>`).Replace(url) + ">"
+	if title == nil {
+		return url
+	}
+
+	return url + ` "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(title.Value) + `"`
+}
+
+// collapseSpaces replaces each run of whitespace with a single space.
+func collapseSpaces(s string) string {
+	var sb strings.Builder
+
+	space := false
+
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			space = true
+
+			continue
+		}
+
+		if space {
+			sb.WriteByte(' ')
+
+			space = false
+		}
+
+		sb.WriteRune(r)
+	}
+
+	if space {
+		sb.WriteByte(' ')
+	}
+
+	return sb.String()
+}
+
+// markdownEscaper escapes all characters that could start Markdown markup.
+//
+//nolint:gochecknoglobals // A Replacer is safe for concurrent use and builds its lookup table only once.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`,
+)
+
+// escapeMarkdown escapes text, so that it is not interpreted as Markdown.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeBlockStart escapes the first character of a paragraph, if it would otherwise start a heading,
+// list or quote.
+func escapeBlockStart(s string) string {
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+
+	switch {
+	case s == "":
+		return s
+	case s[0] == '#' || s[0] == '>':
+		return `\` + s
+	case (s[0] == '-' || s[0] == '+') && (len(s) == 1 || s[1] == ' '):
+		return `\` + s
+	case digits > 0 && digits < len(s) && (s[digits] == '.' || s[digits] == ')'):
+		return s[:digits] + `\` + s[digits:]
+	default:
+		return s
+	}
+}
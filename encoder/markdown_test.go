@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestMarkdownEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		opts    []encoder.MarkdownOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			text: "",
+			want: "",
+		},
+		{
+			name: "titles and sections",
+			text: `#title{Guide}
+Some intro.
+#section{
+	#title{Install}
+	#p{Run the installer.}
+	#section{#title{Details}}
+}`,
+			want: "# Guide\n\nSome intro.\n\n## Install\n\nRun the installer.\n\n### Details\n",
+		},
+		{
+			name: "inline elements",
+			text: `Text with #bold{strong} and #italic{ emphasized } words.`,
+			want: "Text with **strong** and *emphasized* words.\n",
+		},
+		{
+			name: "links and images",
+			text: `See #link @href{https://example.com}{the site} or #link @href{https://example.org}{}.
+#image @src{logo.png} @title{The "logo"}{Logo}`,
+			want: "See [the site](<https://example.com>) or <https://example.org>. " +
+				"![Logo](<logo.png> \"The \\\"logo\\\"\")\n",
+		},
+		{
+			name: "escaping",
+			text: `#p{\# not a heading with *stars* and [brackets]} 1. not a list`,
+			want: "\\# not a heading with \\*stars\\* and \\[brackets\\]\n\n1\\. not a list\n",
+		},
+		{
+			name: "whitespace and comments",
+			text: "#p{  many\n\n   spaces  } #? a comment\n",
+			want: "many spaces\n",
+		},
+		{
+			name: "unknown elements",
+			text: `#custom{plain #bold{text}}`,
+			want: "plain **text**\n",
+		},
+		{
+			name: "custom element",
+			text: `#code{fmt.Println()}`,
+			opts: []encoder.MarkdownOption{
+				encoder.WithMarkdownElement("code", encoder.MarkdownElement{
+					Block: true,
+					Render: func(node *parser.TreeNode, content string, level int) (string, error) {
+						return "```\n" + *node.Children[0].Text + "\n```", nil
+					},
+				}),
+			},
+			want: "```\nfmt.Println()\n```\n",
+		},
+		{
+			name:    "image without source",
+			text:    `#image{Logo}`,
+			wantErr: true,
+		},
+		{
+			name:    "link without target",
+			text:    `#link{Home}`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var writer bytes.Buffer
+
+			err = encoder.NewMarkdownEncoder(&writer, test.opts...).Encode(tree)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			if err == nil && writer.String() != test.want {
+				t.Errorf("wanted\n%q\ngot\n%q", test.want, writer.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// ProtoTextField is the name of the field that contains the text of elements,
+// which also have attributes or child elements.
+const ProtoTextField = "#text"
+
+// Field numbers and wire types of google.protobuf.Struct, Value and ListValue.
+const (
+	protoStructFields   = 1
+	protoEntryKey       = 1
+	protoEntryValue     = 2
+	protoValueNumber    = 2
+	protoValueString    = 3
+	protoValueBool      = 4
+	protoValueStruct    = 5
+	protoValueList      = 6
+	protoListValues     = 1
+	protoWireVarint     = 0
+	protoWireFixed64    = 1
+	protoWireLengthData = 2
+)
+
+// ProtoEncoder writes a parsed tree as a serialized google.protobuf.Struct message, so that it can
+// be sent to gRPC services without converting it to JSON first. Any protobuf library can read it,
+// e.g. with proto.Unmarshal into a structpb.Struct. The tree is mapped like this:
+//
+//   - The root element becomes the Struct.
+//   - Child elements and attributes become fields of the Struct of their parent.
+//     Should a name be used multiple times, the field is a list with all values.
+//   - Elements that only contain text become strings. Texts are trimmed like in the XMLEncoder.
+//   - Elements with attributes or child elements become a Struct, their text is put into the ProtoTextField.
+//     Elements without any content become an empty Struct.
+//   - Attribute values that were written as numbers or booleans in G2 keep their type.
+//   - Comments are ignored.
+type ProtoEncoder struct {
+	writer io.Writer
+}
+
+// NewProtoEncoder creates a new ProtoEncoder that writes to w.
+func NewProtoEncoder(w io.Writer) *ProtoEncoder {
+	return &ProtoEncoder{
+		writer: w,
+	}
+}
+
+// Encode writes the given tree as a google.protobuf.Struct to the writer.
+func (e *ProtoEncoder) Encode(root *parser.TreeNode) error {
+	msg, err := protoStruct(root)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.writer.Write(msg)
+
+	return err
+}
+
+// protoStruct returns the serialized google.protobuf.Struct for the attributes and children of node.
+func protoStruct(node *parser.TreeNode) ([]byte, error) {
+	// Fields keep the order in which they were first used, so that the output is deterministic.
+	var names []string
+
+	fields := map[string][][]byte{}
+
+	addField := func(name string, value []byte) {
+		if _, ok := fields[name]; !ok {
+			names = append(names, name)
+		}
+
+		fields[name] = append(fields[name], value)
+	}
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attr := node.Attributes.GetAt(i)
+
+		value, err := protoAttributeValue(attr.Value, attr.Kind)
+		if err != nil {
+			return nil, token.NewPosError(attr.Range, fmt.Sprintf("invalid value for attribute '%s'", attr.Key)).SetCause(err)
+		}
+
+		addField(attr.Key, value)
+	}
+
+	var text strings.Builder
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsText():
+			text.WriteString(*child.Text)
+		case child.IsNode():
+			value, err := protoValue(child)
+			if err != nil {
+				return nil, err
+			}
+
+			if node.Attributes.Get(child.Name) != nil {
+				return nil, token.NewPosError(child.Range,
+					fmt.Sprintf("'%s' is used for an attribute and an element", child.Name))
+			}
+
+			addField(child.Name, value)
+		}
+	}
+
+	// Names in dyml cannot contain '#', so the text field never collides with other fields.
+	if trimmed := strings.TrimSpace(text.String()); trimmed != "" {
+		addField(ProtoTextField, protoStringValue(trimmed))
+	}
+
+	var msg []byte
+
+	for _, name := range names {
+		values := fields[name]
+
+		value := values[0]
+		if len(values) > 1 {
+			var list []byte
+			for _, v := range values {
+				list = appendProtoBytes(list, protoListValues, v)
+			}
+
+			value = appendProtoBytes(nil, protoValueList, list)
+		}
+
+		var entry []byte
+		entry = appendProtoBytes(entry, protoEntryKey, []byte(name))
+		entry = appendProtoBytes(entry, protoEntryValue, value)
+		msg = appendProtoBytes(msg, protoStructFields, entry)
+	}
+
+	return msg, nil
+}
+
+// protoValue returns the serialized google.protobuf.Value for an element.
+func protoValue(node *parser.TreeNode) ([]byte, error) {
+	var (
+		text     strings.Builder
+		hasText  bool
+		hasNodes bool
+	)
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsText():
+			text.WriteString(*child.Text)
+
+			hasText = true
+		case child.IsNode():
+			hasNodes = true
+		}
+	}
+
+	if hasText && !hasNodes && node.Attributes.Len() == 0 {
+		return protoStringValue(strings.TrimSpace(text.String())), nil
+	}
+
+	msg, err := protoStruct(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendProtoBytes(nil, protoValueStruct, msg), nil
+}
+
+// protoAttributeValue returns the serialized google.protobuf.Value for an attribute value.
+func protoAttributeValue(value string, kind token.ValueKind) ([]byte, error) {
+	switch kind {
+	case token.KindNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		var bits [8]byte
+
+		binary.LittleEndian.PutUint64(bits[:], math.Float64bits(number))
+
+		return append(appendProtoTag(nil, protoValueNumber, protoWireFixed64), bits[:]...), nil
+	case token.KindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+
+		var v uint64
+		if b {
+			v = 1
+		}
+
+		return appendProtoVarint(appendProtoTag(nil, protoValueBool, protoWireVarint), v), nil
+	case token.KindString:
+		return protoStringValue(value), nil
+	default:
+		return nil, fmt.Errorf("unknown kind of value: %d", kind)
+	}
+}
+
+// protoStringValue returns the serialized google.protobuf.Value for a string.
+func protoStringValue(s string) []byte {
+	return appendProtoBytes(nil, protoValueString, []byte(s))
+}
+
+// appendProtoTag appends the key of a field to b.
+func appendProtoTag(b []byte, field, wireType uint64) []byte {
+	return appendProtoVarint(b, field<<3|wireType)
+}
+
+// appendProtoVarint appends v in the variable length encoding of protobuf to b.
+func appendProtoVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// appendProtoBytes appends a length-delimited field, which is a string or a message, to b.
+func appendProtoBytes(b []byte, field uint64, data []byte) []byte {
+	b = appendProtoTag(b, field, protoWireLengthData)
+	b = appendProtoVarint(b, uint64(len(data)))
+
+	return append(b, data...)
+}
@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestProtoEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			text: "",
+			want: map[string]interface{}{},
+		},
+		{
+			name: "texts and structs",
+			text: `#? ignored
+				#server @host{localhost} {
+					#port 8080
+					#tls
+					#name{main} #note{text with #b{bold}}
+				}`,
+			want: map[string]interface{}{
+				"server": map[string]interface{}{
+					"host": "localhost",
+					"port": "8080",
+					"tls":  map[string]interface{}{},
+					"name": "main",
+					"note": map[string]interface{}{
+						"#text": "text with",
+						"b":     "bold",
+					},
+				},
+			},
+		},
+		{
+			name: "lists",
+			text: `#! list { item "a", item "b", other "c" }`,
+			want: map[string]interface{}{
+				"list": map[string]interface{}{
+					"item":  []interface{}{"a", "b"},
+					"other": "c",
+				},
+			},
+		},
+		{
+			name: "typed attributes",
+			text: `#! server @port=8080 @ratio=-0.5 @tls=true @name="8080" {}`,
+			want: map[string]interface{}{
+				"server": map[string]interface{}{
+					"port":  8080.0,
+					"ratio": -0.5,
+					"tls":   true,
+					"name":  "8080",
+				},
+			},
+		},
+		{
+			name:    "attribute and element with the same name",
+			text:    `#a @b{x} {#b}`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var writer bytes.Buffer
+
+			err = encoder.NewProtoEncoder(&writer).Encode(tree)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, but got %v", test.wantErr, err)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			got, err := decodeProtoStruct(writer.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, but got %v", test.want, got)
+			}
+		})
+	}
+}
+
+// protoField is a single field of a protobuf message.
+type protoField struct {
+	number uint64
+	// data is the content of length-delimited fields.
+	data []byte
+	// value is the content of varint and fixed64 fields.
+	value uint64
+}
+
+// decodeProtoFields splits a protobuf message into its fields.
+func decodeProtoFields(msg []byte) ([]protoField, error) {
+	var fields []protoField
+
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, errors.New("invalid key")
+		}
+
+		msg = msg[n:]
+		field := protoField{number: key >> 3}
+
+		switch key & 7 {
+		case 0:
+			field.value, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, errors.New("invalid varint")
+			}
+
+			msg = msg[n:]
+		case 1:
+			if len(msg) < 8 {
+				return nil, errors.New("invalid fixed64")
+			}
+
+			field.value = binary.LittleEndian.Uint64(msg)
+			msg = msg[8:]
+		case 2:
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return nil, errors.New("invalid length")
+			}
+
+			field.data = msg[n : n+int(length)]
+			msg = msg[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d", key&7)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// decodeProtoStruct decodes a google.protobuf.Struct into a map.
+func decodeProtoStruct(msg []byte) (map[string]interface{}, error) {
+	fields, err := decodeProtoFields(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+
+	for _, field := range fields {
+		entry, err := decodeProtoFields(field.data)
+		if err != nil || field.number != 1 || len(entry) != 2 {
+			return nil, fmt.Errorf("invalid map entry: %v", err)
+		}
+
+		if result[string(entry[0].data)], err = decodeProtoValue(entry[1].data); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// decodeProtoValue decodes a google.protobuf.Value into a string, float64, bool, map or slice.
+func decodeProtoValue(msg []byte) (interface{}, error) {
+	fields, err := decodeProtoFields(msg)
+	if err != nil || len(fields) != 1 {
+		return nil, fmt.Errorf("invalid value: %v", err)
+	}
+
+	field := fields[0]
+
+	switch field.number {
+	case 2:
+		return math.Float64frombits(field.value), nil
+	case 3:
+		return string(field.data), nil
+	case 4:
+		return field.value == 1, nil
+	case 5:
+		return decodeProtoStruct(field.data)
+	case 6:
+		values, err := decodeProtoFields(field.data)
+		if err != nil {
+			return nil, err
+		}
+
+		list := []interface{}{}
+
+		for _, v := range values {
+			value, err := decodeProtoValue(v.data)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, value)
+		}
+
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unexpected value field %d", field.number)
+	}
+}
@@ -2,15 +2,22 @@ package encoder
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/golangee/dyml/parser"
 	"github.com/golangee/dyml/token"
 	"github.com/golangee/dyml/util"
 )
 
+// XMLEncoder converts dyml into XML while it is being read. Only the currently open elements and
+// forwarded elements that were not placed yet are kept in memory, so the memory needed does not
+// depend on the size of the document. Elements without content are written as self-closing tags.
 type XMLEncoder struct {
 	filename string
 	reader   io.Reader
@@ -18,290 +25,753 @@ type XMLEncoder struct {
 
 	// openNodes is a stack of elements that are currently opened,
 	// so that the closing tag and other information can be written correctly.
-	openNodes []*node
+	openNodes []node
 	// forwardedAttributes is a list of attributes that are being forwarded into the next node.
 	forwardedAttributes util.AttributeList
-	// forwardedNodes are all (text-) nodes that are being forwarded into this node.
-	// It is important to note that these nodes are either just text or nodes with no attributes.
-	forwardedNodes []*node
-	// indent is the current level of indentation for emitting XML.
-	indent uint
+	// forwarded contains the XML of all forwarded elements and texts that are placed in the next node.
+	forwarded *xmlBuffer
+	// forwardedRange is the position of the first forwarded element or text in forwarded.
+	forwardedRange token.Position
+	// buffers are unused buffers, which are kept to be reused for forwarded elements.
+	buffers []*xmlBuffer
+	// namedReturnArrows contains for each open return arrow, whether it has a name.
+	namedReturnArrows []bool
+
+	// declaration is true if an XML declaration should be written.
+	declaration bool
+	// rootName replaces the name of the root element, if it is not empty.
+	rootName string
+	// blockTypeAttribute is the name of the attribute that contains the block type of elements,
+	// or empty if block types should not be written.
+	blockTypeAttribute string
+	// namespaces are declared on the root element.
+	namespaces []namespace
+	// cdata is true if texts should be written as CDATA sections instead of using entities.
+	cdata bool
+	// omitRoot is true if the single top-level element is written instead of the root, see WithoutRoot.
+	omitRoot bool
+	// topLevel is the number of top-level elements, which are only counted if omitRoot is set.
+	topLevel int
+	// invalidNames decides what happens with names that are not valid in XML, see WithInvalidNames.
+	invalidNames InvalidNames
+	// indentUnit is written once per level of indentation, see WithXMLIndent.
+	indentUnit string
+	// compact is true if the XML is written without indentation and newlines, see WithCompact.
+	compact bool
+	// maxLineWidth is the width of lines after which the attributes of tags are wrapped, or 0.
+	maxLineWidth int
+	// statsHooks are called with the stats of the input, see WithStatsHook.
+	statsHooks []func(stats parser.Stats)
 }
 
-// node is a node that we are currently working on.
+// xmlWriter is implemented by bufio.Writer and bytes.Buffer, which both do not need
+// error handling for every write, as they remember or never return errors.
+type xmlWriter interface {
+	io.Writer
+	io.StringWriter
+	io.ByteWriter
+	WriteRune(r rune) (int, error)
+}
+
+// xmlBuffer collects XML that is written before its indentation is known, e.g. forwarded elements.
+type xmlBuffer struct {
+	bytes.Buffer
+	// indents are the offsets of all indented lines, which are indented further once the XML is placed.
+	indents []int
+}
+
+// Reset empties the buffer, so that it can be reused.
+func (b *xmlBuffer) Reset() {
+	b.Buffer.Reset()
+	b.indents = b.indents[:0]
+}
+
+// node is an element that we are currently working on.
 type node struct {
 	// name is the name in the XML tag which we need to save so that the closing tag can be written.
 	name string
-	// text is text if this is a text node. For a text node all other attributes are irrelevant.
-	text string
+	// rng is the position of the name of the element.
+	rng token.Position
 	// attributes is a list of attributes this node has.
 	attributes util.AttributeList
 	// openTagWritten is set to true once we have written the starting XML tag.
 	openTagWritten bool
-	// isForwarded is true when this node is being forwarded.
-	isForwarded bool
-	// forwardedNodes contains all nodes that this node is holding until they can be written out.
-	forwardedNodes []*node
+	// forwarded contains the XML of forwarded elements and texts that are the first children of this node.
+	forwarded *xmlBuffer
+	// buffer is set for forwarded elements and collects their XML until they are placed in another element.
+	buffer *xmlBuffer
+	// out is where the XML of this element is written to. This is the writer of the encoder or
+	// the buffer of the enclosing forwarded element.
+	out xmlWriter
+	// indent is the level of indentation of the tags of this element.
+	indent int
+	// omitted is true for the root, if its tags are not written, see WithoutRoot.
+	omitted bool
 }
 
-func NewXMLEncoder(filename string, r io.Reader, w io.Writer) *XMLEncoder {
-	return &XMLEncoder{
-		filename: filename,
-		reader:   r,
-		writer:   bufio.NewWriter(w),
+// NewXMLEncoder creates an XMLEncoder that reads dyml from r and writes XML to w.
+func NewXMLEncoder(filename string, r io.Reader, w io.Writer, opts ...XMLEncoderOption) *XMLEncoder {
+	e := &XMLEncoder{
+		filename:   filename,
+		reader:     r,
+		writer:     bufio.NewWriter(w),
+		forwarded:  &xmlBuffer{},
+		indentUnit: "    ",
+	}
+
+	for _, opt := range opts {
+		opt(e)
 	}
+
+	return e
 }
 
 // Encode starts the encoding process, reading input from the reader and writing to the writer.
 // There is no up-front validation, which means that in case of an error incomplete output
 // already got emitted.
 func (e *XMLEncoder) Encode() error {
+	if e.rootName != "" && !isXMLName(e.rootName) {
+		return fmt.Errorf("'%s' is not a valid XML name for the root element", e.rootName)
+	}
+
+	if e.blockTypeAttribute != "" && !isXMLName(e.blockTypeAttribute) {
+		return fmt.Errorf("'%s' is not a valid XML name for the block type attribute", e.blockTypeAttribute)
+	}
+
+	for _, ns := range e.namespaces {
+		if ns.prefix != "" && !isXMLName(ns.prefix) {
+			return fmt.Errorf("'%s' is not a valid namespace prefix", ns.prefix)
+		}
+	}
+
+	if e.declaration {
+		_, _ = e.writer.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+		e.writeNewline(e.writer)
+	}
+
 	v := parser.NewVisitor(e.filename, e.reader)
 	v.SetVisitable(e)
 
+	for _, hook := range e.statsHooks {
+		v.OnStats(hook)
+	}
+
 	return v.Run()
 }
 
 func (e *XMLEncoder) Open(name token.Identifier) error {
-	return e.openNode(name.Value)
+	// The first element is the root, which is created by the visitor.
+	if len(e.openNodes) == 0 {
+		return e.openRoot(name)
+	}
+
+	return e.openNode(name.Value, name.Position, false)
 }
 
-func (e *XMLEncoder) Comment(comment token.CharData) error {
-	if err := e.writeTopNodeOpen(); err != nil {
+// openRoot opens the root element with the configured name and namespaces.
+func (e *XMLEncoder) openRoot(name token.Identifier) error {
+	// The root stays on the stack, so that the top-level element is handled like any other.
+	if e.omitRoot {
+		e.openNodes = append(e.openNodes, node{
+			name:           name.Value,
+			rng:            name.Position,
+			out:            e.writer,
+			indent:         -1,
+			openTagWritten: true,
+			omitted:        true,
+		})
+
+		return nil
+	}
+
+	if e.rootName != "" {
+		name.Value = e.rootName
+	}
+
+	if err := e.openNode(name.Value, name.Position, false); err != nil {
 		return err
 	}
 
-	return e.writeString(fmt.Sprintf("%s<!-- %s -->\n", e.indentString(), escapeXMLSafe(comment.Value)))
+	e.declareNamespaces(e.peek())
+
+	return nil
+}
+
+// declareNamespaces adds the configured namespaces to the attributes of n.
+func (e *XMLEncoder) declareNamespaces(n *node) {
+	for _, ns := range e.namespaces {
+		n.attributes.Set(util.Attribute{Key: ns.attributeKey(), Value: ns.uri})
+	}
+}
+
+func (e *XMLEncoder) Comment(comment token.CharData) error {
+	e.writeTopNodeOpen()
+
+	out, indent := e.content()
+	e.writeIndent(out, indent)
+	_, _ = out.WriteString("<!-- ")
+	writeXMLComment(out, strings.TrimSpace(comment.Value))
+	_, _ = out.WriteString(" -->")
+	e.writeNewline(out)
+
+	return nil
 }
 
 func (e *XMLEncoder) Text(text token.CharData) error {
-	if err := e.writeTopNodeOpen(); err != nil {
-		return err
+	if top := e.peek(); top != nil && top.omitted {
+		return token.NewPosError(text.Position, "text is not allowed outside of the top-level element").
+			SetHint(omittedRootHint)
 	}
 
-	return e.writeString(fmt.Sprintf("%s%s\n", e.indentString(), strings.TrimSpace(escapeXMLSafe(text.Value))))
+	e.writeTopNodeOpen()
+
+	out, indent := e.content()
+	e.writeText(out, indent, text.Value)
+
+	return nil
 }
 
 func (e *XMLEncoder) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	e.namedReturnArrows = append(e.namedReturnArrows, name != nil)
+
+	if err := e.openNode(parser.DefaultReturnName, arrow.Position, false); err != nil {
+		return err
+	}
+
+	// Like in the parser, the name labels the block inside of the return element.
 	if name != nil {
-		return e.openNode(name.Value)
+		return e.openNode(name.Value, name.Position, false)
 	}
 
-	return e.openNode("ret")
+	return nil
 }
 
 func (e *XMLEncoder) CloseReturnArrow() error {
+	named := e.namedReturnArrows[len(e.namedReturnArrows)-1]
+	e.namedReturnArrows = e.namedReturnArrows[:len(e.namedReturnArrows)-1]
+
+	if named {
+		if err := e.Close(); err != nil {
+			return err
+		}
+	}
+
 	return e.Close()
 }
 
 func (e *XMLEncoder) SetBlockType(blockType parser.BlockType) error {
-	// Not used in XML
-	return nil
-}
+	// The block type of the root is always the same, so it is not written.
+	if e.blockTypeAttribute == "" || blockType == parser.BlockNone || len(e.openNodes) < 2 {
+		return nil
+	}
 
-func (e *XMLEncoder) OpenForward(name token.Identifier) error {
-	n := &node{
-		name:        name.Value,
-		isForwarded: true,
+	top := e.peek()
+	if top.attributes.Set(util.Attribute{Key: e.blockTypeAttribute, Value: string(blockType)}) {
+		return token.NewPosError(top.rng, fmt.Sprintf("attribute '%s' is needed for the block type", e.blockTypeAttribute))
 	}
-	e.push(n)
-	e.forwardedNodes = append(e.forwardedNodes, n)
 
 	return nil
 }
 
+func (e *XMLEncoder) OpenForward(name token.Identifier) error {
+	return e.openNode(name.Value, name.Position, true)
+}
+
 func (e *XMLEncoder) TextForward(text token.CharData) error {
-	n := &node{
-		text:        text.Value,
-		isForwarded: true,
-	}
-	e.forwardedNodes = append(e.forwardedNodes, n)
+	e.addForwarded(text.Position)
+	e.writeText(e.forwarded, 0, text.Value)
 
 	return nil
 }
 
 func (e *XMLEncoder) Close() error {
-	// Forwarding nodes should just be popped,
-	// they are already inside e.forwardedNodes
-	if e.peek().isForwarded {
+	top := e.peek()
+
+	if top.omitted {
 		e.pop()
 
 		return nil
 	}
 
-	if err := e.writeTopNodeOpen(); err != nil {
-		return err
+	if !top.openTagWritten && top.forwarded == nil {
+		e.writeIndent(top.out, top.indent)
+		e.writeTag(top, "/>")
+	} else {
+		e.writeTopNodeOpen()
+		e.writeIndent(top.out, top.indent)
+		_, _ = top.out.WriteString("</")
+		_, _ = top.out.WriteString(top.name)
+		_ = top.out.WriteByte('>')
 	}
 
-	e.indent--
+	e.writeNewline(top.out)
 
-	top := e.pop()
+	closed := e.pop()
 
-	err := e.writeString(fmt.Sprintf("%s</%s>\n", e.indentString(), top.name))
-	if err != nil {
-		return err
+	// A forwarded element is complete now and waits for the next element.
+	if closed.buffer != nil {
+		e.addForwarded(closed.rng)
+		e.writeIndented(e.forwarded, 0, closed.buffer)
+		e.release(closed.buffer)
 	}
 
 	return nil
 }
 
 func (e *XMLEncoder) Attribute(key token.Identifier, value token.CharData) error {
-	n := e.peek()
-	attr := util.Attribute{
-		Key:   key.Value,
-		Value: value.Value,
-		Range: token.Position{
-			BeginPos: key.Begin(),
-			EndPos:   value.End(),
-		},
+	attr, err := e.xmlAttribute(key, value)
+	if err != nil {
+		return err
 	}
 
-	if n.attributes.Set(attr) {
-		return token.NewPosError(attr.Range, "key defined twice")
+	if e.peek().attributes.Set(attr) {
+		return token.NewSyntaxError(attr.Range, "key defined twice")
 	}
 
 	return nil
 }
 
 func (e *XMLEncoder) AttributeForward(key token.Identifier, value token.CharData) error {
-	attr := util.Attribute{
-		Key:   key.Value,
-		Value: value.Value,
-		Range: token.Position{
-			BeginPos: key.Begin(),
-			EndPos:   value.End(),
-		},
+	attr, err := e.xmlAttribute(key, value)
+	if err != nil {
+		return err
 	}
 
 	if e.forwardedAttributes.Set(attr) {
-		return token.NewPosError(attr.Range, "key defined twice")
+		return token.NewSyntaxError(attr.Range, "key defined twice")
 	}
 
 	return nil
 }
 
 func (e *XMLEncoder) Finalize() error {
-	if e.writer.Flush() != nil {
-		return fmt.Errorf("failed to flush written XML: %w", e.writer.Flush())
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush written XML: %w", err)
 	}
 
-	return nil
-}
+	if e.forwarded.Len() > 0 {
+		return token.NewPosError(e.forwardedRange, "forwarded node cannot be forwarded anywhere").
+			SetCategory(token.ErrForwarding)
+	}
+
+	if attr := e.forwardedAttributes.Pop(); attr != nil {
+		return token.NewPosError(attr.Range, "forwarded attribute cannot be forwarded anywhere").
+			SetCategory(token.ErrForwarding)
+	}
 
-// writeString is a convenience method to write strings to the underlying writer.
-func (e *XMLEncoder) writeString(s string) error {
-	_, err := e.writer.WriteString(s)
+	if e.omitRoot && e.topLevel == 0 {
+		return errors.New("the document has no top-level element, which is required without the root element")
+	}
 
-	return err
+	return nil
 }
 
 // openNode puts a node on our working stack but does not write it yet.
 // However, its parent node might get written out, since we know that it will not get any more attributes.
-func (e *XMLEncoder) openNode(name string) error {
-	if err := e.writeTopNodeOpen(); err != nil {
+// Forwarded nodes are written into their own buffer, they do not affect their parent.
+func (e *XMLEncoder) openNode(name string, rng token.Position, forwarded bool) error {
+	name, err := e.xmlName(name, rng)
+	if err != nil {
 		return err
 	}
 
-	// Put the node on our stack, so we know how to close it.
-	e.push(&node{
-		name:           name,
-		attributes:     e.forwardedAttributes,
-		forwardedNodes: e.forwardedNodes,
-	})
-
+	n := node{
+		name:       name,
+		rng:        rng,
+		attributes: e.forwardedAttributes,
+	}
 	e.forwardedAttributes = util.AttributeList{}
-	e.forwardedNodes = nil
+
+	switch parent := e.peek(); {
+	case forwarded:
+		n.buffer = e.buffer()
+		n.out = n.buffer
+	case parent != nil:
+		if parent.omitted {
+			if err := e.openTopLevel(&n); err != nil {
+				return err
+			}
+		}
+
+		e.writeTopNodeOpen()
+		n.out, n.indent = parent.out, parent.indent+1
+	default:
+		n.out = e.writer
+	}
+
+	// Forwarded elements and texts are placed in the next element that is not forwarded.
+	if !forwarded && e.forwarded.Len() > 0 {
+		n.forwarded, e.forwarded = e.forwarded, e.buffer()
+	}
+
+	e.openNodes = append(e.openNodes, n)
+
+	return nil
+}
+
+// omittedRootHint explains errors about the content of the document, if the root is omitted.
+const omittedRootHint = "the root element is omitted, which requires a single top-level element"
+
+// openTopLevel checks that n is the only top-level element, if the root is omitted, and declares the
+// namespaces on it instead of on the root.
+func (e *XMLEncoder) openTopLevel(n *node) error {
+	e.topLevel++
+	if e.topLevel > 1 {
+		return token.NewPosError(n.rng, "only one top-level element is allowed").
+			SetHint(omittedRootHint)
+	}
+
+	e.declareNamespaces(n)
 
 	return nil
 }
 
-// writeTopNodeOpen writes the topmost stack node to the writer.
-func (e *XMLEncoder) writeTopNodeOpen() error {
+// writeTopNodeOpen writes the opening tag of the topmost stack node, followed by
+// all elements that were forwarded into it.
+func (e *XMLEncoder) writeTopNodeOpen() {
 	top := e.peek()
-	if top != nil && !top.openTagWritten {
-		top.openTagWritten = true
+	if top == nil || top.openTagWritten {
+		return
+	}
 
-		// Build the opening tag with all attributes
-		var tag strings.Builder
+	top.openTagWritten = true
 
-		tag.WriteString(e.indentString())
-		tag.WriteString("<")
-		tag.WriteString(top.name)
+	e.writeIndent(top.out, top.indent)
+	e.writeTag(top, ">")
+	e.writeNewline(top.out)
 
-		for {
-			attr := top.attributes.Pop()
-			if attr == nil {
-				break
-			}
+	if top.forwarded != nil {
+		// Forwarded XML was written without indentation, as the target was not known yet.
+		e.writeIndented(top.out, top.indent+1, top.forwarded)
+		e.release(top.forwarded)
+		top.forwarded = nil
+	}
+}
 
-			tag.WriteString(fmt.Sprintf(` %s="%s"`, attr.Key, escapeXMLSafe(attr.Value)))
+// writeTag writes the name and attributes of n, followed by end, which closes the tag.
+// If the tag would be wider than maxLineWidth, every attribute is written on its own line.
+func (e *XMLEncoder) writeTag(n *node, end string) {
+	_ = n.out.WriteByte('<')
+	_, _ = n.out.WriteString(n.name)
+
+	if e.maxLineWidth <= 0 || e.compact {
+		for attr := n.attributes.Pop(); attr != nil; attr = n.attributes.Pop() {
+			_ = n.out.WriteByte(' ')
+			writeXMLAttribute(n.out, attr)
 		}
-		tag.WriteString(">\n")
 
-		e.indent++
+		_, _ = n.out.WriteString(end)
 
-		// Place all forwarded nodes here
-		for _, forwardedNode := range top.forwardedNodes {
-			if len(forwardedNode.name) > 0 {
-				tag.WriteString(fmt.Sprintf("%[1]s<%[2]s></%[2]s>\n", e.indentString(), forwardedNode.name))
-			} else if len(forwardedNode.text) > 0 {
-				tag.WriteString(fmt.Sprintf("%s%s\n", e.indentString(), escapeXMLSafe(forwardedNode.text)))
-			}
-		}
+		return
+	}
 
-		top.forwardedNodes = nil
+	var attributes []string
 
-		err := e.writeString(tag.String())
-		if err != nil {
-			return err
+	width := utf8.RuneCountInString(e.indentUnit)*n.indent + 1 + utf8.RuneCountInString(n.name) + len(end)
+
+	for attr := n.attributes.Pop(); attr != nil; attr = n.attributes.Pop() {
+		var b strings.Builder
+
+		writeXMLAttribute(&b, attr)
+		attributes = append(attributes, b.String())
+		width += 1 + utf8.RuneCountInString(b.String())
+	}
+
+	wrap := width > e.maxLineWidth
+
+	for _, attr := range attributes {
+		if wrap {
+			e.writeNewline(n.out)
+			e.writeIndent(n.out, n.indent+1)
+		} else {
+			_ = n.out.WriteByte(' ')
 		}
+
+		_, _ = n.out.WriteString(attr)
 	}
 
-	return nil
+	_, _ = n.out.WriteString(end)
 }
 
-// push a node onto our working stack.
-func (e *XMLEncoder) push(n *node) {
-	e.openNodes = append(e.openNodes, n)
+// content returns where texts and comments for the topmost node are written to and their indentation.
+func (e *XMLEncoder) content() (xmlWriter, int) {
+	if top := e.peek(); top != nil {
+		return top.out, top.indent + 1
+	}
+
+	return e.writer, 0
+}
+
+// addForwarded remembers the position of the first element or text that is added to forwarded.
+func (e *XMLEncoder) addForwarded(rng token.Position) {
+	if e.forwarded.Len() == 0 {
+		e.forwardedRange = rng
+	}
+}
+
+// buffer returns an empty buffer, reusing one that was released if possible.
+func (e *XMLEncoder) buffer() *xmlBuffer {
+	if len(e.buffers) == 0 {
+		return &xmlBuffer{}
+	}
+
+	b := e.buffers[len(e.buffers)-1]
+	e.buffers = e.buffers[:len(e.buffers)-1]
+
+	return b
+}
+
+// release returns a buffer to be reused.
+func (e *XMLEncoder) release(b *xmlBuffer) {
+	b.Reset()
+	e.buffers = append(e.buffers, b)
 }
 
 // peek at the top element in our working stack. Might return nil if the stack is empty.
 func (e *XMLEncoder) peek() *node {
 	if len(e.openNodes) > 0 {
-		n := e.openNodes[len(e.openNodes)-1]
-
-		return n
+		return &e.openNodes[len(e.openNodes)-1]
 	}
 
 	return nil
 }
 
-// pop the top node from the working stack. Might return nil if the stack is empty.
-func (e *XMLEncoder) pop() *node {
-	if len(e.openNodes) > 0 {
-		n := e.openNodes[len(e.openNodes)-1]
-		e.openNodes = e.openNodes[:len(e.openNodes)-1]
+// pop the top node from the working stack.
+func (e *XMLEncoder) pop() node {
+	n := e.openNodes[len(e.openNodes)-1]
+	e.openNodes[len(e.openNodes)-1] = node{}
+	e.openNodes = e.openNodes[:len(e.openNodes)-1]
+
+	return n
+}
+
+// xmlAttribute creates an attribute whose key is a valid XML name, see xmlName.
+func (e *XMLEncoder) xmlAttribute(key token.Identifier, value token.CharData) (util.Attribute, error) {
+	attr := util.Attribute{
+		Value: value.Value,
+		Range: token.Position{
+			BeginPos: key.Begin(),
+			EndPos:   value.End(),
+		},
+	}
+
+	var err error
+	attr.Key, err = e.xmlName(key.Value, key.Position)
 
-		return n
+	return attr, err
+}
+
+// xmlName returns name, if it is a valid XML name. Otherwise it is mangled or an error is returned,
+// as configured by WithInvalidNames.
+func (e *XMLEncoder) xmlName(name string, rng token.Position) (string, error) {
+	if isXMLName(name) {
+		return name, nil
 	}
 
-	return nil
+	if e.invalidNames == MangleInvalidNames {
+		return mangleXMLName(name), nil
+	}
+
+	return name, token.NewPosError(rng, fmt.Sprintf("'%s' is not a valid XML name", name)).
+		SetHint("use WithInvalidNames to replace invalid characters")
+}
+
+// isXMLName returns true if name can be used for XML elements and attributes, which is the case for
+// names without a namespace prefix (NCName). Identifiers in dyml may contain characters that are not
+// allowed, like the ':' of qualified names, and may start with digits and marks.
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		if !isXMLNameChar(r, i == 0) {
+			return false
+		}
+	}
+
+	return true
 }
 
-// indentString returns a string with a number of spaces that matches the
-// current indentation level.
-func (e *XMLEncoder) indentString() string {
-	var tmp strings.Builder
-	for i := uint(0); i < e.indent; i++ {
-		tmp.WriteString("    ")
+// mangleXMLName replaces all characters of name, that are not allowed in XML names, with '_'.
+// If name does not start with a character that may start a name, '_' is prepended.
+func mangleXMLName(name string) string {
+	var sb strings.Builder
+
+	if first, _ := utf8.DecodeRuneInString(name); !isXMLNameChar(first, true) && isXMLNameChar(first, false) {
+		sb.WriteByte('_')
 	}
 
-	return tmp.String()
+	for i, r := range name {
+		if isXMLNameChar(r, i == 0 && sb.Len() == 0) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "_"
+	}
+
+	return sb.String()
 }
 
-// escapeXMLSafe replaces all occurrences of reserved characters in XML: <>&".
-func escapeXMLSafe(s string) string {
-	replacer := strings.NewReplacer("<", "&lt;", ">", "&gt;", "&", "&amp;", `"`, "&quot;")
+// isXMLNameChar returns true if r may appear in an XML name without a namespace prefix. If first is true,
+// r must also be allowed at the beginning, which excludes digits, '-', '.' and combining characters.
+func isXMLNameChar(r rune, first bool) bool {
+	switch {
+	case r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z',
+		r >= 0xC0 && r <= 0xD6, r >= 0xD8 && r <= 0xF6, r >= 0xF8 && r <= 0x2FF,
+		r >= 0x370 && r <= 0x37D, r >= 0x37F && r <= 0x1FFF, r >= 0x200C && r <= 0x200D,
+		r >= 0x2070 && r <= 0x218F, r >= 0x2C00 && r <= 0x2FEF, r >= 0x3001 && r <= 0xD7FF,
+		r >= 0xF900 && r <= 0xFDCF, r >= 0xFDF0 && r <= 0xFFFD, r >= 0x10000 && r <= 0xEFFFF:
+		return true
+	case first:
+		return false
+	}
+
+	return r == '-' || r == '.' || r >= '0' && r <= '9' || r == 0xB7 ||
+		r >= 0x300 && r <= 0x36F || r >= 0x203F && r <= 0x2040
+}
+
+// writeIndent writes the whitespace for the given level of indentation.
+// Buffers remember where the line starts, so that it can be indented further.
+func (e *XMLEncoder) writeIndent(w xmlWriter, indent int) {
+	if e.compact {
+		return
+	}
+
+	if b, ok := w.(*xmlBuffer); ok {
+		b.indents = append(b.indents, b.Len())
+	}
+
+	for i := 0; i < indent; i++ {
+		_, _ = w.WriteString(e.indentUnit)
+	}
+}
+
+// writeNewline ends a line, unless the XML is compact.
+func (e *XMLEncoder) writeNewline(w xmlWriter) {
+	if !e.compact {
+		_ = w.WriteByte('\n')
+	}
+}
+
+// writeIndented writes the XML in b with its indented lines indented further by the given level.
+// Other lines, e.g. in multi-line texts, are written unchanged.
+func (e *XMLEncoder) writeIndented(w xmlWriter, indent int, b *xmlBuffer) {
+	xml := b.Bytes()
+	start := 0
+
+	for _, offset := range b.indents {
+		_, _ = w.Write(xml[start:offset])
+		e.writeIndent(w, indent)
+		start = offset
+	}
+
+	_, _ = w.Write(xml[start:])
+}
+
+// writeText writes an escaped text on its own line. With WithCDATA, texts that would need entities
+// are written as a CDATA section instead. Compact XML has no lines, so the text is not trimmed either.
+func (e *XMLEncoder) writeText(w xmlWriter, indent int, text string) {
+	if !e.compact {
+		text = strings.TrimSpace(text)
+	}
+
+	e.writeIndent(w, indent)
+
+	if e.cdata && strings.ContainsAny(text, "<>&") {
+		writeCDATA(w, text)
+	} else {
+		writeXMLEscaped(w, text, false)
+	}
+
+	e.writeNewline(w)
+}
+
+// writeXMLAttribute writes an attribute with its escaped value, e.g. key="value".
+func writeXMLAttribute(w xmlWriter, attr *util.Attribute) {
+	_, _ = w.WriteString(attr.Key)
+	_, _ = w.WriteString(`="`)
+	writeXMLEscaped(w, attr.Value, true)
+	_ = w.WriteByte('"')
+}
+
+// writeCDATA writes s as a CDATA section. As "]]>" would end the section, it is split
+// into two sections. Characters that are not allowed in XML are replaced like in writeXMLEscaped.
+func writeCDATA(w xmlWriter, s string) {
+	_, _ = w.WriteString("<![CDATA[")
+
+	for i, r := range s {
+		switch {
+		case r == '>' && strings.HasSuffix(s[:i], "]]"):
+			_, _ = w.WriteString("]]><![CDATA[>")
+		case !isXMLChar(r):
+			_, _ = w.WriteRune(unicode.ReplacementChar)
+		default:
+			_, _ = w.WriteRune(r)
+		}
+	}
+
+	_, _ = w.WriteString("]]>")
+}
+
+// writeXMLEscaped writes s with all reserved characters in XML (<>&"') replaced by entities.
+// Characters that are not allowed in XML are replaced with the unicode replacement character.
+// In attributes, newlines and tabs are written as character references, as they would
+// otherwise be replaced by spaces when the XML is read.
+func writeXMLEscaped(w xmlWriter, s string, attribute bool) {
+	for _, r := range s {
+		switch {
+		case r == '<':
+			_, _ = w.WriteString("&lt;")
+		case r == '>':
+			_, _ = w.WriteString("&gt;")
+		case r == '&':
+			_, _ = w.WriteString("&amp;")
+		case r == '"':
+			_, _ = w.WriteString("&quot;")
+		case r == '\'':
+			_, _ = w.WriteString("&apos;")
+		case attribute && r == '\n':
+			_, _ = w.WriteString("&#xA;")
+		case attribute && r == '\r':
+			_, _ = w.WriteString("&#xD;")
+		case attribute && r == '\t':
+			_, _ = w.WriteString("&#x9;")
+		case !isXMLChar(r):
+			_, _ = w.WriteRune(unicode.ReplacementChar)
+		default:
+			_, _ = w.WriteRune(r)
+		}
+	}
+}
+
+// writeXMLComment writes the content of a comment, which cannot contain "--" in XML.
+// Entities are not resolved in comments, so nothing else needs to be escaped.
+func writeXMLComment(w xmlWriter, s string) {
+	var last rune
+
+	for _, r := range s {
+		switch {
+		case r == '-' && last == '-':
+			_, _ = w.WriteString(" -")
+		case !isXMLChar(r):
+			_, _ = w.WriteRune(unicode.ReplacementChar)
+		default:
+			_, _ = w.WriteRune(r)
+		}
+
+		last = r
+	}
+}
 
-	return replacer.Replace(s)
+// isXMLChar returns true if r may appear in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= unicode.MaxRune
 }
@@ -2,10 +2,15 @@ package encoder_test
 
 import (
 	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
 )
 
 func TestXMLEncode(t *testing.T) {
@@ -17,7 +22,7 @@ func TestXMLEncode(t *testing.T) {
 		{
 			name: "simple",
 			text: "",
-			want: "<root></root>",
+			want: "<root/>",
 		},
 		{
 			name: "hello world",
@@ -28,7 +33,7 @@ func TestXMLEncode(t *testing.T) {
 		{
 			name: "Identifier + Attributes",
 			text: `#book @id{my-book} @author{Torben}`,
-			want: `<root><book id="my-book" author="Torben"></book></root>`,
+			want: `<root><book id="my-book" author="Torben"/></root>`,
 		},
 		{
 			name: "book example",
@@ -44,7 +49,7 @@ func TestXMLEncode(t *testing.T) {
 				  }`,
 			want: `<root>
 					<book>
-						<toc></toc>
+						<toc/>
 						<section id="1">
 							<title>
 								The sections title
@@ -104,8 +109,8 @@ func TestXMLEncode(t *testing.T) {
 			want: `<root>
 							<list>
 								<item1><key>value</key></item1>
-								<item2 id="1"></item2>
-								<item3 key="value"></item3>
+								<item2 id="1"/>
+								<item3 key="value"/>
 							</list>
 						</root>`,
 		},
@@ -120,8 +125,8 @@ func TestXMLEncode(t *testing.T) {
 			want: `<root>
 							<list>
 								<item1><key>value</key></item1>
-								<item2 id="1"></item2>
-								<item3 key="value"></item3>
+								<item2 id="1"/>
+								<item3 key="value"/>
 							</list>
 						</root>`,
 		},
@@ -133,7 +138,7 @@ func TestXMLEncode(t *testing.T) {
 						}`,
 			want: `<root>
 						<parent>
-							<item key="value"></item>
+							<item key="value"/>
 						</parent>
 					</root>`,
 		},
@@ -148,10 +153,10 @@ func TestXMLEncode(t *testing.T) {
 						}`,
 			want: `<root>
 						<g2>
-							<item></item>
-							<item key="value" another="one" not="forwarded"></item>
+							<item/>
+							<item key="value" another="one" not="forwarded"/>
 							<parent>
-								<child for="child"></child>
+								<child for="child"/>
 							</parent>
 						</g2>
 					</root>`,
@@ -164,9 +169,9 @@ func TestXMLEncode(t *testing.T) {
 			want: `<root>
 						<g2>
 							<hello>
-								<string></string>
+								<string/>
 								<ret>
-									<int></int>
+									<int/>
 								</ret>
 							</hello>
 						</g2>
@@ -181,9 +186,9 @@ func TestXMLEncode(t *testing.T) {
 						<g2>
 							<fn>
 								<x>
-									<y></y>
+									<y/>
 									<ret>
-										<z></z>
+										<z/>
 									</ret>
 								</x>
 							</fn>
@@ -195,7 +200,7 @@ func TestXMLEncode(t *testing.T) {
 			text: `#? saying "hello world"
 				#hello{world}`,
 			want: ` <root>
-							<!-- saying &quot;hello world&quot; -->
+							<!-- saying "hello world" -->
 							<hello>world
 							</hello>
 						</root>`,
@@ -216,7 +221,7 @@ func TestXMLEncode(t *testing.T) {
 								Greet someone.
 								<Greet>
 									<name>
-										<string></string>
+										<string/>
 									</name>
 								</Greet>
 							</func>
@@ -224,17 +229,17 @@ func TestXMLEncode(t *testing.T) {
 								Run complex calculations.
 								<Run>
 									<x>
-										<int></int>
+										<int/>
 									</x>
 									<y>
-										<int></int>
+										<int/>
 									</y>
 									<z>
-										<string></string>
+										<string/>
 									</z>
 									<ret>
-										<int></int>
-										<error></error>
+										<int/>
+										<error/>
 									</ret>
 								</Run>
 							</func>
@@ -244,7 +249,15 @@ func TestXMLEncode(t *testing.T) {
 		{
 			name: "g2 named return arrow",
 			text: `#! x -> y`,
-			want: "<root><x><y></y></x></root>",
+			want: "<root><x><ret><y/></ret></x></root>",
+		},
+		{
+			name: "g2 named return arrow with block",
+			text: `#! g2 {
+						func Run(x int) -> result (int, error)
+					}`,
+			want: `<root><g2><func><Run><x><int/></x>` +
+				`<ret><result><int/><error/></result></ret></Run></func></g2></root>`,
 		},
 		{
 			name: "forward node",
@@ -252,18 +265,43 @@ func TestXMLEncode(t *testing.T) {
 					##a
 					#b
 				`,
-			want: `<root><b><a></a></b></root>`,
+			want: `<root><b><a/></b></root>`,
 		},
 		{
 			name: "backslashes are okay",
 			text: `#book @id{my-book\\} @author{Torben\\}`,
-			want: `<root><book id="my-book\" author="Torben\"></book></root>`,
+			want: `<root><book id="my-book\" author="Torben\"/></root>`,
 		},
 		{
 			name: "a lot of special chars",
 			text: `<tag></tag>&"hello"`,
 			want: "<root>&lt;tag&gt;&lt;/tag&gt;&amp;&quot;hello&quot;</root>",
 		},
+		{
+			name: "forward node with content",
+			text: `##a @k{v} {#c{text}} #b{x}`,
+			want: `<root><b><a k="v"><c>text</c></a>x</b></root>`,
+		},
+		{
+			name: "nested forward nodes",
+			text: `#x{##a{##b #c} #d}`,
+			want: `<root><x><d><a><c><b/></c></a></d></x></root>`,
+		},
+		{
+			name: "escape attributes",
+			text: "#a @k{it's \"<tab>\"\tand\nnewline}",
+			want: `<root><a k="it&apos;s &quot;&lt;tab&gt;&quot;&#x9;and&#xA;newline"/></root>`,
+		},
+		{
+			name: "invalid characters",
+			text: "#a{bell \u0007}",
+			want: "<root><a>bell \uFFFD</a></root>",
+		},
+		{
+			name: "double dashes in comments",
+			text: "#? a -- b --- <c>\n#x",
+			want: "<root><!-- a - - b - - - <c> --><x/></root>",
+		},
 	}
 
 	t.Parallel()
@@ -288,13 +326,334 @@ func TestXMLEncode(t *testing.T) {
 			if !StringsEqual(test.want, val) {
 				t.Errorf("Test '%s' failed. Wanted '%s', got '%s'", test.name, test.want, val)
 			}
+
+			if err := checkWellFormed(val); err != nil {
+				t.Errorf("output is not well-formed XML: %v", err)
+			}
 		})
 	}
 }
 
+func TestXMLEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "element name starts with digit",
+			text: "#1a",
+		},
+		{
+			name: "attribute name starts with digit",
+			text: "#a @1b{c}",
+		},
+		{
+			name: "qualified element name",
+			text: "#std::vector",
+		},
+		{
+			name: "element name starts with a mark",
+			text: "#a {#\u0301x}",
+		},
+		{
+			name: "dangling forward node",
+			text: "#a ##b{text}",
+		},
+		{
+			name: "dangling forward attribute",
+			text: "#! a @@b=\"c\"\n",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var writer bytes.Buffer
+
+			var posErr *token.PosError
+			if err := encoder.NewXMLEncoder(test.name, strings.NewReader(test.text), &writer).Encode(); !errors.As(err, &posErr) {
+				t.Errorf("expected a positional error, but got %v", err)
+			}
+		})
+	}
+}
+
+// checkWellFormed returns an error if s is not well-formed XML.
+func checkWellFormed(s string) error {
+	decoder := xml.NewDecoder(strings.NewReader(s))
+
+	for {
+		if _, err := decoder.Token(); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
 // StringsEqual compares two given strings but ignores differences in whitespaces, tabs and newlines.
 func StringsEqual(in1, in2 string) bool {
 	r := strings.NewReplacer("\n", "", "\t", "", " ", "")
 
 	return r.Replace(in1) == r.Replace(in2)
 }
+
+func TestXMLEncodeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		opts    []encoder.XMLEncoderOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "xml declaration",
+			text: "#a",
+			opts: []encoder.XMLEncoderOption{encoder.WithXMLDeclaration()},
+			want: `<?xml version="1.0" encoding="UTF-8"?><root><a/></root>`,
+		},
+		{
+			name: "root name",
+			text: "#a",
+			opts: []encoder.XMLEncoderOption{encoder.WithRootName("config")},
+			want: `<config><a/></config>`,
+		},
+		{
+			name:    "invalid root name",
+			opts:    []encoder.XMLEncoderOption{encoder.WithRootName("1config")},
+			wantErr: true,
+		},
+		{
+			name: "block types",
+			text: "#a{#b} #! c (d <e>) f",
+			opts: []encoder.XMLEncoderOption{encoder.WithBlockTypeAttribute("block")},
+			want: `<root><a block="{}"><b/></a><c block="()"><d block="&lt;&gt;"><e/></d></c>f</root>`,
+		},
+		{
+			name:    "block type attribute is already defined",
+			text:    "#a @block{x} {#b}",
+			opts:    []encoder.XMLEncoderOption{encoder.WithBlockTypeAttribute("block")},
+			wantErr: true,
+		},
+		{
+			name: "namespaces",
+			text: "#a",
+			opts: []encoder.XMLEncoderOption{
+				encoder.WithNamespace("", "https://example.com/schema"),
+				encoder.WithNamespace("xsi", "http://www.w3.org/2001/XMLSchema-instance"),
+			},
+			want: `<root xmlns="https://example.com/schema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+				`<a/></root>`,
+		},
+		{
+			name: "cdata",
+			text: "#! g2 { a `x < y && z`, b `plain`, c `]]>` }",
+			opts: []encoder.XMLEncoderOption{encoder.WithCDATA()},
+			want: `<root><g2><a><![CDATA[x < y && z]]></a><b>plain</b><c><![CDATA[]]]]><![CDATA[>]]></c></g2></root>`,
+		},
+		{
+			name: "without root",
+			text: "#? about the config\n##x #config @v{1} {#a #b{text}}",
+			opts: []encoder.XMLEncoderOption{
+				encoder.WithoutRoot(),
+				encoder.WithNamespace("", "https://example.com/schema"),
+			},
+			want: `<!-- about the config --><config xmlns="https://example.com/schema" v="1"><x/><a/><b>text</b></config>`,
+		},
+		{
+			name:    "without root and multiple top-level elements",
+			text:    "#a #b",
+			opts:    []encoder.XMLEncoderOption{encoder.WithoutRoot()},
+			wantErr: true,
+		},
+		{
+			name:    "without root and top-level text",
+			text:    "text #a",
+			opts:    []encoder.XMLEncoderOption{encoder.WithoutRoot()},
+			wantErr: true,
+		},
+		{
+			name:    "without root and no element",
+			text:    "#? only a comment",
+			opts:    []encoder.XMLEncoderOption{encoder.WithoutRoot()},
+			wantErr: true,
+		},
+		{
+			name: "mangle invalid names",
+			text: "#! std::vector @1st=\"a\" @x::y=\"b\" { \u0301x: int }",
+			opts: []encoder.XMLEncoderOption{encoder.WithInvalidNames(encoder.MangleInvalidNames)},
+			want: "<root><std__vector _1st=\"a\" x__y=\"b\"><_\u0301x><type><int/></type></_\u0301x></std__vector></root>",
+		},
+		{
+			name:    "mangled names are defined twice",
+			text:    "#a @x::y{1} @x__y{2}",
+			opts:    []encoder.XMLEncoderOption{encoder.WithInvalidNames(encoder.MangleInvalidNames)},
+			wantErr: true,
+		},
+		{
+			name: "invalid names are still rejected in options",
+			text: "#a",
+			opts: []encoder.XMLEncoderOption{
+				encoder.WithRootName("a:b"),
+				encoder.WithInvalidNames(encoder.MangleInvalidNames),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid namespace prefix",
+			opts:    []encoder.XMLEncoderOption{encoder.WithNamespace("-", "https://example.com")},
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var writer bytes.Buffer
+
+			err := encoder.NewXMLEncoder(test.name, strings.NewReader(test.text), &writer, test.opts...).Encode()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, but got %v", test.wantErr, err)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			if !StringsEqual(test.want, writer.String()) {
+				t.Errorf("wanted '%s', got '%s'", test.want, writer.String())
+			}
+
+			if err := checkWellFormed(writer.String()); err != nil {
+				t.Errorf("output is not well-formed XML: %v", err)
+			}
+		})
+	}
+}
+
+func TestXMLEncodeCDATA(t *testing.T) {
+	t.Parallel()
+
+	text := "if a < b && c > d { return ']]>' }"
+
+	var writer bytes.Buffer
+	if err := encoder.NewXMLEncoder("cdata", strings.NewReader("#! code `"+text+"`"), &writer, encoder.WithCDATA()).Encode(); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Code string `xml:"code"`
+	}
+
+	if err := xml.Unmarshal(writer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(decoded.Code) != text {
+		t.Errorf("expected text '%s', but got '%s' from:\n%s", text, decoded.Code, writer.String())
+	}
+}
+
+func TestXMLEncodeFormatting(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts []encoder.XMLEncoderOption
+		want string
+	}{
+		{
+			name: "default",
+			text: "#a @k{v} {#b text}",
+			want: "<root>\n    <a k=\"v\">\n        <b>\n            text\n        </b>\n    </a>\n</root>\n",
+		},
+		{
+			name: "indent",
+			text: "#a {#b}",
+			opts: []encoder.XMLEncoderOption{encoder.WithXMLIndent("\t")},
+			want: "<root>\n\t<a>\n\t\t<b/>\n\t</a>\n</root>\n",
+		},
+		{
+			name: "compact",
+			text: "#? note\n#p{Some #b{bold}}",
+			opts: []encoder.XMLEncoderOption{encoder.WithCompact(), encoder.WithXMLDeclaration()},
+			want: `<?xml version="1.0" encoding="UTF-8"?><root><!-- note --><p>Some <b>bold</b></p></root>`,
+		},
+		{
+			name: "compact forwards",
+			text: `##a @k{v} {#c{text}} #b{x}`,
+			opts: []encoder.XMLEncoderOption{encoder.WithCompact()},
+			want: `<root><b><a k="v"><c>text</c></a>x</b></root>`,
+		},
+		{
+			name: "multi-line text in forwards",
+			text: "#x{##a{#c{line1\nline2}} #b}",
+			want: "<root>\n    <x>\n        <b>\n            <a>\n                <c>\n" +
+				"                    line1\nline2\n                </c>\n            </a>\n        </b>\n    </x>\n</root>\n",
+		},
+		{
+			name: "wrapped attributes",
+			text: "#server @host{localhost} @port{8080} {#tls @on{true}}",
+			opts: []encoder.XMLEncoderOption{encoder.WithMaxLineWidth(30)},
+			want: "<root>\n    <server\n        host=\"localhost\"\n        port=\"8080\">\n" +
+				"        <tls on=\"true\"/>\n    </server>\n</root>\n",
+		},
+		{
+			name: "width is ignored when compact",
+			text: "#server @host{localhost} @port{8080}",
+			opts: []encoder.XMLEncoderOption{encoder.WithMaxLineWidth(10), encoder.WithCompact()},
+			want: `<root><server host="localhost" port="8080"/></root>`,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var writer bytes.Buffer
+
+			xmlEncoder := encoder.NewXMLEncoder(test.name, strings.NewReader(test.text), &writer, test.opts...)
+			if err := xmlEncoder.Encode(); err != nil {
+				t.Fatal(err)
+			}
+
+			if writer.String() != test.want {
+				t.Errorf("wanted\n%s\ngot\n%s", test.want, writer.String())
+			}
+
+			if err := checkWellFormed(writer.String()); err != nil {
+				t.Errorf("output is not well-formed XML: %v", err)
+			}
+		})
+	}
+}
+
+func TestXMLEncodeStats(t *testing.T) {
+	t.Parallel()
+
+	text := "#a{#b{text}}"
+
+	var got parser.Stats
+
+	err := encoder.NewXMLEncoder("stats", strings.NewReader(text), &bytes.Buffer{},
+		encoder.WithStatsHook(func(stats parser.Stats) { got = stats })).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Nodes != 4 || got.MaxDepth != 3 || got.Bytes != int64(len(text)) || got.Failed {
+		t.Errorf("expected the stats of the input, but got %+v", got)
+	}
+}
@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// XMLDecoder reads XML with encoding/xml and calls the methods of a parser.Visitable for it,
+// just like a parser.Visitor does for dyml, so that all tools that work on visitor events can also
+// read XML. The XML is read while it is visited, so large documents do not need to fit into memory.
+// The root element of the XML becomes the root of the dyml document, unless WithDocumentElement is
+// used. Texts are trimmed and texts
+// that only contain whitespace are dropped, as they usually are indentation, which matches the
+// output of XMLEncoder. Namespace declarations and prefixes, processing instructions and
+// directives are ignored. Elements with content get a normal block.
+// Use a parser.Recorder and Recorder.Tree to read XML into a tree.
+type XMLDecoder struct {
+	decoder   *xml.Decoder
+	positions *positionReader
+	visitMe   parser.Visitable
+
+	// blocks contains for each open element whether its block type was already set.
+	blocks []bool
+	// rootClosed is true once the root element was closed, so that no other element may follow.
+	rootClosed bool
+	// documentElement is true if the root element of the XML is kept, see WithDocumentElement.
+	documentElement bool
+}
+
+// XMLDecoderOption can be passed to NewXMLDecoder to configure how the XML is read.
+type XMLDecoderOption func(d *XMLDecoder)
+
+// WithDocumentElement keeps the root element of the XML with its name and attributes as the single
+// top-level element of the dyml document, which then gets a root like the one created by the
+// parser.Visitor. This is the counterpart of WithoutRoot and needed for most XML that was not written
+// by an XMLEncoder, as their root elements have a meaningful name and often attributes.
+func WithDocumentElement() XMLDecoderOption {
+	return func(d *XMLDecoder) {
+		d.documentElement = true
+	}
+}
+
+// NewXMLDecoder creates an XMLDecoder that reads XML from r. You need to call SetVisitable before Run.
+func NewXMLDecoder(filename string, r io.Reader, opts ...XMLDecoderOption) *XMLDecoder {
+	positions := &positionReader{reader: r, pos: token.Pos{File: filename, Line: 1, Col: 1, UTF16Col: 1}}
+
+	d := &XMLDecoder{
+		decoder:   xml.NewDecoder(positions),
+		positions: positions,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// SetVisitable sets the Visitable that is called for the XML.
+func (d *XMLDecoder) SetVisitable(vis parser.Visitable) {
+	d.visitMe = vis
+}
+
+// Run reads the XML and visits it. Invalid XML results in a token.PosError caused by the error of
+// encoding/xml. Finalize is called once the input has been read completely.
+func (d *XMLDecoder) Run() error {
+	for {
+		begin := d.decoder.InputOffset()
+
+		tok, err := d.decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		rng := token.Position{BeginPos: d.positions.at(begin), EndPos: d.positions.at(d.decoder.InputOffset())}
+
+		if err != nil {
+			return token.NewPosError(rng, "invalid XML").SetCause(err)
+		}
+
+		if err := d.visit(tok, rng); err != nil {
+			return err
+		}
+	}
+
+	if !d.rootClosed {
+		return token.NewPosError(token.Position{BeginPos: d.positions.pos, EndPos: d.positions.pos},
+			"XML document has no root element")
+	}
+
+	return d.visitMe.Finalize()
+}
+
+// visit calls the Visitable for a single XML token that spans rng.
+func (d *XMLDecoder) visit(tok xml.Token, rng token.Position) error {
+	switch t := tok.(type) {
+	case xml.StartElement:
+		return d.open(t, rng)
+	case xml.EndElement:
+		d.blocks = d.blocks[:len(d.blocks)-1]
+		if err := d.visitMe.Close(); err != nil {
+			return err
+		}
+
+		// The root around the document element is closed together with it.
+		if d.documentElement && len(d.blocks) == 1 {
+			d.blocks = d.blocks[:0]
+			if err := d.visitMe.Close(); err != nil {
+				return err
+			}
+		}
+
+		d.rootClosed = len(d.blocks) == 0
+
+		return nil
+	case xml.CharData:
+		text := strings.TrimSpace(string(t))
+		if text == "" || len(d.blocks) == 0 {
+			return nil
+		}
+
+		if err := d.setBlockType(); err != nil {
+			return err
+		}
+
+		return d.visitMe.Text(token.CharData{Position: rng, Value: text})
+	case xml.Comment:
+		if len(d.blocks) == 0 {
+			return nil
+		}
+
+		if err := d.setBlockType(); err != nil {
+			return err
+		}
+
+		return d.visitMe.Comment(token.CharData{Position: rng, Value: strings.TrimSpace(string(t))})
+	}
+
+	return nil
+}
+
+// open opens an element with all of its attributes.
+func (d *XMLDecoder) open(start xml.StartElement, rng token.Position) error {
+	if d.rootClosed {
+		return token.NewPosError(rng, fmt.Sprintf("unexpected element '%s' after the root element", start.Name.Local))
+	}
+
+	if d.documentElement && len(d.blocks) == 0 {
+		if err := d.openRoot(rng); err != nil {
+			return err
+		}
+	}
+
+	if err := d.setBlockType(); err != nil {
+		return err
+	}
+
+	if err := d.visitMe.Open(token.Identifier{Position: rng, Value: start.Name.Local}); err != nil {
+		return err
+	}
+
+	// The root always has a block, like the one created by the parser.Visitor.
+	root := len(d.blocks) == 0
+	d.blocks = append(d.blocks, root)
+
+	if root {
+		if err := d.visitMe.SetBlockType(parser.BlockNormal); err != nil {
+			return err
+		}
+	}
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+
+		key := token.Identifier{Position: rng, Value: attr.Name.Local}
+		if err := d.visitMe.Attribute(key, token.CharData{Position: rng, Value: attr.Value}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openRoot opens the root around the document element, see WithDocumentElement.
+func (d *XMLDecoder) openRoot(rng token.Position) error {
+	if err := d.visitMe.Open(token.Identifier{Position: rng, Value: parser.DefaultRootName}); err != nil {
+		return err
+	}
+
+	d.blocks = append(d.blocks, true)
+
+	return d.visitMe.SetBlockType(parser.BlockNormal)
+}
+
+// setBlockType gives the current element a normal block before its first child, as a dyml element
+// without a block can only have a single child.
+func (d *XMLDecoder) setBlockType() error {
+	if len(d.blocks) == 0 || d.blocks[len(d.blocks)-1] {
+		return nil
+	}
+
+	d.blocks[len(d.blocks)-1] = true
+
+	return d.visitMe.SetBlockType(parser.BlockNormal)
+}
+
+// positionReader calculates the positions of byte offsets in the input, which encoding/xml does not
+// provide. Only the bytes that were read but whose position has not been asked for yet are kept.
+type positionReader struct {
+	reader io.Reader
+	// pending are the bytes from offset pos.Offset on, which have been read already.
+	pending []byte
+	pos     token.Pos
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.pending = append(p.pending, b[:n]...)
+
+	return n, err
+}
+
+// at returns the position of offset, which must not be before the offset of the last call.
+func (p *positionReader) at(offset int64) token.Pos {
+	for int64(p.pos.Offset) < offset && len(p.pending) > 0 {
+		b := p.pending[0]
+		p.pending = p.pending[1:]
+		p.pos.Offset++
+
+		switch {
+		case b == '\n':
+			p.pos.Line++
+			p.pos.Col = 1
+			p.pos.UTF16Col = 1
+		case b&0xC0 == 0x80:
+			// Continuation bytes belong to the column of their rune.
+		case b >= 0xF0:
+			// Runes with four bytes are outside the basic multilingual plane and need a surrogate pair.
+			p.pos.Col++
+			p.pos.UTF16Col += 2
+		default:
+			p.pos.Col++
+			p.pos.UTF16Col++
+		}
+	}
+
+	return p.pos
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// decodeXML reads xml into a tree.
+func decodeXML(xml string, opts ...encoder.XMLDecoderOption) (*parser.TreeNode, error) {
+	recorder := parser.NewRecorder()
+
+	decoder := encoder.NewXMLDecoder("test.xml", strings.NewReader(xml), opts...)
+	decoder.SetVisitable(recorder)
+
+	if err := decoder.Run(); err != nil {
+		return nil, err
+	}
+
+	return recorder.Tree()
+}
+
+func TestXMLDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		// want is the expected tree as dyml, which is compared without comments.
+		want string
+		// comment is the expected first comment, if any.
+		comment string
+	}{
+		{
+			name: "empty root",
+			xml:  `<root/>`,
+			want: ``,
+		},
+		{
+			name: "elements and attributes",
+			xml:  `<?xml version="1.0"?><root><book id="1" author="Torben"><title>A  title</title></book><toc/></root>`,
+			want: `#book @id{1} @author{Torben} {#title{A  title}} #toc`,
+		},
+		{
+			name: "indentation is dropped",
+			xml:  "<root>\n    <a>\n        text\n    </a>\n</root>\n",
+			want: `#a{text}`,
+		},
+		{
+			name:    "comments and mixed content",
+			xml:     `<root><!-- hello --><p>Some <b>bold</b> text &amp; more</p></root>`,
+			want:    `#p{Some #b{bold} text & more}`,
+			comment: "hello",
+		},
+		{
+			name: "cdata",
+			xml:  `<root><code><![CDATA[a < b]]></code></root>`,
+			want: `#code{a < b}`,
+		},
+		{
+			name: "namespaces",
+			xml:  `<root xmlns="urn:a" xmlns:x="urn:x"><x:item x:key="v"/></root>`,
+			want: `#item @key{v}`,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := decodeXML(test.xml)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parser.NewParser("", strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := parser.NormalizeOptions{CollapseWhitespace: true, DropComments: true}
+			if !parser.Equal(got, want, opts) {
+				t.Errorf("expected\n%s\nbut got\n%s", want.Dump(), got.Dump())
+			}
+
+			if test.comment != "" {
+				if comment := got.Children[0].Comment; comment == nil || *comment != test.comment {
+					t.Errorf("expected the comment %q, but got %v", test.comment, comment)
+				}
+			}
+		})
+	}
+}
+
+func TestXMLDecodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	text := `#book @id{my-book} {
+		#? table of contents
+		#toc
+		#chapter @id{1} {
+			#title{Chapter One}
+			Some #red{#bold{ text }} here.
+		}
+	}`
+
+	var buf bytes.Buffer
+	if err := encoder.NewXMLEncoder("", strings.NewReader(text), &buf).Encode(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeXML(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := parser.NewParser("", strings.NewReader(text)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments are trimmed in XML.
+	opts := parser.NormalizeOptions{CollapseWhitespace: true, DropComments: true}
+	if !parser.Equal(got, want, opts) {
+		t.Errorf("expected\n%s\nbut got\n%s", want.Dump(), got.Dump())
+	}
+}
+
+func TestXMLDecodeDocumentElement(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want string
+	}{
+		{
+			name: "named root with attributes",
+			xml:  `<!-- before --><cfg version="1">text<db host="localhost"/></cfg><!-- after -->`,
+			want: `#cfg @version{1} {text #db @host{localhost}}`,
+		},
+		{
+			name: "empty root",
+			xml:  `<cfg/>`,
+			want: `#cfg`,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := decodeXML(test.xml, encoder.WithDocumentElement())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parser.NewParser("", strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := parser.NormalizeOptions{CollapseWhitespace: true, DropComments: true}
+			if !parser.Equal(got, want, opts) {
+				t.Errorf("expected\n%s\nbut got\n%s", want.Dump(), got.Dump())
+			}
+		})
+	}
+}
+
+func TestXMLDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		// line is the line of the error.
+		line int
+	}{
+		{
+			name: "mismatched tag",
+			xml:  "<root>\n<a></b>\n</root>",
+			line: 2,
+		},
+		{
+			name: "no root",
+			xml:  "<!-- nothing -->\n",
+			line: 2,
+		},
+		{
+			name: "second root",
+			xml:  "<root/>\n<other/>",
+			line: 2,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := decodeXML(test.xml)
+
+			var posErr *token.PosError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected a PosError, but got %v", err)
+			}
+
+			if line := posErr.Details[0].Node.Begin().Line; line != test.line {
+				t.Errorf("expected the error in line %d, but got %d: %v", test.line, line, err)
+			}
+		})
+	}
+}
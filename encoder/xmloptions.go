@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package encoder
+
+import "github.com/golangee/dyml/parser"
+
+// XMLEncoderOption can be passed to NewXMLEncoder to configure the XML output.
+type XMLEncoderOption func(e *XMLEncoder)
+
+// WithXMLDeclaration starts the output with an XML declaration, `<?xml version="1.0" encoding="UTF-8"?>`.
+func WithXMLDeclaration() XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.declaration = true
+	}
+}
+
+// WithRootName sets the name of the root element, which is "root" by default.
+func WithRootName(name string) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.rootName = name
+	}
+}
+
+// WithoutRoot writes the top-level element of the document as the root of the XML, instead of wrapping
+// the document in a root element, as many XML consumers require a specific name for their root element.
+// The document must then contain exactly one top-level element and no text outside of it, otherwise Encode
+// returns an error. Namespaces are declared on the top-level element and WithRootName has no effect.
+func WithoutRoot() XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.omitRoot = true
+	}
+}
+
+// WithBlockTypeAttribute adds an attribute with the given name to every element whose children are
+// enclosed in brackets. Its value is the parser.BlockType, e.g. "{}" or "()".
+// An empty name disables the attribute, which is the default.
+func WithBlockTypeAttribute(name string) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.blockTypeAttribute = name
+	}
+}
+
+// WithNamespace declares a namespace on the root element. An empty prefix declares the default
+// namespace, which applies to all elements, as names in dyml cannot have a prefix.
+func WithNamespace(prefix, uri string) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.namespaces = append(e.namespaces, namespace{prefix: prefix, uri: uri})
+	}
+}
+
+// WithCDATA writes texts that contain '<', '>' or '&' as CDATA sections instead of replacing
+// these characters with entities, which keeps code snippets and similar texts readable.
+func WithCDATA() XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.cdata = true
+	}
+}
+
+// WithXMLIndent sets the string that is written once per level of indentation, e.g. "\t" or "  ".
+// The default are four spaces.
+func WithXMLIndent(indent string) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.indentUnit = indent
+	}
+}
+
+// WithCompact writes the XML in a single line without any indentation, e.g. to embed it into other
+// documents. Texts are written as they are, instead of being trimmed and placed on their own lines.
+func WithCompact() XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.compact = true
+	}
+}
+
+// WithMaxLineWidth writes every attribute of a tag on its own line, indented one level deeper than the
+// tag, if the tag would otherwise be wider than width characters. This keeps diffs of generated XML
+// readable. Tags of forwarded elements are measured without the indentation of the element they are
+// placed in. A width of 0 disables wrapping, which is the default, and compact XML is never wrapped.
+func WithMaxLineWidth(width int) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.maxLineWidth = width
+	}
+}
+
+// WithStatsHook registers a hook that is called with the parser.Stats of the dyml input once encoding
+// ended, also if it failed. See parser.Visitor.OnStats.
+func WithStatsHook(hook func(stats parser.Stats)) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.statsHooks = append(e.statsHooks, hook)
+	}
+}
+
+// InvalidNames decides what the XMLEncoder does with names of elements and attributes that are not valid
+// in XML, e.g. qualified names like "std::vector" or names that start with a digit.
+type InvalidNames int
+
+const (
+	// RejectInvalidNames results in a token.PosError at the name. This is the default.
+	RejectInvalidNames InvalidNames = iota
+	// MangleInvalidNames replaces every character that is not allowed with '_' and prepends '_' to names
+	// that would start with a digit, '-' or '.', e.g. "std::vector" becomes "std__vector". Different names
+	// may end up the same, so attributes can still be rejected as defined twice.
+	MangleInvalidNames
+)
+
+// WithInvalidNames sets what happens with names of elements and attributes that are not valid in XML.
+// The names set by other options must always be valid.
+func WithInvalidNames(policy InvalidNames) XMLEncoderOption {
+	return func(e *XMLEncoder) {
+		e.invalidNames = policy
+	}
+}
+
+// namespace is a namespace declaration for the root element.
+type namespace struct {
+	prefix string
+	uri    string
+}
+
+// attributeKey returns the key of the attribute that declares the namespace.
+func (n namespace) attributeKey() string {
+	if n.prefix == "" {
+		return "xmlns"
+	}
+
+	return "xmlns:" + n.prefix
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.18
+// +build go1.18
+
+package dyml_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+)
+
+// FuzzTarget uses most of the types and tags that can be unmarshalled.
+type FuzzTarget struct {
+	Name   string
+	Count  int
+	Small  uint8
+	Ratio  float64
+	Flag   bool
+	Level  Level     `dyml:"level,attr"`
+	Size   Megabytes `dyml:"size,attr"`
+	Tags   []string
+	Items  []FuzzItem `dyml:"item"`
+	Pairs  map[string]int
+	Fixed  [2]int
+	Nested *FuzzTarget
+	Custom CustomUnmarshal
+}
+
+type FuzzItem struct {
+	ID   string `dyml:"id,attr"`
+	Text string `dyml:",inner"`
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range []string{
+		"#Name test #Count 1 #Ratio 0.5 #Flag true",
+		"#Tags{#a #b} #item @id{1} {text} #item @id{2}",
+		"#! Pairs { a 1, b 2 } #Fixed{1 2}",
+		"#! Nested @level=\"debug\" @size=\"5MB\" { Nested { Name \"deep\" } }",
+		"#Custom{#Add 1 #Add 2}",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	f.Fuzz(func(t *testing.T, text string, strict bool) {
+		var target FuzzTarget
+
+		// Any error is fine, but unmarshalling must never panic.
+		_ = Unmarshal(strings.NewReader(text), &target, strict)
+	})
+}
@@ -1,9 +1,3 @@
 module github.com/golangee/dyml
 
 go 1.16
-
-require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/r3labs/diff/v2 v2.13.6
-	golang.org/x/net v0.0.0-20210405180319-a5a99cb37ef4 // indirect
-)
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// MIMEType is the media type of dyml documents.
+const MIMEType = "application/dyml"
+
+// RegisterMIMEType registers MIMEType for the ".dyml" extension with package mime, so that e.g.
+// http.FileServer serves documents with it. The registration is process-wide, so it is left to
+// the application, usually in its main function.
+func RegisterMIMEType() error {
+	return mime.AddExtensionType(".dyml", MIMEType)
+}
+
+// DefaultMaxBodySize is the size limit of request bodies that are read by a Decoder from NewHTTPDecoder,
+// unless another one is set with WithMaxBodySize.
+const DefaultMaxBodySize = 10 * MiB
+
+// ErrBodyTooLarge is the cause of errors about request bodies that exceed their size limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// ErrUnsupportedMediaType is returned by NewHTTPDecoder for requests whose Content-Type is not MIMEType.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// WithMaxBodySize sets the size limit of request bodies read by a Decoder from NewHTTPDecoder.
+// Reading more results in an error caused by ErrBodyTooLarge. A size of 0 means that there is no limit.
+func WithMaxBodySize(size ByteSize) UnmarshalOption {
+	return func(u *unmarshaler) {
+		u.maxBodySize = &size
+	}
+}
+
+// NewHTTPDecoder creates a Decoder that reads the body of r, so that web services can accept dyml
+// like JSON. Requests with another Content-Type than MIMEType are rejected with an error wrapping
+// ErrUnsupportedMediaType, requests without one are accepted. The body is limited to
+// DefaultMaxBodySize, see WithMaxBodySize. Use DecodeContext with the context of r to stop decoding
+// once the client is gone. See NewDecoder for the meaning of strict.
+func NewHTTPDecoder(r *http.Request, strict bool, opts ...UnmarshalOption) (*Decoder, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != MIMEType {
+			return nil, fmt.Errorf("%w '%s', expected '%s'", ErrUnsupportedMediaType, contentType, MIMEType)
+		}
+	}
+
+	var body io.Reader = r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+
+	maxSize := DefaultMaxBodySize
+	if size := newUnmarshaler(strict, opts...).maxBodySize; size != nil {
+		maxSize = *size
+	}
+
+	if maxSize > 0 {
+		body = &limitedBody{reader: body, remaining: int64(maxSize), max: maxSize}
+	}
+
+	// The request path is not used as filename, as errors would try to load it from the file system.
+	return NewDecoder("", body, strict, opts...), nil
+}
+
+// WriteDyml writes v as dyml into the response with MIMEType as Content-Type. v is marshalled
+// completely before anything is written, so that an error can still be answered with another response.
+func WriteDyml(w http.ResponseWriter, v interface{}, opts ...MarshalOption) error {
+	var buf bytes.Buffer
+	if err := Marshal(&buf, v, opts...); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", MIMEType+"; charset=utf-8")
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// limitedBody fails with ErrBodyTooLarge once more than max bytes are read.
+type limitedBody struct {
+	reader    io.Reader
+	remaining int64
+	max       ByteSize
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w, the limit is %s", ErrBodyTooLarge, l.max)
+	}
+
+	// One more byte than allowed is read to find out whether the body is too large.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 {
+		return n + int(l.remaining), fmt.Errorf("%w, the limit is %s", ErrBodyTooLarge, l.max)
+	}
+
+	return n, err
+}
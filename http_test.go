@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"errors"
+	"mime"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+)
+
+func TestNewHTTPDecoder(t *testing.T) {
+	type Request struct {
+		Name string `dyml:"name"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []UnmarshalOption
+		want        string
+		wantErr     error
+	}{
+		{
+			name:        "dyml",
+			contentType: MIMEType + "; charset=utf-8",
+			body:        "#name Gopher",
+			want:        "Gopher",
+		},
+		{
+			name: "without content type",
+			body: "#name Gopher",
+			want: "Gopher",
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"name": "Gopher"}`,
+			wantErr:     ErrUnsupportedMediaType,
+		},
+		{
+			name:    "too large",
+			body:    "#name " + strings.Repeat("x", 100),
+			opts:    []UnmarshalOption{WithMaxBodySize(64 * Byte)},
+			wantErr: ErrBodyTooLarge,
+		},
+		{
+			name: "exactly the limit",
+			body: "#name Gopher",
+			opts: []UnmarshalOption{WithMaxBodySize(12 * Byte)},
+			want: "Gopher",
+		},
+		{
+			name: "no limit",
+			body: "#name " + strings.Repeat("x", 100),
+			opts: []UnmarshalOption{WithMaxBodySize(0)},
+			want: strings.Repeat("x", 100),
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+
+			var got Request
+
+			decoder, err := NewHTTPDecoder(r, true, test.opts...)
+			if err == nil {
+				err = decoder.DecodeContext(r.Context(), &got)
+			}
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("expected an error wrapping '%v', but got %v", test.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Name != test.want {
+				t.Errorf("expected '%s', but got '%s'", test.want, got.Name)
+			}
+		})
+	}
+}
+
+func TestWriteDyml(t *testing.T) {
+	t.Parallel()
+
+	type Response struct {
+		Name string `dyml:"name"`
+	}
+
+	w := httptest.NewRecorder()
+	if err := WriteDyml(w, Response{Name: "Gopher"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, MIMEType) {
+		t.Errorf("expected the content type '%s', but got '%s'", MIMEType, contentType)
+	}
+
+	var got Response
+	if err := Unmarshal(w.Body, &got, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "Gopher" {
+		t.Errorf("expected the response to be decoded again, but got %+v", got)
+	}
+
+	if err := WriteDyml(httptest.NewRecorder(), 42); err == nil {
+		t.Error("expected an error for a value that cannot be marshalled")
+	}
+
+	if err := RegisterMIMEType(); err != nil {
+		t.Fatal(err)
+	}
+
+	if mimeType := mime.TypeByExtension(".dyml"); mimeType != MIMEType {
+		t.Errorf("expected '%s' to be registered for .dyml, but got '%s'", MIMEType, mimeType)
+	}
+}
@@ -0,0 +1,324 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package docsnippet extracts dyml examples from go doc comments and verifies that they
+// parse and can be unmarshalled into the go types they are documented with.
+//
+// A snippet is an indented block in a doc comment that looks like this:
+//
+//	// This dyml snippet...
+//	#item @key{value}
+//	// could be unmarshalled into this go struct.
+//	type Example struct {
+//	    Key string `dyml:"key,attr"`
+//	}
+//
+// The go part is optional. The first type declared in it is checked and may use the other types
+// declared in the snippet, predeclared types and parser.TreeNode. Other types are reported as errors.
+package docsnippet
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+)
+
+const (
+	// dymlMarker starts the dyml part of a snippet.
+	dymlMarker = "// This dyml snippet..."
+	// goMarker ends the dyml part of a snippet and starts the go part.
+	goMarker = "// could be unmarshalled into this go struct."
+)
+
+// Snippet is a dyml example together with the go type it should be unmarshalled into.
+type Snippet struct {
+	// File and Line describe where the snippet starts.
+	File string
+	Line int
+	// Dyml is the dyml source of the snippet.
+	Dyml string
+	// Go is the go type declaration of the snippet, which might be empty.
+	Go string
+}
+
+// ExtractFile extracts all snippets from the comments of a go source file.
+func ExtractFile(filename string) ([]Snippet, error) {
+	fset := gotoken.NewFileSet()
+
+	file, err := goparser.ParseFile(fset, filename, nil, goparser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	return Extract(fset, file), nil
+}
+
+// Extract returns all snippets found in the comments of the given file.
+func Extract(fset *gotoken.FileSet, file *ast.File) []Snippet {
+	var result []Snippet
+
+	for _, group := range file.Comments {
+		var (
+			current *Snippet
+			inGo    bool
+		)
+
+		for _, comment := range group.List {
+			line := strings.TrimPrefix(comment.Text, "//")
+			line = strings.TrimPrefix(line, " ")
+			isIndented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+			trimmed := strings.TrimSpace(line)
+
+			switch {
+			case isIndented && trimmed == dymlMarker:
+				if current != nil {
+					result = append(result, *current)
+				}
+
+				pos := fset.Position(comment.Pos())
+				current = &Snippet{File: pos.Filename, Line: pos.Line + 1}
+				inGo = false
+			case current == nil:
+				// Not inside a snippet.
+			case isIndented && trimmed == goMarker:
+				inGo = true
+			case isIndented && inGo:
+				current.Go += line + "\n"
+			case isIndented:
+				current.Dyml += line + "\n"
+			default:
+				// The indented block has ended.
+				result = append(result, *current)
+				current = nil
+			}
+		}
+
+		if current != nil {
+			result = append(result, *current)
+		}
+	}
+
+	return result
+}
+
+// Verify checks that the dyml part of the snippet parses and that it can be unmarshalled
+// into the go part, if there is one.
+func (s Snippet) Verify() error {
+	tree, err := parser.NewParser(s.File, strings.NewReader(s.Dyml)).Parse()
+	if err != nil {
+		return fmt.Errorf("%s:%d: snippet does not parse: %w", s.File, s.Line, err)
+	}
+
+	if strings.TrimSpace(s.Go) == "" {
+		return nil
+	}
+
+	typ, err := goType(s.Go)
+	if err != nil {
+		return fmt.Errorf("%s:%d: invalid go part in snippet: %w", s.File, s.Line, err)
+	}
+
+	if err := dyml.UnmarshalTree(tree, reflect.New(typ).Interface(), false); err != nil {
+		return fmt.Errorf("%s:%d: snippet cannot be unmarshalled: %w", s.File, s.Line, err)
+	}
+
+	return nil
+}
+
+// goType creates a type from the first type declaration in src.
+func goType(src string) (reflect.Type, error) {
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "", "package snippet\n"+src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := resolver{declared: map[string]ast.Expr{}, resolving: map[string]bool{}}
+
+	var first string
+
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == gotoken.TYPE {
+			for _, s := range gen.Specs {
+				if spec, ok := s.(*ast.TypeSpec); ok {
+					r.declared[spec.Name.Name] = spec.Type
+
+					if first == "" {
+						first = spec.Name.Name
+					}
+				}
+			}
+		}
+	}
+
+	if first == "" {
+		return nil, fmt.Errorf("no type declaration found")
+	}
+
+	return r.reflectType(r.declared[first])
+}
+
+// resolver builds types from the declarations in a snippet.
+type resolver struct {
+	// declared maps the names of the types declared in the snippet to their definition.
+	declared map[string]ast.Expr
+	// resolving contains the declared types that are currently built, to detect recursive types.
+	resolving map[string]bool
+}
+
+// reflectType builds a type at runtime from the given go expression.
+func (r resolver) reflectType(expr ast.Expr) (reflect.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if t, ok := primitives[e.Name]; ok {
+			return t, nil
+		}
+
+		def, ok := r.declared[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type '%s'", e.Name)
+		}
+
+		if r.resolving[e.Name] {
+			return nil, fmt.Errorf("recursive type '%s' is not supported", e.Name)
+		}
+
+		r.resolving[e.Name] = true
+		defer delete(r.resolving, e.Name)
+
+		return r.reflectType(def)
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "parser" && e.Sel.Name == "TreeNode" {
+			return reflect.TypeOf(parser.TreeNode{}), nil
+		}
+
+		return nil, fmt.Errorf("unsupported type '%s'", e.Sel.Name)
+	case *ast.StarExpr:
+		t, err := r.reflectType(e.X)
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.PtrTo(t), nil
+	case *ast.ArrayType:
+		t, err := r.reflectType(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Len == nil {
+			return reflect.SliceOf(t), nil
+		}
+
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok {
+			return nil, fmt.Errorf("array length must be a literal")
+		}
+
+		length, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.ArrayOf(length, t), nil
+	case *ast.MapType:
+		key, err := r.reflectType(e.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.reflectType(e.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.MapOf(key, value), nil
+	case *ast.StructType:
+		return r.structType(e)
+	default:
+		return nil, fmt.Errorf("unsupported type expression %T", expr)
+	}
+}
+
+// structType builds a struct type at runtime from the given go struct definition.
+func (r resolver) structType(s *ast.StructType) (reflect.Type, error) {
+	var fields []reflect.StructField
+
+	for _, field := range s.Fields.List {
+		t, err := r.reflectType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var tag string
+
+		if field.Tag != nil {
+			tag, err = strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(field.Names) == 0 {
+			// Embedded fields are named after their type.
+			fields = append(fields, reflect.StructField{
+				Name:      embeddedName(field.Type),
+				Type:      t,
+				Tag:       reflect.StructTag(tag),
+				Anonymous: true,
+			})
+		}
+
+		for _, name := range field.Names {
+			fields = append(fields, reflect.StructField{
+				Name: name.Name,
+				Type: t,
+				Tag:  reflect.StructTag(tag),
+			})
+		}
+	}
+
+	return reflect.StructOf(fields), nil
+}
+
+// embeddedName returns the implicit name of an embedded field with the given type.
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	default:
+		return ""
+	}
+}
+
+// primitives maps names of predeclared go types to their reflect.Type.
+//
+//nolint:gochecknoglobals
+var primitives = map[string]reflect.Type{
+	"string":  reflect.TypeOf(""),
+	"bool":    reflect.TypeOf(false),
+	"byte":    reflect.TypeOf(byte(0)),
+	"rune":    reflect.TypeOf(rune(0)),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package docsnippet_test
+
+import (
+	goparser "go/parser"
+	gotoken "go/token"
+	"testing"
+
+	. "github.com/golangee/dyml/internal/docsnippet"
+)
+
+const source = `package example
+
+// Example is documented with snippets.
+//
+//  // This dyml snippet...
+//  #item @key{value}
+//  // could be unmarshalled into this go struct.
+//  type Example struct {
+//      Key string ` + "`dyml:\"key,attr\"`" + `
+//  }
+//
+// Snippets without a go type are only parsed.
+//
+//  // This dyml snippet...
+//  #! item {
+//
+// This is no longer part of the snippet.
+type Example struct{}
+`
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	fset := gotoken.NewFileSet()
+
+	file, err := goparser.ParseFile(fset, "example.go", source, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippets := Extract(fset, file)
+	if len(snippets) != 2 {
+		t.Fatalf("expected 2 snippets, but got %d", len(snippets))
+	}
+
+	if snippets[0].Line != 6 {
+		t.Errorf("expected first snippet on line 6, but got %d", snippets[0].Line)
+	}
+
+	if err := snippets[0].Verify(); err != nil {
+		t.Errorf("valid snippet reported an error: %v", err)
+	}
+
+	if snippets[1].Go != "" {
+		t.Errorf("second snippet should not have a go type, but got '%s'", snippets[1].Go)
+	}
+
+	if err := snippets[1].Verify(); err == nil {
+		t.Error("invalid snippet should report an error")
+	}
+}
+
+func TestVerifyTypeMismatch(t *testing.T) {
+	t.Parallel()
+
+	snippet := Snippet{
+		File: "example.go",
+		Dyml: "#Count{not a number}",
+		Go:   "type Example struct {\n    Count int\n}\n",
+	}
+
+	if err := snippet.Verify(); err == nil {
+		t.Error("snippet with incompatible type should report an error")
+	}
+}
+
+func TestVerifyDeclaredTypes(t *testing.T) {
+	t.Parallel()
+
+	snippet := Snippet{
+		File: "example.go",
+		Dyml: "#Name{Gopher} #Age{not a number}",
+		Go:   "type Example struct {\n    Base\n    Age int\n}\ntype Base struct {\n    Name string\n}\n",
+	}
+
+	if err := snippet.Verify(); err == nil {
+		t.Error("the first declared type should be checked, but the invalid age was not reported")
+	}
+
+	snippet.Dyml = "#Name{Gopher} #Age{3}"
+	if err := snippet.Verify(); err != nil {
+		t.Errorf("valid snippet reported an error: %v", err)
+	}
+}
+
+func TestVerifyUnknownType(t *testing.T) {
+	t.Parallel()
+
+	snippet := Snippet{
+		File: "example.go",
+		Dyml: "#Name{Gopher}",
+		Go:   "type Example struct {\n    Base\n}\n",
+	}
+
+	if err := snippet.Verify(); err == nil {
+		t.Error("snippet with an undeclared type should report an error")
+	}
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lint checks parsed dyml trees for problems that are not syntax errors, like empty blocks
+// or misspelled attributes, to enforce a consistent style.
+//
+// Lint runs a set of rules over a tree and returns their findings sorted by position.
+// The built-in rules are configured by their constructors and DefaultRules returns all of them.
+// Custom rules only need a name and a check function:
+//
+//	noTodo := lint.Rule{
+//		Name: "no-todo",
+//		Check: func(root *parser.TreeNode, report lint.Report) {
+//			// Call report for every problem.
+//		},
+//	}
+//	findings := lint.Lint(tree, append(lint.DefaultRules(), noTodo)...)
+package lint
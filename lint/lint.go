@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Finding is a single problem that was found by a rule.
+type Finding struct {
+	// Rule is the name of the rule that reported the problem.
+	Rule string
+	// Message describes the problem.
+	Message string
+	// Range is the position of the offending node or attribute.
+	Range token.Position
+}
+
+// String returns the message and the name of the rule.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s (%s)", f.Message, f.Rule)
+}
+
+// Report is called by rules for every problem they find.
+type Report func(pos token.Position, message string)
+
+// Rule checks a tree for one kind of problem.
+type Rule struct {
+	// Name identifies the rule in findings, e.g. "empty-blocks".
+	Name string
+	// Check calls report for every problem in the tree below root.
+	Check func(root *parser.TreeNode, report Report)
+}
+
+// DefaultRules returns all built-in rules with their default configuration.
+func DefaultRules() []Rule {
+	return []Rule{
+		DuplicateSiblings(),
+		EmptyBlocks(),
+		MixedBlockTypes(),
+		AttributeTypos(),
+	}
+}
+
+// Lint runs all rules over the tree and returns their findings sorted by position.
+func Lint(root *parser.TreeNode, rules ...Rule) []Finding {
+	var findings []Finding
+
+	for _, rule := range rules {
+		name := rule.Name
+		rule.Check(root, func(pos token.Position, message string) {
+			findings = append(findings, Finding{Rule: name, Message: message, Range: pos})
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Range.BeginPos, findings[j].Range.BeginPos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Col < b.Col
+	})
+
+	return findings
+}
+
+// walk calls fn for node and all element nodes below it, in document order.
+func walk(node *parser.TreeNode, fn func(node *parser.TreeNode)) {
+	fn(node)
+
+	for _, child := range node.Children {
+		if child.IsNode() {
+			walk(child, fn)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package lint_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/lint"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		text  string
+		rules []Rule
+		want  []string
+	}{
+		{
+			name:  "duplicate siblings",
+			text:  `#name{a} #item #item #sub{#name #name}`,
+			rules: []Rule{DuplicateSiblings("item")},
+			want:  []string{"doc:1:34: 'name' is already defined at doc:1:28 (duplicate-siblings)"},
+		},
+		{
+			name:  "empty blocks",
+			text:  "#a{} #b{ } #d{text} #e #! c { // note\n }",
+			rules: []Rule{EmptyBlocks()},
+			want: []string{
+				"doc:1:2: 'a' has an empty block (empty-blocks)",
+				"doc:1:7: 'b' has an empty block (empty-blocks)",
+			},
+		},
+		{
+			name:  "mixed block types",
+			text:  `#! list { item(a) item(b) item{c} item d }`,
+			rules: []Rule{MixedBlockTypes()},
+			want:  []string{"doc:1:27: 'item' uses {}, but () at doc:1:11 (mixed-block-types)"},
+		},
+		{
+			name:  "attribute typos",
+			text:  `#a @host{x} #b @host{y} @hots{z} @port{1} #c @prot{2} @ip{3} @id{4}`,
+			rules: []Rule{AttributeTypos("port")},
+			want: []string{
+				"doc:1:26: attribute 'hots' looks like a typo of 'host' (attribute-typos)",
+				"doc:1:47: attribute 'prot' looks like a typo of 'port' (attribute-typos)",
+			},
+		},
+		{
+			name:  "clean document",
+			text:  `#server @host{a} {#port 80} #client @host{b}`,
+			rules: DefaultRules(),
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser("doc", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, finding := range Lint(tree, test.rules...) {
+				got = append(got, finding.Range.BeginPos.String()+": "+finding.String())
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected\n%s\nbut got\n%s", strings.Join(test.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestLintCustomRule(t *testing.T) {
+	t.Parallel()
+
+	tree, err := parser.NewParser("", strings.NewReader(`#b{} #a TODO`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noTodo := Rule{
+		Name: "no-todo",
+		Check: func(root *parser.TreeNode, report Report) {
+			for _, child := range root.Children {
+				for _, text := range child.Children {
+					if text.IsText() && strings.Contains(*text.Text, "TODO") {
+						report(text.Range, "unfinished")
+					}
+				}
+			}
+		},
+	}
+
+	findings := Lint(tree, noTodo, EmptyBlocks())
+
+	want := []Finding{
+		{Rule: "empty-blocks", Message: "'b' has an empty block", Range: tree.Children[0].Range},
+		{Rule: "no-todo", Message: "unfinished", Range: tree.Children[1].Children[0].Range},
+	}
+
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("expected %v, but got %v", want, findings)
+	}
+}
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// DuplicateSiblings reports elements that have the same name as one of their preceding siblings.
+// Elements that are meant to be repeated, like the items of a list, can be allowed by name.
+func DuplicateSiblings(allowed ...string) Rule {
+	isAllowed := toSet(allowed)
+
+	return Rule{
+		Name: "duplicate-siblings",
+		Check: func(root *parser.TreeNode, report Report) {
+			walk(root, func(node *parser.TreeNode) {
+				first := map[string]*parser.TreeNode{}
+
+				for _, child := range node.Children {
+					if !child.IsNode() || isAllowed[child.Name] {
+						continue
+					}
+
+					if previous, ok := first[child.Name]; ok {
+						report(child.Range,
+							fmt.Sprintf("'%s' is already defined at %s", child.Name, previous.Range.BeginPos))
+
+						continue
+					}
+
+					first[child.Name] = child
+				}
+			})
+		},
+	}
+}
+
+// EmptyBlocks reports elements with brackets but without any content, e.g. "#a{}".
+// Blocks that only contain whitespace are empty as well, blocks with comments are not.
+func EmptyBlocks() Rule {
+	return Rule{
+		Name: "empty-blocks",
+		Check: func(root *parser.TreeNode, report Report) {
+			walk(root, func(node *parser.TreeNode) {
+				if node == root || node.BlockType == parser.BlockNone || !isEmpty(node) {
+					return
+				}
+
+				report(node.Range, fmt.Sprintf("'%s' has an empty block", node.Name))
+			})
+		},
+	}
+}
+
+// MixedBlockTypes reports elements that use other brackets than the first element with the same name,
+// e.g. "#a{...}" and "#a<...>". Elements without brackets are not considered.
+func MixedBlockTypes() Rule {
+	return Rule{
+		Name: "mixed-block-types",
+		Check: func(root *parser.TreeNode, report Report) {
+			first := map[string]*parser.TreeNode{}
+
+			walk(root, func(node *parser.TreeNode) {
+				if node == root || node.BlockType == parser.BlockNone {
+					return
+				}
+
+				previous, ok := first[node.Name]
+				if !ok {
+					first[node.Name] = node
+
+					return
+				}
+
+				if previous.BlockType != node.BlockType {
+					report(node.Range, fmt.Sprintf("'%s' uses %s, but %s at %s",
+						node.Name, node.BlockType, previous.BlockType, previous.Range.BeginPos))
+				}
+			})
+		},
+	}
+}
+
+// AttributeTypos reports attributes whose key is very similar to a known key, e.g. "hots" instead of "host".
+// Known keys are the given ones and all keys that are used at least twice in the document.
+func AttributeTypos(known ...string) Rule {
+	return Rule{
+		Name: "attribute-typos",
+		Check: func(root *parser.TreeNode, report Report) {
+			count := map[string]int{}
+
+			walk(root, func(node *parser.TreeNode) {
+				for i := 0; i < node.Attributes.Len(); i++ {
+					count[node.Attributes.GetAt(i).Key]++
+				}
+			})
+
+			isKnown := toSet(known)
+			for key, n := range count {
+				if n > 1 {
+					isKnown[key] = true
+				}
+			}
+
+			// Sort the candidates, so that the reported key does not depend on the map order.
+			candidates := make([]string, 0, len(isKnown))
+			for key := range isKnown {
+				candidates = append(candidates, key)
+			}
+
+			sort.Strings(candidates)
+
+			walk(root, func(node *parser.TreeNode) {
+				for i := 0; i < node.Attributes.Len(); i++ {
+					attr := node.Attributes.GetAt(i)
+					if isKnown[attr.Key] {
+						continue
+					}
+
+					for _, candidate := range candidates {
+						if isTypo(attr.Key, candidate) {
+							report(attr.Range,
+								fmt.Sprintf("attribute '%s' looks like a typo of '%s'", attr.Key, candidate))
+
+							break
+						}
+					}
+				}
+			})
+		},
+	}
+}
+
+// isEmpty returns true if node has neither child elements, comments nor non-whitespace text.
+func isEmpty(node *parser.TreeNode) bool {
+	for _, child := range node.Children {
+		if !child.IsText() || strings.TrimSpace(*child.Text) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isTypo returns true if key differs from candidate by only one edit, or two edits for longer keys.
+func isTypo(key, candidate string) bool {
+	maxEdits := 1
+	if len([]rune(candidate)) > 4 {
+		maxEdits = 2
+	}
+
+	return distance(key, candidate) <= maxEdits
+}
+
+// distance returns the number of inserted, removed, replaced or swapped adjacent runes to turn a into b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b.
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// minInt returns the smallest of the given numbers.
+func minInt(first int, others ...int) int {
+	for _, n := range others {
+		if n < first {
+			first = n
+		}
+	}
+
+	return first
+}
+
+// toSet returns a set containing the given strings.
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+
+	return set
+}
@@ -4,15 +4,16 @@
 package dyml
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
 	"strconv"
 	"strings"
 
-	"github.com/golangee/dyml/token"
-
 	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
 )
 
 // Unmarshaler can be implemented on a struct to define custom unmarshalling behavior.
@@ -20,6 +21,12 @@ type Unmarshaler interface {
 	UnmarshalDyml(node *parser.TreeNode) error
 }
 
+// AttrUnmarshaler can be implemented by types that are used for attributes, to parse the attribute value
+// themselves. This is useful for custom enums or values with units.
+type AttrUnmarshaler interface {
+	UnmarshalDymlAttr(value string) error
+}
+
 // Unmarshal takes dyml input and parses it into the given struct.
 // If "into" is not a struct or a pointer to a struct, this method will panic.
 // As this uses go's reflect package, only exported names can be unmarshalled.
@@ -31,10 +38,10 @@ type Unmarshaler interface {
 // name is parsed, and not the name of the struct field.
 //
 //  // This dyml snippet...
-//  #!{item{...}}
+//  #item{...}
 //  // could be unmarshalled into this go struct.
 //  type Example struct {
-//      SomeName Content `dyml:"item"`
+//      SomeName string `dyml:"item"`
 //  }
 //
 // The second identifier is used to specify what kind of thing is being parsed.
@@ -43,6 +50,9 @@ type Unmarshaler interface {
 // Attributes can be parsed into primitive types: string, bool and the integer (signed & unsigned) and float types.
 // Should the value not be valid for the target type, e.g. an integer that is too large or a negative value for an uint,
 // an error is returned describing the issue.
+// Other types can be used for attributes by implementing AttrUnmarshaler.
+// time.Duration ("30s"), ByteSize ("10MiB") and url.URL are read from text in their usual format,
+// both from elements and attributes, and are written in the same format by Marshal.
 //
 //  // This dyml snippet...
 //  #item @key{value} @X{123}
@@ -52,14 +62,32 @@ type Unmarshaler interface {
 //      X        int    `dyml:",attr"` // You can choose to not rename it, by omitting the rename parameter.
 //  }
 //
+// A 'oneof=...' option restricts elements and attributes to a space separated list of values.
+// Other values result in a token.PosError at their position, which lists the valid values.
+//
+//  type Example struct {
+//      Level string `dyml:"level,attr,oneof=debug info warn error"`
+//  }
+//
+// A 'base64' option reads a []byte field from base64 encoded text, e.g. to embed binary payloads.
+// Whitespace in the text is ignored. Marshal writes such fields as base64 text and adds an
+// 'encoding' attribute to elements. Elements with @encoding{base64} are also decoded into []byte
+// fields without the option.
+//
+//  // This dyml snippet...
+//  #Icon @checksum{AAE=} {#data @encoding{base64} {SGVsbG8=}}
+//  // could be unmarshalled into this go struct.
+//  type Icon struct {
+//      Checksum []byte `dyml:"checksum,attr,base64"`
+//      Data     []byte `dyml:"data,base64"`
+//  }
+//
 // 'inner' can be used to parse elements that are the contents of the surrounding element.
 // Consider this example to parse plain text without surrounding elements:
 //
 //  // This dyml snippet...
-//  #! {
-//      "hello"
-//      "more text"
-//  }
+//  #! "hello"
+//  #! "more text"
 //  // could be unmarshalled into this go struct.
 //  type Example struct {
 //      Something string `dyml:",inner"`
@@ -70,30 +98,76 @@ type Unmarshaler interface {
 // In the following example inner is used to parse a map-like Dyml definition into a map without a supporting element.
 //
 //  // This dyml snippet...
-//  #! {
-//      A "B"
-//      C "D"
-//  }
+//  #! A "B"
+//  #! C "D"
 //  // could be unmarshalled into this go struct.
 //  type Example struct {
 //      Something map[string]string `dyml:",inner"`
 //  }
 //
+// 'raw' reads the text of an element just like it was written in the source, without interpreting
+// it, see parser.SourceMap.Content. The field must be a string or []byte. This is not possible for
+// trees that are already parsed, e.g. with UnmarshalTree.
+//
+//  // This dyml snippet...
+//  #script{if a < b then run #x @y{z}}
+//  // could be unmarshalled into this go struct, so that Script is "if a < b then run #x @y{z}".
+//  type Example struct {
+//      Script string `dyml:"script,raw"`
+//  }
+//
+// 'any' collects all child elements that are not read by other fields of the struct, e.g. to keep
+// extensions of a format. The field must be a slice. Elements of type parser.TreeNode or *parser.TreeNode
+// hold the children themselves, all other types are unmarshalled from the children. Texts and
+// comments are not collected.
 //
-// dyml can unmarshal into maps. The map key must be a primitive type. The map value must be a primitive
-// type, parser.TreeNode or *parser.TreeNode.
+//  // This dyml snippet...
+//  #name Gopher #color blue #size 3
+//  // could be unmarshalled into this go struct, so that Unknown holds the elements 'color' and 'size'.
+//  type Example struct {
+//      Name    string
+//      Unknown []*parser.TreeNode `dyml:",any"`
+//  }
+//
+// 'children' collects all children of an element in document order, including texts and comments, as
+// needed for markup, where texts and elements are interleaved. The field must be a []Content.
+//
+//  // This dyml snippet...
+//  #p @class{note} {Some #b{bold} text}
+//  // could be unmarshalled into this go struct, so that Content holds a text, the element 'b' and a text.
+//  type Paragraph struct {
+//      Class   string    `dyml:"class,attr"`
+//      Content []Content `dyml:",children"`
+//  }
+//
+// Fields of type parser.TreeNode or *parser.TreeNode receive the element itself instead of being unmarshalled
+// from it, which allows to read documents with mixed content partly into typed fields. Renamed slices of them
+// receive all elements with that name.
+//
+//  // This dyml snippet...
+//  #title{Hello} #body{Some #b{bold} text}
+//  // could be unmarshalled into this go struct, so that Body holds the element 'body' with its children.
+//  type Example struct {
+//      Title string           `dyml:"title"`
+//      Body  *parser.TreeNode `dyml:"body"`
+//  }
+//
+// dyml can unmarshal into maps. The map key must be a primitive type. The map value can be a primitive
+// type, a struct, a slice, an array, another map, parser.TreeNode or *parser.TreeNode.
 // Parsing maps will read first level elements as map keys and the first child of each as the map value.
-// In strict mode the map key is required to have exactly one child.
+// In strict mode the map key is required to have exactly one child and every key may only be defined once.
+// Should the value be a slice, array or map, then all children of the key are used as its elements,
+// so that e.g. map[string][]string or map[string]map[string]int can be unmarshalled. Such keys may have
+// any number of children, even in strict mode, which instead checks the elements like for any other
+// slice, array or map, e.g. that an array gets exactly as many elements as its length.
 // By specifying parser.TreeNode (or a pointer to it) as the value type you can access the raw tree that would be
 // parsed as a value. This is useful if you want to have more control over the value for doing more complex
 // manipulations than just parsing a primitive.
 //
 //  // This dyml snippet...
-//  #! {
-//      SomeMap {
-//          a 123,  // Numbers are valid identifiers, so this works
-//          b "1.5" // but all other values should be enclosed in quotes.
-//      }
+//  #! SomeMap {
+//      a 123,  // Numbers are valid identifiers, so this works
+//      b "1.5" // but all other values should be enclosed in quotes.
 //  }
 //  // could be unmarshalled into this go struct.
 //  type Example struct {
@@ -103,37 +177,96 @@ type Unmarshaler interface {
 // dyml also supports unmarshalling slices. When no tag is specified in the struct, elements in dyml
 // are unmarshalled into the slice directly. Should you specify a tag on the field in your struct,
 // then only elements with that tag will be parsed. See the examples for more details.
+// Arrays are unmarshalled just like slices, but surplus elements are ignored. In strict mode
+// the number of elements must match the length of the array.
+//
+// Fields of embedded (anonymous) structs are unmarshalled as if they were fields of the surrounding
+// struct, just like encoding/json does it. Renaming an embedded struct with a tag will
+// unmarshal it from a child element instead. Nil pointers to embedded structs will be allocated.
+//
+//  // This dyml snippet...
+//  #Name Gopher #Age 3
+//  // could be unmarshalled into this go struct.
+//  type Example struct {
+//      Base
+//      Age int
+//  }
+//  type Base struct {
+//      Name string
+//  }
+//
+// Named struct fields with a 'squash' (or 'flatten') tag are read from the surrounding element
+// in the same way, which makes it easy to reuse common groups of fields.
+//
+//  // This dyml snippet...
+//  #Name Gopher #Created 2021
+//  // could be unmarshalled into these go structs.
+//  type Timestamps struct {
+//      Created int
+//  }
+//  type Example struct {
+//      Name  string
+//      Times Timestamps `dyml:",squash"`
+//  }
 //
+// Structs that implement Validator are validated once all of their fields have been unmarshalled.
+//
+// Use a Decoder if you need additional information about the unmarshalling process, like
+// the source position of every field.
 func Unmarshal(r io.Reader, into interface{}, strict bool) error {
-	parse := parser.NewParser("", r)
-
-	if into == nil {
-		return fmt.Errorf("cannot unmarshal into nil")
-	}
+	return NewDecoder("", r, strict).Decode(into)
+}
 
-	tree, err := parse.Parse()
-	if err != nil {
-		return err
-	}
+// UnmarshalContext works like Unmarshal, but stops with an error wrapping ctx.Err() once ctx is done.
+// Use this to bound the time spent on huge or untrusted inputs.
+func UnmarshalContext(ctx context.Context, r io.Reader, into interface{}, strict bool) error {
+	return NewDecoder("", r, strict).DecodeContext(ctx, into)
+}
 
-	return UnmarshalTree(tree, into, strict)
+// UnmarshalPath works like Unmarshal, but only unmarshals the first element at path, whose names
+// are separated by slashes, e.g. "server/http" for the element #http within #server.
+// The element is unmarshalled like a whole document, so its attributes and children become the
+// fields of the given value. The rest of the document is skipped while parsing, see parser.WithSelection.
+func UnmarshalPath(r io.Reader, path string, into interface{}, strict bool) error {
+	return NewDecoder("", r, strict).DecodePath(path, into)
 }
 
 // UnmarshalTree works like Unmarshal, but processes an already parsed tree.
 func UnmarshalTree(tree *parser.TreeNode, into interface{}, strict bool) error {
-	value := reflect.ValueOf(into)
-	unmarshal := unmarshaler{strict: strict}
-
-	if err := unmarshal.doAny(tree, value); err != nil {
-		return err
-	}
-
-	return nil
+	return NewDecoder("", nil, strict).DecodeTree(tree, into)
 }
 
 // unmarshaler is a helper struct for easier managing the unmarshalling process.
 type unmarshaler struct {
 	strict bool
+	// path contains the names of all fields (and slice or map indices) that lead to the
+	// value that is currently being unmarshalled.
+	path []string
+	// provenance is nil, unless the position of each field should be recorded.
+	provenance Provenance
+	// preprocessors are applied to the tree before unmarshalling it.
+	preprocessors []func(tree *parser.TreeNode) error
+	// parserOptions are used for parsing the input, if it has not been parsed yet.
+	parserOptions []parser.ParserOption
+	// source is nil, unless the input was parsed for a value with 'raw' fields.
+	source *parser.SourceMap
+	// naming converts the names of fields without a rename tag, if it is set.
+	naming NamingStrategy
+	// caseInsensitive is true if names of elements and attributes are compared without regard to case.
+	caseInsensitive bool
+	// maxBodySize is the size limit of request bodies set by WithMaxBodySize, or nil for the default.
+	maxBodySize *ByteSize
+}
+
+// newUnmarshaler creates an unmarshaler with all options applied.
+func newUnmarshaler(strict bool, opts ...UnmarshalOption) *unmarshaler {
+	u := &unmarshaler{strict: strict}
+
+	for _, opt := range opts {
+		opt(u)
+	}
+
+	return u
 }
 
 // While unmarshalling we might need to process a node as an attribute.
@@ -144,6 +277,9 @@ const (
 	unmarshalNormal unmarshalType = iota
 	unmarshalAttribute
 	unmarshalInner
+	unmarshalAny
+	unmarshalRaw
+	unmarshalChildren
 )
 
 // unmarshalMapValue is a helper to decide what kind of map value should be unmarshalled.
@@ -154,6 +290,16 @@ const (
 	mapValueIsCustomType
 	mapValueIsNode
 	mapValueIsNodePointer
+	mapValueIsCollection
+)
+
+// treeNodeType and treeNodePointerType are the types of values, that receive the node itself instead
+// of being unmarshalled from it.
+//
+//nolint:gochecknoglobals // reflect.Types cannot be constants.
+var (
+	treeNodeType        = reflect.TypeOf(parser.TreeNode{})
+	treeNodePointerType = reflect.TypeOf(&parser.TreeNode{})
 )
 
 // UnmarshalError is an error that occurred during unmarshalling.
@@ -180,13 +326,30 @@ func (u UnmarshalError) Error() string {
 	return fmt.Sprintf("cannot unmarshal into '%s', %s", u.Node.Name, u.Detail)
 }
 
-func (u *UnmarshalError) Unwrap() error {
+func (u UnmarshalError) Unwrap() error {
 	return u.wrapping
 }
 
+// Is returns true for token.ErrType, as the node does not fit the Go value it is unmarshalled into.
+func (u UnmarshalError) Is(target error) bool {
+	return target == token.ErrType
+}
+
 // doAny will parse arbitrary contents of the dyml node into the given value.
 // tags are any field tags that may be relevant to process the current node.
 func (u *unmarshaler) doAny(node *parser.TreeNode, value reflect.Value, tags ...string) error {
+	// Trees keep the node as it is, so that mixed content can be read without a custom type.
+	switch value.Type() {
+	case treeNodePointerType:
+		value.Set(reflect.ValueOf(node))
+
+		return nil
+	case treeNodeType:
+		value.Set(reflect.ValueOf(*node))
+
+		return nil
+	}
+
 	// Check for custom unmarshalling method.
 	customUnmarshalMethod := value.MethodByName("UnmarshalDyml")
 
@@ -210,7 +373,21 @@ func (u *unmarshaler) doAny(node *parser.TreeNode, value reflect.Value, tags ...
 		}
 
 		// We have done custom unmarshalling and don't want the default behavior now
-		return nil
+		return u.validate(node, value)
+	}
+
+	if tv, ok := textValues[value.Type()]; ok {
+		return u.doTextValue(node, value, tv)
+	}
+
+	if encoding, ok := binaryEncoding(node, value, tags); ok && value.Kind() != reflect.Ptr {
+		return u.doBinary(node, value, encoding)
+	}
+
+	if kind := textKind(node); kind != token.KindString {
+		if err := checkValueKind(kind, value.Type()); err != nil {
+			return NewUnmarshalError(node, fmt.Sprintf("invalid value for '%s'", value.Type()), err)
+		}
 	}
 
 	switch value.Kind() {
@@ -240,7 +417,7 @@ func (u *unmarshaler) doAny(node *parser.TreeNode, value reflect.Value, tags ...
 			return err
 		}
 	case reflect.Ptr:
-		return u.doPointer(node, value)
+		return u.doPointer(node, value, tags)
 	case reflect.Map:
 		err := u.doMap(node, value, tags)
 		if err != nil {
@@ -252,12 +429,17 @@ func (u *unmarshaler) doAny(node *parser.TreeNode, value reflect.Value, tags ...
 			return err
 		}
 	case reflect.Array:
-		return NewUnmarshalError(node, "arrays not supported, use a slice instead", nil)
+		err := u.doArray(node, value, tags)
+		if err != nil {
+			return err
+		}
 	case reflect.Struct:
 		err := u.doStruct(node, value)
 		if err != nil {
 			return err
 		}
+
+		return u.validate(node, value)
 	default:
 		return NewUnmarshalError(
 			node,
@@ -269,20 +451,11 @@ func (u *unmarshaler) doAny(node *parser.TreeNode, value reflect.Value, tags ...
 
 // doSlice parses the children of the node as a slice into value. tags are needed to infer unmarshalling rules.
 func (u *unmarshaler) doSlice(node *parser.TreeNode, value reflect.Value, tags []string) error {
-	// Figure out type for elements. Should this be a slice we want to know what type is stored in it.
 	elementType := value.Type().Elem()
-	if elementType.Kind() == reflect.Slice {
-		elementType = elementType.Elem()
-	}
 
 	// Create, process and append children
-	for _, child := range nonCommentChildren(node) {
-		if len(tags) > 0 {
-			// Use rename tag to filter for slice elements with the given name.
-			if child.Name != tags[0] {
-				continue
-			}
-		}
+	for _, child := range u.elementChildren(node, tags) {
+		u.pushPath(fmt.Sprintf("[%d]", value.Len()))
 
 		element := reflect.New(elementType).Elem()
 		if err := u.doAny(child, element); err != nil {
@@ -290,11 +463,62 @@ func (u *unmarshaler) doSlice(node *parser.TreeNode, value reflect.Value, tags [
 		}
 
 		value.Set(reflect.Append(value, element))
+		u.record(child.Range)
+		u.popPath()
+	}
+
+	return nil
+}
+
+// doArray parses the children of the node into the array in value, just like doSlice.
+// Elements that do not fit into the array are ignored, in strict mode
+// the number of elements must match the length of the array exactly.
+func (u *unmarshaler) doArray(node *parser.TreeNode, value reflect.Value, tags []string) error {
+	children := u.elementChildren(node, tags)
+
+	if u.strict && len(children) != value.Len() {
+		return NewUnmarshalError(node,
+			fmt.Sprintf("expected %d array elements, but got %d", value.Len(), len(children)), nil)
+	}
+
+	for i, child := range children {
+		if i >= value.Len() {
+			break
+		}
+
+		u.pushPath(fmt.Sprintf("[%d]", i))
+
+		if err := u.doAny(child, value.Index(i)); err != nil {
+			return NewUnmarshalError(node, fmt.Sprintf("cannot read array children for '%s'", node.Name), err)
+		}
+
+		u.record(child.Range)
+		u.popPath()
 	}
 
 	return nil
 }
 
+// elementChildren returns all children of node that should be unmarshalled as elements of a slice or array.
+// A rename tag in tags is used to filter for elements with that name.
+func (u *unmarshaler) elementChildren(node *parser.TreeNode, tags []string) []*parser.TreeNode {
+	children := nonCommentChildren(node)
+
+	if len(tags) == 0 || len(tags[0]) == 0 {
+		return children
+	}
+
+	var result []*parser.TreeNode
+
+	for _, child := range children {
+		if u.namesMatch(child.Name, tags[0]) {
+			result = append(result, child)
+		}
+	}
+
+	return result
+}
+
 // doMap will parse the node as a map into value. tags are needed to infer unmarshalling rules.
 func (u *unmarshaler) doMap(node *parser.TreeNode, value reflect.Value, tags []string) error {
 	mapKeyType := value.Type().Key()
@@ -307,12 +531,14 @@ func (u *unmarshaler) doMap(node *parser.TreeNode, value reflect.Value, tags []s
 
 	// Map value must be primitive or a (pointer to) parser.TreeNode
 	var valueMode unmarshalMapValue
-	if u.isPrimitive(mapValueType) {
+	if u.isPrimitive(indirectType(mapValueType)) {
 		valueMode = mapValueIsPrimitive
-	} else if mapValueType == reflect.TypeOf(parser.TreeNode{}) {
+	} else if mapValueType == treeNodeType {
 		valueMode = mapValueIsNode
-	} else if mapValueType == reflect.TypeOf(&parser.TreeNode{}) {
+	} else if mapValueType == treeNodePointerType {
 		valueMode = mapValueIsNodePointer
+	} else if isSliceOrArray(indirectType(mapValueType)) || indirectType(mapValueType).Kind() == reflect.Map {
+		valueMode = mapValueIsCollection
 	} else {
 		valueMode = mapValueIsCustomType
 	}
@@ -338,15 +564,21 @@ func (u *unmarshaler) doMap(node *parser.TreeNode, value reflect.Value, tags []s
 			return NewUnmarshalError(node, "invalid map key", err)
 		}
 
-		// Now that we parsed the key we continue with parsing the value
+		if u.strict && value.MapIndex(mapKey).IsValid() {
+			return NewUnmarshalError(keyNode, fmt.Sprintf("map key '%v' defined multiple times", mapKey), nil)
+		}
+
+		// Now that we parsed the key we continue with parsing the value.
+		// Collections use all children of the key as their elements, so they may have any number of children.
+		// Strict mode checks them while unmarshalling the collection.
 		keyNodeChildren := nonCommentChildren(keyNode)
-		if len(keyNodeChildren) == 0 {
-			return NewUnmarshalError(node, fmt.Sprintf("no value in map for key '%v'", mapKey), nil)
-		} else if u.strict && len(keyNodeChildren) != 1 {
-			return NewUnmarshalError(node, fmt.Sprintf("key '%v' needs exactly one value", mapKey), nil)
+		if valueMode != mapValueIsCollection {
+			if err := checkMapValue(node, keyNode, mapKey, u.strict); err != nil {
+				return err
+			}
 		}
 
-		valueNode := keyNodeChildren[0]
+		u.pushPath(fmt.Sprintf("[%v]", mapKey))
 
 		// Make mapValue be a zero value of the maps value type
 		mapValue := reflect.New(mapValueType).Elem()
@@ -360,7 +592,14 @@ func (u *unmarshaler) doMap(node *parser.TreeNode, value reflect.Value, tags []s
 			if err := u.doAny(keyNode, mapValue, tags...); err != nil {
 				return err
 			}
+		case mapValueIsCollection:
+			// Tags of the map must not be used for the value, as they would filter the elements.
+			if err := u.doAny(keyNode, mapValue); err != nil {
+				return NewUnmarshalError(node, fmt.Sprintf("invalid value for key '%v'", mapKey), err)
+			}
 		case mapValueIsPrimitive:
+			valueNode := keyNodeChildren[0]
+
 			if u.strict && len(nonCommentChildren(valueNode)) > 0 {
 				return NewUnmarshalError(node, fmt.Sprintf("value for key '%v' must have no children", mapKey), nil)
 			}
@@ -385,21 +624,23 @@ func (u *unmarshaler) doMap(node *parser.TreeNode, value reflect.Value, tags []s
 		}
 
 		value.SetMapIndex(mapKey, mapValue)
+		u.record(keyNode.Range)
+		u.popPath()
 	}
 
 	return nil
 }
 
 // doPointer will dereference the pointer in value or create a new zero value for it,
-// and then parse the node into that.
-func (u *unmarshaler) doPointer(node *parser.TreeNode, value reflect.Value) error {
+// and then parse the node into that. tags are passed on to the dereferenced value.
+func (u *unmarshaler) doPointer(node *parser.TreeNode, value reflect.Value, tags []string) error {
 	// Create value for nil pointer
 	if value.IsNil() {
 		v := reflect.New(value.Type().Elem())
 		value.Set(v)
 	}
 	// Dereference pointer
-	return u.doAny(node, value.Elem())
+	return u.doAny(node, value.Elem(), tags...)
 }
 
 // doFloat parses the node as a float into value.
@@ -503,52 +744,50 @@ func (u *unmarshaler) doString(node *parser.TreeNode, value reflect.Value) error
 
 // doStruct parses the node as a struct into value.
 func (u *unmarshaler) doStruct(node *parser.TreeNode, value reflect.Value) error {
-	// Iterate over all struct fields.
-	for i := 0; i < value.NumField(); i++ {
-		fieldType := value.Type().Field(i)
-		field := value.Field(i)
+	structInfo := cachedStructType(value.Type())
 
-		fieldName := fieldType.Name
-		unmarshalAs := unmarshalNormal
-
-		var tags []string
-
-		// Some tags will change the behavior of how this field will be processed.
-		if structTag, ok := fieldType.Tag.Lookup("dyml"); ok {
-			tags = strings.Split(structTag, ",")
+	// Iterate over all struct fields.
+	for _, info := range structInfo.fields {
+		field := value.Field(info.index)
 
-			// The first tag will rename the field
-			if len(tags) > 0 {
-				rename := tags[0]
-				if len(rename) > 0 {
-					fieldName = rename
+		// Fields of embedded structs are read from the current node, unless the struct is renamed.
+		if info.embedded {
+			if embedded, ok := u.embeddedStruct(field); ok {
+				if err := u.doStruct(node, embedded); err != nil {
+					return err
 				}
-			}
 
-			// The second tag indicates the type we are parsing
-			if len(tags) > 1 {
-				as := tags[1]
-				switch as {
-				case "attr":
-					unmarshalAs = unmarshalAttribute
-				case "inner":
-					unmarshalAs = unmarshalInner
-				case "":
-					unmarshalAs = unmarshalNormal
-				default:
-					return NewUnmarshalError(node, fmt.Sprintf("field type '%s' invalid", as), nil)
-				}
+				continue
 			}
 		}
 
-		switch unmarshalAs {
+		if info.squash {
+			return NewUnmarshalError(node, fmt.Sprintf("cannot squash field '%s', it is not a struct", info.goName), nil)
+		}
+
+		if info.invalidAs != "" {
+			return NewUnmarshalError(node, fmt.Sprintf("field type '%s' invalid", info.invalidAs), nil)
+		}
+
+		fieldName := info.documentName(u.naming)
+		tags := info.tags
+
+		u.pushPath(info.goName)
+
+		switch info.as {
 		case unmarshalNormal:
 			// Should the field be a slice and a rename param is set, then we need to pass the whole node in,
 			// not just a subnode, to allow for filtering of elements.
-			if field.Kind() == reflect.Slice && len(tags) > 0 && len(tags[0]) > 0 {
-				if err := u.doSlice(node, field, tags); err != nil {
+			if info.filtered {
+				if err := u.doAny(node, allocate(field), tags...); err != nil {
 					return err
 				}
+
+				if err := u.checkOneOf(info, field, node.Range); err != nil {
+					return err
+				}
+
+				u.record(node.Range)
 			} else {
 				nodeForField, err := u.findSingleChild(node, fieldName)
 				if err != nil {
@@ -556,28 +795,47 @@ func (u *unmarshaler) doStruct(node *parser.TreeNode, value reflect.Value) error
 				}
 
 				if nodeForField == nil {
+					u.popPath()
+
 					continue
 				}
 
 				err = u.doAny(nodeForField, field, tags...)
 				if err != nil {
-					return NewUnmarshalError(node, fmt.Sprintf("while processing field '%s'", fieldType.Name), err)
+					return NewUnmarshalError(node, fmt.Sprintf("while processing field '%s'", info.goName), err)
+				}
+
+				if err := u.checkOneOf(info, field, nodeForField.Range); err != nil {
+					return err
 				}
+
+				u.record(nodeForField.Range)
 			}
 		case unmarshalAttribute:
-			attr := node.Attributes.Get(fieldName)
+			if isAttributeList(info, field) {
+				if err := u.doAttributeList(node, info, fieldName, field); err != nil {
+					return err
+				}
+
+				break
+			}
+
+			attr := u.findAttribute(node, fieldName)
 			if attr != nil {
-				// We have everything ready to set the attribute.
-				// We want to handle integers and strings easily so we recurse here by creating a fake node.
-				// As this node is a string, it can *only* be parsed as a primitive type, everything else
-				// will return an error, just like we want.
-				fakeNode := parser.NewStringNode(attr.Value)
+				doAttribute := u.doAttribute
+				if info.base64 {
+					doAttribute = u.doBinaryAttribute
+				}
 
-				err := u.doAny(fakeNode, field)
-				if err != nil {
-					// We throw away the error, as it was created with a fake node containing useless information.
-					return NewUnmarshalError(node, fmt.Sprintf("attribute '%s' requires primitve type", fieldName), nil)
+				if err := doAttribute(node, attr, field); err != nil {
+					return err
+				}
+
+				if err := u.checkOneOf(info, field, attr.Range); err != nil {
+					return err
 				}
+
+				u.record(attr.Range)
 			} else if u.strict {
 				return NewUnmarshalError(node, fmt.Sprintf("attribute '%s' required", fieldName), nil)
 			}
@@ -585,17 +843,369 @@ func (u *unmarshaler) doStruct(node *parser.TreeNode, value reflect.Value) error
 			if err := u.doAny(node, field); err != nil {
 				return NewUnmarshalError(node, "'inner' struct tag caused an error", err)
 			}
+
+			u.record(node.Range)
+		case unmarshalAny:
+			if err := u.doUnknownChildren(node, structInfo.elements, field); err != nil {
+				return err
+			}
+		case unmarshalChildren:
+			if err := u.doChildren(node, field); err != nil {
+				return err
+			}
+		case unmarshalRaw:
+			nodeForField, err := u.findSingleChild(node, fieldName)
+			if err != nil {
+				return err
+			}
+
+			if nodeForField == nil {
+				u.popPath()
+
+				continue
+			}
+
+			if err := u.doRaw(nodeForField, field); err != nil {
+				return err
+			}
+
+			u.record(nodeForField.Range)
 		default:
 			// Should never happen. We provide a helpful message just in case.
-			return fmt.Errorf("unmarshal in invalid state: unmarshalType=%v. this is a bug", unmarshalAs)
+			return fmt.Errorf("unmarshal in invalid state: unmarshalType=%v. this is a bug", info.as)
+		}
+
+		u.popPath()
+	}
+
+	return nil
+}
+
+// checkOneOf returns a positional error at rng, if the field has a 'oneof' tag and its value,
+// or any element of a slice or array, is not one of the allowed values.
+func (u *unmarshaler) checkOneOf(info structField, field reflect.Value, rng token.Position) error {
+	if len(info.oneOf) == 0 {
+		return nil
+	}
+
+	value := field
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+
+		value = value.Elem()
+	}
+
+	values := []reflect.Value{value}
+
+	if isSliceOrArray(value.Type()) {
+		values = values[:0]
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, reflect.Indirect(value.Index(i)))
+		}
+	}
+
+	for _, v := range values {
+		text, err := marshalPrimitive(v)
+		if err != nil {
+			return fmt.Errorf("'oneof' struct tag of field '%s' requires a primitive type: %w", info.goName, err)
+		}
+
+		if !containsString(info.oneOf, text) {
+			return token.NewPosError(rng, fmt.Sprintf("'%s' is not a valid value for '%s', expected one of: %s",
+				text, info.goName, strings.Join(info.oneOf, ", "))).SetCategory(token.ErrType)
+		}
+	}
+
+	return nil
+}
+
+// containsString returns true if s is in list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
 		}
 	}
 
+	return false
+}
+
+// doRaw sets value, which must be a string or []byte, to the source text of the children of node.
+func (u *unmarshaler) doRaw(node *parser.TreeNode, value reflect.Value) error {
+	if u.source == nil {
+		return NewUnmarshalError(node, "'raw' struct tag requires the source text, which parsed trees do not have", nil)
+	}
+
+	value = allocate(value)
+	content := u.source.Content(node)
+
+	switch {
+	case value.Kind() == reflect.String:
+		value.SetString(content)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		value.SetBytes([]byte(content))
+	default:
+		return NewUnmarshalError(node,
+			fmt.Sprintf("'raw' struct tag requires a string or []byte, not '%s'", value.Type()), nil)
+	}
+
+	return nil
+}
+
+// doUnknownChildren appends all child elements of node, whose names are not in elements, to the slice in value.
+// Elements of type parser.TreeNode and *parser.TreeNode are set to the child itself.
+func (u *unmarshaler) doUnknownChildren(node *parser.TreeNode, elements []structField, value reflect.Value) error {
+	slice := allocate(value)
+	if slice.Kind() != reflect.Slice {
+		return NewUnmarshalError(node, fmt.Sprintf("'any' struct tag requires a slice, not '%s'", slice.Type()), nil)
+	}
+
+	elementType := slice.Type().Elem()
+
+	for _, child := range node.Children {
+		if !child.IsNode() || u.isKnownElement(elements, child.Name) {
+			continue
+		}
+
+		u.pushPath(fmt.Sprintf("[%d]", slice.Len()))
+
+		element := reflect.New(elementType).Elem()
+		if err := u.doAny(child, element); err != nil {
+			return NewUnmarshalError(node, fmt.Sprintf("cannot read unknown child '%s'", child.Name), err)
+		}
+
+		slice.Set(reflect.Append(slice, element))
+		u.record(child.Range)
+		u.popPath()
+	}
+
+	return nil
+}
+
+// doAttribute parses the value of the attribute into value. node is the node the attribute belongs to.
+// Types implementing AttrUnmarshaler will unmarshal themselves, everything else must be a primitive.
+func (u *unmarshaler) doAttribute(node *parser.TreeNode, attr *util.Attribute, value reflect.Value) error {
+	if custom, ok := attrUnmarshaler(value); ok {
+		if err := custom.UnmarshalDymlAttr(attr.Value); err != nil {
+			return NewUnmarshalError(node, fmt.Sprintf("invalid value for attribute '%s'", attr.Key), err)
+		}
+
+		return nil
+	}
+
+	if tv, ok := textValues[indirectType(value.Type())]; ok {
+		if err := tv.parse(strings.TrimSpace(attr.Value), allocate(value)); err != nil {
+			return NewUnmarshalError(node, fmt.Sprintf("invalid %s for attribute '%s'", tv.name, attr.Key), err)
+		}
+
+		return nil
+	}
+
+	if err := checkValueKind(attr.Kind, value.Type()); err != nil {
+		return NewUnmarshalError(node, fmt.Sprintf("invalid value for attribute '%s'", attr.Key), err)
+	}
+
+	// We want to handle integers and strings easily so we recurse here by creating a fake node.
+	// As this node is a string, it can *only* be parsed as a primitive type, everything else
+	// will return an error, just like we want.
+	fakeNode := parser.NewStringNode(attr.Value)
+
+	if err := u.doAny(fakeNode, value); err != nil {
+		// We throw away the error, as it was created with a fake node containing useless information.
+		return NewUnmarshalError(node, fmt.Sprintf("attribute '%s' requires primitve type", attr.Key), nil)
+	}
+
+	return nil
+}
+
+// isAttributeList returns true if field is a slice for all values of an attribute, see doAttributeList.
+// Byte slices, text values and types implementing AttrUnmarshaler are single values.
+func isAttributeList(info structField, field reflect.Value) bool {
+	if info.base64 || field.Kind() != reflect.Slice || field.Type().Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+
+	if _, ok := textValues[field.Type()]; ok {
+		return false
+	}
+
+	return !reflect.PtrTo(field.Type()).Implements(reflect.TypeOf((*AttrUnmarshaler)(nil)).Elem())
+}
+
+// doAttributeList parses the values of all attributes with the given name into the elements of the
+// slice value, e.g. for attributes that were repeated with parser.CollectDuplicateAttributes.
+func (u *unmarshaler) doAttributeList(node *parser.TreeNode, info structField, name string, value reflect.Value) error {
+	attrs := u.findAttributes(node, name)
+	if len(attrs) == 0 {
+		if u.strict {
+			return NewUnmarshalError(node, fmt.Sprintf("attribute '%s' required", name), nil)
+		}
+
+		return nil
+	}
+
+	slice := reflect.MakeSlice(value.Type(), len(attrs), len(attrs))
+
+	for i, attr := range attrs {
+		element := slice.Index(i)
+
+		if err := u.doAttribute(node, attr, element); err != nil {
+			return err
+		}
+
+		if err := u.checkOneOf(info, element, attr.Range); err != nil {
+			return err
+		}
+
+		u.record(attr.Range)
+	}
+
+	value.Set(slice)
+
+	return nil
+}
+
+// checkValueKind returns an error if an attribute or text was written as a number or boolean,
+// but cannot be unmarshalled into the given type. Strings can hold every value.
+func checkValueKind(kind token.ValueKind, t reflect.Type) error {
+	var want token.ValueKind
+
+	switch indirectType(t).Kind() {
+	case reflect.Bool:
+		want = token.KindBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		want = token.KindNumber
+	default:
+		return nil
+	}
+
+	if kind != token.KindString && kind != want {
+		return fmt.Errorf("expected a %s, but got a %s", want, kind)
+	}
+
 	return nil
 }
 
+// attrUnmarshaler returns the AttrUnmarshaler implemented by value or a pointer to it.
+// Nil pointers that implement the interface will be allocated.
+func attrUnmarshaler(value reflect.Value) (AttrUnmarshaler, bool) {
+	attrUnmarshalerType := reflect.TypeOf((*AttrUnmarshaler)(nil)).Elem()
+
+	if value.Kind() == reflect.Ptr && value.Type().Implements(attrUnmarshalerType) {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		return value.Interface().(AttrUnmarshaler), true //nolint:forcetypeassert
+	}
+
+	if value.CanAddr() && value.Addr().Type().Implements(attrUnmarshalerType) {
+		return value.Addr().Interface().(AttrUnmarshaler), true //nolint:forcetypeassert
+	}
+
+	return nil, false
+}
+
+// embeddedStruct returns the struct value of an embedded field, which might be a pointer to a struct.
+// Nil pointers will be allocated. Returns false if the field does not hold a struct.
+func (u *unmarshaler) embeddedStruct(field reflect.Value) (reflect.Value, bool) {
+	switch field.Kind() {
+	case reflect.Struct:
+		return field, true
+	case reflect.Ptr:
+		if field.Type().Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		if field.IsNil() {
+			if !field.CanSet() {
+				// Pointers to embedded unexported structs cannot be allocated.
+				return reflect.Value{}, false
+			}
+
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+
+		return field.Elem(), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// hasRenameTag returns true if the dyml tag of the field sets a new name.
+func hasRenameTag(field reflect.StructField) bool {
+	tag := field.Tag.Get("dyml")
+
+	return len(strings.Split(tag, ",")[0]) > 0
+}
+
+// pushPath appends an element to the path of the value that is currently being unmarshalled.
+func (u *unmarshaler) pushPath(element string) {
+	u.path = append(u.path, element)
+}
+
+// popPath removes the last element from the path of the value that is currently being unmarshalled.
+func (u *unmarshaler) popPath() {
+	u.path = u.path[:len(u.path)-1]
+}
+
+// record saves the position for the value that is currently being unmarshalled, if provenance is enabled.
+func (u *unmarshaler) record(pos token.Position) {
+	if u.provenance == nil {
+		return
+	}
+
+	var path strings.Builder
+
+	for _, element := range u.path {
+		if path.Len() > 0 && !strings.HasPrefix(element, "[") {
+			path.WriteByte('.')
+		}
+
+		path.WriteString(element)
+	}
+
+	u.provenance[path.String()] = pos
+}
+
+// indirectType returns the type that t points to, following any number of pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// allocate follows all pointers in value, creating new values for nil pointers on the way.
+// The returned value is the first one that is not a pointer.
+func allocate(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		value = value.Elem()
+	}
+
+	return value
+}
+
+// isSliceOrArray returns true if the given type is a slice or an array.
+func isSliceOrArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
 // isPrimitive returns true if the given type is a primitive one.
 func (u *unmarshaler) isPrimitive(t reflect.Type) bool {
+	if _, ok := textValues[t]; ok {
+		return true
+	}
+
 	switch t.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
@@ -606,6 +1216,33 @@ func (u *unmarshaler) isPrimitive(t reflect.Type) bool {
 	}
 }
 
+// textKind returns how the text of node was written, if node is a text or an element with a single text.
+// Literals in G2 can be numbers or booleans, see token.WithLiterals.
+func textKind(node *parser.TreeNode) token.ValueKind {
+	if node.IsText() {
+		return node.TextKind
+	}
+
+	var text *parser.TreeNode
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsComment():
+			continue
+		case !child.IsText() || text != nil:
+			return token.KindString
+		default:
+			text = child
+		}
+	}
+
+	if text == nil {
+		return token.KindString
+	}
+
+	return text.TextKind
+}
+
 // nonCommentChildren returns all children of the given node that are not comments.
 func nonCommentChildren(node *parser.TreeNode) []*parser.TreeNode {
 	var result []*parser.TreeNode
@@ -624,28 +1261,7 @@ func nonCommentChildren(node *parser.TreeNode) []*parser.TreeNode {
 // In non-strict mode this method might return (nil, nil) which means that no such child exists, or it will
 // return the first item with that name.
 func (u *unmarshaler) findSingleChild(node *parser.TreeNode, name string) (*parser.TreeNode, error) {
-	var child *parser.TreeNode
-
-	for _, c := range nonCommentChildren(node) {
-		if c.Name == name {
-			if child == nil {
-				child = c
-
-				if !u.strict {
-					// We found a child and don't care if there are other ones in non-strict mode.
-					break
-				}
-			} else {
-				return nil, NewUnmarshalError(node, fmt.Sprintf("'%s' defined multiple times", name), nil)
-			}
-		}
-	}
-
-	if u.strict && child == nil {
-		return nil, NewUnmarshalError(node, fmt.Sprintf("child '%s' required", name), nil)
-	}
-
-	return child, nil
+	return singleChild(node, name, u.strict, u.namesMatch)
 }
 
 // findText will find text inside the children of the given node or will return the text of a text node directly.
@@ -653,31 +1269,7 @@ func (u *unmarshaler) findSingleChild(node *parser.TreeNode, name string) (*pars
 // In non-strict mode all text children will be concatenated. This might then return an empty string
 // if there are no text children.
 func (u *unmarshaler) findText(node *parser.TreeNode) (string, error) {
-	if node.IsText() {
-		return *node.Text, nil
-	}
-
-	foundAny := false
-
-	var text strings.Builder
-
-	for _, c := range nonCommentChildren(node) {
-		if c.IsText() {
-			if foundAny && u.strict {
-				return "", NewUnmarshalError(node, "multiple occurrences of text, where only one is allowed", nil)
-			}
-
-			foundAny = true
-
-			text.WriteString(*c.Text)
-		}
-	}
-
-	if u.strict && !foundAny {
-		return "", NewUnmarshalError(node, "text inside element required", nil)
-	}
-
-	return text.String(), nil
+	return singleText(node, u.strict)
 }
 
 // getAsText will return a string from the given node.
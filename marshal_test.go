@@ -4,14 +4,16 @@
 package dyml_test
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/golangee/dyml/compare"
 	"github.com/golangee/dyml/parser"
-	"github.com/r3labs/diff/v2"
+	"github.com/golangee/dyml/token"
 
 	. "github.com/golangee/dyml"
 )
@@ -32,8 +34,7 @@ func ExampleUnmarshal() {
 	// Output: Hello 3 year old Gopher !
 }
 
-//nolint:govet
-func ExampleUnmarshal_Slice() {
+func ExampleUnmarshal_slice() {
 	type SimpleSlice struct {
 		Nums []int
 	}
@@ -50,11 +51,10 @@ func ExampleUnmarshal_Slice() {
 	// Output: [1 2 3]
 }
 
-// ExampleComplexSlice demonstrates more complex slice usage.
+// ExampleUnmarshal_complexSlice demonstrates more complex slice usage.
 // Values will be placed in the correct slices because they
 // have a rename tag set.
-//nolint:govet
-func ExampleUnmarshal_ComplexSlice() {
+func ExampleUnmarshal_complexSlice() {
 	type Animal struct {
 		Name string `dyml:"name,attr"`
 		Age  uint   `dyml:"age"`
@@ -95,6 +95,10 @@ type CustomUnmarshal struct {
 func (c *CustomUnmarshal) UnmarshalDyml(node *parser.TreeNode) error {
 	for _, add := range node.Children {
 		if add.Name == "Add" {
+			if len(add.Children) == 0 || !add.Children[0].IsText() {
+				return errors.New("Add requires a number")
+			}
+
 			iNode := add.Children[0]
 
 			i, err := strconv.Atoi(strings.TrimSpace(*iNode.Text))
@@ -109,6 +113,41 @@ func (c *CustomUnmarshal) UnmarshalDyml(node *parser.TreeNode) error {
 	return nil
 }
 
+// Level is used to test the AttrUnmarshaler interface with an enum.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelError
+)
+
+func (l *Level) UnmarshalDymlAttr(value string) error {
+	switch value {
+	case "debug":
+		*l = LevelDebug
+	case "error":
+		*l = LevelError
+	default:
+		return fmt.Errorf("unknown level '%s'", value)
+	}
+
+	return nil
+}
+
+// Megabytes is used to test the AttrUnmarshaler interface with a value that has a unit.
+type Megabytes int
+
+func (m *Megabytes) UnmarshalDymlAttr(value string) error {
+	i, err := strconv.Atoi(strings.TrimSuffix(value, "MB"))
+	if err != nil {
+		return err
+	}
+
+	*m = Megabytes(i)
+
+	return nil
+}
+
 func TestUnmarshal(t *testing.T) {
 	// Base for testing
 	type TestCase struct {
@@ -327,6 +366,34 @@ func TestUnmarshal(t *testing.T) {
 		},
 	})
 
+	testCases = append(testCases, TestCase{
+		name: "typed g2 attributes",
+		text: `#! item @Attribute=42 @x=123 @b=true @f=123.456`,
+		into: &SimpleAttribute{},
+		want: &SimpleAttribute{
+			Inner: SimpleAttributeInner{
+				Attribute: "42",
+				Renamed:   123,
+				Boolean:   true,
+				Float:     123.456,
+			},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name:    "number attribute into bool",
+		text:    `#! item @b=1`,
+		into:    &SimpleAttribute{},
+		wantErr: true,
+	})
+
+	testCases = append(testCases, TestCase{
+		name:    "bool attribute into int",
+		text:    `#! item @x=true`,
+		into:    &SimpleAttribute{},
+		wantErr: true,
+	})
+
 	type RequiredAttributeStrictInner struct {
 		Attribute string `dyml:",attr"`
 	}
@@ -434,6 +501,29 @@ func TestUnmarshal(t *testing.T) {
 		}},
 	})
 
+	testCases = append(testCases, TestCase{
+		name: "duplicate map key overwrites in non-strict mode",
+		text: `#! Things {
+					key1 first,
+					key1 second
+				}`,
+		into: &StringStringMap{},
+		want: &StringStringMap{Things: map[string]string{
+			"key1": "second",
+		}},
+	})
+
+	testCases = append(testCases, TestCase{
+		name: "duplicate map key is denied in strict mode",
+		text: `#! Things {
+					key1 first,
+					key1 second
+				}`,
+		into:    &StringStringMap{},
+		strict:  true,
+		wantErr: true,
+	})
+
 	testCases = append(testCases, TestCase{
 		name: "map with comments",
 		text: `#! Things {
@@ -450,6 +540,25 @@ func TestUnmarshal(t *testing.T) {
 		}},
 	})
 
+	type SignedValues struct {
+		Ints   []int
+		Floats []float64
+		Lookup map[int]int8
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "unquoted negative numbers",
+		text: `#! Ints { -1, 2, -3 }
+				#! Floats { -0.5 }
+				#! Lookup { -1 -2, 3 -4 }`,
+		into: &SignedValues{},
+		want: &SignedValues{
+			Ints:   []int{-1, 2, -3},
+			Floats: []float64{-0.5},
+			Lookup: map[int]int8{-1: -2, 3: -4},
+		},
+	})
+
 	type BoolFloatMap struct {
 		Things map[bool]float64
 	}
@@ -558,6 +667,303 @@ func TestUnmarshal(t *testing.T) {
 		want: &CustomUnmarshal{Sum: 6},
 	})
 
+	type EmbeddedBase struct {
+		Name string
+		ID   int `dyml:"id,attr"`
+	}
+
+	type Embedding struct {
+		EmbeddedBase
+		Age int
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "embedded struct",
+		text: `#Name{Gopher} #Age 3`,
+		into: &Embedding{},
+		want: &Embedding{
+			EmbeddedBase: EmbeddedBase{Name: "Gopher"},
+			Age:          3,
+		},
+	})
+
+	type EmbeddingPointer struct {
+		*EmbeddedBase
+		Age int
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "embedded struct pointer",
+		text: `#Name{Gopher} #Age 3`,
+		into: &EmbeddingPointer{},
+		want: &EmbeddingPointer{
+			EmbeddedBase: &EmbeddedBase{Name: "Gopher"},
+			Age:          3,
+		},
+	})
+
+	type EmbeddingRenamed struct {
+		EmbeddedBase `dyml:"base"`
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "renamed embedded struct is a child",
+		text: `#Name Wrong #base @id{7} { #Name{Gopher} }`,
+		into: &EmbeddingRenamed{},
+		want: &EmbeddingRenamed{
+			EmbeddedBase: EmbeddedBase{Name: "Gopher", ID: 7},
+		},
+	})
+
+	type SquashedTimestamps struct {
+		Created int `dyml:"created,attr"`
+		Updated int
+	}
+
+	type SquashedItem struct {
+		Name    string
+		Times   SquashedTimestamps  `dyml:",squash"`
+		Flat    *SquashedTimestamps `dyml:"ignored,flatten"`
+		Unknown []*parser.TreeNode  `dyml:",any"`
+	}
+
+	type Squashing struct {
+		Item SquashedItem `dyml:"item"`
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "squashed struct",
+		text: `#item @created{5} {#Name{Gopher} #Updated 6}`,
+		into: &Squashing{},
+		want: &Squashing{
+			Item: SquashedItem{
+				Name:  "Gopher",
+				Times: SquashedTimestamps{Created: 5, Updated: 6},
+				Flat:  &SquashedTimestamps{Created: 5, Updated: 6},
+			},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name: "squashed field not a struct",
+		text: `#Name Gopher`,
+		into: &struct {
+			Name string `dyml:",squash"`
+		}{},
+		wantErr: true,
+	})
+
+	testCases = append(testCases, TestCase{
+		name:    "embedded struct fields are required in strict mode",
+		text:    `#Age 3`,
+		into:    &Embedding{},
+		strict:  true,
+		wantErr: true,
+	})
+
+	type PointerItem struct {
+		Name string `dyml:"name,attr"`
+	}
+
+	type PointerMapValue struct {
+		Value int
+	}
+
+	type PointerSlices struct {
+		Items    []*PointerItem `dyml:"item"`
+		ItemsPtr *[]PointerItem `dyml:"other"`
+		Nums     *[]int
+		NumPtrs  []*int          `dyml:"n"`
+		Deep     **int           `dyml:"deep"`
+		Map      map[string]*int `dyml:"map"`
+		MapPtr   *map[string]*PointerMapValue
+	}
+
+	one, two, three := 1, 2, 3
+	threePtr := &three
+
+	testCases = append(testCases, TestCase{
+		name: "nested pointers",
+		text: `#! item @name="a",
+				#! item @name="b",
+				#! other @name="c",
+				#! Nums { 1, 2 }
+				#! n 1,
+				#! n 2,
+				#! deep 3,
+				#! map { one 1, two 2 }
+				#! MapPtr { x { Value 4 } }`,
+		into: &PointerSlices{},
+		want: &PointerSlices{
+			Items:    []*PointerItem{{Name: "a"}, {Name: "b"}},
+			ItemsPtr: &[]PointerItem{{Name: "c"}},
+			Nums:     &[]int{1, 2},
+			NumPtrs:  []*int{&one, &two},
+			Deep:     &threePtr,
+			Map:      map[string]*int{"one": &one, "two": &two},
+			MapPtr:   &map[string]*PointerMapValue{"x": {Value: 4}},
+		},
+	})
+
+	type NestedSlices struct {
+		Matrix [][]int
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "slice of slices",
+		text: `#! Matrix {
+					row { 1, 2 }
+					row { 3, 4 }
+				}`,
+		into: &NestedSlices{},
+		want: &NestedSlices{
+			Matrix: [][]int{{1, 2}, {3, 4}},
+		},
+	})
+
+	type Arrays struct {
+		Point [3]int
+		Pair  [2]string `dyml:"p"`
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "arrays",
+		text: `#! Point { 1, 2, 3 }
+				#! p "a",
+				#! p "b"`,
+		into: &Arrays{},
+		want: &Arrays{
+			Point: [3]int{1, 2, 3},
+			Pair:  [2]string{"a", "b"},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name: "array with too few and too many elements",
+		text: `#! Point { 1, 2 }
+				#! p "a",
+				#! p "b",
+				#! p "c"`,
+		into: &Arrays{},
+		want: &Arrays{
+			Point: [3]int{1, 2, 0},
+			Pair:  [2]string{"a", "b"},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name: "array length must match in strict mode",
+		text: `#! Point { 1, 2 }
+				#! p "a",
+				#! p "b"`,
+		into:    &Arrays{},
+		strict:  true,
+		wantErr: true,
+	})
+
+	type CollectionMaps struct {
+		Lists  map[string][]string
+		Nested map[string]map[string]int
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "map with slices and maps as values",
+		text: `#! Lists {
+					a { "x", "y" }
+					b { "z" }
+					c {}
+				}
+				#! Nested {
+					first { one 1, two 2 }
+					second { three 3 }
+				}`,
+		into: &CollectionMaps{},
+		want: &CollectionMaps{
+			Lists: map[string][]string{
+				"a": {"x", "y"},
+				"b": {"z"},
+				"c": nil,
+			},
+			Nested: map[string]map[string]int{
+				"first":  {"one": 1, "two": 2},
+				"second": {"three": 3},
+			},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name: "nested map values need exactly one value in strict mode",
+		text: `#! Lists { a { "x" } }
+				#! Nested {
+					first { one 1 2 }
+				}`,
+		into:    &CollectionMaps{},
+		strict:  true,
+		wantErr: true,
+	})
+
+	type StrictCollectionMaps struct {
+		Lists  map[string][]int
+		Pairs  map[string][2]int
+		Nested map[string]map[string]int
+	}
+
+	testCases = append(testCases, TestCase{
+		name: "collection map values may have any number of elements in strict mode",
+		text: `#! Lists {
+					none {}
+					many { 1, 2, 3 }
+				}
+				#! Pairs { p { 1, 2 } }
+				#! Nested { empty {} }`,
+		into:   &StrictCollectionMaps{},
+		strict: true,
+		want: &StrictCollectionMaps{
+			Lists:  map[string][]int{"none": nil, "many": {1, 2, 3}},
+			Pairs:  map[string][2]int{"p": {1, 2}},
+			Nested: map[string]map[string]int{"empty": {}},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name:    "array map values need all elements in strict mode",
+		text:    `#! Pairs { p { 1 } }`,
+		into:    &StrictCollectionMaps{},
+		strict:  true,
+		wantErr: true,
+	})
+
+	type CustomAttributesInner struct {
+		Level   Level      `dyml:"level,attr"`
+		Size    *Megabytes `dyml:"size,attr"`
+		Missing *Megabytes `dyml:"missing,attr"`
+	}
+
+	type CustomAttributes struct {
+		Log CustomAttributesInner `dyml:"log"`
+	}
+
+	size := Megabytes(10)
+
+	testCases = append(testCases, TestCase{
+		name: "custom attribute unmarshal",
+		text: `#log @level{error} @size{10MB}`,
+		into: &CustomAttributes{},
+		want: &CustomAttributes{
+			Log: CustomAttributesInner{
+				Level: LevelError,
+				Size:  &size,
+			},
+		},
+	})
+
+	testCases = append(testCases, TestCase{
+		name:    "custom attribute unmarshal error",
+		text:    `#log @level{verbose}`,
+		into:    &CustomAttributes{},
+		wantErr: true,
+	})
+
 	// Run all test cases
 	t.Parallel()
 
@@ -582,38 +988,367 @@ func TestUnmarshal(t *testing.T) {
 				}
 			}
 
-			differences, err := diff.Diff(tc.want, tc.into)
-			if err != nil {
-				log.Println(fmt.Errorf("cannot compare test result: %w", err))
-				t.SkipNow()
-
-				return
+			for _, difference := range differences("", reflect.ValueOf(tc.want), reflect.ValueOf(tc.into)) {
+				t.Error(difference)
 			}
+		})
+	}
+}
+
+func TestUnmarshalErrorCategories(t *testing.T) {
+	t.Parallel()
+
+	type Config struct {
+		Port  int    `dyml:"port"`
+		Level string `dyml:"level,attr,oneof=debug info"`
+	}
+
+	tests := map[string]error{
+		"#port eighty":          token.ErrType,
+		"#port 80 @level{warn}": token.ErrType,
+		"#port{80":              token.ErrSyntax,
+	}
+
+	for text, want := range tests {
+		var into Config
+		if err := Unmarshal(strings.NewReader(text), &into, false); !errors.Is(err, want) {
+			t.Errorf("%s: expected an error in the category '%v', but got %v", text, want, err)
+		}
+	}
+}
+
+func TestUnmarshalDuplicateMapKey(t *testing.T) {
+	t.Parallel()
+
+	type IntMap struct {
+		Things map[int]string
+	}
+
+	text := `#! Things {
+	1 first,
+	01 second
+}`
+
+	var into IntMap
+
+	err := Unmarshal(strings.NewReader(text), &into, true)
+
+	if err == nil {
+		t.Fatal("expected an error, but got none")
+	}
+
+	// Find the innermost UnmarshalError, which is the one describing the duplicate.
+	var unmarshalErr UnmarshalError
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ue, ok := e.(UnmarshalError); ok { //nolint:errorlint
+			unmarshalErr = ue
+		}
+	}
+
+	// The error must point to the second definition, which is parsed as the same key.
+	if unmarshalErr.Node.Name != "01" || unmarshalErr.Node.Range.Begin().Line != 3 {
+		t.Errorf("error should point to the duplicate key, but points to '%s' at %s",
+			unmarshalErr.Node.Name, unmarshalErr.Node.Range.Begin())
+	}
+}
+
+func TestUnmarshalAny(t *testing.T) {
+	t.Parallel()
+
+	type Base struct {
+		Version int
+	}
+
+	type Extension struct {
+		Value string `dyml:",inner"`
+	}
+
+	type Config struct {
+		Base
+		Name      string
+		Ports     []int              `dyml:"port"`
+		Unknown   []*parser.TreeNode `dyml:",any"`
+		Values    []parser.TreeNode  `dyml:",any"`
+		Extension []Extension        `dyml:",any"`
+	}
+
+	text := `#Version{2} #Name{x} #port{1} #port{2} #color{blue} #size{3}`
+
+	var config Config
+	if err := Unmarshal(strings.NewReader(text), &config, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Version != 2 || config.Name != "x" || len(config.Ports) != 2 {
+		t.Errorf("known fields were not unmarshalled: %+v", config)
+	}
+
+	var names []string
+	for _, node := range config.Unknown {
+		names = append(names, node.Name)
+	}
+
+	if strings.Join(names, ",") != "color,size" {
+		t.Errorf("expected unknown children 'color,size', got '%s'", strings.Join(names, ","))
+	}
+
+	if len(config.Values) != 2 || config.Values[1].Name != "size" {
+		t.Errorf("expected unknown children as values, got %+v", config.Values)
+	}
+
+	if len(config.Extension) != 2 || config.Extension[0].Value != "blue" {
+		t.Errorf("expected unknown children to be unmarshalled, got %+v", config.Extension)
+	}
+
+	var invalid struct {
+		Unknown string `dyml:",any"`
+	}
+
+	if err := Unmarshal(strings.NewReader("#a"), &invalid, false); err == nil {
+		t.Error("expected an error for an 'any' field that is not a slice")
+	}
+}
+
+func TestUnmarshalTreeFields(t *testing.T) {
+	t.Parallel()
+
+	type Article struct {
+		Title    string             `dyml:"title"`
+		Body     *parser.TreeNode   `dyml:"body"`
+		Summary  parser.TreeNode    `dyml:"summary"`
+		Sections []*parser.TreeNode `dyml:"section"`
+	}
+
+	text := `#title{Hello} #body{Some #b{bold} text} #summary{short} #section @id{1} {one} #section @id{2} {two}`
+
+	var article Article
+	if err := Unmarshal(strings.NewReader(text), &article, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if article.Title != "Hello" {
+		t.Errorf("expected the title to be unmarshalled, got '%s'", article.Title)
+	}
+
+	if body := article.Body; body == nil || body.Name != "body" || len(body.Children) != 3 ||
+		body.Children[1].Name != "b" {
+		t.Errorf("expected the mixed content of 'body', got %+v", body)
+	}
+
+	if article.Summary.Name != "summary" || len(article.Summary.Children) != 1 {
+		t.Errorf("expected the element 'summary', got %+v", article.Summary)
+	}
+
+	if len(article.Sections) != 2 || article.Sections[1].Attributes.Get("id").Value != "2" {
+		t.Errorf("expected both sections, got %+v", article.Sections)
+	}
+}
+
+func TestUnmarshalChildren(t *testing.T) {
+	t.Parallel()
+
+	type Paragraph struct {
+		Class   string    `dyml:"class,attr"`
+		Content []Content `dyml:",children"`
+	}
+
+	type Document struct {
+		P Paragraph `dyml:"p"`
+	}
+
+	var doc Document
+	if err := Unmarshal(strings.NewReader("#p @class{note} {Some #? remark #b{bold} text}"), &doc, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	for _, content := range doc.P.Content {
+		switch content.Kind {
+		case ContentText:
+			got = append(got, "text "+strings.TrimSpace(content.Text))
+		case ContentComment:
+			got = append(got, "comment "+strings.TrimSpace(content.Text))
+		case ContentElement:
+			got = append(got, "element "+content.Element.Name)
+		}
+	}
+
+	want := []string{"text Some", "comment remark", "element b", "text text"}
+	if doc.P.Class != "note" || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the children %v in order, got %v", want, got)
+	}
+
+	var invalid struct {
+		Content []string `dyml:",children"`
+	}
+
+	if err := Unmarshal(strings.NewReader("#a"), &invalid, false); err == nil {
+		t.Error("expected an error for a 'children' field that is not a []Content")
+	}
+}
+
+func TestUnmarshalRaw(t *testing.T) {
+	t.Parallel()
+
+	type Step struct {
+		Name   string `dyml:"name,attr"`
+		Script []byte `dyml:"run,raw"`
+	}
+
+	type Pipeline struct {
+		Title  *string `dyml:"title,raw"`
+		Steps  []Step  `dyml:"step"`
+		Script string  `dyml:"script,raw"`
+	}
+
+	text := `#title{A #b{bold} title}
+#step @name{build} {#run{go build ./... && echo "done"}}
+#step @name{test} {#run{go test -run 'Test(A|B)'}}
+#script{a < b #x @y{z}}`
+
+	var pipeline Pipeline
+	if err := Unmarshal(strings.NewReader(text), &pipeline, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if pipeline.Title == nil || *pipeline.Title != "A #b{bold} title" {
+		t.Errorf("unexpected title %v", pipeline.Title)
+	}
+
+	if len(pipeline.Steps) != 2 || string(pipeline.Steps[1].Script) != "go test -run 'Test(A|B)'" {
+		t.Errorf("unexpected steps %+v", pipeline.Steps)
+	}
+
+	if pipeline.Script != "a < b #x @y{z}" {
+		t.Errorf("unexpected script '%s'", pipeline.Script)
+	}
+
+	tree, err := parser.NewParser("", strings.NewReader(text)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UnmarshalTree(tree, &pipeline, false); err == nil {
+		t.Error("expected an error for 'raw' fields in parsed trees")
+	}
+
+	var invalid struct {
+		Script int `dyml:"script,raw"`
+	}
+
+	if err := Unmarshal(strings.NewReader(text), &invalid, false); err == nil {
+		t.Error("expected an error for a 'raw' field that is not a string")
+	}
+}
 
-			// These descriptions map the type of a change to a more readable format.
-			changeTypeDescription := map[string]string{
-				"create": "was added",
-				"update": "is different",
-				"delete": "is missing",
+// differences describes every difference between want and got, which are found at path.
+// Trees are compared with parser.Equal, so that their ranges are ignored. Unexported fields are skipped.
+func differences(path string, want, got reflect.Value) []string {
+	different := func() []string {
+		return []string{fmt.Sprintf("property '%s' is different, expected '%v' but got '%v'",
+			path, printable(want), printable(got))}
+	}
+
+	if !want.IsValid() || !got.IsValid() {
+		if want.IsValid() != got.IsValid() {
+			return different()
+		}
+
+		return nil
+	}
+
+	if want.Type() != got.Type() {
+		return different()
+	}
+
+	if want.Type() == reflect.TypeOf(parser.TreeNode{}) && want.CanAddr() && got.CanAddr() {
+		return treeDifferences(path, want.Addr().Interface().(*parser.TreeNode), got.Addr().Interface().(*parser.TreeNode))
+	}
+
+	var result []string
+
+	switch want.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if want.IsNil() || got.IsNil() {
+			if want.IsNil() != got.IsNil() {
+				return different()
 			}
 
-			if len(differences) > 0 {
-				for _, d := range differences {
-					nicePath := strings.Join(d.Path, ".")
+			return nil
+		}
 
-					// Skip differences on node ranges, as those are too noisy to test.
-					// This is a bit hacky, but is fine for testing. It would be nicer to
-					// have a custom recursive function to compare nodes.
-					if strings.Contains(nicePath, "Range.") {
-						continue
-					}
+		if tree, ok := want.Interface().(*parser.TreeNode); ok {
+			return treeDifferences(path, tree, got.Interface().(*parser.TreeNode))
+		}
 
-					t.Errorf("property '%s' %s, expected '%v' but got '%v'",
-						nicePath,
-						changeTypeDescription[d.Type],
-						d.From, d.To)
-				}
+		return differences(path, want.Elem(), got.Elem())
+	case reflect.Struct:
+		for i := 0; i < want.NumField(); i++ {
+			if field := want.Type().Field(i); field.PkgPath == "" {
+				result = append(result, differences(joinPath(path, field.Name), want.Field(i), got.Field(i))...)
 			}
-		})
+		}
+	case reflect.Slice, reflect.Array:
+		if want.Len() != got.Len() {
+			return different()
+		}
+
+		for i := 0; i < want.Len(); i++ {
+			result = append(result, differences(joinPath(path, strconv.Itoa(i)), want.Index(i), got.Index(i))...)
+		}
+	case reflect.Map:
+		if want.Len() != got.Len() {
+			return different()
+		}
+
+		iter := want.MapRange()
+		for iter.Next() {
+			value := got.MapIndex(iter.Key())
+			if !value.IsValid() {
+				return different()
+			}
+
+			result = append(result, differences(joinPath(path, fmt.Sprint(iter.Key())), iter.Value(), value)...)
+		}
+	default:
+		if !reflect.DeepEqual(printable(want), printable(got)) {
+			return different()
+		}
+	}
+
+	return result
+}
+
+// treeDifferences describes the differences between the trees want and got, which are found at path.
+func treeDifferences(path string, want, got *parser.TreeNode) []string {
+	if parser.Equal(want, got, parser.NormalizeOptions{}) {
+		return nil
+	}
+
+	result := []string{fmt.Sprintf("property '%s' is a different tree", path)}
+	for _, change := range compare.Diff(want, got) {
+		result = append(result, fmt.Sprintf("property '%s' is different: %s", path, change))
+	}
+
+	return result
+}
+
+// joinPath appends a field name or index to path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
 	}
+
+	return path + "." + name
+}
+
+// printable returns the value as an interface, or nil if it cannot be accessed.
+func printable(value reflect.Value) interface{} {
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+
+	return value.Interface()
 }
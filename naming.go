@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/util"
+)
+
+// NamingStrategy converts the name of a go struct field into the name of its element or attribute,
+// e.g. SnakeCase. Use WithNamingStrategy to apply it to all fields without a rename tag.
+type NamingStrategy func(fieldName string) string
+
+// SnakeCase converts a field name into snake case, e.g. "ServerURL" becomes "server_url".
+func SnakeCase(fieldName string) string {
+	return strings.Join(splitWords(fieldName), "_")
+}
+
+// KebabCase converts a field name into kebab case, e.g. "ServerURL" becomes "server-url".
+// Note that names in dyml documents cannot contain '-', so this is only useful for trees
+// that were created in other ways, e.g. read by a CBORDecoder.
+func KebabCase(fieldName string) string {
+	return strings.Join(splitWords(fieldName), "-")
+}
+
+// CamelCase converts a field name into camel case with a lower case first word, e.g. "ServerURL"
+// becomes "serverUrl".
+func CamelCase(fieldName string) string {
+	var sb strings.Builder
+
+	for i, word := range splitWords(fieldName) {
+		if i > 0 {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+
+		sb.WriteString(word)
+	}
+
+	return sb.String()
+}
+
+// splitWords splits a go name into its lower case words. Upper case letters start a new word,
+// unless they are part of an initialism like "URL". Underscores separate words as well.
+func splitWords(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+
+	runes := []rune(name)
+
+	for i, r := range runes {
+		if r == '_' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if !unicode.IsUpper(prev) || nextIsLower {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+
+		word = append(word, unicode.ToLower(r))
+	}
+
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	return words
+}
+
+// documentName returns the name of the element or attribute for the field, which is converted
+// by naming unless it is nil or the field is renamed.
+func (f structField) documentName(naming NamingStrategy) string {
+	if naming != nil && !f.renamed {
+		return naming(f.goName)
+	}
+
+	return f.name
+}
+
+// namesMatch returns true if the name in the document matches the expected name.
+func (u *unmarshaler) namesMatch(name, expected string) bool {
+	if u.caseInsensitive {
+		return strings.EqualFold(name, expected)
+	}
+
+	return name == expected
+}
+
+// findAttribute returns the attribute of node with the given name or nil.
+func (u *unmarshaler) findAttribute(node *parser.TreeNode, name string) *util.Attribute {
+	if !u.caseInsensitive {
+		return node.Attributes.Get(name)
+	}
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		if attr := node.Attributes.GetAt(i); strings.EqualFold(attr.Key, name) {
+			return attr
+		}
+	}
+
+	return nil
+}
+
+// findAttributes returns all attributes of node with the given name in order.
+func (u *unmarshaler) findAttributes(node *parser.TreeNode, name string) []*util.Attribute {
+	if !u.caseInsensitive {
+		return node.Attributes.GetAll(name)
+	}
+
+	var attrs []*util.Attribute
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		if attr := node.Attributes.GetAt(i); strings.EqualFold(attr.Key, name) {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return attrs
+}
+
+// isKnownElement returns true if an element with the given name is read by one of the fields.
+func (u *unmarshaler) isKnownElement(fields []structField, name string) bool {
+	for _, field := range fields {
+		if u.namesMatch(name, field.documentName(u.naming)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		snake string
+		kebab string
+		camel string
+	}{
+		{name: "Port", snake: "port", kebab: "port", camel: "port"},
+		{name: "ServerURL", snake: "server_url", kebab: "server-url", camel: "serverUrl"},
+		{name: "HTTPServer", snake: "http_server", kebab: "http-server", camel: "httpServer"},
+		{name: "MaxConns2", snake: "max_conns2", kebab: "max-conns2", camel: "maxConns2"},
+		{name: "Already_Snake", snake: "already_snake", kebab: "already-snake", camel: "alreadySnake"},
+		{name: "ID", snake: "id", kebab: "id", camel: "id"},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := SnakeCase(test.name); got != test.snake {
+				t.Errorf("expected snake case '%s', but got '%s'", test.snake, got)
+			}
+
+			if got := KebabCase(test.name); got != test.kebab {
+				t.Errorf("expected kebab case '%s', but got '%s'", test.kebab, got)
+			}
+
+			if got := CamelCase(test.name); got != test.camel {
+				t.Errorf("expected camel case '%s', but got '%s'", test.camel, got)
+			}
+		})
+	}
+}
+
+func TestDecoderNaming(t *testing.T) {
+	t.Parallel()
+
+	type Server struct {
+		ListenAddr string `dyml:"listen_addr,attr"`
+		MaxConns   int    `dyml:",attr"`
+	}
+
+	type Config struct {
+		ServerName string
+		Servers    []Server           `dyml:"server"`
+		Retries    int                `dyml:"tries"`
+		Unknown    []*parser.TreeNode `dyml:",any"`
+	}
+
+	tests := []struct {
+		name string
+		text string
+		opts []UnmarshalOption
+		want Config
+	}{
+		{
+			name: "snake case",
+			text: `#server_name{main} #server @listen_addr{:80} @max_conns{5} #tries 3`,
+			opts: []UnmarshalOption{WithNamingStrategy(SnakeCase)},
+			want: Config{ServerName: "main", Servers: []Server{{ListenAddr: ":80", MaxConns: 5}}, Retries: 3},
+		},
+		{
+			name: "camel case",
+			text: `#serverName{main} #server @listen_addr{:80} @maxConns{5}`,
+			opts: []UnmarshalOption{WithNamingStrategy(CamelCase)},
+			want: Config{ServerName: "main", Servers: []Server{{ListenAddr: ":80", MaxConns: 5}}},
+		},
+		{
+			name: "case insensitive",
+			text: `#SERVERNAME{main} #Server @Listen_Addr{:80} @maxconns{5} #Tries 3`,
+			opts: []UnmarshalOption{WithCaseInsensitiveNames()},
+			want: Config{ServerName: "main", Servers: []Server{{ListenAddr: ":80", MaxConns: 5}}, Retries: 3},
+		},
+		{
+			name: "case insensitive snake case",
+			text: `#Server_Name{main} #TRIES 3`,
+			opts: []UnmarshalOption{WithNamingStrategy(SnakeCase), WithCaseInsensitiveNames()},
+			want: Config{ServerName: "main", Retries: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var config Config
+
+			decoder := NewDecoder(test.name, strings.NewReader(test.text), false, test.opts...)
+			if err := decoder.Decode(&config); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(config.Unknown) > 0 {
+				t.Errorf("expected all elements to be known, but '%s' is not", config.Unknown[0].Name)
+			}
+
+			config.Unknown = nil
+
+			if !reflect.DeepEqual(config, test.want) {
+				t.Errorf("expected %+v, but got %+v", test.want, config)
+			}
+		})
+	}
+}
+
+func TestDecoderNamingUnknownElements(t *testing.T) {
+	t.Parallel()
+
+	var config struct {
+		ServerName string
+		Unknown    []string `dyml:",any"`
+	}
+
+	decoder := NewDecoder("", strings.NewReader(`#ServerName{a} #server_name{b} #other{c}`), false,
+		WithNamingStrategy(SnakeCase))
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.ServerName != "b" {
+		t.Errorf("expected the snake case element to be read, but got '%s'", config.ServerName)
+	}
+
+	if want := []string{"a", "c"}; !reflect.DeepEqual(config.Unknown, want) {
+		t.Errorf("expected unknown elements %v, but got %v", want, config.Unknown)
+	}
+}
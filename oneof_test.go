@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/token"
+)
+
+func TestUnmarshalOneOf(t *testing.T) {
+	type Logger struct {
+		Level   string   `dyml:"level,attr,oneof=debug info warn error"`
+		Format  string   `dyml:",oneof=json text"`
+		Outputs []string `dyml:"output,oneof=stdout stderr"`
+		Retries *int     `dyml:",oneof=1 2 3"`
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		wantLine int
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			text: "#Logger @level{warn} {#Format{json} #output{stderr} #Retries{2}}",
+		},
+		{
+			name: "missing values are not checked",
+			text: "#Logger",
+		},
+		{
+			name:     "invalid attribute",
+			text:     "#Logger\n@level{verbose}",
+			wantLine: 2,
+			wantErr:  "'verbose' is not a valid value for 'Level', expected one of: debug, info, warn, error",
+		},
+		{
+			name:     "invalid element",
+			text:     "#Logger {\n#Format{xml}}",
+			wantLine: 2,
+			wantErr:  "'xml' is not a valid value for 'Format', expected one of: json, text",
+		},
+		{
+			name:     "invalid slice element",
+			text:     "#Logger {#output{stdout} #output{file}}",
+			wantLine: 1,
+			wantErr:  "'file' is not a valid value for 'Outputs', expected one of: stdout, stderr",
+		},
+		{
+			name:     "invalid number",
+			text:     "#Logger {#Retries{5}}",
+			wantLine: 1,
+			wantErr:  "'5' is not a valid value for 'Retries', expected one of: 1, 2, 3",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var value struct {
+				Logger Logger
+			}
+
+			err := Unmarshal(strings.NewReader(test.text), &value, false)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			var posErr *token.PosError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected a positional error, but got %v", err)
+			}
+
+			if posErr.Error() != test.wantErr {
+				t.Errorf("expected '%s', but got '%s'", test.wantErr, posErr.Error())
+			}
+
+			if line := posErr.Details[0].Node.Begin().Line; line != test.wantLine {
+				t.Errorf("expected the error in line %d, but got %d", test.wantLine, line)
+			}
+		})
+	}
+}
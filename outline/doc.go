@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package outline extracts the hierarchy of headings from a parsed text-centric dyml tree,
+// e.g. to build a navigation or to fill a #toc element.
+//
+// Headings are elements like #title, which are nested by section elements like #chapter:
+//
+//	#book {
+//		#title{A book}
+//		#chapter @id{ch1} {
+//			#title{Chapter One}
+//		}
+//	}
+//
+// results in the entry "A book" with the child "Chapter One". The names of both kinds of elements
+// can be configured with WithHeadings and WithSections.
+package outline
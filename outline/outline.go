@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package outline
+
+import (
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Entry is a heading in the outline of a document.
+type Entry struct {
+	// Title is the text of the heading, with whitespace collapsed into single spaces.
+	Title string
+	// ID is the @id attribute of the heading or, if it has none, of the section that contains it.
+	// It is empty if neither has one.
+	ID string
+	// Level is the depth of the entry in the outline, starting with 1 for top-level entries.
+	Level int
+	// Node is the heading element.
+	Node *parser.TreeNode
+	// Range is the position of the heading element.
+	Range token.Position
+	// Children are the entries of the headings in the sections that follow this heading.
+	Children []*Entry
+}
+
+// Option is used to configure Extract.
+type Option func(o *options)
+
+// options contains the names of the elements that make up the outline.
+type options struct {
+	headings map[string]bool
+	sections map[string]bool
+}
+
+// WithHeadings sets the names of the heading elements, the default is "title".
+func WithHeadings(names ...string) Option {
+	return func(o *options) {
+		o.headings = set(names)
+	}
+}
+
+// WithSections sets the names of the elements, whose headings are nested below the heading
+// that precedes them. The default names are "book", "part", "chapter" and "section".
+func WithSections(names ...string) Option {
+	return func(o *options) {
+		o.sections = set(names)
+	}
+}
+
+// Extract returns the top-level entries of the outline of the tree below root.
+// Elements that are neither headings nor sections are searched for headings as well, as if
+// their children were part of the enclosing element. Headings within headings are ignored.
+func Extract(root *parser.TreeNode, opts ...Option) []*Entry {
+	o := &options{
+		headings: set([]string{"title"}),
+		sections: set([]string{"book", "part", "chapter", "section"}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	e := &extractor{options: o}
+	e.section(root, nil, 1)
+
+	return e.entries
+}
+
+// extractor collects entries while the tree is walked.
+type extractor struct {
+	*options
+	entries []*Entry
+}
+
+// section collects the headings in the children of node. parent is the entry that new entries
+// belong to and nil for top-level entries.
+func (e *extractor) section(node *parser.TreeNode, parent *Entry, level int) {
+	// current is the most recent heading of this section, which contains following sections.
+	var current *Entry
+
+	var visit func(n *parser.TreeNode)
+
+	visit = func(n *parser.TreeNode) {
+		for _, child := range n.Children {
+			switch {
+			case !child.IsNode():
+				continue
+			case e.headings[child.Name]:
+				current = &Entry{
+					Title: title(child),
+					ID:    id(child, node),
+					Level: level,
+					Node:  child,
+					Range: child.Range,
+				}
+
+				if parent == nil {
+					e.entries = append(e.entries, current)
+				} else {
+					parent.Children = append(parent.Children, current)
+				}
+			case e.sections[child.Name]:
+				if current != nil {
+					e.section(child, current, level+1)
+				} else {
+					e.section(child, parent, level)
+				}
+			default:
+				visit(child)
+			}
+		}
+	}
+
+	visit(node)
+}
+
+// title returns the text of all text nodes below node.
+func title(node *parser.TreeNode) string {
+	var sb strings.Builder
+
+	var collect func(node *parser.TreeNode)
+
+	collect = func(node *parser.TreeNode) {
+		for i, child := range node.Children {
+			switch {
+			case child.IsText():
+				// Whitespace after an element is not part of the following text, but the ranges show it.
+				if i > 0 && node.Children[i-1].IsNode() &&
+					child.Range.BeginPos.Offset > node.Children[i-1].Range.EndPos.Offset {
+					sb.WriteByte(' ')
+				}
+
+				sb.WriteString(*child.Text)
+			case child.IsNode():
+				collect(child)
+			}
+		}
+	}
+
+	collect(node)
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// id returns the @id attribute of the heading or of its section.
+func id(heading, section *parser.TreeNode) string {
+	if attr := heading.Attributes.Get("id"); attr != nil {
+		return attr.Value
+	}
+
+	if attr := section.Attributes.Get("id"); attr != nil {
+		return attr.Value
+	}
+
+	return ""
+}
+
+// set returns a set with all names.
+func set(names []string) map[string]bool {
+	result := make(map[string]bool, len(names))
+	for _, name := range names {
+		result[name] = true
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package outline_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/outline"
+	"github.com/golangee/dyml/parser"
+)
+
+// format returns one line for each entry with its level, title, id and line, indented by its level.
+func format(entries []*outline.Entry) string {
+	var sb strings.Builder
+
+	var write func(entries []*outline.Entry)
+
+	write = func(entries []*outline.Entry) {
+		for _, entry := range entries {
+			sb.WriteString(fmt.Sprintf("%s%d %s #%s @%d\n",
+				strings.Repeat("  ", entry.Level-1), entry.Level, entry.Title, entry.ID, entry.Range.BeginPos.Line))
+			write(entry.Children)
+		}
+	}
+
+	write(entries)
+
+	return sb.String()
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts []outline.Option
+		want string
+	}{
+		{
+			name: "empty",
+			text: "some text",
+			want: "",
+		},
+		{
+			name: "book example",
+			text: `#book {
+					#toc{}
+					#section @id{1} {
+					  #title {
+						  The sections title
+					  }
+
+					  The sections text.
+					}
+				  }`,
+			want: "1 The sections title #1 @4\n",
+		},
+		{
+			name: "nested chapters",
+			text: `#book @id{my-book} {
+							#title { A very simple book }
+							#chapter @id{ch1} {
+								#title { Chapter #bold{One} }
+								#section { #title @id{intro} {Intro} }
+							}
+							#p{ #chapter { #title{Hidden in a paragraph} } }
+							#chapter { #title { Chapter Two } #title { Chapter Three } }
+						}`,
+			want: "1 A very simple book #my-book @2\n" +
+				"  2 Chapter One #ch1 @4\n" +
+				"    3 Intro #intro @5\n" +
+				"  2 Hidden in a paragraph # @7\n" +
+				"  2 Chapter Two # @8\n" +
+				"  2 Chapter Three # @8\n",
+		},
+		{
+			name: "sections without headings",
+			text: `#section { #section { #title{Deep} } } #title{Top}`,
+			want: "1 Deep # @1\n1 Top # @1\n",
+		},
+		{
+			name: "custom names",
+			text: `#div { #h{First} #div { #h{Second} } }`,
+			opts: []outline.Option{outline.WithHeadings("h"), outline.WithSections("div")},
+			want: "1 First # @1\n  2 Second # @1\n",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := format(outline.Extract(tree, test.opts...))
+			if got != test.want {
+				t.Errorf("expected\n%s\nbut got\n%s", test.want, got)
+			}
+		})
+	}
+}
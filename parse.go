@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// ParseFile parses the file at the given path into a tree. The path is used for positional information.
+func ParseFile(path string, opts ...parser.ParserOption) (*parser.TreeNode, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return parser.NewParser(path, file, opts...).Parse()
+}
+
+// ParseString parses s into a tree. The name is used for positional information.
+func ParseString(name, s string, opts ...parser.ParserOption) (*parser.TreeNode, error) {
+	return parser.NewParser(name, strings.NewReader(s), opts...).Parse()
+}
+
+// ParseBytes parses b into a tree. The name is used for positional information.
+func ParseBytes(name string, b []byte, opts ...parser.ParserOption) (*parser.TreeNode, error) {
+	return parser.NewParser(name, bytes.NewReader(b), opts...).Parse()
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestParseHelpers(t *testing.T) {
+	t.Parallel()
+
+	const text = "#item @key{value} text"
+
+	path := filepath.Join(t.TempDir(), "doc.dyml")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	parse := map[string]func(opts ...parser.ParserOption) (*parser.TreeNode, error){
+		"file": func(opts ...parser.ParserOption) (*parser.TreeNode, error) {
+			return dyml.ParseFile(path, opts...)
+		},
+		"string": func(opts ...parser.ParserOption) (*parser.TreeNode, error) {
+			return dyml.ParseString(path, text, opts...)
+		},
+		"bytes": func(opts ...parser.ParserOption) (*parser.TreeNode, error) {
+			return dyml.ParseBytes(path, []byte(text), opts...)
+		},
+	}
+
+	for name, fn := range parse {
+		fn := fn
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := fn()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			item := tree.Children[0]
+			if item.Name != "item" || item.Attributes.Get("key").Value != "value" || *item.Children[0].Text != "text" {
+				t.Errorf("unexpected tree %+v", item)
+			}
+
+			if file := item.Range.BeginPos.File; file != path {
+				t.Errorf("expected file '%s' in positions, got '%s'", path, file)
+			}
+
+			var limitErr token.LimitError
+			if _, err := fn(parser.WithMaxNodes(1)); !errors.As(err, &limitErr) {
+				t.Errorf("expected options to be applied, got %v", err)
+			}
+		})
+	}
+
+	if _, err := dyml.ParseFile(filepath.Join(t.TempDir(), "missing.dyml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected an error for a missing file, got %v", err)
+	}
+}
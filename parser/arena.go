@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+// arenaChunkSize is the number of nodes, children and strings that a nodeArena allocates at once.
+const arenaChunkSize = 256
+
+// nodeArena hands out TreeNodes, children slices and strings from larger chunks, so that building
+// a tree does not need separate allocations for every node. As a node keeps its whole chunk alive,
+// it is only used for trees whose nodes are expected to be live for about the same time.
+type nodeArena struct {
+	nodes    []TreeNode
+	children []*TreeNode
+	strings  []string
+}
+
+// newNodeArena creates an arena that can hold exactly the given number of nodes, children and
+// strings without allocating again. Further requests are served from regular chunks.
+func newNodeArena(nodes, children, strings int) nodeArena {
+	return nodeArena{
+		nodes:    make([]TreeNode, nodes),
+		children: make([]*TreeNode, children),
+		strings:  make([]string, strings),
+	}
+}
+
+// node returns a pointer to a new zero TreeNode.
+func (a *nodeArena) node() *TreeNode {
+	if len(a.nodes) == 0 {
+		a.nodes = make([]TreeNode, arenaChunkSize)
+	}
+
+	node := &a.nodes[0]
+	a.nodes = a.nodes[1:]
+
+	return node
+}
+
+// string returns a pointer to a copy of s.
+func (a *nodeArena) string(s string) *string {
+	if len(a.strings) == 0 {
+		a.strings = make([]string, arenaChunkSize)
+	}
+
+	ptr := &a.strings[0]
+	*ptr = s
+	a.strings = a.strings[1:]
+
+	return ptr
+}
+
+// childSlice returns a copy of children or nil, if there are none.
+// The capacity of the copy is limited to its length, so appending to it never writes into the arena.
+func (a *nodeArena) childSlice(children []*TreeNode) []*TreeNode {
+	if len(children) == 0 {
+		return nil
+	}
+
+	if len(a.children) < len(children) {
+		// Large slices get their own allocation, so that they do not waste most of a chunk.
+		if len(children) > arenaChunkSize/4 {
+			return append([]*TreeNode(nil), children...)
+		}
+
+		a.children = make([]*TreeNode, arenaChunkSize)
+	}
+
+	slice := a.children[:len(children):len(children)]
+	copy(slice, children)
+	a.children = a.children[len(children):]
+
+	return slice
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// largeDocument generates a document with n sections that use G1 and G2 syntax,
+// attributes, forwarding, comments and text.
+func largeDocument(n int) string {
+	var sb strings.Builder
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "#? section %d\n", i)
+		fmt.Fprintf(&sb, "#section @id{s%d} @class{large} {\n", i)
+		sb.WriteString("  #title Some title with text\n")
+		sb.WriteString("  ##note @@important{yes} #p{A paragraph with #b{bold} and #i{italic} text.}\n")
+		sb.WriteString("  #list{#item{one} #item{two} #item{three}}\n")
+		sb.WriteString("}\n")
+		fmt.Fprintf(&sb, "#! impl%d {\n", i)
+		sb.WriteString("  // a comment in g2\n")
+		sb.WriteString("  @@visibility=\"public\"\n")
+		sb.WriteString("  func Run(x int, y string) -> (int, error)\n")
+		sb.WriteString("  return \"text\", value 42\n")
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchmarkParse(b)
+}
+
+func BenchmarkParseInterning(b *testing.B) {
+	benchmarkParse(b, WithLexerOptions(token.WithCharDataInterning(16)))
+}
+
+func benchmarkParse(b *testing.B, opts ...ParserOption) {
+	b.Helper()
+
+	for _, size := range []int{10, 1000} {
+		text := largeDocument(size)
+
+		b.Run(fmt.Sprintf("sections=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+
+			for i := 0; i < b.N; i++ {
+				if _, err := NewParser("bench", strings.NewReader(text), opts...).Parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// smallDocument is a typical request body of a service.
+const smallDocument = `#request @id{42} {#user{gopher} #action{update} #! fields { name "dyml", tags ("a", "b") }}`
+
+func BenchmarkParseSmall(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(smallDocument)))
+
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser("bench", strings.NewReader(smallDocument)).Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParserPool(b *testing.B) {
+	pool := NewParserPool()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(smallDocument)))
+
+	for i := 0; i < b.N; i++ {
+		if _, err := pool.Parse("bench", strings.NewReader(smallDocument)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	tree, err := NewParser("bench", strings.NewReader(largeDocument(100))).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		tree.Clone()
+	}
+}
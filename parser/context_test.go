@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+// endlessReader repeats its pattern forever.
+type endlessReader struct {
+	pattern string
+	offset  int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.pattern[r.offset%len(r.pattern)]
+		r.offset++
+	}
+
+	return len(p), nil
+}
+
+func TestParseContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := ParseContext(context.Background(), "ok", strings.NewReader("#a{#b}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tree.Children) != 1 || tree.Children[0].Name != "a" {
+			t.Errorf("unexpected tree: %s", PrettyValue(tree))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := ParseContext(ctx, "cancelled", strings.NewReader("#a{#b}"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation, but got %v", err)
+		}
+	})
+
+	for _, pattern := range []string{"#a{#b @key{value} text} ", "endless text "} {
+		pattern := pattern
+
+		t.Run("deadline "+pattern, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := ParseContext(ctx, "deadline", &endlessReader{pattern: pattern})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected deadline to be exceeded, but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangee/dyml/token"
+)
+
+// Dump returns a human-readable representation of this subtree for debugging, e.g. in failing tests
+// or bug reports. Each node is written on its own line and indented by two spaces per level:
+//
+//	root {}                          1:1-4:2
+//	  server {} @port="8080"         1:4-4:2
+//	    comment "the host"           2:6-2:14
+//	    text "localhost"             3:3-3:14
+//
+// Elements are written with their name, block type and attributes, texts and comments with their
+// quoted value. Ranges are written as "line:col-line:col" and omitted for nodes without a range.
+func (t *TreeNode) Dump() string {
+	var sb strings.Builder
+
+	dumpNode(&sb, t, 0)
+
+	return sb.String()
+}
+
+// String returns the same as Dump.
+func (t *TreeNode) String() string {
+	return t.Dump()
+}
+
+// dumpNode writes a line for the node and then all of its children one level deeper.
+func dumpNode(sb *strings.Builder, t *TreeNode, depth int) {
+	line := strings.Repeat("  ", depth) + dumpLabel(t)
+
+	if t.Range != (token.Position{}) {
+		// Pad the labels, so that the ranges of short lines are aligned.
+		if pad := 32 - len(line); pad > 0 {
+			line += strings.Repeat(" ", pad)
+		}
+
+		line += " " + dumpRange(t.Range)
+	}
+
+	sb.WriteString(line)
+	sb.WriteByte('\n')
+
+	for _, child := range t.Children {
+		dumpNode(sb, child, depth+1)
+	}
+}
+
+// dumpLabel describes a node without its children and range.
+func dumpLabel(t *TreeNode) string {
+	switch {
+	case t.IsText():
+		return fmt.Sprintf("text %q", *t.Text)
+	case t.IsComment():
+		return fmt.Sprintf("comment %q", *t.Comment)
+	}
+
+	label := t.Name
+	if t.BlockType != BlockNone {
+		label += " " + string(t.BlockType)
+	}
+
+	for i := 0; i < t.Attributes.Len(); i++ {
+		attr := t.Attributes.GetAt(i)
+		label += fmt.Sprintf(" @%s=%q", attr.Key, attr.Value)
+	}
+
+	return label
+}
+
+// dumpRange formats a range as "line:col-line:col".
+func dumpRange(r token.Position) string {
+	return fmt.Sprintf("%d:%d-%d:%d", r.BeginPos.Line, r.BeginPos.Col, r.EndPos.Line, r.EndPos.Col)
+}
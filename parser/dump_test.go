@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tree func(t *testing.T) *TreeNode
+		want string
+	}{
+		{
+			name: "parsed",
+			tree: func(t *testing.T) *TreeNode {
+				t.Helper()
+
+				return mustParse(t, "#! server @port=8080 {\n  // the host\n  \"localhost\"\n}")
+			},
+			want: `root {}                          1:1-4:2
+  server {} @port="8080"         1:4-4:2
+    comment "the host"           2:6-2:14
+    text "localhost"             3:3-3:14
+`,
+		},
+		{
+			name: "constructed without ranges",
+			tree: func(t *testing.T) *TreeNode {
+				t.Helper()
+
+				return NewNode("a").Block(BlockGeneric).AddAttribute("x", "say \"hi\"").AddChildren(
+					NewNode("b").AddChildren(NewStringNode("line\nbreak")),
+					NewStringCommentNode("note"),
+				)
+			},
+			want: `a <> @x="say \"hi\""
+  b
+    text "line\nbreak"
+  comment "note"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := test.tree(t)
+
+			if got := tree.Dump(); got != test.want {
+				t.Errorf("expected\n%s\nbut got\n%s", test.want, got)
+			}
+
+			if got := tree.String(); got != test.want {
+				t.Errorf("expected String to equal Dump, but got\n%s", got)
+			}
+		})
+	}
+}
+
+func TestDumpLongLabel(t *testing.T) {
+	t.Parallel()
+
+	tree := mustParse(t, "#a @description{a rather long attribute value}")
+
+	lines := strings.Split(tree.Dump(), "\n")
+	if want := `  a @description="a rather long attribute value" 1:2-1:47`; lines[1] != want {
+		t.Errorf("expected '%s', but got '%s'", want, lines[1])
+	}
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+// Equal returns true if the trees a and b have the same content after both have been normalized with opts,
+// e.g. to compare a parsed tree with an expected tree in tests.
+// Names, texts, comments, block types and attribute keys and values are compared, ranges, metadata and
+// parser internals are ignored. Use compare.Diff to find out where two trees differ.
+func Equal(a, b *TreeNode, opts NormalizeOptions) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if opts != (NormalizeOptions{}) {
+		a, b = Normalize(a, opts), Normalize(b, opts)
+	}
+
+	return equalNodes(a, b)
+}
+
+// equalNodes compares two nodes and all of their children.
+func equalNodes(a, b *TreeNode) bool {
+	if a.Name != b.Name || a.BlockType != b.BlockType || !equalStrings(a.Text, b.Text) ||
+		!equalStrings(a.Comment, b.Comment) || a.Attributes.Len() != b.Attributes.Len() ||
+		len(a.Children) != len(b.Children) {
+		return false
+	}
+
+	for i := 0; i < a.Attributes.Len(); i++ {
+		attrA, attrB := a.Attributes.GetAt(i), b.Attributes.GetAt(i)
+		if attrA.Key != attrB.Key || attrA.Value != attrB.Value {
+			return false
+		}
+	}
+
+	for i := range a.Children {
+		if !equalNodes(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalStrings returns true if both strings are nil or have the same value.
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
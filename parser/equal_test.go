@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		opts  NormalizeOptions
+		equal bool
+	}{
+		{
+			name:  "different positions",
+			a:     "#a @x{1} {text}",
+			b:     "#a  @x{1}   {text}",
+			equal: true,
+		},
+		{
+			name:  "different attribute values",
+			a:     "#a @x{1}",
+			b:     "#a @x{2}",
+			equal: false,
+		},
+		{
+			name:  "different attribute order",
+			a:     "#a @x{1} @y{2}",
+			b:     "#a @y{2} @x{1}",
+			equal: false,
+		},
+		{
+			name:  "sorted attributes",
+			a:     "#a @x{1} @y{2}",
+			b:     "#a @y{2} @x{1}",
+			opts:  NormalizeOptions{SortAttributes: true},
+			equal: true,
+		},
+		{
+			name:  "different whitespace",
+			a:     "#a{some text}",
+			b:     "#a{ some\n text }",
+			equal: false,
+		},
+		{
+			name:  "collapsed whitespace",
+			a:     "#a{some text}",
+			b:     "#a{ some\n text }",
+			opts:  NormalizeOptions{CollapseWhitespace: true},
+			equal: true,
+		},
+		{
+			name:  "different children",
+			a:     "#a{#b}",
+			b:     "#a{#b #c}",
+			equal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := NewParser("a", strings.NewReader(test.a)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := NewParser("b", strings.NewReader(test.b)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if equal := Equal(a, b, test.opts); equal != test.equal {
+				t.Errorf("expected Equal to return %v, but got %v", test.equal, equal)
+			}
+		})
+	}
+}
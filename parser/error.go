@@ -25,6 +25,21 @@ func NewUnexpectedTokenError(tok token.Token, expected ...token.Type) error {
 	}
 }
 
+// Token returns the token that was not expected.
+func (u UnexpectedTokenError) Token() token.Token {
+	return u.tok
+}
+
+// Expected returns the types of the tokens that were expected instead.
+func (u UnexpectedTokenError) Expected() []token.Type {
+	return append([]token.Type(nil), u.expected...)
+}
+
+// Is returns true for token.ErrSyntax.
+func (u UnexpectedTokenError) Is(target error) bool {
+	return target == token.ErrSyntax
+}
+
 func (u UnexpectedTokenError) Error() string {
 	// Build a pretty string with expected tokens
 	var expectedTokens []string
@@ -60,6 +75,11 @@ func (e ForwardAttrError) Error() string {
 	return "expected a forward attribute"
 }
 
+// Is returns true for token.ErrForwarding.
+func (e ForwardAttrError) Is(target error) bool {
+	return target == token.ErrForwarding
+}
+
 // NewForwardAttrError creates a new ForwardAttrError.
 func NewForwardAttrError() error {
 	return ForwardAttrError{}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestErrorCategories(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{name: "lexer", text: `#! a "text`, want: token.ErrSyntax},
+		{name: "unexpected token", text: "#! a { -> }", want: token.ErrSyntax},
+		{name: "duplicate attribute", text: "#a @x{1} @x{2}", want: token.ErrSyntax},
+		{name: "forward without target", text: "#a {##b}", want: token.ErrForwarding},
+		{name: "forward in G1 line", text: "#! a { # ##b\n}", want: token.ErrForwarding},
+		{name: "forward attribute required", text: "#! @@a=1 @b=2 c", want: token.ErrForwarding},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewParser("", strings.NewReader(test.text)).Parse()
+			if !errors.Is(err, test.want) {
+				t.Fatalf("expected an error in the category '%v', but got %v", test.want, err)
+			}
+
+			if errors.Is(err, token.ErrType) {
+				t.Errorf("expected no type error, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestUnexpectedTokenError(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewParser("", strings.NewReader("#! a { -> }")).Parse()
+
+	var unexpected UnexpectedTokenError
+	if !errors.As(err, &unexpected) {
+		t.Fatalf("expected an UnexpectedTokenError, but got %v", err)
+	}
+
+	if unexpected.Token().Type() != token.TokenG2Arrow || len(unexpected.Expected()) == 0 {
+		t.Errorf("unexpected token %s, expected %v", unexpected.Token().Type(), unexpected.Expected())
+	}
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// delayedForward contains the events of a forwarded node, attribute or text, that passes over
+// more than one following element, e.g. '###item'. The events are passed on to the Visitable
+// once skip more elements have been opened, so that they arrive right before their target.
+type delayedForward struct {
+	events []Event
+	// ends are the positions at which the nodes were closed, one for each EventClose.
+	// They are restored on replay, so that the nodes keep their ranges.
+	ends []token.Pos
+	skip int
+}
+
+// forwardRecording records the events of a forwarded node and its children that is delayed.
+type forwardRecording struct {
+	recorder Recorder
+	// ends are the positions at which the recorded nodes were closed.
+	ends []token.Pos
+	// target is the Visitable that the recorder replaces while the node is visited.
+	target Visitable
+	// depth is the length of openNodes before the recorded node was opened.
+	depth int
+	skip  int
+}
+
+// delayForward keeps the events until skip more elements have been opened.
+func (v *Visitor) delayForward(skip int, ends []token.Pos, events ...Event) {
+	v.delayed = append(v.delayed, delayedForward{events: events, ends: ends, skip: skip})
+}
+
+// startForwardRecording records all following events instead of passing them on,
+// if the forwarded node named name passes over more elements than the next one.
+// The recording ends once the node is closed, see finishForwardRecording.
+func (v *Visitor) startForwardRecording(name token.Identifier) error {
+	if v.forwardSkip == 0 {
+		return nil
+	}
+
+	if v.recording != nil {
+		return nestedForwardError(&name)
+	}
+
+	v.recording = &forwardRecording{target: v.visitMe, depth: len(v.openNodes), skip: v.forwardSkip}
+	v.visitMe = &v.recording.recorder
+
+	return nil
+}
+
+// finishForwardRecording delays the recorded events, if the recorded node has just been closed.
+func (v *Visitor) finishForwardRecording() {
+	if v.recording == nil {
+		return
+	}
+
+	if !v.isCurrentNodeSpecial() {
+		v.recording.ends = append(v.recording.ends, v.lastEnd)
+	}
+
+	if len(v.openNodes) != v.recording.depth {
+		return
+	}
+
+	v.visitMe = v.recording.target
+	v.delayForward(v.recording.skip, v.recording.ends, v.recording.recorder.Events()...)
+	v.recording = nil
+}
+
+// textForward passes forwarded text on or delays it.
+func (v *Visitor) textForward(text token.CharData) error {
+	if v.forwardSkip > 0 {
+		if v.recording != nil {
+			return nestedForwardError(&text)
+		}
+
+		v.delayForward(v.forwardSkip, nil, Event{Kind: EventTextForward, Value: text})
+
+		return nil
+	}
+
+	return v.visitMe.TextForward(text)
+}
+
+// attributeForward passes a forwarded attribute on or delays it, if it passes over skip elements.
+func (v *Visitor) attributeForward(key token.Identifier, value token.CharData, skip int) error {
+	if skip > 0 {
+		if v.recording != nil {
+			return nestedForwardError(&key)
+		}
+
+		v.delayForward(skip, nil, Event{Kind: EventAttributeForward, Name: key, Value: value})
+
+		return nil
+	}
+
+	return v.visitMe.AttributeForward(key, value)
+}
+
+// nestedForwardError is returned for a forward that passes over multiple elements within a node,
+// that passes over multiple elements itself.
+func nestedForwardError(node token.Node) error {
+	return token.NewPosError(
+		node,
+		"cannot forward over multiple elements within a node that is forwarded over multiple elements",
+	).SetHint("use a single '##' or '@@' here").SetCategory(token.ErrForwarding)
+}
+
+// releaseForwards is called before an element is opened. All delayed forwards that do not pass over
+// this element are passed on, so that they are applied to it. The others pass over one element less.
+// With all set, every delayed forward is passed on, which is used once the input ends.
+func (v *Visitor) releaseForwards(all bool) error {
+	if v.recording != nil || len(v.delayed) == 0 {
+		return nil
+	}
+
+	remaining := v.delayed[:0]
+
+	for _, delayed := range v.delayed {
+		if delayed.skip > 0 && !all {
+			delayed.skip--
+			remaining = append(remaining, delayed)
+
+			continue
+		}
+
+		if err := v.replayForward(delayed); err != nil {
+			return err
+		}
+	}
+
+	v.delayed = remaining
+
+	return nil
+}
+
+// replayForward passes the events of a delayed forward on. Nodes are closed at their original end.
+func (v *Visitor) replayForward(delayed delayedForward) error {
+	lastEnd := v.lastEnd
+
+	defer func() {
+		v.lastEnd = lastEnd
+	}()
+
+	ends := delayed.ends
+
+	for i := range delayed.events {
+		if delayed.events[i].Kind == EventClose && len(ends) > 0 {
+			v.lastEnd, ends = ends[0], ends[1:]
+		}
+
+		if err := replayEvent(&delayed.events[i], v.visitMe); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
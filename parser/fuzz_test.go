@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.18
+// +build go1.18
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"hello #item1 world #item2 #item3 more text",
+		"##a @@key{value} #b{#c}",
+		"#? comment\n#! g2 { item @key=\"value\" (a, b) -> (c) <d> }",
+		"#! g2 {\n@@x=\"y\" func Run(x int) -> (int, error)\n#title text\n}",
+		"#! raw `multi\nline` \"\\u00e4\\n\"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		tree, err := NewParser("fuzz", strings.NewReader(text)).Parse()
+		if err == nil && tree == nil {
+			t.Fatal("parser returned neither a tree nor an error")
+		}
+	})
+}
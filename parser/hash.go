@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	"github.com/golangee/dyml/util"
+)
+
+// HashOption can be passed to TreeNode.Hash to change what is considered equal.
+type HashOption func(h *hasher)
+
+// WithUnorderedAttributes ignores the order of attributes, so that "#a @x{1} @y{2}" and
+// "#a @y{2} @x{1}" have the same hash.
+func WithUnorderedAttributes() HashOption {
+	return func(h *hasher) {
+		h.unorderedAttributes = true
+	}
+}
+
+// hasher writes nodes into a hash.
+type hasher struct {
+	hash                hash.Hash
+	unorderedAttributes bool
+	buf                 [binary.MaxVarintLen64]byte
+}
+
+// Hash returns a SHA-256 hash of the content of this subtree, so that semantically equal trees have
+// the same hash, e.g. to detect if a configuration actually changed.
+// Names, attributes, block types and texts are included. Comments, ranges and the kind of attribute
+// values are ignored. Whitespace in texts is normalized like Normalize does with CollapseWhitespace
+// and MergeTexts.
+// As elements without brackets contain their children just like "{}" blocks, both are treated the same.
+func (t *TreeNode) Hash(opts ...HashOption) [sha256.Size]byte {
+	h := &hasher{hash: sha256.New()}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.node(Normalize(t, NormalizeOptions{
+		SortAttributes:     h.unorderedAttributes,
+		CollapseWhitespace: true,
+		DropComments:       true,
+		MergeTexts:         true,
+	}))
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.hash.Sum(nil))
+
+	return sum
+}
+
+// node writes the name, attributes, block type and children of an element, which must be normalized.
+func (h *hasher) node(t *TreeNode) {
+	if t.IsText() {
+		h.kind('t')
+		h.string(*t.Text)
+
+		return
+	}
+
+	h.kind('e')
+	h.string(t.Name)
+
+	blockType := t.BlockType
+	if blockType == BlockNone {
+		blockType = BlockNormal
+	}
+
+	h.string(string(blockType))
+	h.attributes(t.Attributes)
+
+	h.length(len(t.Children))
+
+	for _, child := range t.Children {
+		h.node(child)
+	}
+}
+
+// attributes writes all attributes in their order.
+func (h *hasher) attributes(list util.AttributeList) {
+	h.length(list.Len())
+
+	for i := 0; i < list.Len(); i++ {
+		attr := list.GetAt(i)
+		h.string(attr.Key)
+		h.string(attr.Value)
+	}
+}
+
+// kind writes a byte that distinguishes elements from texts.
+func (h *hasher) kind(k byte) {
+	h.buf[0] = k
+	h.hash.Write(h.buf[:1])
+}
+
+// length writes n as a varint.
+func (h *hasher) length(n int) {
+	size := binary.PutUvarint(h.buf[:], uint64(n))
+	h.hash.Write(h.buf[:size])
+}
+
+// string writes s with its length, so that the boundaries between strings are part of the hash.
+func (h *hasher) string(s string) {
+	h.length(len(s))
+	h.hash.Write([]byte(s))
+}
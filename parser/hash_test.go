@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		opts  []HashOption
+		equal bool
+	}{
+		{
+			name:  "whitespace and comments",
+			a:     "#server @host{a} {#port 80 #name{some   text}}",
+			b:     "#? The main server.\n#server @host{a} {\n\t#port{80}\n\t#name{some\n\ttext}\n}",
+			equal: true,
+		},
+		{
+			name:  "g1 and g2",
+			a:     `#server @host{a} {#port{80}}`,
+			b:     `#! server @host="a" { port "80" }`,
+			equal: true,
+		},
+		{
+			name:  "changed text",
+			a:     `#name{Gopher}`,
+			b:     `#name{Ferris}`,
+			equal: false,
+		},
+		{
+			name:  "changed attribute",
+			a:     `#server @host{a}`,
+			b:     `#server @host{b}`,
+			equal: false,
+		},
+		{
+			name:  "moved text",
+			a:     `#a{x} #b`,
+			b:     `#a #b{x}`,
+			equal: false,
+		},
+		{
+			name:  "string boundaries",
+			a:     `#a @b{cd}`,
+			b:     `#a @bc{d}`,
+			equal: false,
+		},
+		{
+			name:  "block types",
+			a:     `#! a(b)`,
+			b:     `#! a{b}`,
+			equal: false,
+		},
+		{
+			name:  "attribute order",
+			a:     `#a @x{1} @y{2}`,
+			b:     `#a @y{2} @x{1}`,
+			equal: false,
+		},
+		{
+			name:  "unordered attributes",
+			a:     `#a @x{1} @y{2}`,
+			b:     `#a @y{2} @x{1}`,
+			opts:  []HashOption{WithUnorderedAttributes()},
+			equal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := NewParser("a", strings.NewReader(test.a)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := NewParser("b", strings.NewReader(test.b)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if equal := a.Hash(test.opts...) == b.Hash(test.opts...); equal != test.equal {
+				t.Errorf("expected equal hashes to be %v, but got %v", test.equal, equal)
+			}
+		})
+	}
+}
+
+func TestHashSubtree(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader(`#a{#x 1} #b{#x 1}`)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Children[0].Children[0].Hash() != tree.Children[1].Children[0].Hash() {
+		t.Error("expected equal subtrees to have the same hash")
+	}
+
+	if tree.Children[0].Hash() == tree.Children[1].Hash() {
+		t.Error("expected subtrees with different names to have different hashes")
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.23
+// +build go1.23
+
+package parser
+
+import (
+	"iter"
+
+	"github.com/golangee/dyml/util"
+)
+
+// The iterators in this file are the preferred way to traverse a tree, as they do not depend on how
+// the children and attributes of a node are stored. They require Go 1.23 or newer.
+
+// ChildrenIter returns an iterator over all children of this node in order, including texts and comments.
+func (t *TreeNode) ChildrenIter() iter.Seq[*TreeNode] {
+	return func(yield func(*TreeNode) bool) {
+		for _, child := range t.Children {
+			if !yield(child) {
+				return
+			}
+		}
+	}
+}
+
+// ChildrenNamed returns an iterator over all child elements with the given name in order.
+func (t *TreeNode) ChildrenNamed(name string) iter.Seq[*TreeNode] {
+	return func(yield func(*TreeNode) bool) {
+		for _, child := range t.Children {
+			if child.IsNode() && child.Name == name && !yield(child) {
+				return
+			}
+		}
+	}
+}
+
+// AttributesIter returns an iterator over copies of all attributes of this node in order.
+func (t *TreeNode) AttributesIter() iter.Seq[util.Attribute] {
+	return t.Attributes.All()
+}
+
+// DescendantsIter returns an iterator over all descendants of this node in depth-first order,
+// where each node comes before its children. The node itself is not included.
+func (t *TreeNode) DescendantsIter() iter.Seq[*TreeNode] {
+	return func(yield func(*TreeNode) bool) {
+		t.descendants(yield)
+	}
+}
+
+// descendants passes all descendants of this node to yield and returns false once yield did.
+func (t *TreeNode) descendants(yield func(*TreeNode) bool) bool {
+	for _, child := range t.Children {
+		if !yield(child) || !child.descendants(yield) {
+			return false
+		}
+	}
+
+	return true
+}
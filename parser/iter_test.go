@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.23
+// +build go1.23
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+// label returns the name of an element or the value of a text or comment.
+func label(node *TreeNode) string {
+	switch {
+	case node.IsText():
+		return *node.Text
+	case node.IsComment():
+		return *node.Comment
+	}
+
+	return node.Name
+}
+
+func TestIterators(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader("#a @k{1} @l{2} {#b{x} #c #b{#d}} #e")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := tree.Children[0]
+
+	var children []string
+	for child := range a.ChildrenIter() {
+		children = append(children, label(child))
+	}
+
+	if got := strings.Join(children, " "); got != "b c b" {
+		t.Errorf("expected the children 'b c b', but got '%s'", got)
+	}
+
+	count := 0
+	for child := range a.ChildrenNamed("b") {
+		if child.Name != "b" {
+			t.Errorf("expected only elements named b, but got '%s'", child.Name)
+		}
+
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("expected 2 elements named b, but got %d", count)
+	}
+
+	var attributes []string
+	for attr := range a.AttributesIter() {
+		attributes = append(attributes, attr.Key+"="+attr.Value)
+	}
+
+	if got := strings.Join(attributes, " "); got != "k=1 l=2" {
+		t.Errorf("expected the attributes 'k=1 l=2', but got '%s'", got)
+	}
+
+	var descendants []string
+	for node := range tree.DescendantsIter() {
+		descendants = append(descendants, label(node))
+	}
+
+	if got := strings.Join(descendants, " "); got != "a b x c b d e" {
+		t.Errorf("expected the descendants 'a b x c b d e' in depth-first order, but got '%s'", got)
+	}
+
+	descendants = descendants[:0]
+	for node := range tree.DescendantsIter() {
+		if node.IsText() {
+			break
+		}
+
+		descendants = append(descendants, label(node))
+	}
+
+	if got := strings.Join(descendants, " "); got != "a b" {
+		t.Errorf("expected the iteration to stop at the first text, but got '%s'", got)
+	}
+}
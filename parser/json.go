@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// jsonNode is the JSON form of a TreeNode, see TreeNode.MarshalJSON.
+type jsonNode struct {
+	Name         string          `json:"name,omitempty"`
+	Synthetic    bool            `json:"synthetic,omitempty"`
+	Block        BlockType       `json:"block,omitempty"`
+	Attrs        []jsonAttribute `json:"attrs,omitempty"`
+	Text         *string         `json:"text,omitempty"`
+	TextKind     string          `json:"textKind,omitempty"`
+	Comment      *string         `json:"comment,omitempty"`
+	CommentKind  string          `json:"commentKind,omitempty"`
+	CommentRange *jsonRange      `json:"commentRange,omitempty"`
+	Range        *jsonRange      `json:"range,omitempty"`
+	Children     []*TreeNode     `json:"children,omitempty"`
+}
+
+// jsonAttribute is the JSON form of an attribute.
+type jsonAttribute struct {
+	Key   string     `json:"key"`
+	Value string     `json:"value"`
+	Kind  string     `json:"kind,omitempty"`
+	Range *jsonRange `json:"range,omitempty"`
+}
+
+// jsonRange is the JSON form of a token.Position.
+type jsonRange struct {
+	Begin jsonPos `json:"begin"`
+	End   jsonPos `json:"end"`
+}
+
+// jsonPos is the JSON form of a token.Pos.
+type jsonPos struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	UTF16Col int    `json:"utf16Col,omitempty"`
+	Offset   int    `json:"offset"`
+}
+
+// MarshalJSON encodes this node and its descendants in a stable JSON form, so that tools written in
+// other languages can consume parse results. Each node is an object with these optional fields:
+//
+//  name          the name of an element
+//  synthetic     true for the root element created by the parser, see TreeNode.Synthetic
+//  block         the block type of an element, "{}", "()" or "<>"
+//  attrs         the attributes in order, objects with key, value, kind and range
+//  text          the text of a text node
+//  textKind      "number" or "boolean" for texts that were written as such literals, see token.WithLiterals
+//  comment       the text of a comment node
+//  commentKind   "g1", "g2" or "g1line", how a comment was written, see token.CommentKind
+//  commentRange  the range of a comment including its delimiter
+//  range         the begin and end of the node, objects with file, line, col, utf16Col and offset
+//  children      the child nodes in order
+//
+// Empty fields and ranges without positions, like those of nodes created by NewNode, are omitted.
+// The parent of a node is not part of it, see Parent.
+func (t *TreeNode) MarshalJSON() ([]byte, error) {
+	node := jsonNode{
+		Name:      t.Name,
+		Synthetic: t.Synthetic,
+		Block:     t.BlockType,
+		Text:      t.Text,
+		TextKind:  jsonKind(t.TextKind),
+		Comment:   t.Comment,
+		Range:     newJSONRange(t.Range),
+		Children:  t.Children,
+	}
+
+	if t.IsComment() {
+		node.CommentKind = jsonCommentKind(t.CommentKind)
+		node.CommentRange = newJSONRange(t.CommentRange)
+	}
+
+	for i := 0; i < t.Attributes.Len(); i++ {
+		attr := t.Attributes.GetAt(i)
+		node.Attrs = append(node.Attrs, jsonAttribute{
+			Key:   attr.Key,
+			Value: attr.Value,
+			Kind:  jsonKind(attr.Kind),
+			Range: newJSONRange(attr.Range),
+		})
+	}
+
+	return json.Marshal(node)
+}
+
+// UnmarshalJSON decodes a node in the form written by MarshalJSON and links the parents of its descendants.
+func (t *TreeNode) UnmarshalJSON(data []byte) error {
+	var node jsonNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+
+	switch node.Block {
+	case BlockNone, BlockNormal, BlockGroup, BlockGeneric:
+	default:
+		return fmt.Errorf("invalid block type '%s'", node.Block)
+	}
+
+	if node.Text != nil && node.Comment != nil {
+		return errors.New("a node cannot have both a text and a comment")
+	}
+
+	textKind, err := parseJSONKind(node.TextKind)
+	if err != nil {
+		return err
+	}
+
+	commentKind, err := parseJSONCommentKind(node.CommentKind)
+	if err != nil {
+		return err
+	}
+
+	*t = TreeNode{
+		Name:         node.Name,
+		Synthetic:    node.Synthetic,
+		Text:         node.Text,
+		Comment:      node.Comment,
+		TextKind:     textKind,
+		CommentKind:  commentKind,
+		CommentRange: node.CommentRange.position(),
+		Attributes:   util.NewAttributeList(),
+		Children:     node.Children,
+		BlockType:    node.Block,
+		Range:        node.Range.position(),
+	}
+
+	for _, attr := range node.Attrs {
+		kind, err := parseJSONKind(attr.Kind)
+		if err != nil {
+			return err
+		}
+
+		overwritten := t.Attributes.Set(util.Attribute{
+			Key:   attr.Key,
+			Value: attr.Value,
+			Kind:  kind,
+			Range: attr.Range.position(),
+		})
+		if overwritten {
+			return fmt.Errorf("duplicate attribute '%s'", attr.Key)
+		}
+	}
+
+	t.adopt(t.Children)
+
+	return nil
+}
+
+// jsonKind returns the name of kind in the JSON form, which is empty for token.KindString.
+func jsonKind(kind token.ValueKind) string {
+	if kind == token.KindString {
+		return ""
+	}
+
+	return kind.String()
+}
+
+// parseJSONKind is the inverse of jsonKind.
+func parseJSONKind(name string) (token.ValueKind, error) {
+	for _, kind := range []token.ValueKind{token.KindString, token.KindNumber, token.KindBool} {
+		if jsonKind(kind) == name {
+			return kind, nil
+		}
+	}
+
+	return token.KindString, fmt.Errorf("invalid value kind '%s'", name)
+}
+
+// jsonCommentKind returns the name of kind in the JSON form, which is empty for token.CommentNone.
+func jsonCommentKind(kind token.CommentKind) string {
+	if kind == token.CommentNone {
+		return ""
+	}
+
+	return kind.String()
+}
+
+// parseJSONCommentKind is the inverse of jsonCommentKind.
+func parseJSONCommentKind(name string) (token.CommentKind, error) {
+	kinds := []token.CommentKind{token.CommentNone, token.CommentG1, token.CommentG2, token.CommentG1Line}
+	for _, kind := range kinds {
+		if jsonCommentKind(kind) == name {
+			return kind, nil
+		}
+	}
+
+	return token.CommentNone, fmt.Errorf("invalid comment kind '%s'", name)
+}
+
+// newJSONRange returns the JSON form of pos, or nil if it has no positions.
+func newJSONRange(pos token.Position) *jsonRange {
+	if pos == (token.Position{}) {
+		return nil
+	}
+
+	return &jsonRange{Begin: newJSONPos(pos.BeginPos), End: newJSONPos(pos.EndPos)}
+}
+
+func newJSONPos(pos token.Pos) jsonPos {
+	return jsonPos{File: pos.File, Line: pos.Line, Col: pos.Col, UTF16Col: pos.UTF16Col, Offset: pos.Offset}
+}
+
+// position is the inverse of newJSONRange.
+func (r *jsonRange) position() token.Position {
+	if r == nil {
+		return token.Position{}
+	}
+
+	return token.Position{BeginPos: r.Begin.pos(), EndPos: r.End.pos()}
+}
+
+func (p jsonPos) pos() token.Pos {
+	return token.Pos{File: p.File, Line: p.Line, Col: p.Col, UTF16Col: p.UTF16Col, Offset: p.Offset}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"#? comment\n#a @k{v} {#b{text} #c}",
+		"#! server @port=8080 {\n  enabled true\n  ratio 1.5\n  fn @@order=2 Run(x int) -> (int, error)\n" +
+			"  list<string>\n  // note\n  # #b #? line\n}",
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewParser("in.dyml", strings.NewReader(test), WithLexerOptions(token.WithLiterals())).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := json.Marshal(tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got TreeNode
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !Equal(tree, &got, NormalizeOptions{}) || tree.Dump() != got.Dump() {
+				t.Errorf("expected\n%s\nbut got\n%s", tree.Dump(), got.Dump())
+			}
+
+			again, err := json.Marshal(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(again) != string(data) {
+				t.Errorf("expected the same JSON again, but got\n%s\nand\n%s", data, again)
+			}
+
+			for _, child := range got.Children {
+				if child.Parent() != &got {
+					t.Error("expected the parents to be linked")
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	tree := NewNode("root").Block(BlockNormal).AddChildren(
+		NewNode("a").AddAttribute("k", "v").AddChildren(NewStringNode("x")),
+		NewStringCommentNode("c"),
+	)
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"root","block":"{}","children":[` +
+		`{"name":"a","attrs":[{"key":"k","value":"v"}],"children":[{"text":"x"}]},` +
+		`{"comment":"c"}]}`
+
+	if string(data) != want {
+		t.Errorf("expected\n%s\nbut got\n%s", want, data)
+	}
+}
+
+func TestUnmarshalJSONInvalid(t *testing.T) {
+	inputs := []string{
+		`{"name":"a","block":"[]"}`,
+		`{"text":"a","comment":"b"}`,
+		`{"text":"1","textKind":"integer"}`,
+		`{"comment":"a","commentKind":"block"}`,
+		`{"name":"a","attrs":[{"key":"k","value":"1"},{"key":"k","value":"2"}]}`,
+		`{"name":"a","children":[{"name":1}]}`,
+	}
+
+	t.Parallel()
+
+	for _, in := range inputs {
+		input := in
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			var node TreeNode
+			if err := json.Unmarshal([]byte(input), &node); err == nil {
+				t.Errorf("expected an error, but got\n%s", node.Dump())
+			}
+		})
+	}
+}
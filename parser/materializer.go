@@ -0,0 +1,310 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// Materializer is a Visitable that passes all events on to a streaming target, except for the elements
+// that target asks to materialize. The events of such an element are collected instead and target
+// receives it as a TreeNode once it is closed, so that only these elements have to fit into memory,
+// e.g. the occasional structured record in a huge log.
+//
+// Forwarded nodes and attributes in front of a materialized element have already been passed on to
+// target before it is opened, they are part of its tree nonetheless.
+type Materializer struct {
+	target Visitable
+	opts   []ParserOption
+
+	// forwards records the forwarded nodes and attributes since the last element was opened.
+	forwards Recorder
+	// forwardDepth is the number of open forwarded nodes and nodes within them outside of a subtree.
+	forwardDepth int
+	// opening is true while target handles the Open of an element that can be materialized.
+	opening bool
+	// pending is the callback that was passed to Materialize during Open.
+	pending func(tree *TreeNode) error
+
+	// subtree records the events of the materialized element within a root.
+	subtree Recorder
+	// depth is the number of open nodes in subtree, without its root.
+	depth int
+	// done is called with the materialized element once it is closed.
+	done func(tree *TreeNode) error
+}
+
+// NewMaterializer creates a Materializer that passes events on to target. Target usually keeps the
+// Materializer to call Materialize. The options configure the Parser that builds the trees,
+// e.g. WithReturnName if the Visitor uses another return name.
+func NewMaterializer(target Visitable, opts ...ParserOption) *Materializer {
+	return &Materializer{target: target, opts: opts}
+}
+
+// Materialize builds the element whose Open is currently handled by target. All further events up to
+// the Close of the element are not passed on to target. Instead done is called with the element, which
+// has no parent, before target gets the Close, so that Open and Close stay balanced. Errors returned
+// by done stop visiting.
+// Materialize panics if it is called anywhere else than in the Open method of target, elements in
+// forwarded nodes cannot be materialized.
+func (m *Materializer) Materialize(done func(tree *TreeNode) error) {
+	if !m.opening {
+		panic("Materialize must be called in the Open method of the target")
+	}
+
+	m.pending = done
+}
+
+// SetVisitContext passes the context on to target, if it is a ContextVisitable.
+// The context also describes the nodes in materialized elements.
+func (m *Materializer) SetVisitContext(ctx *VisitContext) {
+	if cv, ok := m.target.(ContextVisitable); ok {
+		cv.SetVisitContext(ctx)
+	}
+}
+
+// begin starts recording the element name in subtree, starting with the forwards in front of it.
+func (m *Materializer) begin(name token.Identifier) error {
+	m.done, m.pending = m.pending, nil
+	m.depth = 1
+
+	if err := m.subtree.Open(token.Identifier{Value: DefaultRootName}); err != nil {
+		return err
+	}
+
+	if err := m.subtree.SetBlockType(BlockNormal); err != nil {
+		return err
+	}
+
+	if err := m.forwards.Replay(&m.subtree); err != nil {
+		return err
+	}
+
+	return m.subtree.Open(name)
+}
+
+// end builds the tree of the materialized element, passes it to done and closes it in target.
+func (m *Materializer) end() error {
+	if err := m.subtree.Close(); err != nil {
+		return err
+	}
+
+	if err := m.subtree.Finalize(); err != nil {
+		return err
+	}
+
+	tree, err := m.subtree.Tree(m.opts...)
+	m.subtree.Reset()
+
+	if err != nil {
+		return err
+	}
+
+	element := tree.Children[0]
+	element.parent = nil
+
+	if err := m.done(element); err != nil {
+		return err
+	}
+
+	return m.target.Close()
+}
+
+func (m *Materializer) Open(name token.Identifier) error {
+	if m.depth > 0 {
+		m.depth++
+
+		return m.subtree.Open(name)
+	}
+
+	if m.forwardDepth > 0 {
+		m.forwardDepth++
+
+		if err := m.forwards.Open(name); err != nil {
+			return err
+		}
+
+		return m.target.Open(name)
+	}
+
+	m.opening = true
+	err := m.target.Open(name)
+	m.opening = false
+
+	defer m.forwards.Reset()
+
+	if err != nil {
+		m.pending = nil
+
+		return err
+	}
+
+	if m.pending != nil {
+		return m.begin(name)
+	}
+
+	return nil
+}
+
+func (m *Materializer) Comment(comment token.CharData) error {
+	if m.depth > 0 {
+		return m.subtree.Comment(comment)
+	}
+
+	if m.forwardDepth > 0 {
+		if err := m.forwards.Comment(comment); err != nil {
+			return err
+		}
+	}
+
+	return m.target.Comment(comment)
+}
+
+func (m *Materializer) Text(text token.CharData) error {
+	if m.depth > 0 {
+		return m.subtree.Text(text)
+	}
+
+	if m.forwardDepth > 0 {
+		if err := m.forwards.Text(text); err != nil {
+			return err
+		}
+	}
+
+	return m.target.Text(text)
+}
+
+func (m *Materializer) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	if m.depth > 0 {
+		m.depth++
+
+		return m.subtree.OpenReturnArrow(arrow, name)
+	}
+
+	if m.forwardDepth > 0 {
+		m.forwardDepth++
+
+		if err := m.forwards.OpenReturnArrow(arrow, name); err != nil {
+			return err
+		}
+	} else {
+		m.forwards.Reset()
+	}
+
+	return m.target.OpenReturnArrow(arrow, name)
+}
+
+func (m *Materializer) CloseReturnArrow() error {
+	if m.depth > 0 {
+		m.depth--
+
+		return m.subtree.CloseReturnArrow()
+	}
+
+	if m.forwardDepth > 0 {
+		m.forwardDepth--
+
+		if err := m.forwards.CloseReturnArrow(); err != nil {
+			return err
+		}
+	}
+
+	return m.target.CloseReturnArrow()
+}
+
+func (m *Materializer) SetBlockType(blockType BlockType) error {
+	if m.depth > 0 {
+		return m.subtree.SetBlockType(blockType)
+	}
+
+	if m.forwardDepth > 0 {
+		if err := m.forwards.SetBlockType(blockType); err != nil {
+			return err
+		}
+	}
+
+	return m.target.SetBlockType(blockType)
+}
+
+func (m *Materializer) OpenForward(name token.Identifier) error {
+	if m.depth > 0 {
+		m.depth++
+
+		return m.subtree.OpenForward(name)
+	}
+
+	m.forwardDepth++
+
+	if err := m.forwards.OpenForward(name); err != nil {
+		return err
+	}
+
+	return m.target.OpenForward(name)
+}
+
+func (m *Materializer) TextForward(text token.CharData) error {
+	if m.depth > 0 {
+		return m.subtree.TextForward(text)
+	}
+
+	if err := m.forwards.TextForward(text); err != nil {
+		return err
+	}
+
+	return m.target.TextForward(text)
+}
+
+func (m *Materializer) Close() error {
+	if m.depth > 0 {
+		m.depth--
+
+		if err := m.subtree.Close(); err != nil {
+			return err
+		}
+
+		if m.depth == 0 {
+			return m.end()
+		}
+
+		return nil
+	}
+
+	if m.forwardDepth > 0 {
+		m.forwardDepth--
+
+		if err := m.forwards.Close(); err != nil {
+			return err
+		}
+	}
+
+	return m.target.Close()
+}
+
+func (m *Materializer) Attribute(key token.Identifier, value token.CharData) error {
+	if m.depth > 0 {
+		return m.subtree.Attribute(key, value)
+	}
+
+	if m.forwardDepth > 0 {
+		if err := m.forwards.Attribute(key, value); err != nil {
+			return err
+		}
+	}
+
+	return m.target.Attribute(key, value)
+}
+
+func (m *Materializer) AttributeForward(key token.Identifier, value token.CharData) error {
+	if m.depth > 0 {
+		return m.subtree.AttributeForward(key, value)
+	}
+
+	if err := m.forwards.AttributeForward(key, value); err != nil {
+		return err
+	}
+
+	return m.target.AttributeForward(key, value)
+}
+
+func (m *Materializer) Finalize() error {
+	return m.target.Finalize()
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// recordStream streams a log and materializes all elements named "record".
+type recordStream struct {
+	Recorder
+	materializer *Materializer
+	// opened are the names of all elements opened in the stream.
+	opened []string
+	// records are the materialized elements.
+	records []*TreeNode
+	// err is returned for every materialized element, if set.
+	err error
+}
+
+func (s *recordStream) Open(name token.Identifier) error {
+	s.opened = append(s.opened, name.Value)
+
+	if name.Value == "record" {
+		s.materializer.Materialize(func(tree *TreeNode) error {
+			s.records = append(s.records, tree)
+
+			return s.err
+		})
+	}
+
+	return s.Recorder.Open(name)
+}
+
+func TestMaterializer(t *testing.T) {
+	t.Parallel()
+
+	text := "#line{a} #record @id{1} {#x{y}} #line{b}\n##note #record @id{2} #line{c}"
+
+	stream := &recordStream{}
+	stream.materializer = NewMaterializer(stream)
+
+	visitor := NewVisitor("", strings.NewReader(text))
+	visitor.SetVisitable(stream.materializer)
+
+	if err := visitor.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(stream.opened, " "); got != "root line record line record line" {
+		t.Errorf("expected the materialized elements to be opened in the stream, but got '%s'", got)
+	}
+
+	for i, want := range []string{"#record @id{1} {#x{y}}", "##note #record @id{2}"} {
+		tree, err := NewParser("", strings.NewReader(want)).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i >= len(stream.records) {
+			t.Fatalf("expected %d records, but got %d", i+1, len(stream.records))
+		}
+
+		record := stream.records[i]
+		if record.Parent() != nil {
+			t.Error("expected a record without a parent")
+		}
+
+		if want := Select(tree, "record"); !Equal(want, record, NormalizeOptions{}) {
+			t.Errorf("expected\n%s\nbut got\n%s", PrettyValue(want), PrettyValue(record))
+		}
+	}
+
+	// The stream still contains everything but the content of the records.
+	tree, err := stream.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := NewParser("", strings.NewReader("#line{a} #record #line{b}\n##note #record #line{c}")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !Equal(want, tree, NormalizeOptions{}) {
+		t.Errorf("expected the stream\n%s\nbut got\n%s", PrettyValue(want), PrettyValue(tree))
+	}
+}
+
+func TestMaterializerErrors(t *testing.T) {
+	t.Parallel()
+
+	errRecord := errors.New("invalid record")
+
+	stream := &recordStream{err: errRecord}
+	stream.materializer = NewMaterializer(stream)
+
+	visitor := NewVisitor("", strings.NewReader("#record{#x}"))
+	visitor.SetVisitable(stream.materializer)
+
+	if err := visitor.Run(); !errors.Is(err, errRecord) {
+		t.Errorf("expected the error of the callback, but got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when materializing outside of Open")
+		}
+	}()
+
+	stream.materializer.Materialize(func(*TreeNode) error { return nil })
+}
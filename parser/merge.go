@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+// ChildrenMerge decides how the children of two merged nodes are combined.
+type ChildrenMerge int
+
+const (
+	// MergeKeyed merges children of the overlay into matching children of the base.
+	// Elements match if they have the same name and, if MergeStrategy.Key is set and the overlay element
+	// has that attribute, the same value for it. Otherwise, the n-th overlay element with a name is
+	// matched with the n-th base element with that name. Elements without a match are appended.
+	// Should the overlay contain text, it replaces all text and comments of the base.
+	MergeKeyed ChildrenMerge = iota
+	// MergeAppend appends all children of the overlay to the children of the base.
+	MergeAppend
+	// MergeReplace uses the children of the overlay instead of those of the base,
+	// unless the overlay has no children.
+	MergeReplace
+)
+
+// MergeStrategy configures Merge.
+type MergeStrategy struct {
+	// Children decides how children are combined.
+	Children ChildrenMerge
+	// Key is the name of an attribute that identifies elements in MergeKeyed mode, e.g. "id".
+	Key string
+}
+
+// Merge deep-merges the overlay into the base, e.g. to apply environment specific configuration to defaults.
+// Attributes of the overlay override those of the base and a BlockType set in the overlay replaces the one
+// of the base. See ChildrenMerge for how children are combined.
+// The names of base and overlay are not compared, the result uses the name of the base.
+// Neither base nor overlay are modified, the result is a new tree.
+func Merge(base, overlay *TreeNode, strategy MergeStrategy) *TreeNode {
+	result := base.Clone()
+	mergeInto(result, overlay, strategy)
+
+	return result
+}
+
+// mergeInto merges a copy of overlay into result, which may be modified.
+func mergeInto(result, overlay *TreeNode, strategy MergeStrategy) {
+	attributes := overlay.Attributes
+	for attr := attributes.Pop(); attr != nil; attr = attributes.Pop() {
+		result.Attributes.Set(*attr)
+	}
+
+	if overlay.BlockType != BlockNone {
+		result.BlockType = overlay.BlockType
+	}
+
+	switch strategy.Children {
+	case MergeAppend:
+		for _, child := range overlay.Children {
+			result.AddChildren(child.Clone())
+		}
+	case MergeReplace:
+		if len(overlay.Children) > 0 {
+			result.Children = overlay.Clone().Children
+			result.adopt(result.Children)
+		}
+	case MergeKeyed:
+		mergeKeyed(result, overlay, strategy)
+	}
+}
+
+// mergeKeyed merges the children of overlay into the children of result, see MergeKeyed.
+func mergeKeyed(result, overlay *TreeNode, strategy MergeStrategy) {
+	overlayHasText := false
+
+	for _, child := range overlay.Children {
+		if child.IsText() {
+			overlayHasText = true
+
+			break
+		}
+	}
+
+	// Replace the text and comments of the base by those of the overlay,
+	// placing them where the first text or comment was.
+	if overlayHasText {
+		var children []*TreeNode
+
+		inserted := false
+
+		for _, child := range result.Children {
+			if child.IsNode() {
+				children = append(children, child)
+			} else if !inserted {
+				children = append(children, cloneNonElements(overlay.Children)...)
+				inserted = true
+			}
+		}
+
+		if !inserted {
+			children = append(children, cloneNonElements(overlay.Children)...)
+		}
+
+		result.Children = children
+		result.adopt(children)
+	}
+
+	// matched contains all children of the base that already had an overlay element merged into them.
+	matched := map[*TreeNode]bool{}
+
+	for _, child := range overlay.Children {
+		if !child.IsNode() {
+			continue
+		}
+
+		if match := findMatch(result.Children, child, strategy.Key, matched); match != nil {
+			matched[match] = true
+			mergeInto(match, child, strategy)
+		} else {
+			clone := child.Clone()
+			matched[clone] = true
+			result.AddChildren(clone)
+		}
+	}
+}
+
+// findMatch returns the first element in children that matches node and has not been matched yet.
+func findMatch(children []*TreeNode, node *TreeNode, key string, matched map[*TreeNode]bool) *TreeNode {
+	var keyAttr *string
+
+	if key != "" {
+		if attr := node.Attributes.Get(key); attr != nil {
+			keyAttr = &attr.Value
+		}
+	}
+
+	for _, child := range children {
+		if !child.IsNode() || child.Name != node.Name || matched[child] {
+			continue
+		}
+
+		if keyAttr != nil {
+			if attr := child.Attributes.Get(key); attr == nil || attr.Value != *keyAttr {
+				continue
+			}
+		}
+
+		return child
+	}
+
+	return nil
+}
+
+// cloneNonElements returns copies of all text and comment nodes in nodes.
+func cloneNonElements(nodes []*TreeNode) []*TreeNode {
+	var result []*TreeNode
+
+	for _, node := range nodes {
+		if !node.IsNode() {
+			result = append(result, node.Clone())
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/compare"
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		overlay  string
+		strategy MergeStrategy
+		want     string
+	}{
+		{
+			name:    "attribute override",
+			base:    `#server @host{localhost} @port{80}`,
+			overlay: `#server @port{8080} @tls{true}`,
+			want:    `#server @host{localhost} @port{8080} @tls{true}`,
+		},
+		{
+			name:    "text replaces text",
+			base:    `#! cfg { port "80", host "localhost" }`,
+			overlay: `#! cfg { port "8080" }`,
+			want:    `#! cfg { port "8080", host "localhost" }`,
+		},
+		{
+			name:    "positional matching",
+			base:    `#! list { item "a", item "b" }`,
+			overlay: `#! list { item @x="1", item @x="2", item "c" }`,
+			want:    `#! list { item @x="1" "a", item @x="2" "b", item "c" }`,
+		},
+		{
+			name: "keyed merge",
+			base: `#! service @id="web" { port "80" }
+				#! service @id="db" { port "5432" }`,
+			overlay: `#! service @id="db" { port "6543" }
+				#! service @id="cache" { port "6379" }`,
+			strategy: MergeStrategy{Key: "id"},
+			want: `#! service @id="web" { port "80" }
+				#! service @id="db" { port "6543" }
+				#! service @id="cache" { port "6379" }`,
+		},
+		{
+			name:     "append",
+			base:     `#! list { a, b }`,
+			overlay:  `#! list { c }`,
+			strategy: MergeStrategy{Children: MergeAppend},
+			want:     `#! list { a, b } #! list { c }`,
+		},
+		{
+			name:     "replace",
+			base:     `#! list { a, b }`,
+			overlay:  `#! list { c }`,
+			strategy: MergeStrategy{Children: MergeReplace},
+			want:     `#! list { c }`,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			base := mustParse(t, test.base)
+			overlay := mustParse(t, test.overlay)
+			want := mustParse(t, test.want)
+
+			got := Merge(base, overlay, test.strategy)
+
+			for _, change := range compare.Diff(want, got) {
+				t.Error(change)
+			}
+
+			// The inputs must not be modified.
+			for _, change := range compare.Diff(mustParse(t, test.base), base) {
+				t.Errorf("base was modified: %s", change)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, text string) *TreeNode {
+	t.Helper()
+
+	tree, err := NewParser(t.Name(), strings.NewReader(text)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
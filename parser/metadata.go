@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+// SetMetadata annotates this node with a value and can be used builder-style.
+// Keys should be prefixed with the name of the pass that owns them, e.g. "lint.severity",
+// so that independent passes do not overwrite each other's values.
+func (t *TreeNode) SetMetadata(key string, value interface{}) *TreeNode {
+	if t.Metadata == nil {
+		t.Metadata = map[string]interface{}{}
+	}
+
+	t.Metadata[key] = value
+
+	return t
+}
+
+// GetMetadata returns the value that was set for the key and whether it exists.
+func (t *TreeNode) GetMetadata(key string) (interface{}, bool) {
+	value, ok := t.Metadata[key]
+
+	return value, ok
+}
+
+// RemoveMetadata removes the value for the key, if it exists, and can be used builder-style.
+func (t *TreeNode) RemoveMetadata(key string) *TreeNode {
+	delete(t.Metadata, key)
+
+	return t
+}
+
+// cloneMetadata returns a copy of the metadata map. The values themselves are not copied.
+func (t *TreeNode) cloneMetadata() map[string]interface{} {
+	if t.Metadata == nil {
+		return nil
+	}
+
+	clone := make(map[string]interface{}, len(t.Metadata))
+	for key, value := range t.Metadata {
+		clone[key] = value
+	}
+
+	return clone
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	tree := mustParse(t, "#a #b")
+	a := tree.Children[0]
+
+	if _, ok := a.GetMetadata("lint.checked"); ok {
+		t.Error("expected parsed nodes to have no metadata")
+	}
+
+	a.SetMetadata("lint.checked", true).SetMetadata("doc.anchor", "intro")
+
+	if value, ok := a.GetMetadata("doc.anchor"); !ok || value != "intro" {
+		t.Errorf("expected 'intro', but got %v", value)
+	}
+
+	clone := tree.Clone()
+	clone.Children[0].SetMetadata("doc.anchor", "changed").RemoveMetadata("lint.checked")
+
+	if value, _ := a.GetMetadata("doc.anchor"); value != "intro" {
+		t.Errorf("clone shares metadata with the original, got %v", value)
+	}
+
+	if _, ok := a.GetMetadata("lint.checked"); !ok {
+		t.Error("removing metadata from the clone changed the original")
+	}
+
+	if !Equal(tree, clone, NormalizeOptions{}) {
+		t.Error("expected metadata to be ignored by Equal")
+	}
+
+	if tree.Hash() != clone.Hash() {
+		t.Error("expected metadata to be ignored by Hash")
+	}
+
+	if tree.Children[1].Metadata != nil {
+		t.Error("expected other nodes to have no metadata")
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+// Parent returns the node that contains this node in its Children, or nil for the root of a tree.
+// It is set by the parser, AddChildren and Clone. Call LinkParents after modifying Children directly.
+func (t *TreeNode) Parent() *TreeNode {
+	return t.parent
+}
+
+// NextSibling returns the node that follows this node in the Children of its parent, or nil if there is none.
+func (t *TreeNode) NextSibling() *TreeNode {
+	i := t.index()
+	if i < 0 || i+1 >= len(t.parent.Children) {
+		return nil
+	}
+
+	return t.parent.Children[i+1]
+}
+
+// PrevSibling returns the node that precedes this node in the Children of its parent, or nil if there is none.
+func (t *TreeNode) PrevSibling() *TreeNode {
+	i := t.index()
+	if i <= 0 {
+		return nil
+	}
+
+	return t.parent.Children[i-1]
+}
+
+// LinkParents sets the parent of all descendants of this node, so that Parent and the sibling
+// accessors work again after Children have been modified directly.
+func (t *TreeNode) LinkParents() {
+	for _, child := range t.Children {
+		child.parent = t
+		child.LinkParents()
+	}
+}
+
+// adopt sets the parent of children to this node.
+func (t *TreeNode) adopt(children []*TreeNode) {
+	for _, child := range children {
+		child.parent = t
+	}
+}
+
+// index returns the index of this node in the Children of its parent, or -1 if it has no parent
+// or was removed from it.
+func (t *TreeNode) index() int {
+	if t.parent == nil {
+		return -1
+	}
+
+	for i, child := range t.parent.Children {
+		if child == t {
+			return i
+		}
+	}
+
+	return -1
+}
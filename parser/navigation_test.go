@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestNavigation(t *testing.T) {
+	t.Parallel()
+
+	tree := mustParse(t, "#a {#b #c{text} #d}")
+	a := tree.Children[0]
+	b, c, d := a.Children[0], a.Children[1], a.Children[2]
+
+	if tree.Parent() != nil {
+		t.Error("expected the root to have no parent")
+	}
+
+	if a.Parent() != tree || c.Parent() != a || c.Children[0].Parent() != c {
+		t.Error("expected parsed nodes to know their parents")
+	}
+
+	if b.PrevSibling() != nil || b.NextSibling() != c || c.NextSibling() != d || d.NextSibling() != nil {
+		t.Error("expected the siblings in document order")
+	}
+
+	if d.PrevSibling() != c || tree.NextSibling() != nil {
+		t.Error("expected the previous sibling of d to be c and the root to have no siblings")
+	}
+
+	clone := tree.Clone()
+	cloned := clone.Children[0]
+
+	if cloned.Parent() != clone || cloned.Children[1].NextSibling() != cloned.Children[2] {
+		t.Error("expected the clone to be linked to itself")
+	}
+
+	if a.Clone().Parent() != nil {
+		t.Error("expected a cloned subtree to be detached")
+	}
+
+	e := NewNode("e")
+	c.AddChildren(e)
+
+	if e.Parent() != c || e.PrevSibling() != c.Children[0] {
+		t.Error("expected AddChildren to set the parent")
+	}
+
+	// Modifying Children directly requires linking the parents again.
+	a.Children = []*TreeNode{d, b}
+	if d.NextSibling() != b || c.NextSibling() != nil {
+		t.Error("expected the siblings to follow the new order and removed nodes to have none")
+	}
+
+	f := NewNode("f")
+	a.Children = append(a.Children, f)
+	a.LinkParents()
+
+	if f.Parent() != a || b.NextSibling() != f {
+		t.Error("expected LinkParents to set the parent")
+	}
+
+	if _, err := json.Marshal(tree); err != nil {
+		t.Errorf("expected trees to be marshalled without cycles, but got %v", err)
+	}
+}
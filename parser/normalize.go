@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// NormalizeOptions configures Normalize.
+type NormalizeOptions struct {
+	// SortAttributes sorts the attributes of every element by key.
+	SortAttributes bool
+	// CollapseWhitespace trims texts and replaces runs of whitespace by a single space.
+	// Texts that only consist of whitespace are removed.
+	CollapseWhitespace bool
+	// DropComments removes all comments.
+	DropComments bool
+	// MergeTexts joins adjacent texts into a single text node, which spans the ranges of all of them.
+	MergeTexts bool
+	// ClearRanges removes all positions from nodes and attributes, so that trees parsed from
+	// different sources can be compared directly.
+	ClearRanges bool
+}
+
+// Normalize returns a copy of the tree in a canonical form, e.g. to compare trees without caring
+// about formatting. See NormalizeOptions for the available steps.
+// Comments are dropped before texts are merged, so that texts around a comment are joined.
+// Texts are merged before whitespace is collapsed, so that their original separation is kept.
+// The tree is not modified.
+func Normalize(tree *TreeNode, opts NormalizeOptions) *TreeNode {
+	result := tree.Clone()
+	normalizeInto(result, opts)
+
+	return result
+}
+
+// normalizeInto normalizes node and all of its descendants in place.
+func normalizeInto(node *TreeNode, opts NormalizeOptions) {
+	if opts.SortAttributes {
+		node.Attributes = sortedAttributes(node.Attributes)
+	}
+
+	if opts.ClearRanges {
+		node.Range = token.Position{}
+		node.CommentRange = token.Position{}
+
+		for i := 0; i < node.Attributes.Len(); i++ {
+			node.Attributes.GetAt(i).Range = token.Position{}
+		}
+	}
+
+	if node.IsText() && opts.CollapseWhitespace {
+		collapsed := strings.Join(strings.Fields(*node.Text), " ")
+		node.Text = &collapsed
+	}
+
+	children := node.Children[:0]
+
+	for _, child := range node.Children {
+		if child.IsComment() && opts.DropComments {
+			continue
+		}
+
+		if last := len(children) - 1; opts.MergeTexts && child.IsText() && last >= 0 && children[last].IsText() {
+			merged := *children[last].Text + *child.Text
+			children[last].Text = &merged
+			children[last].TextKind = token.KindString
+			children[last].Range.EndPos = child.Range.EndPos
+
+			continue
+		}
+
+		children = append(children, child)
+	}
+
+	node.Children = children[:0]
+
+	for _, child := range children {
+		normalizeInto(child, opts)
+
+		if child.IsText() && opts.CollapseWhitespace && *child.Text == "" {
+			continue
+		}
+
+		node.Children = append(node.Children, child)
+	}
+}
+
+// sortedAttributes returns a copy of the attributes, which is sorted by key.
+func sortedAttributes(list util.AttributeList) util.AttributeList {
+	attrs := make([]util.Attribute, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		attrs = append(attrs, *list.GetAt(i))
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+
+	sorted := util.NewAttributeList()
+	for _, attr := range attrs {
+		sorted.Add(attr)
+	}
+
+	return sorted
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	g2Comment := NewStringCommentNode("comment")
+	g2Comment.CommentKind = token.CommentG2
+
+	tests := []struct {
+		name string
+		text string
+		opts NormalizeOptions
+		want *TreeNode
+	}{
+		{
+			name: "sort attributes",
+			text: `#a @y{2} @x{1}`,
+			opts: NormalizeOptions{SortAttributes: true, ClearRanges: true},
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("a").AddAttribute("x", "1").AddAttribute("y", "2"),
+			),
+		},
+		{
+			name: "collapse whitespace",
+			text: "#a{  some\n\ttext } #b{ }",
+			opts: NormalizeOptions{CollapseWhitespace: true, ClearRanges: true},
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("a").Block(BlockNormal).AddChildren(NewStringNode("some text")),
+				NewNode("b").Block(BlockNormal),
+			),
+		},
+		{
+			name: "merge texts around comments",
+			text: "#! a { \"x\" // comment\n \"y\" }",
+			opts: NormalizeOptions{DropComments: true, MergeTexts: true, ClearRanges: true},
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("a").Block(BlockNormal).AddChildren(NewStringNode("xy")),
+			),
+		},
+		{
+			name: "keep comments",
+			text: "#! a { \"x\" // comment\n \"y\" }",
+			opts: NormalizeOptions{MergeTexts: true, ClearRanges: true},
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("a").Block(BlockNormal).AddChildren(
+					NewStringNode("x"),
+					g2Comment,
+					NewStringNode("y"),
+				),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The parser marks the root that it created.
+			test.want.Synthetic = true
+
+			got := Normalize(tree, test.opts)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected\n%s\nbut got\n%s", PrettyValue(test.want), PrettyValue(got))
+			}
+		})
+	}
+}
+
+func TestNormalizeDoesNotModify(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader("#a @y{2} @x{1} { text } #! b { \"x\" // comment\n \"y\" }")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := tree.Clone()
+
+	normalized := Normalize(tree, NormalizeOptions{
+		SortAttributes:     true,
+		CollapseWhitespace: true,
+		DropComments:       true,
+		MergeTexts:         true,
+		ClearRanges:        true,
+	})
+
+	if !reflect.DeepEqual(tree, before) {
+		t.Error("expected the tree not to be modified")
+	}
+
+	if normalized.Children[0].Range != (token.Position{}) {
+		t.Error("expected ranges to be cleared")
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// ParserOption can be passed to NewParser to configure the parser.
+// Limits are meant for parsing untrusted input. Exceeding a limit results in a token.PosError,
+// which is caused by a token.LimitError. A limit of 0 means that there is no limit.
+type ParserOption func(p *parserConfig)
+
+// parserConfig collects the configuration of a Parser, before its lexer is created.
+type parserConfig struct {
+	lexerOptions  []token.LexerOption
+	maxDepth      int
+	maxNodes      int
+	maxAttributes int
+	sourceMap     bool
+	// charsetDetection is set by WithCharsetDetection.
+	charsetDetection bool
+	textHooks        []TextHook
+	// uniqueSiblings is set by WithUniqueSiblings, repeatable contains the names that are still allowed
+	// multiple times.
+	uniqueSiblings bool
+	repeatable     map[string]bool
+	// duplicateAttributes is set by WithDuplicateAttributes.
+	duplicateAttributes DuplicateAttributes
+	// selection is the path of the element set by WithSelection.
+	selection []string
+	// warningsAsErrors is set by WithWarningsAsErrors.
+	warningsAsErrors bool
+	// rootName is set by WithRootName.
+	rootName string
+	// returnName is set by WithReturnName.
+	returnName string
+	// reservedNameHook is set by WithReservedNameHook.
+	reservedNameHook ReservedNameHook
+	// statsHooks are set by WithStatsHook.
+	statsHooks []func(stats Stats)
+}
+
+// DuplicateAttributes decides what the parser does with attributes, whose key is already used by
+// another attribute of the same element.
+type DuplicateAttributes int
+
+const (
+	// RejectDuplicateAttributes results in a token.PosError at the second attribute. This is the default.
+	RejectDuplicateAttributes DuplicateAttributes = iota
+	// LastAttributeWins replaces the value of the earlier attribute, which keeps its position in the list.
+	LastAttributeWins
+	// CollectDuplicateAttributes keeps all attributes in source order, so that an attribute can have
+	// multiple values. Use util.AttributeList.GetAll to get them, Get only returns the first one.
+	CollectDuplicateAttributes
+)
+
+// WithLexerOptions passes the given options to the lexer.
+func WithLexerOptions(opts ...token.LexerOption) ParserOption {
+	return func(p *parserConfig) {
+		p.lexerOptions = append(p.lexerOptions, opts...)
+	}
+}
+
+// WithMaxDepth limits how deep elements may be nested. Children of the root have a depth of 1.
+func WithMaxDepth(max int) ParserOption {
+	return func(p *parserConfig) {
+		p.maxDepth = max
+	}
+}
+
+// WithMaxNodes limits the total number of elements, texts and comments in the document.
+func WithMaxNodes(max int) ParserOption {
+	return func(p *parserConfig) {
+		p.maxNodes = max
+	}
+}
+
+// WithMaxAttributes limits the number of attributes per element, including forwarded attributes.
+func WithMaxAttributes(max int) ParserOption {
+	return func(p *parserConfig) {
+		p.maxAttributes = max
+	}
+}
+
+// WithMaxTextLength limits the length of texts, comments, attribute values and identifiers in bytes.
+// The limit is checked while reading the input, so that huge tokens are never read completely.
+func WithMaxTextLength(max int) ParserOption {
+	return WithLexerOptions(token.WithMaxTokenLength(max))
+}
+
+// WithPreserveWhitespace keeps the whitespace between elements and at the beginning of blocks in G1
+// as text nodes, which are dropped otherwise, e.g. to keep the line breaks of G1 documents. Whitespace
+// after names and attributes is still dropped, so the input cannot be reproduced exactly from the tree.
+// Normalize with CollapseWhitespace removes them again. See token.WithPreserveWhitespace.
+func WithPreserveWhitespace() ParserOption {
+	return WithLexerOptions(token.WithPreserveWhitespace())
+}
+
+// WithCharsetDetection lets the parser read input that is not stored as UTF-8, by transcoding it
+// with a token.CharsetReader. UTF-16 with a byte order mark and Latin-1 are supported.
+// Input that cannot be transcoded results in an error that wraps token.ErrCharset.
+func WithCharsetDetection() ParserOption {
+	return func(p *parserConfig) {
+		p.charsetDetection = true
+	}
+}
+
+// WithSourceMap lets the parser keep a copy of its input, so that Parser.SourceMap can be used after parsing.
+func WithSourceMap() ParserOption {
+	return func(p *parserConfig) {
+		p.sourceMap = true
+	}
+}
+
+// WithUniqueSiblings rejects elements that have the same name as one of their siblings, e.g. to allow
+// at most one #database block in a configuration. Elements that are meant to be repeated, like the
+// items of a list, can be allowed by name. A duplicate results in a token.PosError at its position,
+// which also points to the first element with that name.
+func WithUniqueSiblings(allowed ...string) ParserOption {
+	return func(p *parserConfig) {
+		p.uniqueSiblings = true
+
+		if p.repeatable == nil {
+			p.repeatable = map[string]bool{}
+		}
+
+		for _, name := range allowed {
+			p.repeatable[name] = true
+		}
+	}
+}
+
+// WithDuplicateAttributes sets what happens if an element has multiple attributes with the same key,
+// which includes forwarded attributes. Some XML-like inputs legitimately repeat attributes.
+func WithDuplicateAttributes(policy DuplicateAttributes) ParserOption {
+	return func(p *parserConfig) {
+		p.duplicateAttributes = policy
+	}
+}
+
+// WithSelection lets the parser build only the first element at path, e.g. "server", "http" for the
+// element #http within #server, with all of its content. Of its ancestors only the names and block types
+// are kept, all other elements, texts, comments and attributes are skipped while parsing, so that
+// no memory is used for them. Use Select to find the element in the parsed tree. The input is still
+// read completely and must be valid.
+func WithSelection(path ...string) ParserOption {
+	return func(p *parserConfig) {
+		p.selection = path
+	}
+}
+
+// WithWarningsAsErrors lets parsing fail if there are any warnings, see Parser.Warnings. The error is a
+// token.PosError in the category token.ErrSyntax at the first warning, with all others as details.
+func WithWarningsAsErrors() ParserOption {
+	return func(p *parserConfig) {
+		p.warningsAsErrors = true
+	}
+}
+
+// WithRootName sets the name of the root element that contains the whole document, which is DefaultRootName
+// by default. The root is always marked as TreeNode.Synthetic, so that it can be told apart from elements
+// with the same name.
+func WithRootName(name string) ParserOption {
+	return func(p *parserConfig) {
+		p.rootName = name
+	}
+}
+
+// WithReturnName sets the name of the element that contains the results after a return arrow, which is
+// DefaultReturnName by default. Choose another one if documents use "ret" as the name of their own elements.
+func WithReturnName(name string) ParserOption {
+	return func(p *parserConfig) {
+		p.returnName = name
+	}
+}
+
+// WithReservedNameHook lets the parser pass the names of elements, that are also used for the elements
+// created by the parser, through hook, see Visitor.SetReservedNameHook.
+func WithReservedNameHook(hook ReservedNameHook) ParserOption {
+	return func(p *parserConfig) {
+		p.reservedNameHook = hook
+	}
+}
+
+// WithStatsHook registers a hook that is called with the Stats of the document once parsing ended, also
+// if it failed, e.g. to monitor the parsing of documents in a service. See Visitor.OnStats.
+func WithStatsHook(hook func(stats Stats)) ParserOption {
+	return func(p *parserConfig) {
+		p.statsHooks = append(p.statsHooks, hook)
+	}
+}
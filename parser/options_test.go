@@ -0,0 +1,561 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		reader    io.Reader
+		opts      []ParserOption
+		wantLimit string
+	}{
+		{
+			name: "within all limits",
+			text: `#a @x{1} {#b{text}}`,
+			opts: []ParserOption{
+				WithMaxDepth(2), WithMaxNodes(3), WithMaxAttributes(1), WithMaxTextLength(4),
+			},
+		},
+		{
+			name:      "depth",
+			text:      `#a{#b{#c}}`,
+			opts:      []ParserOption{WithMaxDepth(2)},
+			wantLimit: "nesting depth",
+		},
+		{
+			name:      "depth g2",
+			text:      `#! a { b { c } }`,
+			opts:      []ParserOption{WithMaxDepth(2)},
+			wantLimit: "nesting depth",
+		},
+		{
+			name:      "nodes",
+			text:      `#a #b text`,
+			opts:      []ParserOption{WithMaxNodes(2)},
+			wantLimit: "number of nodes",
+		},
+		{
+			name:      "attributes",
+			text:      `#a @x{1} @y{2}`,
+			opts:      []ParserOption{WithMaxAttributes(1)},
+			wantLimit: "number of attributes",
+		},
+		{
+			name:      "forwarded attributes",
+			text:      `@@x{1} @@y{2} #a`,
+			opts:      []ParserOption{WithMaxAttributes(1)},
+			wantLimit: "number of attributes",
+		},
+		{
+			name:      "forwarded and regular attributes",
+			text:      `@@x{1} #a @y{2}`,
+			opts:      []ParserOption{WithMaxAttributes(1)},
+			wantLimit: "number of attributes",
+		},
+		{
+			name:      "text length",
+			text:      `#a{too long}`,
+			opts:      []ParserOption{WithMaxTextLength(4)},
+			wantLimit: "token length",
+		},
+		{
+			name:      "identifier length",
+			text:      `#toolong`,
+			opts:      []ParserOption{WithMaxTextLength(4)},
+			wantLimit: "token length",
+		},
+		{
+			name:      "endless text",
+			reader:    &endlessReader{pattern: "text "},
+			opts:      []ParserOption{WithMaxTextLength(1024)},
+			wantLimit: "token length",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			reader := test.reader
+			if reader == nil {
+				reader = strings.NewReader(test.text)
+			}
+
+			_, err := NewParser(test.name, reader, test.opts...).Parse()
+			if test.wantLimit == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			var limitErr token.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected a limit error, but got %v", err)
+			}
+
+			if limitErr.Limit != test.wantLimit {
+				t.Errorf("expected limit '%s' to be exceeded, but got '%s'", test.wantLimit, limitErr.Limit)
+			}
+		})
+	}
+}
+
+func TestWithUniqueSiblings(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		allowed   []string
+		wantLine  int
+		wantFirst int
+	}{
+		{
+			name: "unique",
+			text: "#database #server {#port 1 #host x} #cache {#port 2}",
+		},
+		{
+			name:      "duplicate",
+			text:      "#database\n#server\n#database",
+			wantLine:  3,
+			wantFirst: 1,
+		},
+		{
+			name:      "nested duplicate",
+			text:      "#! server {\n  port 1,\n  port 2\n}",
+			wantLine:  3,
+			wantFirst: 2,
+		},
+		{
+			name:    "allowed",
+			text:    "#list {#item a #item b}",
+			allowed: []string{"item"},
+		},
+		{
+			name:      "forwarded",
+			text:      "##a ##a #y",
+			wantLine:  1,
+			wantFirst: 1,
+		},
+		{
+			name: "texts and comments",
+			text: "#! a { \"x\" \"x\" // c\n// c\n}",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewParser("", strings.NewReader(test.text), WithUniqueSiblings(test.allowed...)).Parse()
+			if test.wantLine == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			var posErr *token.PosError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected a PosError, but got %v", err)
+			}
+
+			if line := posErr.Details[0].Node.Begin().Line; line != test.wantLine {
+				t.Errorf("expected the duplicate in line %d, but got %d", test.wantLine, line)
+			}
+
+			if line := posErr.Details[1].Node.Begin().Line; line != test.wantFirst {
+				t.Errorf("expected the first element in line %d, but got %d", test.wantFirst, line)
+			}
+		})
+	}
+}
+
+func TestWithDuplicateAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		policy  DuplicateAttributes
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "reject",
+			text:    `#a @class{x} @class{y}`,
+			policy:  RejectDuplicateAttributes,
+			wantErr: true,
+		},
+		{
+			name:    "reject forwarded",
+			text:    `@@class{x} #a @class{y}`,
+			policy:  RejectDuplicateAttributes,
+			wantErr: true,
+		},
+		{
+			name:   "last wins",
+			text:   `#a @class{x} @id{1} @class{y}`,
+			policy: LastAttributeWins,
+			want:   []string{"class=y", "id=1"},
+		},
+		{
+			name:   "collect",
+			text:   `@@class{x} #a @class{y} @id{1} @class{z}`,
+			policy: CollectDuplicateAttributes,
+			want:   []string{"class=x", "class=y", "id=1", "class=z"},
+		},
+		{
+			name:   "collect g2",
+			text:   `#! a @class="x" @class="y"`,
+			policy: CollectDuplicateAttributes,
+			want:   []string{"class=x", "class=y"},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewParser("", strings.NewReader(test.text), WithDuplicateAttributes(test.policy)).Parse()
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attributes := tree.Children[0].Attributes
+
+			var got []string
+
+			for i := 0; i < attributes.Len(); i++ {
+				attr := attributes.GetAt(i)
+				got = append(got, attr.Key+"="+attr.Value)
+			}
+
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("expected attributes %v, but got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGetAllAttributes(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader(`#a @class{x} @id{1} @class{y}`),
+		WithDuplicateAttributes(CollectDuplicateAttributes)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := tree.Children[0].Attributes
+
+	all := attributes.GetAll("class")
+	if len(all) != 2 || all[0].Value != "x" || all[1].Value != "y" {
+		t.Errorf("expected both classes, but got %v", all)
+	}
+
+	if first := attributes.Get("class"); first == nil || first.Value != "x" {
+		t.Errorf("expected Get to return the first class, but got %v", first)
+	}
+
+	if missing := attributes.GetAll("missing"); missing != nil {
+		t.Errorf("expected nil for a missing key, but got %v", missing)
+	}
+}
+
+func TestWithCharsetDetection(t *testing.T) {
+	t.Parallel()
+
+	// "#a{é}" in UTF-16LE with a byte order mark.
+	utf16 := []byte{0xFF, 0xFE, '#', 0, 'a', 0, '{', 0, 0xE9, 0, '}', 0}
+
+	tree, err := NewParser("", bytes.NewReader(utf16), WithCharsetDetection(), WithSourceMap()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text := tree.Children[0].Children[0].Text; text == nil || *text != "é" {
+		t.Errorf("expected the transcoded text, but got %v", text)
+	}
+
+	if _, err := NewParser("", bytes.NewReader(utf16)).Parse(); err == nil {
+		t.Error("expected UTF-16 to be rejected without charset detection")
+	}
+
+	_, err = NewParser("", bytes.NewReader(utf16[:len(utf16)-1]), WithCharsetDetection()).Parse()
+	if !errors.Is(err, token.ErrCharset) {
+		t.Errorf("expected an error wrapping ErrCharset, but got %v", err)
+	}
+}
+
+func TestWithPreserveWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// texts are all texts of the tree in document order.
+		texts []string
+	}{
+		{
+			name:  "between elements",
+			text:  "#a{x}\n#b{y}\n",
+			texts: []string{"x", "\n", "y", "\n"},
+		},
+		{
+			name:  "indented block",
+			text:  "#list {\n  #item {a}\n  #item {b}\n}",
+			texts: []string{"\n  ", "a", "\n  ", "b", "\n"},
+		},
+		{
+			name:  "around text",
+			text:  "#a{ x } y",
+			texts: []string{" x ", " y"},
+		},
+		{
+			name:  "after g2",
+			text:  "#! a {}\n\n#b{x}",
+			texts: []string{"\n\n", "x"},
+		},
+		{
+			name:  "after names",
+			text:  "#a   text #b\n#c",
+			texts: []string{"text "},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewParser("", strings.NewReader(test.text), WithPreserveWhitespace()).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			texts := collectTexts(nil, tree)
+			if strings.Join(texts, "|") != strings.Join(test.texts, "|") {
+				t.Errorf("expected texts %q, but got %q", test.texts, texts)
+			}
+
+			plain, err := NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := NormalizeOptions{CollapseWhitespace: true}
+			if !Equal(tree, plain, opts) {
+				t.Errorf("expected the same tree without whitespace, but got\n%s", Normalize(tree, opts).Dump())
+			}
+		})
+	}
+}
+
+// collectTexts appends all texts of node and its children in document order.
+func collectTexts(texts []string, node *TreeNode) []string {
+	if node.IsText() {
+		return append(texts, *node.Text)
+	}
+
+	for _, child := range node.Children {
+		texts = collectTexts(texts, child)
+	}
+
+	return texts
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// lines are the lines of all warnings in order.
+		lines []int
+	}{
+		{name: "none", text: "#a{x}\n#! b (c) <d>"},
+		{name: "whitespace after backslash", text: "#a{x}\n#b{x\\ y}", lines: []int{2}},
+		{name: "empty blocks", text: "#a{}\n#! b {\n  c()\n}", lines: []int{1, 3}},
+		{name: "different brackets", text: "#! list {\n  item {a}\n  item <b>\n  other <c>\n  item {d}\n}", lines: []int{3}},
+		{name: "empty document", text: ""},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := NewParser("", strings.NewReader(test.text))
+			if _, err := parser.Parse(); err != nil {
+				t.Fatal(err)
+			}
+
+			var lines []int
+			for _, warning := range parser.Warnings() {
+				lines = append(lines, warning.Begin().Line)
+			}
+
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("expected warnings in lines %v, but got %v", test.lines, parser.Warnings())
+			}
+
+			_, err := NewParser("", strings.NewReader(test.text), WithWarningsAsErrors()).Parse()
+			if (err != nil) != (len(test.lines) > 0) {
+				t.Fatalf("expected an error only for warnings, but got %v", err)
+			}
+
+			var posErr *token.PosError
+			if err != nil && (!errors.As(err, &posErr) || len(posErr.Details) != len(test.lines)) {
+				t.Errorf("expected a PosError with all warnings, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestWithRootName(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader("#root{x}"), WithRootName("document")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Name != "document" || !tree.Synthetic {
+		t.Errorf("expected a synthetic root named 'document', but got\n%s", tree.Dump())
+	}
+
+	if element := tree.Children[0]; element.Name != "root" || element.Synthetic {
+		t.Errorf("expected the element 'root' of the document, but got\n%s", element.Dump())
+	}
+
+	tree, err = NewParser("", strings.NewReader("#a")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Name != DefaultRootName || !tree.Synthetic || !tree.Clone().Synthetic {
+		t.Errorf("expected a synthetic root named '%s', but got\n%s", DefaultRootName, tree.Dump())
+	}
+}
+
+// elementNames returns the names of the elements below node, with their children in brackets.
+func elementNames(node *TreeNode) string {
+	var names []string
+
+	for _, child := range node.Children {
+		if !child.IsNode() {
+			continue
+		}
+
+		if len(child.Children) > 0 {
+			names = append(names, child.Name+"("+elementNames(child)+")")
+		} else {
+			names = append(names, child.Name)
+		}
+	}
+
+	return strings.Join(names, " ")
+}
+
+func TestReservedNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ParserOption
+		want    string
+		wantErr error
+	}{
+		{
+			name: "no hook",
+			want: "ret type root f(ret(int)) a(type(ret))",
+		},
+		{
+			name: "return name",
+			opts: []ParserOption{WithReturnName("results")},
+			want: "ret type root f(results(int)) a(type(ret))",
+		},
+		{
+			name: "suffix",
+			opts: []ParserOption{WithReservedNameHook(SuffixReservedNames("_"))},
+			want: "ret_ type_ root_ f(ret(int)) a(type(ret_))",
+		},
+		{
+			name: "suffix with another return and root name",
+			opts: []ParserOption{
+				WithReturnName("results"),
+				WithRootName("document"),
+				WithReservedNameHook(SuffixReservedNames("_")),
+			},
+			want: "ret type_ root f(results(int)) a(type(ret))",
+		},
+		{
+			name:    "reject",
+			opts:    []ParserOption{WithReservedNameHook(RejectReservedNames())},
+			wantErr: ErrReservedName,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			text := "#ret #type #root\n#! f -> (int)\n#! a: ret"
+
+			tree, err := NewParser("", strings.NewReader(text), test.opts...).Parse()
+			if test.wantErr != nil {
+				var posErr *token.PosError
+				if !errors.Is(err, test.wantErr) || !errors.As(err, &posErr) {
+					t.Fatalf("expected a PosError wrapping '%v', but got %v", test.wantErr, err)
+				}
+
+				if line := posErr.Details[0].Node.Begin().Line; line != 1 {
+					t.Errorf("expected the error in line 1, but got %d", line)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := elementNames(tree); got != test.want {
+				t.Errorf("expected the elements '%s', but got '%s'", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/golangee/dyml/util"
+)
+
+// ParserPool reuses Parsers across documents, so that servers parsing many small documents allocate less.
+// A pooled Parser keeps the buffers of its lexer and visitor, its working stacks and the nodes of its
+// arena that are not part of a tree yet. Trees returned by the pool do not share anything that is modified
+// later. A ParserPool is safe for concurrent use and its zero value is ready to use.
+// As the Parser is not returned, Parser.SourceMap and Parser.Warnings are not available, but
+// WithWarningsAsErrors works as usual.
+type ParserPool struct {
+	pool sync.Pool
+	opts []ParserOption
+}
+
+// NewParserPool creates a ParserPool whose parsers use opts for every document.
+func NewParserPool(opts ...ParserOption) *ParserPool {
+	return &ParserPool{opts: opts}
+}
+
+// Parse parses the input with a pooled Parser, like NewParser(filename, r, opts...).Parse().
+// The options are used after the ones of the pool.
+func (pp *ParserPool) Parse(filename string, r io.Reader, opts ...ParserOption) (*TreeNode, error) {
+	return pp.ParseContext(context.Background(), filename, r, opts...)
+}
+
+// ParseContext works like Parse, but stops with an error wrapping ctx.Err() once ctx is done.
+func (pp *ParserPool) ParseContext(ctx context.Context, filename string, r io.Reader,
+	opts ...ParserOption) (*TreeNode, error) {
+	p, ok := pp.pool.Get().(*Parser)
+	if !ok {
+		p = &Parser{}
+	}
+
+	if len(opts) > 0 {
+		opts = append(pp.opts[:len(pp.opts):len(pp.opts)], opts...)
+	} else {
+		opts = pp.opts
+	}
+
+	p.init(filename, r, opts)
+	tree, err := p.ParseContext(ctx)
+	p.release()
+	pp.pool.Put(p)
+
+	return tree, err
+}
+
+// release drops all references to the last input and its tree, so that a pooled Parser does not keep
+// them alive. The buffers are kept for the next input.
+func (p *Parser) release() {
+	p.finalTree = nil
+	p.workingStack = clearNodes(p.workingStack)
+	p.forwardedNodes = clearNodes(p.forwardedNodes)
+	p.children = clearNodes(p.children)
+	p.childrenStart = p.childrenStart[:0]
+	p.forwardedAttributes = util.AttributeList{}
+	p.nodes = 0
+	p.source = nil
+	p.warnings = nil
+	p.visitor.reset("", nil)
+}
+
+// clearNodes returns nodes without any elements, whose whole capacity has been set to nil.
+func clearNodes(nodes []*TreeNode) []*TreeNode {
+	all := nodes[:cap(nodes)]
+	for i := range all {
+		all[i] = nil
+	}
+
+	return all[:0]
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestParserPool(t *testing.T) {
+	t.Parallel()
+
+	texts := []string{
+		"",
+		largeDocument(3),
+		"#? comment\n#a @k{v} {#b{text}}",
+		`#! g2 { @@id="1" fn(x) -> (int) }`,
+	}
+
+	pool := NewParserPool()
+
+	var (
+		trees []*TreeNode
+		wants []*TreeNode
+	)
+
+	// Parsing documents in turn must not change the trees that were returned before.
+	for i := 0; i < 3; i++ {
+		for _, text := range texts {
+			want, err := NewParser("pool", strings.NewReader(text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tree, err := pool.Parse("pool", strings.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wants = append(wants, want)
+			trees = append(trees, tree)
+
+			// A failed document leaves the pooled parser usable.
+			if _, err := pool.Parse("pool", strings.NewReader("#a{")); err == nil {
+				t.Fatal("expected an error for an unclosed block")
+			}
+		}
+	}
+
+	for i := range trees {
+		if !Equal(wants[i], trees[i], NormalizeOptions{}) {
+			t.Errorf("expected\n%s\nbut got\n%s", PrettyValue(wants[i]), PrettyValue(trees[i]))
+		}
+
+		if wants[i].Range != trees[i].Range {
+			t.Errorf("expected the range %v, but got %v", wants[i].Range, trees[i].Range)
+		}
+	}
+}
+
+func TestParserPoolOptions(t *testing.T) {
+	t.Parallel()
+
+	pool := NewParserPool(WithMaxNodes(2))
+
+	var limitErr token.LimitError
+	if _, err := pool.Parse("", strings.NewReader("#a #b #c")); !errors.As(err, &limitErr) {
+		t.Errorf("expected the limit of the pool, but got %v", err)
+	}
+
+	// Options of a single document do not stay with the pooled parser.
+	tree, err := pool.Parse("", strings.NewReader("#a #b"), WithRootName("doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree.Name != "doc" {
+		t.Errorf("expected the root name of the options, but got '%s'", tree.Name)
+	}
+
+	if tree, err := pool.Parse("", strings.NewReader("#a")); err != nil || tree.Name != DefaultRootName {
+		t.Errorf("expected the default root name, but got %v", err)
+	}
+}
+
+func TestParserPoolConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var (
+		pool ParserPool
+		wg   sync.WaitGroup
+	)
+
+	errs := make(chan error, 8)
+
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				text := fmt.Sprintf("#item @id{%d} {#value %d}", i, j)
+
+				tree, err := pool.Parse("", strings.NewReader(text))
+				if err != nil {
+					errs <- err
+
+					return
+				}
+
+				if got := *tree.Children[0].Children[0].Children[0].Text; got != fmt.Sprint(j) {
+					errs <- fmt.Errorf("expected the value %d, but got %s", j, got)
+
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golangee/dyml/token"
+)
+
+// EventKind is the Visitable method that an Event represents.
+type EventKind uint8
+
+const (
+	EventOpen EventKind = iota
+	EventComment
+	EventText
+	EventOpenReturnArrow
+	EventCloseReturnArrow
+	EventSetBlockType
+	EventOpenForward
+	EventTextForward
+	EventClose
+	EventAttribute
+	EventAttributeForward
+	EventFinalize
+)
+
+// Event is a single call of a Visitable method with its arguments.
+type Event struct {
+	Kind EventKind
+	// Name is the argument of Open and OpenForward, the key of Attribute and AttributeForward
+	// and the optional name of OpenReturnArrow, see HasName.
+	Name token.Identifier
+	// HasName is true if OpenReturnArrow was called with a name.
+	HasName bool
+	// Value is the argument of Comment, Text and TextForward and the value of Attribute and AttributeForward.
+	Value token.CharData
+	// Arrow is the argument of OpenReturnArrow.
+	Arrow token.G2Arrow
+	// BlockType is the argument of SetBlockType.
+	BlockType BlockType
+}
+
+// Recorder is a Visitable that records all events, so that they can be replayed into another Visitable
+// later, e.g. to cache a document or to run multiple passes over it without lexing it again.
+type Recorder struct {
+	events []Event
+}
+
+// NewRecorder creates an empty Recorder. Use it with Visitor.SetVisitable or call Record.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record visits the input and returns a Recorder with all of its events.
+func Record(filename string, r io.Reader, opts ...token.LexerOption) (*Recorder, error) {
+	recorder := NewRecorder()
+
+	visitor := NewVisitor(filename, r, opts...)
+	visitor.SetVisitable(recorder)
+
+	if err := visitor.Run(); err != nil {
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+// Events returns all recorded events. The slice must not be modified.
+func (r *Recorder) Events() []Event {
+	return r.events
+}
+
+// Reset removes all recorded events, so that the Recorder can be used again.
+func (r *Recorder) Reset() {
+	r.events = r.events[:0]
+}
+
+// Replay calls the methods of v for all recorded events in the order they were recorded.
+// The first error stops the replay. A ContextVisitable gets its VisitContext just like from a Visitor.
+func (r *Recorder) Replay(v Visitable) error {
+	v = withVisitContext(v, DefaultReturnName)
+
+	for i := range r.events {
+		if err := replayEvent(&r.events[i], v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tree replays the events into a new Parser and returns the resulting tree.
+// The recording must be complete, so that the tree has been finalized.
+func (r *Recorder) Tree(opts ...ParserOption) (*TreeNode, error) {
+	p := NewParser("", nil, opts...)
+
+	if err := r.Replay(p); err != nil {
+		return nil, err
+	}
+
+	if p.finalTree == nil {
+		return nil, fmt.Errorf("recording is incomplete, it has not been finalized")
+	}
+
+	return p.finalTree, nil
+}
+
+// replayEvent calls the method of v that belongs to the event.
+func replayEvent(e *Event, v Visitable) error {
+	switch e.Kind {
+	case EventOpen:
+		return v.Open(e.Name)
+	case EventComment:
+		return v.Comment(e.Value)
+	case EventText:
+		return v.Text(e.Value)
+	case EventOpenReturnArrow:
+		if e.HasName {
+			name := e.Name
+
+			return v.OpenReturnArrow(e.Arrow, &name)
+		}
+
+		return v.OpenReturnArrow(e.Arrow, nil)
+	case EventCloseReturnArrow:
+		return v.CloseReturnArrow()
+	case EventSetBlockType:
+		return v.SetBlockType(e.BlockType)
+	case EventOpenForward:
+		return v.OpenForward(e.Name)
+	case EventTextForward:
+		return v.TextForward(e.Value)
+	case EventClose:
+		return v.Close()
+	case EventAttribute:
+		return v.Attribute(e.Name, e.Value)
+	case EventAttributeForward:
+		return v.AttributeForward(e.Name, e.Value)
+	case EventFinalize:
+		return v.Finalize()
+	default:
+		return fmt.Errorf("cannot replay unknown event kind %d", e.Kind)
+	}
+}
+
+func (r *Recorder) Open(name token.Identifier) error {
+	r.events = append(r.events, Event{Kind: EventOpen, Name: name})
+
+	return nil
+}
+
+func (r *Recorder) Comment(comment token.CharData) error {
+	r.events = append(r.events, Event{Kind: EventComment, Value: comment})
+
+	return nil
+}
+
+func (r *Recorder) Text(text token.CharData) error {
+	r.events = append(r.events, Event{Kind: EventText, Value: text})
+
+	return nil
+}
+
+func (r *Recorder) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	event := Event{Kind: EventOpenReturnArrow, Arrow: arrow}
+	if name != nil {
+		event.Name = *name
+		event.HasName = true
+	}
+
+	r.events = append(r.events, event)
+
+	return nil
+}
+
+func (r *Recorder) CloseReturnArrow() error {
+	r.events = append(r.events, Event{Kind: EventCloseReturnArrow})
+
+	return nil
+}
+
+func (r *Recorder) SetBlockType(blockType BlockType) error {
+	r.events = append(r.events, Event{Kind: EventSetBlockType, BlockType: blockType})
+
+	return nil
+}
+
+func (r *Recorder) OpenForward(name token.Identifier) error {
+	r.events = append(r.events, Event{Kind: EventOpenForward, Name: name})
+
+	return nil
+}
+
+func (r *Recorder) TextForward(text token.CharData) error {
+	r.events = append(r.events, Event{Kind: EventTextForward, Value: text})
+
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.events = append(r.events, Event{Kind: EventClose})
+
+	return nil
+}
+
+func (r *Recorder) Attribute(key token.Identifier, value token.CharData) error {
+	r.events = append(r.events, Event{Kind: EventAttribute, Name: key, Value: value})
+
+	return nil
+}
+
+func (r *Recorder) AttributeForward(key token.Identifier, value token.CharData) error {
+	r.events = append(r.events, Event{Kind: EventAttributeForward, Name: key, Value: value})
+
+	return nil
+}
+
+func (r *Recorder) Finalize() error {
+	r.events = append(r.events, Event{Kind: EventFinalize})
+
+	return nil
+}
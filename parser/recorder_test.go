@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/encoder"
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "G1",
+			text: `#A @id{5} { text #B{#C} } #? a comment`,
+		},
+		{
+			name: "G1 forwards",
+			text: `##subA @@key{value} ##subB #item`,
+		},
+		{
+			name: "G2",
+			text: `#! g2 {
+						@@key="value"
+						item @not="forwarded" "text",
+						// note
+						list<a, b>
+						func(x) -> (int)
+					}`,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder, err := Record("", strings.NewReader(test.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Replaying twice must not change the recording.
+			for i := 0; i < 2; i++ {
+				got, err := recorder.Tree()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !Equal(want, got, NormalizeOptions{}) {
+					t.Errorf("expected\n%s\nbut got\n%s", PrettyValue(want), PrettyValue(got))
+				}
+			}
+		})
+	}
+}
+
+func TestRecorderReplayIntoEncoder(t *testing.T) {
+	t.Parallel()
+
+	text := `#! list { item @key="value" "text", other<a> }`
+
+	var want bytes.Buffer
+	if err := encoder.NewXMLEncoder("", strings.NewReader(text), &want).Encode(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder, err := Record("", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	if err := recorder.Replay(encoder.NewXMLEncoder("", nil, &got)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.String() != want.String() {
+		t.Errorf("expected\n%s\nbut got\n%s", want.String(), got.String())
+	}
+}
+
+func TestRecorderIncomplete(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder()
+	if _, err := recorder.Tree(); err == nil {
+		t.Error("expected an error for an empty recording")
+	}
+
+	recorder, err := Record("", strings.NewReader("#a #b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.Events()) == 0 {
+		t.Fatal("expected events to be recorded")
+	}
+
+	recorder.Reset()
+
+	if len(recorder.Events()) != 0 {
+		t.Error("expected no events after Reset")
+	}
+}
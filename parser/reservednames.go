@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"errors"
+
+	"github.com/golangee/dyml/token"
+)
+
+// ErrReservedName is the cause of errors about elements with reserved names, see RejectReservedNames.
+var ErrReservedName = errors.New("the name is reserved for elements created by the parser")
+
+// ReservedNameHook is called for an element of the document, whose name is also used for the elements
+// that the visitor and its Visitable create, which are the root, the element after a return arrow and
+// TypeElement. It returns the name that is used instead, or an error that stops the visitor.
+// Errors that are no token.PosError are wrapped in one at the name. See Visitor.SetReservedNameHook.
+type ReservedNameHook func(name token.Identifier) (string, error)
+
+// RejectReservedNames returns a ReservedNameHook that rejects all elements with reserved names with
+// an error caused by ErrReservedName, so that documents cannot be ambiguous.
+func RejectReservedNames() ReservedNameHook {
+	return func(name token.Identifier) (string, error) {
+		return "", ErrReservedName
+	}
+}
+
+// SuffixReservedNames returns a ReservedNameHook that appends suffix to reserved names, e.g. "_" to
+// rename an element "ret" of the document to "ret_", so that it cannot be confused with the element
+// that contains the results of a return arrow.
+func SuffixReservedNames(suffix string) ReservedNameHook {
+	return func(name token.Identifier) (string, error) {
+		return name.Value + suffix, nil
+	}
+}
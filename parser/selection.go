@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// selection is a Visitable that only passes the events on to target, that are needed to build the
+// first element at path and its ancestors, see WithSelection. All other events are skipped, so that
+// no nodes are created for them.
+type selection struct {
+	target  Visitable
+	visitor *Visitor
+	path    []string
+
+	// kept contains for each open node, starting with the root, whether it is passed on to target.
+	kept []bool
+	// returnArrows contains the number of nodes that each open return arrow has opened.
+	returnArrows []int
+	// match is the index in kept of the selected element while it is open, or -1.
+	match int
+	// done is true once the selected element has been closed, so that nothing else is kept.
+	done bool
+
+	// forwards records forwarded nodes and attributes until the element they are forwarded to is opened.
+	forwards Recorder
+	// forwardEnds are the positions at which the recorded nodes were closed.
+	forwardEnds []token.Pos
+	// forwardDepth is the number of open forwarded nodes and nodes within them.
+	forwardDepth int
+}
+
+// newSelection creates a selection for the element at path, which is visited by visitor.
+func newSelection(target Visitable, visitor *Visitor, path []string) *selection {
+	return &selection{target: target, visitor: visitor, path: path, match: -1}
+}
+
+// inside returns true if the innermost open node is the selected element or one of its descendants.
+func (s *selection) inside() bool {
+	return s.match >= 0
+}
+
+// open decides whether a node named name that is opened now is kept, and pushes it.
+func (s *selection) open(name string) bool {
+	depth := len(s.kept)
+
+	keep := false
+
+	switch {
+	case s.done:
+	case depth == 0 || s.inside():
+		keep = true
+	case s.kept[depth-1] && depth <= len(s.path) && s.path[depth-1] == name:
+		keep = true
+
+		if depth == len(s.path) {
+			s.match = depth
+		}
+	}
+
+	s.kept = append(s.kept, keep)
+
+	return keep
+}
+
+// close pops the innermost node and returns whether it was kept.
+func (s *selection) close() bool {
+	last := len(s.kept) - 1
+	keep := s.kept[last]
+	s.kept = s.kept[:last]
+
+	if last == s.match {
+		s.match = -1
+		s.done = true
+	}
+
+	return keep
+}
+
+// releaseForwards passes the recorded forwards on, if the element that they are forwarded to is kept.
+// Otherwise they are dropped.
+func (s *selection) releaseForwards(keep bool) error {
+	defer func() {
+		s.forwards.Reset()
+		s.forwardEnds = s.forwardEnds[:0]
+	}()
+
+	if !keep {
+		return nil
+	}
+
+	lastEnd := s.visitor.lastEnd
+
+	defer func() {
+		s.visitor.lastEnd = lastEnd
+	}()
+
+	ends := s.forwardEnds
+	events := s.forwards.Events()
+
+	for i := range events {
+		// Nodes are closed at their original end, so that they keep their ranges.
+		if events[i].Kind == EventClose && len(ends) > 0 {
+			s.visitor.lastEnd, ends = ends[0], ends[1:]
+		}
+
+		if err := replayEvent(&events[i], s.target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *selection) Open(name token.Identifier) error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth++
+
+		return s.forwards.Open(name)
+	}
+
+	keep := s.open(name.Value)
+
+	if err := s.releaseForwards(keep); err != nil {
+		return err
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return s.target.Open(name)
+}
+
+func (s *selection) Comment(comment token.CharData) error {
+	if s.forwardDepth > 0 {
+		return s.forwards.Comment(comment)
+	}
+
+	if !s.inside() {
+		return nil
+	}
+
+	return s.target.Comment(comment)
+}
+
+func (s *selection) Text(text token.CharData) error {
+	if s.forwardDepth > 0 {
+		return s.forwards.Text(text)
+	}
+
+	if !s.inside() {
+		return nil
+	}
+
+	return s.target.Text(text)
+}
+
+func (s *selection) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth++
+
+		return s.forwards.OpenReturnArrow(arrow, name)
+	}
+
+	keep := s.open(s.visitor.ReturnName())
+	count := 1
+
+	// A named return arrow opens a second node, which is only kept together with the return element.
+	if name != nil {
+		if !s.open(name.Value) {
+			keep = false
+			s.kept[len(s.kept)-2] = false
+		}
+
+		count = 2
+	}
+
+	s.returnArrows = append(s.returnArrows, count)
+
+	if err := s.releaseForwards(keep); err != nil {
+		return err
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return s.target.OpenReturnArrow(arrow, name)
+}
+
+func (s *selection) CloseReturnArrow() error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth--
+
+		return s.forwards.CloseReturnArrow()
+	}
+
+	last := len(s.returnArrows) - 1
+	count := s.returnArrows[last]
+	s.returnArrows = s.returnArrows[:last]
+
+	keep := false
+	for i := 0; i < count; i++ {
+		keep = s.close()
+	}
+
+	if !keep {
+		return nil
+	}
+
+	return s.target.CloseReturnArrow()
+}
+
+func (s *selection) SetBlockType(blockType BlockType) error {
+	if s.forwardDepth > 0 {
+		return s.forwards.SetBlockType(blockType)
+	}
+
+	if !s.kept[len(s.kept)-1] {
+		return nil
+	}
+
+	return s.target.SetBlockType(blockType)
+}
+
+func (s *selection) OpenForward(name token.Identifier) error {
+	s.forwardDepth++
+
+	return s.forwards.OpenForward(name)
+}
+
+func (s *selection) TextForward(text token.CharData) error {
+	return s.forwards.TextForward(text)
+}
+
+func (s *selection) Close() error {
+	if s.forwardDepth > 0 {
+		s.forwardDepth--
+		s.forwardEnds = append(s.forwardEnds, s.visitor.lastEnd)
+
+		return s.forwards.Close()
+	}
+
+	if !s.close() {
+		return nil
+	}
+
+	return s.target.Close()
+}
+
+func (s *selection) Attribute(key token.Identifier, value token.CharData) error {
+	if s.forwardDepth > 0 {
+		return s.forwards.Attribute(key, value)
+	}
+
+	if !s.inside() {
+		return nil
+	}
+
+	return s.target.Attribute(key, value)
+}
+
+func (s *selection) AttributeForward(key token.Identifier, value token.CharData) error {
+	return s.forwards.AttributeForward(key, value)
+}
+
+func (s *selection) Finalize() error {
+	// Forwards without a target are passed on, so that target reports them.
+	if err := s.releaseForwards(true); err != nil {
+		return err
+	}
+
+	return s.target.Finalize()
+}
+
+// Select returns the first element at path below tree, e.g. "server", "http" for the element #http
+// within #server, or nil if there is none. An empty path selects tree itself.
+func Select(tree *TreeNode, path ...string) *TreeNode {
+	if len(path) == 0 {
+		return tree
+	}
+
+	for _, child := range tree.Children {
+		if child.IsNode() && child.Name == path[0] {
+			if selected := Select(child, path[1:]...); selected != nil {
+				return selected
+			}
+		}
+	}
+
+	return nil
+}
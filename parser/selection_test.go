@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+)
+
+func TestWithSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		path []string
+		// want is the expected tree, in which only the selected element and its ancestors are left.
+		want string
+	}{
+		{
+			name: "nested element",
+			text: "#a{x} #server @id{1} {#http @port{80} {#host localhost} #grpc{y}} #b",
+			path: []string{"server", "http"},
+			want: "#server{#http @port{80} {#host localhost}}",
+		},
+		{
+			name: "first match",
+			text: "#server{#grpc} #server{#http{a}} #server{#http{b}}",
+			path: []string{"server", "http"},
+			want: "#server{} #server{#http{a}}",
+		},
+		{
+			name: "forwarded nodes",
+			text: "#server{##note{x} #http{y} ##skip{z} #grpc}",
+			path: []string{"server", "http"},
+			want: "#server{#http{#note{x} y}}",
+		},
+		{
+			name: "forwarded attributes",
+			text: "@@k{v} #other #server{@@a{b} #http}",
+			path: []string{"server", "http"},
+			want: "#server{#http @a{b}}",
+		},
+		{
+			name: "multi-level forward",
+			text: "#server{###note{x} #grpc{a} #http{y}}",
+			path: []string{"server", "http"},
+			want: "#server{#http{#note{x} y}}",
+		},
+		{
+			name: "g2",
+			text: "#! server {http @port=80 {host \"localhost\"} grpc}",
+			path: []string{"server", "http"},
+			want: "#server{#http @port{80} {#host localhost}}",
+		},
+		{
+			name: "return arrow",
+			text: "#! fn { Run(x int) -> result (int, error) other }",
+			path: []string{"fn", "Run", "ret", "result"},
+			want: "#! fn { Run() -> result (int, error) }",
+		},
+		{
+			name: "no match",
+			text: "#server{#grpc{x}} #a",
+			path: []string{"server", "http"},
+			want: "#server{}",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewParser("", strings.NewReader(test.text), WithSelection(test.path...)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := NewParser("", strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := NormalizeOptions{CollapseWhitespace: true}
+			if !Equal(got, want, opts) {
+				t.Errorf("expected\n%s\nbut got\n%s", Normalize(want, opts).Dump(), Normalize(got, opts).Dump())
+			}
+
+			full, err := NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The selected element is the same as in the full tree, including its ranges.
+			selected, wantSelected := Select(got, test.path...), Select(full, test.path...)
+			if (selected == nil) != (wantSelected == nil) {
+				t.Fatalf("expected %v to be selected, but got %v", wantSelected, selected)
+			}
+
+			if selected != nil && selected.Dump() != wantSelected.Dump() {
+				t.Errorf("expected the selected element\n%s\nbut got\n%s", wantSelected.Dump(), selected.Dump())
+			}
+		})
+	}
+}
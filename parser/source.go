@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"bytes"
+
+	"github.com/golangee/dyml/token"
+)
+
+// SourceMap maps the nodes of a parsed tree to the text they were parsed from and back.
+// Use the WithSourceMap option and Parser.SourceMap to get one for a parsed document,
+// or NewSourceMap if the input is already in memory.
+type SourceMap struct {
+	source []byte
+	root   *TreeNode
+}
+
+// NewSourceMap creates a SourceMap for a tree that was parsed from source.
+func NewSourceMap(source []byte, root *TreeNode) *SourceMap {
+	return &SourceMap{source: source, root: root}
+}
+
+// Root returns the tree of this SourceMap.
+func (m *SourceMap) Root() *TreeNode {
+	return m.root
+}
+
+// Source returns the text that the given node was parsed from, which is the text in its Range.
+// The names of elements do not include the leading '#', but attributes and the closing bracket
+// of blocks are included. Returns an empty string if the Range is not part of the source.
+func (m *SourceMap) Source(node *TreeNode) string {
+	return m.Slice(node.Range)
+}
+
+// Slice returns the text at the given position, e.g. the Range of an attribute.
+// Returns an empty string if the position is not part of the source.
+func (m *SourceMap) Slice(rng token.Position) string {
+	begin, end := rng.BeginPos.Offset, rng.EndPos.Offset
+	if begin < 0 || end > len(m.source) || begin > end {
+		return ""
+	}
+
+	return string(m.source[begin:end])
+}
+
+// Content returns the text of the children of the given node, just like it was written in the source.
+// For nodes with brackets this is the text between the brackets, otherwise it is the text after the
+// name and attributes. The root node contains the whole source.
+// Returns an empty string if the Range of the node is not part of the source.
+func (m *SourceMap) Content(node *TreeNode) string {
+	begin, end := node.Range.BeginPos.Offset, node.Range.EndPos.Offset
+	if begin < 0 || end > len(m.source) || begin > end {
+		return ""
+	}
+
+	// The root node has no name in the source, all other nodes start with their name.
+	if node.Name == "" || !bytes.HasPrefix(m.source[begin:end], []byte(node.Name)) {
+		return string(m.source[begin:end])
+	}
+
+	start := begin + len(node.Name)
+
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attrEnd := node.Attributes.GetAt(i).Range.EndPos.Offset
+		if attrEnd <= start || attrEnd > end {
+			continue
+		}
+
+		// The Range of attributes in G1 does not include the closing bracket of the value.
+		if attrEnd < end && m.source[attrEnd] == '}' {
+			attrEnd++
+		}
+
+		start = attrEnd
+	}
+
+	if node.BlockType == BlockNone {
+		return string(m.source[start:end])
+	}
+
+	open, close := node.BlockType[0], node.BlockType[1]
+
+	// Brackets in the source of descendants do not belong to the block of the node.
+	var skip []token.Position
+
+	var collect func(n *TreeNode)
+	collect = func(n *TreeNode) {
+		for _, child := range n.Children {
+			skip = append(skip, child.Range)
+			collect(child)
+		}
+	}
+
+	collect(node)
+
+	inDescendant := func(offset int) bool {
+		for _, rng := range skip {
+			if offset >= rng.BeginPos.Offset && offset < rng.EndPos.Offset {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	contentBegin := -1
+
+	for i := start; i < end; i++ {
+		switch {
+		case inDescendant(i):
+			continue
+		case contentBegin < 0 && m.source[i] == open:
+			contentBegin = i + 1
+		case contentBegin >= 0 && m.source[i] == close:
+			return string(m.source[contentBegin:i])
+		}
+	}
+
+	return ""
+}
+
+// NodeAt returns the innermost node whose Range contains the given line and column, e.g. to find the
+// node below the cursor in an editor. File and Offset of pos are ignored. Returns nil if no node
+// contains the position, which only happens for positions outside of the document.
+func (m *SourceMap) NodeAt(pos token.Pos) *TreeNode {
+	var (
+		found *TreeNode
+		depth int
+	)
+
+	var visit func(node *TreeNode, d int)
+	visit = func(node *TreeNode, d int) {
+		// Forwarded nodes are placed after their position, so all children need to be checked,
+		// even if their parent does not contain the position.
+		if contains(node.Range, pos) && (found == nil || d > depth) {
+			found, depth = node, d
+		}
+
+		for _, child := range node.Children {
+			visit(child, d+1)
+		}
+	}
+
+	if m.root != nil {
+		visit(m.root, 0)
+	}
+
+	return found
+}
+
+// contains returns true if pos is at or after the beginning and before the end of rng.
+func contains(rng token.Position, pos token.Pos) bool {
+	return !before(pos, rng.BeginPos) && before(pos, rng.EndPos)
+}
+
+// before compares line and column of two positions and returns true if a is before b.
+func before(a, b token.Pos) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+
+	return a.Col < b.Col
+}
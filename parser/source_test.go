@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestSourceMap(t *testing.T) {
+	text := "#? comment\n#server @host{ä} {#port 80}\n#! g2 {fn @public=true (x) -> (z)\na \"text\"}"
+
+	parser := NewParser("test", strings.NewReader(text), WithSourceMap())
+	if parser.SourceMap() != nil {
+		t.Error("expected no source map before parsing")
+	}
+
+	tree, err := parser.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceMap := parser.SourceMap()
+	if sourceMap == nil || sourceMap.Root() != tree {
+		t.Fatal("expected a source map for the parsed tree")
+	}
+
+	server, g2 := tree.Children[1], tree.Children[2]
+	fn := g2.Children[0]
+
+	tests := []struct {
+		name       string
+		node       *TreeNode
+		wantSource string
+		// pos is a position that is expected to be within the node.
+		pos token.Pos
+	}{
+		{
+			name:       "root",
+			node:       tree,
+			wantSource: text,
+			pos:        token.Pos{Line: 1, Col: 1},
+		},
+		{
+			name:       "comment",
+			node:       tree.Children[0],
+			wantSource: "comment\n",
+			pos:        token.Pos{Line: 1, Col: 6},
+		},
+		{
+			name:       "element with multibyte attribute",
+			node:       server,
+			wantSource: "server @host{ä} {#port 80}",
+			pos:        token.Pos{Line: 2, Col: 14},
+		},
+		{
+			name:       "text after multibyte character",
+			node:       server.Children[0].Children[0],
+			wantSource: "80",
+			pos:        token.Pos{Line: 2, Col: 25},
+		},
+		{
+			name:       "g2 element",
+			node:       fn,
+			wantSource: "fn @public=true (x) -> (z)",
+			pos:        token.Pos{Line: 3, Col: 12},
+		},
+		{
+			name:       "return arrow",
+			node:       fn.Children[1],
+			wantSource: "-> (z)",
+			pos:        token.Pos{Line: 3, Col: 29},
+		},
+		{
+			name:       "quoted text",
+			node:       g2.Children[1].Children[0],
+			wantSource: `"text"`,
+			pos:        token.Pos{Line: 4, Col: 4},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := sourceMap.Source(test.node); got != test.wantSource {
+				t.Errorf("expected source '%s', but got '%s'", test.wantSource, got)
+			}
+
+			if got := sourceMap.NodeAt(test.pos); got != test.node {
+				t.Errorf("expected node at %v to be '%s', but got %s", test.pos, test.wantSource, PrettyValue(got))
+			}
+		})
+	}
+
+	if public := fn.Attributes.Get("public"); sourceMap.Slice(public.Range) != "public=true" {
+		t.Errorf("unexpected source of attribute: '%s'", sourceMap.Slice(public.Range))
+	}
+
+	if node := sourceMap.NodeAt(token.Pos{Line: 10, Col: 1}); node != nil {
+		t.Errorf("expected no node outside of the document, but got %s", PrettyValue(node))
+	}
+}
+
+func TestSourceMapContent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		// path are the indices of the children that lead to the node.
+		path []int
+		want string
+	}{
+		{name: "root", text: "#a{b}", want: "#a{b}"},
+		{name: "block", text: "#script{ x < y #b{y} z }", path: []int{0}, want: " x < y #b{y} z "},
+		{name: "attributes", text: `#s @a{x} @b{y\}} {#c{d}}`, path: []int{0}, want: "#c{d}"},
+		{name: "without brackets", text: "#s @a{x} some text", path: []int{0}, want: " some text"},
+		{name: "g2 block", text: `#! g2 { script @a="}" { a "b}", c d } }`, path: []int{0, 0}, want: ` a "b}", c d `},
+		{name: "g2 group with arrow", text: "#! g2 {fn (x, y) -> (z)}", path: []int{0, 0}, want: "x, y"},
+		{name: "forwarded", text: "#s {##x{a} #y{z}}", path: []int{0}, want: "##x{a} #y{z}"},
+		{name: "empty", text: "#s{}", path: []int{0}, want: ""},
+	}
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := NewParser("test", strings.NewReader(test.text), WithSourceMap())
+
+			node, err := parser.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, i := range test.path {
+				node = node.Children[i]
+			}
+
+			if got := parser.SourceMap().Content(node); got != test.want {
+				t.Errorf("expected content '%s', but got '%s'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSourceMapWithoutOption(t *testing.T) {
+	t.Parallel()
+
+	parser := NewParser("test", strings.NewReader("#a"))
+	if _, err := parser.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if parser.SourceMap() != nil {
+		t.Error("expected no source map without WithSourceMap")
+	}
+}
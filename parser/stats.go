@@ -0,0 +1,24 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "time"
+
+// Stats describes the work done to visit a single document, so that services embedding dyml can find
+// parsing hot spots. All fields are plain numbers, which can be published with expvar or added to
+// Prometheus metrics as they are. See Visitor.OnStats and WithStatsHook.
+type Stats struct {
+	// Tokens is the number of tokens read from the lexer.
+	Tokens int
+	// Nodes is the number of nodes, texts and comments, including the root, forwarded nodes and return arrows.
+	Nodes int
+	// MaxDepth is the deepest nesting of nodes, where the root has a depth of 1.
+	MaxDepth int
+	// Bytes is the number of bytes read from the input.
+	Bytes int64
+	// Duration is the time from the start of the run until it ended.
+	Duration time.Duration
+	// Failed is true if the run ended with an error. The other fields then describe the input up to the error.
+	Failed bool
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// countTokens returns the number of tokens in text.
+func countTokens(t *testing.T, text string) int {
+	t.Helper()
+
+	lexer := token.NewLexer("", strings.NewReader(text))
+
+	for count := 0; ; count++ {
+		if _, err := lexer.Token(); errors.Is(err, io.EOF) {
+			return count
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStatsHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantNodes  int
+		wantDepth  int
+		wantFailed bool
+	}{
+		{
+			name:      "empty",
+			text:      "",
+			wantNodes: 1,
+			wantDepth: 1,
+		},
+		{
+			name:      "nested",
+			text:      "#a{#b{text}} #? c",
+			wantNodes: 5,
+			wantDepth: 3,
+		},
+		{
+			name:      "g2",
+			text:      "#! g {fn(x) -> (int)}",
+			wantNodes: 6,
+			wantDepth: 5,
+		},
+		{
+			name:       "failed",
+			text:       "#a @k{1} @k{2}",
+			wantNodes:  2,
+			wantDepth:  2,
+			wantFailed: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls []Stats
+
+			_, err := NewParser("", strings.NewReader(test.text), WithStatsHook(func(stats Stats) {
+				calls = append(calls, stats)
+			})).Parse()
+			if (err != nil) != test.wantFailed {
+				t.Fatalf("expected failure %v, but got %v", test.wantFailed, err)
+			}
+
+			if len(calls) != 1 {
+				t.Fatalf("expected the hook to be called once, but got %d calls", len(calls))
+			}
+
+			stats := calls[0]
+			if stats.Nodes != test.wantNodes || stats.MaxDepth != test.wantDepth || stats.Failed != test.wantFailed {
+				t.Errorf("expected %d nodes, a depth of %d and failed %v, but got %+v",
+					test.wantNodes, test.wantDepth, test.wantFailed, stats)
+			}
+
+			if test.wantFailed {
+				return
+			}
+
+			if want := countTokens(t, test.text); stats.Tokens != want {
+				t.Errorf("expected %d tokens, but got %d", want, stats.Tokens)
+			}
+
+			if stats.Bytes != int64(len(test.text)) {
+				t.Errorf("expected %d bytes, but got %d", len(test.text), stats.Bytes)
+			}
+
+			if stats.Duration <= 0 {
+				t.Errorf("expected a duration, but got %v", stats.Duration)
+			}
+		})
+	}
+}
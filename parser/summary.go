@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// Summary describes a completely visited document. It is passed to the hooks registered with
+// Visitor.OnFinalize, so that whole-document checks do not need to walk the document again.
+type Summary struct {
+	// Nodes is the number of opened nodes, including the root, forwarded nodes and return arrows.
+	Nodes int
+	// Texts is the number of text nodes, including forwarded text.
+	Texts int
+	// Comments is the number of comments.
+	Comments int
+	// MaxDepth is the deepest nesting of nodes, where the root has a depth of 1.
+	MaxDepth int
+	// RootBlockType is the BlockType of the root node.
+	RootBlockType BlockType
+	// DanglingForwards contains the positions of forwarded nodes, texts and attributes that
+	// were not consumed by a following node.
+	DanglingForwards []token.Position
+	// End is the position at the end of the input.
+	End token.Pos
+}
+
+// summaryRecorder wraps a Visitable and records a Summary of all events that pass through it.
+type summaryRecorder struct {
+	Visitable
+	summary Summary
+	// forwarded is a stack that contains for each open node, whether it is forwarded.
+	forwarded []bool
+	// positions is a stack with the position of each open node.
+	positions []token.Position
+	// returnArrows is a stack with the number of nodes each open return arrow has opened.
+	returnArrows []int
+	// pendingAttributes are the positions of forwarded attributes that were not consumed yet.
+	pendingAttributes []token.Position
+	// pendingNodes are the positions of forwarded nodes and texts that were not consumed yet.
+	pendingNodes []token.Position
+}
+
+// push records a newly opened node.
+func (s *summaryRecorder) push(forwarded bool, pos token.Position) {
+	s.forwarded = append(s.forwarded, forwarded)
+	s.positions = append(s.positions, pos)
+	s.summary.Nodes++
+
+	if len(s.forwarded) > s.summary.MaxDepth {
+		s.summary.MaxDepth = len(s.forwarded)
+	}
+}
+
+func (s *summaryRecorder) Open(name token.Identifier) error {
+	s.push(false, name.Position)
+	s.pendingAttributes = nil
+	s.pendingNodes = nil
+
+	return s.Visitable.Open(name)
+}
+
+func (s *summaryRecorder) Comment(comment token.CharData) error {
+	s.summary.Comments++
+
+	return s.Visitable.Comment(comment)
+}
+
+func (s *summaryRecorder) Text(text token.CharData) error {
+	s.summary.Texts++
+
+	return s.Visitable.Text(text)
+}
+
+func (s *summaryRecorder) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	s.push(false, arrow.Position)
+	s.pendingAttributes = nil
+	s.pendingNodes = nil
+
+	if name != nil {
+		s.push(false, name.Position)
+		s.returnArrows = append(s.returnArrows, 2)
+	} else {
+		s.returnArrows = append(s.returnArrows, 1)
+	}
+
+	return s.Visitable.OpenReturnArrow(arrow, name)
+}
+
+func (s *summaryRecorder) CloseReturnArrow() error {
+	// A named return arrow opened two nodes, which are closed together.
+	count := s.returnArrows[len(s.returnArrows)-1]
+	s.returnArrows = s.returnArrows[:len(s.returnArrows)-1]
+
+	s.forwarded = s.forwarded[:len(s.forwarded)-count]
+	s.positions = s.positions[:len(s.positions)-count]
+
+	return s.Visitable.CloseReturnArrow()
+}
+
+func (s *summaryRecorder) SetBlockType(blockType BlockType) error {
+	if len(s.forwarded) == 1 {
+		s.summary.RootBlockType = blockType
+	}
+
+	return s.Visitable.SetBlockType(blockType)
+}
+
+func (s *summaryRecorder) OpenForward(name token.Identifier) error {
+	s.push(true, name.Position)
+	s.pendingAttributes = nil
+
+	return s.Visitable.OpenForward(name)
+}
+
+func (s *summaryRecorder) TextForward(text token.CharData) error {
+	s.summary.Texts++
+	s.pendingNodes = append(s.pendingNodes, text.Position)
+
+	return s.Visitable.TextForward(text)
+}
+
+func (s *summaryRecorder) Close() error {
+	if last := len(s.forwarded) - 1; last >= 0 {
+		if s.forwarded[last] {
+			s.pendingNodes = append(s.pendingNodes, s.positions[last])
+		}
+
+		s.forwarded = s.forwarded[:last]
+		s.positions = s.positions[:last]
+	}
+
+	return s.Visitable.Close()
+}
+
+func (s *summaryRecorder) AttributeForward(key token.Identifier, value token.CharData) error {
+	s.pendingAttributes = append(s.pendingAttributes, token.Position{BeginPos: key.Begin(), EndPos: value.End()})
+
+	return s.Visitable.AttributeForward(key, value)
+}
+
+// finish completes the summary once all events have been processed. end is the position at the end of the input.
+func (s *summaryRecorder) finish(end token.Pos) Summary {
+	s.summary.End = end
+	s.summary.DanglingForwards = append(append([]token.Position{}, s.pendingNodes...), s.pendingAttributes...)
+
+	return s.summary
+}
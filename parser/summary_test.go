@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// nopVisitable accepts all events without building anything, so that documents that a Parser
+// would reject, like ones with dangling forwards, still reach the finalize hooks.
+type nopVisitable struct{}
+
+func (nopVisitable) Open(token.Identifier) error                             { return nil }
+func (nopVisitable) Comment(token.CharData) error                            { return nil }
+func (nopVisitable) Text(token.CharData) error                               { return nil }
+func (nopVisitable) OpenReturnArrow(token.G2Arrow, *token.Identifier) error  { return nil }
+func (nopVisitable) CloseReturnArrow() error                                 { return nil }
+func (nopVisitable) SetBlockType(BlockType) error                            { return nil }
+func (nopVisitable) OpenForward(token.Identifier) error                      { return nil }
+func (nopVisitable) TextForward(token.CharData) error                        { return nil }
+func (nopVisitable) Close() error                                            { return nil }
+func (nopVisitable) Attribute(token.Identifier, token.CharData) error        { return nil }
+func (nopVisitable) AttributeForward(token.Identifier, token.CharData) error { return nil }
+func (nopVisitable) Finalize() error                                         { return nil }
+
+func TestOnFinalize(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantNodes    int
+		wantTexts    int
+		wantComments int
+		wantDepth    int
+		wantDangling int
+	}{
+		{
+			name:      "empty",
+			text:      "",
+			wantNodes: 1,
+			wantDepth: 1,
+		},
+		{
+			name:         "g1",
+			text:         `#? comment #a{#b{text}} more text`,
+			wantNodes:    3,
+			wantTexts:    2,
+			wantComments: 1,
+			wantDepth:    3,
+		},
+		{
+			name:      "forwarding",
+			text:      `##a @@key{value} #b`,
+			wantNodes: 3,
+			wantDepth: 2,
+		},
+		{
+			name:      "return arrows",
+			text:      `#! g2 { hello(string) -> (int) }`,
+			wantNodes: 6,
+			wantDepth: 5,
+		},
+		{
+			name:         "dangling forwards",
+			text:         `#a{##b} ##c`,
+			wantNodes:    4,
+			wantDepth:    3,
+			wantDangling: 2,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var summary *Summary
+
+			visitor := NewVisitor(test.name, strings.NewReader(test.text))
+			visitor.SetVisitable(nopVisitable{})
+			visitor.OnFinalize(func(s Summary) error {
+				summary = &s
+
+				return nil
+			})
+
+			if err := visitor.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if summary == nil {
+				t.Fatal("finalize hook was not called")
+			}
+
+			if summary.RootBlockType != BlockNormal {
+				t.Errorf("expected root block type %v, but got %v", BlockNormal, summary.RootBlockType)
+			}
+
+			got := []int{summary.Nodes, summary.Texts, summary.Comments, summary.MaxDepth, len(summary.DanglingForwards)}
+			want := []int{test.wantNodes, test.wantTexts, test.wantComments, test.wantDepth, test.wantDangling}
+
+			for i, name := range []string{"nodes", "texts", "comments", "depth", "dangling forwards"} {
+				if got[i] != want[i] {
+					t.Errorf("expected %d %s, but got %d", want[i], name, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOnFinalizeError(t *testing.T) {
+	t.Parallel()
+
+	errTooDeep := errors.New("too deep")
+
+	visitor := NewVisitor("error", strings.NewReader("#a{#b{#c}}"))
+	visitor.SetVisitable(nopVisitable{})
+	visitor.OnFinalize(func(s Summary) error {
+		if s.MaxDepth > 3 {
+			return errTooDeep
+		}
+
+		return nil
+	})
+
+	if err := visitor.Run(); !errors.Is(err, errTooDeep) {
+		t.Errorf("expected error from hook, but got %v", err)
+	}
+}
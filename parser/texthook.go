@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golangee/dyml/token"
+)
+
+// TextHook transforms the text of a text node while it is parsed. pos is the position of the text
+// in the input. See WithTextHook.
+type TextHook func(text string, pos token.Position) string
+
+// WithTextHook lets the parser pass every text, including forwarded texts, through hook before
+// it is added to the tree, e.g. to expand entities or abbreviations without walking the tree again.
+// Comments and attribute values are not changed. Hooks are called in the order they were added.
+func WithTextHook(hook TextHook) ParserOption {
+	return func(p *parserConfig) {
+		p.textHooks = append(p.textHooks, hook)
+	}
+}
+
+// Entities returns the named entities that are expanded by ExpandEntities with a nil map.
+func Entities() map[string]string {
+	return map[string]string{
+		"amp":    "&",
+		"lt":     "<",
+		"gt":     ">",
+		"quot":   `"`,
+		"apos":   "'",
+		"nbsp":   "\u00a0",
+		"copy":   "©",
+		"reg":    "®",
+		"trade":  "™",
+		"deg":    "°",
+		"ndash":  "–",
+		"mdash":  "—",
+		"hellip": "…",
+		"laquo":  "«",
+		"raquo":  "»",
+		"euro":   "€",
+		"times":  "×",
+	}
+}
+
+// ExpandEntities returns a TextHook that replaces entities like "&copy;" with their value from
+// entities, or from Entities if entities is nil. Numeric references like "&#169;" and "&#xA9;"
+// are expanded as well. Unknown entities are kept as they are.
+func ExpandEntities(entities map[string]string) TextHook {
+	if entities == nil {
+		entities = Entities()
+	}
+
+	return func(text string, _ token.Position) string {
+		if !strings.Contains(text, "&") {
+			return text
+		}
+
+		var sb strings.Builder
+
+		for {
+			start := strings.IndexByte(text, '&')
+			if start < 0 {
+				break
+			}
+
+			end := strings.IndexByte(text[start:], ';')
+			if end < 0 {
+				break
+			}
+
+			end += start
+
+			sb.WriteString(text[:start])
+
+			if value, ok := expandEntity(text[start+1:end], entities); ok {
+				sb.WriteString(value)
+				text = text[end+1:]
+			} else {
+				// The next entity may begin within this one, e.g. "& &amp;".
+				sb.WriteByte('&')
+				text = text[start+1:]
+			}
+		}
+
+		sb.WriteString(text)
+
+		return sb.String()
+	}
+}
+
+// expandEntity returns the value of the entity with the given name, which may be a numeric reference.
+func expandEntity(name string, entities map[string]string) (string, bool) {
+	if !strings.HasPrefix(name, "#") {
+		value, ok := entities[name]
+
+		return value, ok
+	}
+
+	base := 10
+	digits := name[1:]
+
+	if strings.HasPrefix(digits, "x") || strings.HasPrefix(digits, "X") {
+		base = 16
+		digits = digits[1:]
+	}
+
+	code, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return "", false
+	}
+
+	return string(rune(code)), true
+}
+
+// SmartQuotes returns a TextHook that replaces straight quotes with typographic ones, e.g.
+// "it's" becomes "it’s" and "\"quoted\"" becomes "“quoted”". A quote is opening at the beginning
+// of a text or after whitespace or an opening bracket, otherwise it is closing.
+func SmartQuotes() TextHook {
+	return func(text string, _ token.Position) string {
+		if !strings.ContainsAny(text, `"'`) {
+			return text
+		}
+
+		var sb strings.Builder
+
+		prev := ' '
+
+		for _, r := range text {
+			opening := unicode.IsSpace(prev) || strings.ContainsRune("([{“‘", prev)
+
+			switch {
+			case r == '"' && opening:
+				sb.WriteRune('“')
+			case r == '"':
+				sb.WriteRune('”')
+			case r == '\'' && opening:
+				sb.WriteRune('‘')
+			case r == '\'':
+				sb.WriteRune('’')
+			default:
+				sb.WriteRune(r)
+			}
+
+			prev = r
+		}
+
+		return sb.String()
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestTextHooks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		hook TextHook
+		want string
+	}{
+		{
+			name: "named entities",
+			text: "&copy; 2021 &mdash; all rights reserved",
+			hook: ExpandEntities(nil),
+			want: "© 2021 — all rights reserved",
+		},
+		{
+			name: "numeric entities",
+			text: "&#169; &#xA9; &#X2014;",
+			hook: ExpandEntities(nil),
+			want: "© © —",
+		},
+		{
+			name: "unknown and broken entities",
+			text: "&unknown; & &amp; &#xZZ; &copy",
+			hook: ExpandEntities(nil),
+			want: "&unknown; & & &#xZZ; &copy",
+		},
+		{
+			name: "custom entities",
+			text: "&dyml; &copy;",
+			hook: ExpandEntities(map[string]string{"dyml": "Dyml Markup Language"}),
+			want: "Dyml Markup Language &copy;",
+		},
+		{
+			name: "smart quotes",
+			text: `She said "it's ('fine')".`,
+			hook: SmartQuotes(),
+			want: "She said “it’s (‘fine’)”.",
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := test.hook(test.text, token.Position{})
+			if got != test.want {
+				t.Errorf("expected '%s' but got '%s'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestWithTextHook(t *testing.T) {
+	t.Parallel()
+
+	var positions []int
+
+	record := func(text string, pos token.Position) string {
+		positions = append(positions, pos.BeginPos.Line)
+
+		return text
+	}
+
+	tree, err := NewParser("", strings.NewReader("#a @x{&copy;} {&copy; \"x\"}\n#? &copy;\n##b &amp;\n#c"),
+		WithTextHook(ExpandEntities(nil)), WithTextHook(SmartQuotes()), WithTextHook(record)).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := NewNode("root").Block(BlockNormal).AddChildren(
+		NewNode("a").Block(BlockNormal).AddAttribute("x", "&copy;").AddChildren(NewStringNode("© “x”")),
+		NewStringCommentNode("&copy;\n"),
+		NewNode("c").AddChildren(NewNode("b").AddChildren(NewStringNode("&\n"))),
+	)
+
+	if !Equal(tree, want, NormalizeOptions{}) {
+		t.Errorf("expected\n%s\nbut got\n%s", PrettyValue(want), PrettyValue(tree))
+	}
+
+	if len(positions) != 2 || positions[0] != 1 || positions[1] != 3 {
+		t.Errorf("expected the hook to be called for the texts in lines 1 and 3, but got %v", positions)
+	}
+}
@@ -4,22 +4,42 @@
 package parser
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/golangee/dyml/util"
 
 	"github.com/golangee/dyml/token"
 )
 
+// TypeElement is the name of the element that contains the type of an element
+// annotated with a colon in G2, like "name: string".
+const TypeElement = "type"
+
 // TreeNode is a node in the parse tree.
 // For regular nodes Text and Comment will always be nil.
 // For terminal text nodes Children and Name will be empty and Text will be set.
 // For comment nodes Children and Name will be empty and only Comment will be set.
 type TreeNode struct {
-	Name       string
-	Text       *string
-	Comment    *string
+	Name    string
+	Text    *string
+	Comment *string
+	// TextKind is a hint how the text was written, e.g. KindNumber for an unquoted number in G2,
+	// see token.WithLiterals. It is KindString for all other nodes.
+	TextKind token.ValueKind
+	// CommentKind tells how a comment was written, e.g. token.CommentG2 for a '//' comment.
+	// It is token.CommentNone for all other nodes.
+	CommentKind token.CommentKind
+	// CommentRange spans a comment including its delimiter, while Range only spans its text.
+	CommentRange token.Position
+	// Attributes are kept in source order, with forwarded attributes before the node's own ones.
+	// Keys are unique, unless the parser keeps duplicates with CollectDuplicateAttributes.
+	// Use Attributes.Keys or Attributes.GetAt to iterate them.
 	Attributes util.AttributeList
 	Children   []*TreeNode
 	// BlockType describes the type of brackets the children were surrounded with.
@@ -27,6 +47,15 @@ type TreeNode struct {
 	BlockType BlockType
 	// Range will span all tokens that were processed to build this node.
 	Range token.Position
+	// Synthetic is true for the root element that the parser creates around the whole document, see WithRootName.
+	// Encoders can omit it, as it is not part of the input.
+	Synthetic bool
+	// Metadata holds values that passes like validators, linters or doc generators attach to this node.
+	// It is not part of the document and nil until SetMetadata is used.
+	Metadata map[string]interface{}
+	// parent is the node that contains this node, see Parent. It is not part of the JSON form of a tree,
+	// see MarshalJSON.
+	parent *TreeNode
 	// forwarded is set to true when this node was/should be forwarded.
 	forwarded bool
 	// isNamedReturnArrow is true if this node is the node that was added from a named return arrow.
@@ -45,7 +74,8 @@ func NewNode(name string) *TreeNode {
 // NewTextNode creates a node that will only contain text.
 func NewTextNode(cd *token.CharData) *TreeNode {
 	return &TreeNode{
-		Text: &cd.Value,
+		Text:     &cd.Value,
+		TextKind: cd.Kind,
 		Range: token.Position{
 			BeginPos: cd.Begin(),
 			EndPos:   cd.End(),
@@ -56,7 +86,9 @@ func NewTextNode(cd *token.CharData) *TreeNode {
 // NewCommentNode creates a node that will only contain a comment.
 func NewCommentNode(cd *token.CharData) *TreeNode {
 	return &TreeNode{
-		Comment: &cd.Value,
+		Comment:      &cd.Value,
+		CommentKind:  cd.CommentKind,
+		CommentRange: commentRange(cd),
 		Range: token.Position{
 			BeginPos: cd.Begin(),
 			EndPos:   cd.End(),
@@ -64,6 +96,15 @@ func NewCommentNode(cd *token.CharData) *TreeNode {
 	}
 }
 
+// commentRange returns the range of the comment cd including its delimiter.
+func commentRange(cd *token.CharData) token.Position {
+	if cd.Delimiter == (token.Position{}) {
+		return cd.Position
+	}
+
+	return token.Position{BeginPos: cd.Delimiter.BeginPos, EndPos: cd.End()}
+}
+
 // NewStringNode will create a text node, like NewTextNode,
 // but without positional information. This is only used for testing.
 // Use NewTextNode with a CharData token if you can.
@@ -90,6 +131,8 @@ func (t *TreeNode) AddChildren(children ...*TreeNode) *TreeNode {
 		t.Children = children
 	}
 
+	t.adopt(children)
+
 	return t
 }
 
@@ -103,6 +146,13 @@ func (t *TreeNode) AddAttribute(key, value string) *TreeNode {
 	return t
 }
 
+// RemoveAttribute removes the attribute with the given key, if it exists, and can be used builder-style.
+func (t *TreeNode) RemoveAttribute(key string) *TreeNode {
+	t.Attributes.Remove(key)
+
+	return t
+}
+
 // Block is used to set the BlockType of this node.
 func (t *TreeNode) Block(blockType BlockType) *TreeNode {
 	t.BlockType = blockType
@@ -110,6 +160,61 @@ func (t *TreeNode) Block(blockType BlockType) *TreeNode {
 	return t
 }
 
+// Clone returns a deep copy of this node and all of its children.
+func (t *TreeNode) Clone() *TreeNode {
+	// Size the arena upfront, so that the whole tree can be copied with a handful of allocations.
+	var nodes, children, strings int
+
+	t.walk(func(node *TreeNode) {
+		nodes++
+		children += len(node.Children)
+
+		if node.Text != nil || node.Comment != nil {
+			strings++
+		}
+	})
+
+	arena := newNodeArena(nodes, children, strings)
+
+	clone := t.cloneInto(&arena)
+	clone.parent = nil
+
+	return clone
+}
+
+// cloneInto deep copies this node with all memory taken from the arena.
+func (t *TreeNode) cloneInto(arena *nodeArena) *TreeNode {
+	clone := arena.node()
+	*clone = *t
+	clone.Attributes = t.Attributes.Clone()
+	clone.Metadata = t.cloneMetadata()
+
+	if t.Text != nil {
+		clone.Text = arena.string(*t.Text)
+	}
+
+	if t.Comment != nil {
+		clone.Comment = arena.string(*t.Comment)
+	}
+
+	clone.Children = arena.childSlice(t.Children)
+	for i, child := range clone.Children {
+		clone.Children[i] = child.cloneInto(arena)
+		clone.Children[i].parent = clone
+	}
+
+	return clone
+}
+
+// walk calls fn for this node and all of its descendants in document order.
+func (t *TreeNode) walk(fn func(node *TreeNode)) {
+	fn(t)
+
+	for _, child := range t.Children {
+		child.walk(fn)
+	}
+}
+
 // IsClosedBy returns true if tok is a BlockEnd/GroupEnd/GenericEnd that is the correct
 // match for closing this TreeNode.
 func (t *TreeNode) IsClosedBy(tok token.Token) bool {
@@ -143,6 +248,16 @@ func (t *TreeNode) IsNode() bool {
 	return !t.IsText() && !t.IsComment()
 }
 
+// NameParts splits a qualified name like "http.server" or "std::vector" into its parts, which are
+// separated by '.' or '::'. Names without qualifiers have a single part, texts and comments have none.
+func (t *TreeNode) NameParts() []string {
+	if t.Name == "" {
+		return nil
+	}
+
+	return strings.Split(strings.ReplaceAll(t.Name, "::", "."), ".")
+}
+
 // Parser is used to get a tree representation from dyml input.
 type Parser struct {
 	// finalTree is created when Close is called on the last TreeNode in the workingStack.
@@ -158,26 +273,182 @@ type Parser struct {
 	// They will be constructed on the workingStack and moved into this list once
 	// they have been closed.
 	forwardedNodes []*TreeNode
+	// children collects the children of all nodes on the workingStack. The children of a node
+	// are only copied into the node once it is closed, which is when their number is known.
+	children []*TreeNode
+	// childrenStart contains for each node on the workingStack the index in children,
+	// where its children begin.
+	childrenStart []int
+	// arena provides the memory for all nodes in the tree.
+	arena nodeArena
+	// config contains the limits of this parser.
+	config parserConfig
+	// nodes is the number of nodes created so far, without the root.
+	nodes int
+	// source contains everything read from the input, if WithSourceMap is used.
+	source *bytes.Buffer
+	// warnings are found while building the tree, the lexer keeps its own ones, see Warnings.
+	warnings []token.Warning
 }
 
 // NewParser creates and returns a new Parser with corresponding Visitor.
-func NewParser(filename string, r io.Reader) *Parser {
-	return &Parser{
-		visitor: NewVisitor(filename, r),
+func NewParser(filename string, r io.Reader, opts ...ParserOption) *Parser {
+	p := &Parser{}
+	p.init(filename, r, opts)
+
+	return p
+}
+
+// init configures the parser for the input r. Parsers from a ParserPool are initialized again for every
+// input, which keeps the buffers of their visitor and the unused nodes of their arena.
+func (p *Parser) init(filename string, r io.Reader, opts []ParserOption) {
+	p.config = parserConfig{}
+
+	for _, opt := range opts {
+		opt(&p.config)
+	}
+
+	// The source map contains the transcoded input, as the positions of nodes refer to it.
+	if p.config.charsetDetection {
+		r = token.NewCharsetReader(r)
+	}
+
+	if p.config.sourceMap {
+		p.source = &bytes.Buffer{}
+		r = io.TeeReader(r, p.source)
+	}
+
+	if p.visitor == nil {
+		p.visitor = NewVisitor(filename, r, p.config.lexerOptions...)
+	} else {
+		p.visitor.reset(filename, r, p.config.lexerOptions...)
 	}
+
+	if p.config.rootName != "" {
+		p.visitor.SetRootName(p.config.rootName)
+	}
+
+	if p.config.returnName != "" {
+		p.visitor.SetReturnName(p.config.returnName)
+	}
+
+	p.visitor.SetReservedNameHook(p.config.reservedNameHook)
+
+	for _, hook := range p.config.statsHooks {
+		p.visitor.OnStats(hook)
+	}
+}
+
+// ParseContext creates a Parser and parses the input, stopping with an error once ctx is done.
+func ParseContext(ctx context.Context, filename string, r io.Reader, opts ...ParserOption) (*TreeNode, error) {
+	return NewParser(filename, r, opts...).ParseContext(ctx)
 }
 
 // Parse returns a parsed tree.
 func (p *Parser) Parse() (*TreeNode, error) {
-	p.visitor.SetVisitable(p)
+	return p.ParseContext(context.Background())
+}
+
+// ParseContext works like Parse, but stops with an error wrapping ctx.Err() once ctx is done.
+func (p *Parser) ParseContext(ctx context.Context) (*TreeNode, error) {
+	if len(p.config.selection) > 0 {
+		p.visitor.SetVisitable(newSelection(p, p.visitor, p.config.selection))
+	} else {
+		p.visitor.SetVisitable(p)
+	}
+
+	if err := p.visitor.RunContext(ctx); err != nil {
+		p.finalTree = nil
 
-	if err := p.visitor.Run(); err != nil {
 		return nil, err
 	}
 
+	if warnings := p.Warnings(); p.config.warningsAsErrors && len(warnings) > 0 {
+		p.finalTree = nil
+
+		details := make([]token.ErrDetail, 0, len(warnings)-1)
+		for _, warning := range warnings[1:] {
+			details = append(details, token.NewErrDetail(warning.Position, warning.Message))
+		}
+
+		return nil, token.NewSyntaxError(warnings[0].Position, warnings[0].Message, details...).
+			SetHint("warnings are treated as errors")
+	}
+
 	return p.finalTree, nil
 }
 
+// Warnings returns the suspicious parts of the input, which did not stop parsing, sorted by their position.
+// These are whitespace after a backslash, which is kept as text, empty blocks and elements that use other
+// brackets than earlier siblings with the same name, like "item {a}, item <b>".
+func (p *Parser) Warnings() []token.Warning {
+	warnings := append(append([]token.Warning(nil), p.visitor.lexer.Warnings()...), p.warnings...)
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Begin().Offset < warnings[j].Begin().Offset
+	})
+
+	return warnings
+}
+
+// warn records a warning at rng.
+func (p *Parser) warn(rng token.Position, msg string) {
+	p.warnings = append(p.warnings, token.Warning{Position: rng, Message: msg})
+}
+
+// checkBlocks records warnings for an empty block of node and for children of node with the same name,
+// that use different brackets. It is called once node has been removed from the working stack.
+func (p *Parser) checkBlocks(node *TreeNode) {
+	// The root always has a block, which is empty for empty documents.
+	if node.BlockType != BlockNone && len(node.Children) == 0 && len(p.workingStack) > 0 {
+		p.warn(node.Range, fmt.Sprintf("'%s' has an empty block", node.Name))
+	}
+
+	// Children with different brackets require at least one that does not use the usual '{}'.
+	unusual := false
+
+	for _, child := range node.Children {
+		if child.BlockType == BlockGroup || child.BlockType == BlockGeneric {
+			unusual = true
+
+			break
+		}
+	}
+
+	if !unusual {
+		return
+	}
+
+	first := map[string]*TreeNode{}
+
+	for _, child := range node.Children {
+		if !child.IsNode() || child.BlockType == BlockNone {
+			continue
+		}
+
+		previous, ok := first[child.Name]
+		if !ok {
+			first[child.Name] = child
+
+			continue
+		}
+
+		if previous.BlockType != child.BlockType {
+			p.warn(child.Range, fmt.Sprintf("'%s' uses '%s', but an earlier '%s' uses '%s'",
+				child.Name, child.BlockType, child.Name, previous.BlockType))
+		}
+	}
+}
+
+// SourceMap returns a SourceMap for the parsed tree. Returns nil if the parser was not created
+// with WithSourceMap or the input was not parsed successfully.
+func (p *Parser) SourceMap() *SourceMap {
+	if p.source == nil || p.finalTree == nil {
+		return nil
+	}
+
+	return NewSourceMap(p.source.Bytes(), p.finalTree)
+}
+
 // getStackTop returns the topmost element in the working stack.
 func (p *Parser) getStackTop() (*TreeNode, error) {
 	if len(p.workingStack) > 0 {
@@ -187,12 +458,20 @@ func (p *Parser) getStackTop() (*TreeNode, error) {
 	return nil, errors.New("you found a bug: could not get top of stack in parser")
 }
 
-// popStack removes the topmost element from the working stack.
+// popStack removes the topmost element from the working stack and moves its collected
+// children into it.
 func (p *Parser) popStack() (*TreeNode, error) {
 	if len(p.workingStack) > 0 {
 		node := p.workingStack[len(p.workingStack)-1]
 		p.workingStack = p.workingStack[:len(p.workingStack)-1]
 
+		start := p.childrenStart[len(p.childrenStart)-1]
+		p.childrenStart = p.childrenStart[:len(p.childrenStart)-1]
+
+		node.Children = p.arena.childSlice(p.children[start:])
+		node.adopt(node.Children)
+		p.children = p.children[:start]
+
 		return node, nil
 	}
 
@@ -202,6 +481,137 @@ func (p *Parser) popStack() (*TreeNode, error) {
 // pushStack adds an element to the top of the stack.
 func (p *Parser) pushStack(node *TreeNode) {
 	p.workingStack = append(p.workingStack, node)
+	p.childrenStart = append(p.childrenStart, len(p.children))
+}
+
+// addChild adds a child to the node on top of the working stack.
+func (p *Parser) addChild(child *TreeNode) error {
+	if len(p.workingStack) == 0 {
+		return errors.New("you found a bug: could not get top of stack in parser")
+	}
+
+	p.children = append(p.children, child)
+
+	return nil
+}
+
+// countNode counts a new node at rng, that will be pushed onto the working stack if push is set.
+// It returns an error if this exceeds the limits for the number of nodes or the nesting depth.
+func (p *Parser) countNode(rng token.Position, push bool) error {
+	// The root does not count.
+	if len(p.workingStack) == 0 {
+		return nil
+	}
+
+	p.nodes++
+	if p.config.maxNodes > 0 && p.nodes > p.config.maxNodes {
+		return token.NewLimitError(rng, "number of nodes", p.config.maxNodes)
+	}
+
+	if push && p.config.maxDepth > 0 && len(p.workingStack) > p.config.maxDepth {
+		return token.NewLimitError(rng, "nesting depth", p.config.maxDepth)
+	}
+
+	return nil
+}
+
+// checkAttributes returns an error if count exceeds the limit for attributes per element.
+func (p *Parser) checkAttributes(rng token.Position, count int) error {
+	if p.config.maxAttributes > 0 && count > p.config.maxAttributes {
+		return token.NewLimitError(rng, "number of attributes", p.config.maxAttributes)
+	}
+
+	return nil
+}
+
+// checkSiblings returns an error if WithUniqueSiblings is used and node has multiple child elements
+// with the same name, which is not allowed to be repeated.
+func (p *Parser) checkSiblings(node *TreeNode) error {
+	if !p.config.uniqueSiblings || len(node.Children) < 2 {
+		return nil
+	}
+
+	first := map[string]*TreeNode{}
+
+	for _, child := range node.Children {
+		if !child.IsNode() || p.config.repeatable[child.Name] {
+			continue
+		}
+
+		if previous, ok := first[child.Name]; ok {
+			return token.NewSyntaxError(child.Range, fmt.Sprintf("'%s' is already defined", child.Name),
+				token.NewErrDetail(previous.Range, "first defined here"))
+		}
+
+		first[child.Name] = child
+	}
+
+	return nil
+}
+
+// newNode is like NewNode, but takes the memory from the arena.
+func (p *Parser) newNode(name string, rng token.Position) (*TreeNode, error) {
+	if err := p.countNode(rng, true); err != nil {
+		return nil, err
+	}
+
+	node := p.arena.node()
+	node.Name = name
+	node.BlockType = BlockNone
+	node.Range = rng
+
+	return node, nil
+}
+
+// newCharDataNode creates a node with a copy of the value from cd, which becomes either the Text
+// or the Comment of the node.
+func (p *Parser) newCharDataNode(cd *token.CharData, comment bool) (*TreeNode, error) {
+	if err := p.countNode(cd.Position, false); err != nil {
+		return nil, err
+	}
+
+	node := p.arena.node()
+	node.Range = token.Position{
+		BeginPos: cd.Begin(),
+		EndPos:   cd.End(),
+	}
+
+	if comment {
+		node.Comment = p.arena.string(cd.Value)
+		node.CommentKind = cd.CommentKind
+		node.CommentRange = commentRange(cd)
+	} else {
+		text := cd.Value
+		for _, hook := range p.config.textHooks {
+			text = hook(text, cd.Position)
+		}
+
+		// A literal that was changed by a hook is an ordinary text.
+		if text == cd.Value {
+			node.TextKind = cd.Kind
+		}
+
+		node.Text = p.arena.string(text)
+	}
+
+	return node, nil
+}
+
+// addAttribute adds attr to node as configured by WithDuplicateAttributes.
+// It returns true if the key is already used and duplicates are rejected.
+func (p *Parser) addAttribute(node *TreeNode, attr util.Attribute) bool {
+	switch p.config.duplicateAttributes {
+	case LastAttributeWins:
+		node.Attributes.Set(attr)
+
+		return false
+	case CollectDuplicateAttributes:
+		node.Attributes.Add(attr)
+
+		return false
+	default:
+		return node.Attributes.Set(attr)
+	}
 }
 
 // applyForwardedAttributes applies all forwarded attributes to the node.
@@ -210,8 +620,10 @@ func (p *Parser) applyForwardedAttributes(node *TreeNode) error {
 		attr := p.forwardedAttributes.Pop()
 		if attr == nil {
 			break
-		} else if node.Attributes.Set(*attr) {
-			return token.NewPosError(attr.Range, "attribute defined multiple times")
+		} else if p.addAttribute(node, *attr) {
+			return token.NewSyntaxError(attr.Range, "attribute defined multiple times")
+		} else if err := p.checkAttributes(attr.Range, node.Attributes.Len()); err != nil {
+			return err
 		}
 	}
 
@@ -219,55 +631,59 @@ func (p *Parser) applyForwardedAttributes(node *TreeNode) error {
 }
 
 func (p *Parser) Open(name token.Identifier) error {
-	return p.openNode(name.Value)
+	return p.openNode(name.Value, name.Position)
 }
 
-func (p *Parser) openNode(name string) error {
-	node := NewNode(name)
+// openNode pushes a new node onto the working stack. rng is the position of the tokens
+// that opened the node, its end will be updated once the node gets closed.
+func (p *Parser) openNode(name string, rng token.Position) error {
+	node, err := p.newNode(name, rng)
+	if err != nil {
+		return err
+	}
 
 	if err := p.applyForwardedAttributes(node); err != nil {
 		return err
 	}
 
-	// Place all forwarded nodes in this node.
-	node.AddChildren(p.forwardedNodes...)
-	p.forwardedNodes = nil
+	// The first node is the root, which the visitor opens around the document.
+	node.Synthetic = p.finalTree == nil && len(p.workingStack) == 0
 
 	p.pushStack(node)
 
+	// Place all forwarded nodes in this node.
+	p.children = append(p.children, p.forwardedNodes...)
+	p.forwardedNodes = p.forwardedNodes[:0]
+
 	return nil
 }
 
 func (p *Parser) Comment(comment token.CharData) error {
-	top, err := p.getStackTop()
+	node, err := p.newCharDataNode(&comment, true)
 	if err != nil {
 		return err
 	}
 
-	top.AddChildren(NewCommentNode(&comment))
-
-	return nil
+	return p.addChild(node)
 }
 
 func (p *Parser) Text(text token.CharData) error {
-	top, err := p.getStackTop()
+	node, err := p.newCharDataNode(&text, false)
 	if err != nil {
 		return err
 	}
 
-	top.AddChildren(NewTextNode(&text))
-
-	return nil
+	return p.addChild(node)
 }
 
 func (p *Parser) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
-	if err := p.openNode("ret"); err != nil {
+	if err := p.openNode(p.visitor.ReturnName(), arrow.Position); err != nil {
 		return err
 	}
 
 	// A named return will have an additional node.
 	if name != nil {
-		if err := p.openNode(name.Value); err != nil {
+		if err := p.openNode(name.Value, name.Position); err != nil {
 			return err
 		}
 
@@ -289,12 +705,16 @@ func (p *Parser) CloseReturnArrow() error {
 		}
 	}
 
-	// Pop the "ret" element
+	// Pop the return element
 	return p.Close()
 }
 
 func (p *Parser) OpenForward(name token.Identifier) error {
-	node := NewNode(name.Value)
+	node, err := p.newNode(name.Value, name.Position)
+	if err != nil {
+		return err
+	}
+
 	node.forwarded = true
 	p.pushStack(node)
 
@@ -306,7 +726,11 @@ func (p *Parser) OpenForward(name token.Identifier) error {
 }
 
 func (p *Parser) TextForward(text token.CharData) error {
-	node := NewTextNode(&text)
+	node, err := p.newCharDataNode(&text, false)
+	if err != nil {
+		return err
+	}
+
 	node.forwarded = true
 	p.forwardedNodes = append(p.forwardedNodes, node)
 
@@ -332,6 +756,17 @@ func (p *Parser) Close() error {
 		return err
 	}
 
+	// All tokens of this node have been processed, so the last one marks its end.
+	if p.visitor.lastEnd.After(child.Range.EndPos) {
+		child.Range.EndPos = p.visitor.lastEnd
+	}
+
+	if err := p.checkSiblings(child); err != nil {
+		return err
+	}
+
+	p.checkBlocks(child)
+
 	if child.forwarded {
 		p.forwardedNodes = append(p.forwardedNodes, child)
 
@@ -339,7 +774,7 @@ func (p *Parser) Close() error {
 	}
 
 	if len(p.workingStack) > 0 {
-		p.workingStack[len(p.workingStack)-1].AddChildren(child)
+		p.children = append(p.children, child)
 	} else {
 		if p.finalTree == nil {
 			p.finalTree = child
@@ -357,18 +792,19 @@ func (p *Parser) Attribute(key token.Identifier, value token.CharData) error {
 		return err
 	}
 
-	if top.Attributes.Set(util.Attribute{
+	if p.addAttribute(top, util.Attribute{
 		Key:   key.Value,
 		Value: value.Value,
 		Range: token.Position{
 			BeginPos: key.Begin(),
 			EndPos:   value.End(),
 		},
+		Kind: value.Kind,
 	}) {
-		return token.NewPosError(key.Pos(), "attribute already defined")
+		return token.NewSyntaxError(key.Pos(), "attribute already defined")
 	}
 
-	return nil
+	return p.checkAttributes(key.Position, top.Attributes.Len())
 }
 
 func (p *Parser) AttributeForward(key token.Identifier, value token.CharData) error {
@@ -379,22 +815,25 @@ func (p *Parser) AttributeForward(key token.Identifier, value token.CharData) er
 			BeginPos: key.Begin(),
 			EndPos:   value.End(),
 		},
+		Kind: value.Kind,
 	})
 
-	return nil
+	return p.checkAttributes(key.Position, p.forwardedAttributes.Len())
 }
 
 func (p *Parser) Finalize() error {
 	if len(p.forwardedNodes) > 0 {
 		node := p.forwardedNodes[0]
 
-		return token.NewPosError(node.Range, "forwarded node cannot be forwarded anywhere")
+		return token.NewPosError(node.Range, "forwarded node cannot be forwarded anywhere").
+			SetCategory(token.ErrForwarding)
 	}
 
 	if p.forwardedAttributes.Len() > 0 {
 		attr := p.forwardedAttributes.Pop()
 
-		return token.NewPosError(attr.Range, "forwarded attribute cannot be forwarded anywhere")
+		return token.NewPosError(attr.Range, "forwarded attribute cannot be forwarded anywhere").
+			SetCategory(token.ErrForwarding)
 	}
 
 	return nil
@@ -9,8 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/golangee/dyml/compare"
 	. "github.com/golangee/dyml/parser"
-	"github.com/r3labs/diff/v2"
+	"github.com/golangee/dyml/token"
 )
 
 func TestParser(t *testing.T) {
@@ -370,9 +371,9 @@ func TestParser(t *testing.T) {
 				NewNode("g2").Block(BlockNormal).AddChildren(
 					NewNode("item"),
 					NewNode("item").
-						AddAttribute("not", "forwarded").
 						AddAttribute("key", "value").
-						AddAttribute("another", "one"),
+						AddAttribute("another", "one").
+						AddAttribute("not", "forwarded"),
 					NewNode("parent").
 						AddChildren(
 							NewNode("child").
@@ -422,6 +423,83 @@ func TestParser(t *testing.T) {
 				),
 			),
 		},
+		{
+			name: "flag attributes",
+			text: `#item @disabled @@hidden #a {#b @y} #c @x @z{1}
+					#! g2 {
+						d @x, e @y=2 @z { f }
+						# #g @flag
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("item").AddAttribute("disabled", "true"),
+				NewNode("a").AddAttribute("hidden", "true").Block(BlockNormal).AddChildren(
+					NewNode("b").AddAttribute("y", "true"),
+				),
+				NewNode("c").AddAttribute("x", "true").AddAttribute("z", "1"),
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("d").AddAttribute("x", "true"),
+					NewNode("e").AddAttribute("y", "2").AddAttribute("z", "true").Block(BlockNormal).AddChildren(
+						NewNode("f"),
+					),
+					NewNode("g").AddAttribute("flag", "true"),
+				),
+			),
+		},
+		{
+			name: "G2 in G1 line",
+			text: `#! g2 {
+						# #! a {b} text #! c {d}
+						e
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("a").Block(BlockNormal).AddChildren(NewNode("b")),
+					NewStringNode("text "),
+					NewNode("c").Block(BlockNormal).AddChildren(NewNode("d")),
+					NewNode("e"),
+				),
+			),
+		},
+		{
+			name: "G2 and G1 nested at multiple depths",
+			text: `#doc {
+						#! api {
+							# Returns #b{all} items. #! list {
+								fn -> (item)
+								# #note {#! see {other}}
+							}
+							count
+						}
+						#footer
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("doc").Block(BlockNormal).AddChildren(
+					NewNode("api").Block(BlockNormal).AddChildren(
+						NewStringNode("Returns "),
+						NewNode("b").Block(BlockNormal).AddChildren(NewStringNode("all")),
+						NewStringNode("items. "),
+						NewNode("list").Block(BlockNormal).AddChildren(
+							NewNode("fn").AddChildren(
+								NewNode("ret").Block(BlockGroup).AddChildren(NewNode("item")),
+							),
+							NewNode("note").Block(BlockNormal).AddChildren(
+								NewNode("see").Block(BlockNormal).AddChildren(NewNode("other")),
+							),
+						),
+						NewNode("count"),
+					),
+					NewNode("footer"),
+				),
+			),
+		},
+		{
+			name: "G2 in forward G1 line",
+			text: `#! g2 {
+						## #! a {b}
+						c
+					}`,
+			wantErr: true,
+		},
 		{
 			name: "forward G1 line",
 			text: `#! g2 {
@@ -469,6 +547,45 @@ func TestParser(t *testing.T) {
 				),
 			),
 		},
+		{
+			name: "multi-level forwarding",
+			text: `###a{#x} ##b @@@k{v} #c #d`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("c").AddChildren(
+					NewNode("b"),
+				),
+				NewNode("d").AddAttribute("k", "v").AddChildren(
+					NewNode("a").Block(BlockNormal).AddChildren(
+						NewNode("x"),
+					),
+				),
+			),
+		},
+		{
+			name: "multi-level forward G1 line",
+			text: `#! g2 {
+						### hello
+						a, b
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("a"),
+					NewNode("b").AddChildren(
+						NewStringNode("hello"),
+					),
+				),
+			),
+		},
+		{
+			name:    "multi-level forwarding without target",
+			text:    `###a #b`,
+			wantErr: true,
+		},
+		{
+			name:    "nested multi-level forwarding",
+			text:    `###a{###x #y #z} #b #c`,
+			wantErr: true,
+		},
 		{
 			name: "invalid forward G1 line",
 			text: `#! g2 {
@@ -651,6 +768,113 @@ func TestParser(t *testing.T) {
 					}`,
 			wantErr: true,
 		},
+		{
+			name: "g2 named return arrow with block",
+			text: `#! g2 {
+						Run(x int) -> result (int, error)
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("Run").Block(BlockGroup).AddChildren(
+						NewNode("x").AddChildren(NewNode("int")),
+						NewNode("ret").AddChildren(
+							NewNode("result").Block(BlockGroup).AddChildren(
+								NewNode("int"),
+								NewNode("error"),
+							),
+						),
+					),
+				),
+			),
+		},
+		{
+			name: "g2 named return arrow followed by sibling",
+			text: `#! g2 {
+						x -> y, z
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("x").AddChildren(
+						NewNode("ret").AddChildren(
+							NewNode("y"),
+						),
+					),
+					NewNode("z"),
+				),
+			),
+		},
+		{
+			name: "g2 return arrow with attributes",
+			text: `#! g2 {
+						Find(id string) -> @nullable=true @since="1.2" (User)
+						Load(id string) -> user @cached=true (User)
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("g2").Block(BlockNormal).AddChildren(
+					NewNode("Find").Block(BlockGroup).AddChildren(
+						NewNode("id").AddChildren(NewNode("string")),
+						NewNode("ret").Block(BlockGroup).
+							AddAttribute("nullable", "true").
+							AddAttribute("since", "1.2").
+							AddChildren(NewNode("User")),
+					),
+					NewNode("Load").Block(BlockGroup).AddChildren(
+						NewNode("id").AddChildren(NewNode("string")),
+						NewNode("ret").AddChildren(
+							NewNode("user").Block(BlockGroup).AddAttribute("cached", "true").AddChildren(
+								NewNode("User"),
+							),
+						),
+					),
+				),
+			),
+		},
+		{
+			name: "g2 return arrow with attributes before its name",
+			text: `#! g2 {
+						Load(id string) -> @cached=true user (User)
+					}`,
+			wantErr: true,
+		},
+		{
+			name: "g2 type annotations",
+			text: `#! schema {
+						name: string @max="64"
+						tags: list<string>, age: int
+						address: Address { street: string }
+					}`,
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("schema").Block(BlockNormal).AddChildren(
+					NewNode("name").AddChildren(
+						NewNode(TypeElement).AddChildren(NewNode("string").AddAttribute("max", "64")),
+					),
+					NewNode("tags").AddChildren(
+						NewNode(TypeElement).AddChildren(
+							NewNode("list").Block(BlockGeneric).AddChildren(NewNode("string")),
+						),
+					),
+					NewNode("age").AddChildren(
+						NewNode(TypeElement).AddChildren(NewNode("int")),
+					),
+					NewNode("address").AddChildren(
+						NewNode(TypeElement).AddChildren(
+							NewNode("Address").Block(BlockNormal).AddChildren(
+								NewNode("street").AddChildren(
+									NewNode(TypeElement).AddChildren(NewNode("string")),
+								),
+							),
+						),
+					),
+				),
+			),
+		},
+		{
+			name: "g2 type annotation without type",
+			text: `#! schema {
+						name: , age: int
+					}`,
+			wantErr: true,
+		},
 		{
 			name: "g2 arrow directly after preamble",
 			text: `#! x -> y`,
@@ -791,6 +1015,35 @@ func TestParser(t *testing.T) {
 				),
 			),
 		},
+		{
+			name: "g2 qualified names",
+			text: "#! http.server @tls::mode=\"strict\" { std::vector: ns::list }",
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("http.server").
+					AddAttribute("tls::mode", "strict").
+					Block(BlockNormal).
+					AddChildren(
+						NewNode("std::vector").AddChildren(
+							NewNode(TypeElement).AddChildren(NewNode("ns::list")),
+						),
+					),
+			),
+		},
+		{
+			name:    "g2 invalid qualified name",
+			text:    "#! a:::b",
+			wantErr: true,
+		},
+		{
+			name: "g2 raw strings",
+			text: "#! snippet @lang=`go` {\n\t`if a {\n\t\"#b\"\n}`\n}",
+			want: NewNode("root").Block(BlockNormal).AddChildren(
+				NewNode("snippet").
+					AddAttribute("lang", "go").
+					Block(BlockNormal).
+					AddChildren(NewStringNode("if a {\n\t\"#b\"\n}")),
+			),
+		},
 	}
 
 	t.Parallel()
@@ -829,39 +1082,11 @@ func TestParser(t *testing.T) {
 				}
 			}
 
-			differences, err := diff.Diff(tt.want, tree,
-				diff.Filter(func(path []string, parent reflect.Type, field reflect.StructField) bool {
-					// Skip any unexported fields when comparing
-					return field.IsExported()
-				}))
-			if err != nil {
-				t.Error(err)
-
-				return
-			}
-
-			// These descriptions map the type of a change to a more readable format.
-			changeTypeDescription := map[string]string{
-				"create": "was added",
-				"update": "is different",
-				"delete": "is missing",
-			}
+			if !Equal(tt.want, tree, NormalizeOptions{}) {
+				t.Error("expected the parsed tree to match")
 
-			if len(differences) > 0 {
-				for _, d := range differences {
-					nicePath := strings.Join(d.Path, ".")
-
-					// Skip differences on node ranges, as those are too noisy to test.
-					// This is a bit hacky, but is fine for testing. It would be nicer to
-					// have a custom recursive function to compare nodes.
-					if strings.Contains(nicePath, "Range.") {
-						continue
-					}
-
-					t.Errorf("property '%s' %s, expected %s but got %s",
-						nicePath,
-						changeTypeDescription[d.Type],
-						PrettyValue(d.From), PrettyValue(d.To))
+				for _, change := range compare.Diff(tt.want, tree) {
+					t.Log(change)
 				}
 			}
 		})
@@ -870,11 +1095,176 @@ func TestParser(t *testing.T) {
 
 // PrettyValue transforms values into a human readable form.
 // Usually "%#v" in fmt.Sprintf can give a nice description of the thing
-// you're passing in, but that does not apply to e.g. string pointers.
+// you're passing in, but that does not apply to e.g. string pointers or trees.
 func PrettyValue(v interface{}) string {
 	if s, ok := v.(*string); ok {
 		return fmt.Sprintf("%#v", *s)
 	}
 
+	if node, ok := v.(*TreeNode); ok && node != nil {
+		return node.Dump()
+	}
+
 	return fmt.Sprintf("%#v", v)
 }
+
+func TestParsedNodesAreIndependent(t *testing.T) {
+	t.Parallel()
+
+	tree := mustParse(t, "#a{#x #y} #b{#z}")
+
+	// Nodes share memory internally, appending to one node must not change another one.
+	for _, tt := range []*TreeNode{tree, tree.Clone()} {
+		a, b := tt.Children[0], tt.Children[1]
+		a.AddChildren(NewNode("added"))
+		*a.Children[0] = *NewNode("replaced")
+
+		if len(a.Children) != 3 || len(b.Children) != 1 || b.Children[0].Name != "z" {
+			t.Errorf("unexpected change in sibling: %s", PrettyValue(b.Children[0]))
+		}
+	}
+
+	if clone := tree.Clone(); clone.Children[0] == tree.Children[0] {
+		t.Error("clone shares nodes with the original")
+	}
+}
+
+func TestAttributeOrder(t *testing.T) {
+	t.Parallel()
+
+	tree := mustParse(t, "##x @@b{1} @@a{2} #y @d{3} @c{4} #! z @f=5 @e=6")
+	y := tree.Children[0]
+
+	if got, want := strings.Join(y.Attributes.Keys(), " "), "b a d c"; got != want {
+		t.Errorf("expected attributes '%s', but got '%s'", want, got)
+	}
+
+	if got, want := strings.Join(tree.Children[1].Attributes.Keys(), " "), "f e"; got != want {
+		t.Errorf("expected attributes '%s', but got '%s'", want, got)
+	}
+
+	if attr := y.Attributes.GetAt(2); attr == nil || attr.Key != "d" || attr.Range.BeginPos.Col == 0 {
+		t.Errorf("unexpected attribute at index 2: %v", attr)
+	}
+
+	if y.Attributes.GetAt(4) != nil || y.Attributes.GetAt(-1) != nil {
+		t.Error("expected nil for indices out of range")
+	}
+
+	// Removing from a copy must not change the original.
+	attributes := y.Attributes
+	if !attributes.Remove("b") || attributes.Remove("missing") {
+		t.Error("unexpected result of Remove")
+	}
+
+	if got, want := strings.Join(attributes.Keys(), " "), "a d c"; got != want {
+		t.Errorf("expected attributes '%s' after removal, but got '%s'", want, got)
+	}
+
+	if y.Attributes.Len() != 4 || y.Attributes.GetAt(0).Key != "b" {
+		t.Errorf("removal changed the original list: %v", y.Attributes.Keys())
+	}
+
+	y.RemoveAttribute("c")
+
+	if got, want := strings.Join(y.Attributes.Keys(), " "), "b a d"; got != want {
+		t.Errorf("expected attributes '%s' after removal, but got '%s'", want, got)
+	}
+}
+
+func TestNameParts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		node *TreeNode
+		want []string
+	}{
+		{node: NewNode("server"), want: []string{"server"}},
+		{node: NewNode("http.server"), want: []string{"http", "server"}},
+		{node: NewNode("std::vector"), want: []string{"std", "vector"}},
+		{node: NewNode("a.b::c"), want: []string{"a", "b", "c"}},
+		{node: NewStringNode("text"), want: nil},
+	}
+
+	for _, test := range tests {
+		if got := test.node.NameParts(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expected %q for '%s', but got %q", test.want, test.node.Name, got)
+		}
+	}
+}
+
+func TestParserLiterals(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewParser("", strings.NewReader(`#! a { 1.5, true, "text" }`),
+		WithLexerOptions(token.WithLiterals())).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []token.ValueKind{token.KindNumber, token.KindBool, token.KindString}
+
+	children := tree.Children[0].Children
+	if len(children) != len(want) {
+		t.Fatalf("expected %d texts, but got\n%s", len(want), tree.Dump())
+	}
+
+	for i, child := range children {
+		if !child.IsText() || child.TextKind != want[i] {
+			t.Errorf("expected a %s text, but got %s", want[i], child.Dump())
+		}
+	}
+}
+
+func TestCommentKinds(t *testing.T) {
+	t.Parallel()
+
+	text := "#? first\n#! a {\n  // second\n  # #b #?  third\n}"
+	tree := mustParse(t, text)
+
+	var comments []*TreeNode
+
+	var collect func(node *TreeNode)
+	collect = func(node *TreeNode) {
+		if node.IsComment() {
+			comments = append(comments, node)
+		}
+
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(tree)
+
+	want := []struct {
+		kind   token.CommentKind
+		source string
+	}{
+		{kind: token.CommentG1, source: "#? first\n"},
+		{kind: token.CommentG2, source: "// second"},
+		{kind: token.CommentG1Line, source: "#?  third"},
+	}
+
+	if len(comments) != len(want) {
+		t.Fatalf("expected %d comments, but got\n%s", len(want), tree.Dump())
+	}
+
+	for i, comment := range comments {
+		if comment.CommentKind != want[i].kind {
+			t.Errorf("expected a %s comment, but got %s", want[i].kind, comment.CommentKind)
+		}
+
+		rng := comment.CommentRange
+		if source := text[rng.BeginPos.Offset:rng.EndPos.Offset]; source != want[i].source {
+			t.Errorf("expected the comment range to span %q, but got %q", want[i].source, source)
+		}
+
+		if !strings.HasPrefix(want[i].source, comment.CommentKind.Delimiter()) {
+			t.Errorf("unexpected delimiter '%s'", comment.CommentKind.Delimiter())
+		}
+
+		if comment.Range.BeginPos.Offset <= rng.BeginPos.Offset || comment.Range.EndPos != rng.EndPos {
+			t.Errorf("expected the range of the text %v to end the comment range %v", comment.Range, rng)
+		}
+	}
+}
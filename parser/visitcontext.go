@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import "github.com/golangee/dyml/token"
+
+// ContextVisitable is a Visitable that wants to know where in the document an event occurs,
+// so that it does not have to keep track of the open nodes by itself.
+type ContextVisitable interface {
+	Visitable
+	// SetVisitContext is called once before the first event. The visitor updates ctx before
+	// each event, so that it can be inspected in every method of the Visitable.
+	SetVisitContext(ctx *VisitContext)
+}
+
+// VisitContext describes the nodes that are open while an event is visited. The root node
+// created by the visitor is included, so that top-level elements have a depth of 2.
+// In Open, OpenForward and OpenReturnArrow the context already contains the opened node,
+// in Close and CloseReturnArrow it still contains the node that is being closed.
+// Return arrows are named like in the tree of a Parser, see Visitor.SetReturnName.
+type VisitContext struct {
+	names      []string
+	blockTypes []BlockType
+	// returnArrows is a stack with the number of nodes each open return arrow has opened.
+	returnArrows []int
+}
+
+// Depth returns the number of open nodes.
+func (c *VisitContext) Depth() int {
+	return len(c.names)
+}
+
+// Path returns the names of all open nodes, starting with the root.
+func (c *VisitContext) Path() []string {
+	return append([]string(nil), c.names...)
+}
+
+// BlockTypes returns the BlockType of all open nodes, starting with the root.
+// Nodes whose block type has not been set yet have BlockNone.
+func (c *VisitContext) BlockTypes() []BlockType {
+	return append([]BlockType(nil), c.blockTypes...)
+}
+
+// Name returns the name of the innermost open node or "" if no node is open.
+func (c *VisitContext) Name() string {
+	if len(c.names) == 0 {
+		return ""
+	}
+
+	return c.names[len(c.names)-1]
+}
+
+// BlockType returns the BlockType of the innermost open node or BlockNone if no node is open.
+func (c *VisitContext) BlockType() BlockType {
+	if len(c.blockTypes) == 0 {
+		return BlockNone
+	}
+
+	return c.blockTypes[len(c.blockTypes)-1]
+}
+
+// push records a newly opened node.
+func (c *VisitContext) push(name string) {
+	c.names = append(c.names, name)
+	c.blockTypes = append(c.blockTypes, BlockNone)
+}
+
+// pop removes the count innermost nodes.
+func (c *VisitContext) pop(count int) {
+	if count > len(c.names) {
+		count = len(c.names)
+	}
+
+	c.names = c.names[:len(c.names)-count]
+	c.blockTypes = c.blockTypes[:len(c.blockTypes)-count]
+}
+
+// withVisitContext wraps v in a contextTracker, if it is a ContextVisitable.
+// returnName is the name of the elements after return arrows.
+func withVisitContext(v Visitable, returnName string) Visitable {
+	cv, ok := v.(ContextVisitable)
+	if !ok {
+		return v
+	}
+
+	tracker := &contextTracker{Visitable: v, returnName: returnName}
+	cv.SetVisitContext(&tracker.ctx)
+
+	return tracker
+}
+
+// contextTracker wraps a Visitable and updates a VisitContext before each event.
+type contextTracker struct {
+	Visitable
+	ctx VisitContext
+	// returnName is the name of the elements after return arrows.
+	returnName string
+}
+
+func (c *contextTracker) Open(name token.Identifier) error {
+	c.ctx.push(name.Value)
+
+	return c.Visitable.Open(name)
+}
+
+func (c *contextTracker) OpenReturnArrow(arrow token.G2Arrow, name *token.Identifier) error {
+	c.ctx.push(c.returnName)
+
+	if name != nil {
+		c.ctx.push(name.Value)
+		c.ctx.returnArrows = append(c.ctx.returnArrows, 2)
+	} else {
+		c.ctx.returnArrows = append(c.ctx.returnArrows, 1)
+	}
+
+	return c.Visitable.OpenReturnArrow(arrow, name)
+}
+
+func (c *contextTracker) CloseReturnArrow() error {
+	// A named return arrow opened two nodes, which are closed together.
+	count := 1
+	if last := len(c.ctx.returnArrows) - 1; last >= 0 {
+		count = c.ctx.returnArrows[last]
+		c.ctx.returnArrows = c.ctx.returnArrows[:last]
+	}
+
+	err := c.Visitable.CloseReturnArrow()
+	c.ctx.pop(count)
+
+	return err
+}
+
+func (c *contextTracker) SetBlockType(blockType BlockType) error {
+	if last := len(c.ctx.blockTypes) - 1; last >= 0 {
+		c.ctx.blockTypes[last] = blockType
+	}
+
+	return c.Visitable.SetBlockType(blockType)
+}
+
+func (c *contextTracker) OpenForward(name token.Identifier) error {
+	c.ctx.push(name.Value)
+
+	return c.Visitable.OpenForward(name)
+}
+
+func (c *contextTracker) Close() error {
+	err := c.Visitable.Close()
+	c.ctx.pop(1)
+
+	return err
+}
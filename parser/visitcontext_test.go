@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package parser_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// pathVisitable records the context of each Open, Text and Close event.
+type pathVisitable struct {
+	nopVisitable
+	ctx    *VisitContext
+	events []string
+}
+
+func (p *pathVisitable) SetVisitContext(ctx *VisitContext) {
+	p.ctx = ctx
+}
+
+func (p *pathVisitable) record(event string) {
+	p.events = append(p.events, fmt.Sprintf("%s %s %d", event, strings.Join(p.ctx.Path(), "/"), p.ctx.Depth()))
+}
+
+func (p *pathVisitable) Open(token.Identifier) error {
+	p.record("open")
+
+	return nil
+}
+
+func (p *pathVisitable) Text(token.CharData) error {
+	p.record("text")
+
+	return nil
+}
+
+func (p *pathVisitable) Close() error {
+	p.record(fmt.Sprintf("close%s", p.ctx.BlockType()))
+
+	return nil
+}
+
+func (p *pathVisitable) CloseReturnArrow() error {
+	p.record("closeret")
+
+	return nil
+}
+
+func TestVisitContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "G1",
+			text: `#a{#b hello}`,
+			want: []string{
+				"open root 1",
+				"open root/a 2",
+				"open root/a/b 3",
+				"text root/a/b 3",
+				"close root/a/b 3",
+				"close{} root/a 2",
+				"close{} root 1",
+			},
+		},
+		{
+			name: "G2 return arrow",
+			text: `#! g {f(x) -> (int)}`,
+			want: []string{
+				"open root 1",
+				"open root/g 2",
+				"open root/g/f 3",
+				"open root/g/f/x 4",
+				"close root/g/f/x 4",
+				"open root/g/f/ret/int 5",
+				"close root/g/f/ret/int 5",
+				"closeret root/g/f/ret 4",
+				"close() root/g/f 3",
+				"close{} root/g 2",
+				"close{} root 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			visitable := &pathVisitable{}
+
+			visitor := NewVisitor("", strings.NewReader(test.text))
+			visitor.SetVisitable(visitable)
+
+			if err := visitor.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(visitable.events, test.want) {
+				t.Errorf("expected\n%s\nbut got\n%s", strings.Join(test.want, "\n"), strings.Join(visitable.events, "\n"))
+			}
+
+			// Replaying the events must provide the same context.
+			recorder, err := Record("", strings.NewReader(test.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			replayed := &pathVisitable{}
+			if err := recorder.Replay(replayed); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(replayed.events, visitable.events) {
+				t.Errorf("expected replay\n%s\nbut got\n%s",
+					strings.Join(visitable.events, "\n"), strings.Join(replayed.events, "\n"))
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/golangee/dyml/token"
 )
@@ -97,14 +100,87 @@ type Visitor struct {
 	// with the correct type of bracket and to keep track of open
 	// nodes.
 	openNodes []BlockType
+
+	// forwardSkip is the number of elements that the forwarded nodes currently visited pass over.
+	forwardSkip int
+	// recording is the forwarded node that is currently delayed, see startForwardRecording.
+	recording *forwardRecording
+	// delayed contains the forwards that still pass over following elements.
+	delayed []delayedForward
+
+	// lastEnd is the end position of the token that was most recently returned by next().
+	// It is used to determine where a node ends when it gets closed.
+	lastEnd token.Pos
+
+	// finalizeHooks are called after the Visitable has been finalized.
+	finalizeHooks []func(summary Summary) error
+	// statsHooks are called after each run, see OnStats.
+	statsHooks []func(stats Stats)
+	// summary records the events of the current run, if there are finalizeHooks or statsHooks.
+	summary *summaryRecorder
+	// tokensLexed and bytesRead count the tokens and bytes read from the lexer and the input.
+	tokensLexed int
+	bytesRead   int64
+
+	// ctx is the context of the current run, which stops the visitor once it is done.
+	ctx context.Context
+	// tokensRead counts the tokens read from the lexer, to check ctx only every contextCheckInterval tokens.
+	tokensRead int
+
+	// rootName is the name of the root element, see SetRootName.
+	rootName string
+	// returnName is the name of the element after a return arrow, see SetReturnName.
+	returnName string
+	// reservedNameHook is called for elements with reserved names, see SetReservedNameHook.
+	reservedNameHook ReservedNameHook
 }
 
+// DefaultRootName is the name of the element that the visitor opens around the whole document,
+// unless another one is set with Visitor.SetRootName or WithRootName.
+const DefaultRootName = "root"
+
+// DefaultReturnName is the name of the element that contains the results after a return arrow,
+// unless another one is set with Visitor.SetReturnName or WithReturnName.
+const DefaultReturnName = "ret"
+
+// contextCheckInterval is the number of tokens after which the context of a run is checked again.
+const contextCheckInterval = 64
+
 // NewVisitor creates a new visitor that can be start with Run().
 // You need to call SetVisitable before that!
-func NewVisitor(filename string, reader io.Reader) *Visitor {
-	return &Visitor{
-		lexer: token.NewLexer(filename, reader),
+// The given options are used to configure the lexer.
+func NewVisitor(filename string, reader io.Reader, opts ...token.LexerOption) *Visitor {
+	v := &Visitor{rootName: DefaultRootName, returnName: DefaultReturnName}
+	v.lexer = token.NewLexer(filename, contextReader{reader: reader, visitor: v}, opts...)
+
+	return v
+}
+
+// reset prepares the visitor for another input like NewVisitor, but keeps the buffers of its lexer.
+func (v *Visitor) reset(filename string, reader io.Reader, opts ...token.LexerOption) {
+	lexer := v.lexer
+	*v = Visitor{rootName: DefaultRootName, returnName: DefaultReturnName, lexer: lexer}
+	lexer.Reset(filename, contextReader{reader: reader, visitor: v}, opts...)
+}
+
+// contextReader fails once the context of the visitor is done. This stops the lexer
+// even while it reads a huge token.
+type contextReader struct {
+	reader  io.Reader
+	visitor *Visitor
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if ctx := r.visitor.ctx; ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
 	}
+
+	n, err := r.reader.Read(p)
+	r.visitor.bytesRead += int64(n)
+
+	return n, err
 }
 
 // SetVisitable sets the visitMe field to an implementation of the Visitable interface.
@@ -112,18 +188,130 @@ func (v *Visitor) SetVisitable(vis Visitable) {
 	v.visitMe = vis
 }
 
+// SetRootName sets the name of the element that is opened around the whole document, which is
+// DefaultRootName by default. Choose another one if documents use "root" as the name of their own elements.
+func (v *Visitor) SetRootName(name string) {
+	v.rootName = name
+}
+
+// SetReturnName sets the name of the element that contains the results after a return arrow, which is
+// DefaultReturnName by default. The visitor does not open this element itself, Visitables that create it
+// get its name from ReturnName.
+func (v *Visitor) SetReturnName(name string) {
+	v.returnName = name
+}
+
+// ReturnName returns the name of the element that contains the results after a return arrow.
+func (v *Visitor) ReturnName() string {
+	return v.returnName
+}
+
+// SetReservedNameHook sets a hook that is called for every element of the document that has the name of
+// the root, the element after a return arrow or TypeElement, so that these elements can be renamed or
+// rejected. Without a hook, they are passed on like any other element.
+func (v *Visitor) SetReservedNameHook(hook ReservedNameHook) {
+	v.reservedNameHook = hook
+}
+
+// OnStats registers a hook that is called with the Stats of every run once it ended, also if it failed.
+// Only runs with hooks measure their Stats.
+func (v *Visitor) OnStats(hook func(stats Stats)) {
+	v.statsHooks = append(v.statsHooks, hook)
+}
+
+// OnFinalize registers a hook that is called with a Summary of the document once the input
+// has been visited completely and Finalize of the Visitable succeeded.
+// This allows for whole-document checks without walking the document again.
+// Hooks are called in the order they were registered, the first error stops the visitor.
+func (v *Visitor) OnFinalize(hook func(summary Summary) error) {
+	v.finalizeHooks = append(v.finalizeHooks, hook)
+}
+
 // Run runs the visitor, starting the traversion of the syntax tree.
 func (v *Visitor) Run() error {
+	return v.RunContext(context.Background())
+}
+
+// RunContext works like Run, but stops with an error wrapping ctx.Err() once ctx is done.
+// The context is checked periodically, so that long-running runs on huge or hostile inputs can be cancelled.
+func (v *Visitor) RunContext(ctx context.Context) error {
+	if len(v.statsHooks) == 0 {
+		return v.run(ctx)
+	}
+
+	start := time.Now()
+	tokens, bytes := v.tokensLexed, v.bytesRead
+
+	err := v.run(ctx)
+
+	stats := Stats{
+		Tokens:   v.tokensLexed - tokens,
+		Bytes:    v.bytesRead - bytes,
+		Duration: time.Since(start),
+		Failed:   err != nil,
+	}
+
+	if v.summary != nil {
+		summary := v.summary.summary
+		stats.Nodes = summary.Nodes + summary.Texts + summary.Comments
+		stats.MaxDepth = summary.MaxDepth
+		v.summary = nil
+	}
+
+	for _, hook := range v.statsHooks {
+		hook(stats)
+	}
+
+	return err
+}
+
+// run visits the whole input, see RunContext.
+func (v *Visitor) run(ctx context.Context) error {
+	v.ctx = ctx
+
+	defer func() {
+		v.ctx = nil
+	}()
+
+	// The context is only tracked if the Visitable is interested in it.
+	visitable := v.visitMe
+	v.visitMe = withVisitContext(visitable, v.returnName)
+
+	defer func() {
+		v.visitMe = visitable
+		v.recording = nil
+		v.delayed = nil
+	}()
+
+	// The summary is only recorded if anyone is interested in it.
+	var recorder *summaryRecorder
+
+	if len(v.finalizeHooks) > 0 || len(v.statsHooks) > 0 {
+		recorder = &summaryRecorder{Visitable: v.visitMe}
+		v.visitMe = recorder
+	}
+
+	if len(v.statsHooks) > 0 {
+		v.summary = recorder
+	}
+
 	// Prepare G1.
 	// Prepend and append tokens for the root element.
 	// This makes the root just another element, which simplifies parsing a lot.
-	v.tokenBuffer = append([]tokenWithError{
+	head := []tokenWithError{
 		{tok: &token.DefineElement{}},
-		{tok: &token.Identifier{Value: "root"}},
+		{tok: &token.Identifier{Value: v.rootName}},
 		{tok: &token.BlockStart{}},
-	},
-		v.tokenBuffer...,
-	)
+	}
+
+	// Generated tokens have no positional information, so we place them at the start of the input.
+	for _, twe := range head {
+		lexPos := v.lexer.Pos()
+		twe.tok.Pos().BeginPos = lexPos
+		twe.tok.Pos().EndPos = lexPos
+	}
+
+	v.tokenBuffer = append(head, v.tokenBuffer...)
 
 	v.tokenTailBuffer = append(v.tokenTailBuffer,
 		tokenWithError{tok: &token.BlockEnd{}},
@@ -141,10 +329,25 @@ func (v *Visitor) Run() error {
 		}
 	}
 
+	// Forwards that are still delayed have no target, which is reported by the Visitable.
+	if err := v.releaseForwards(true); err != nil {
+		return err
+	}
+
 	if err := v.visitMe.Finalize(); err != nil {
 		return err
 	}
 
+	if len(v.finalizeHooks) > 0 {
+		summary := recorder.finish(v.lexer.Pos())
+
+		for _, hook := range v.finalizeHooks {
+			if err := hook(summary); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -156,21 +359,59 @@ func (v *Visitor) closeNode() error {
 	v.openNodes = v.openNodes[:len(v.openNodes)-1]
 
 	if !v.isCurrentNodeSpecial() {
-		return v.visitMe.Close()
+		if err := v.visitMe.Close(); err != nil {
+			return err
+		}
 	}
 
+	v.finishForwardRecording()
+
 	return nil
 }
 
 // openNode opens a new node for processing.
+// Delayed forwards that target this node are passed on before.
 func (v *Visitor) openNode(name token.Identifier) error {
+	if err := v.releaseForwards(false); err != nil {
+		return err
+	}
+
 	v.openNodes = append(v.openNodes, BlockNone)
 
 	return v.visitMe.Open(name)
 }
 
+// documentName passes the name of an element of the document to the ReservedNameHook, if it is reserved.
+// The returned identifier is a copy, so that tokens are not changed.
+func (v *Visitor) documentName(name *token.Identifier) (*token.Identifier, error) {
+	if v.reservedNameHook == nil ||
+		name.Value != v.rootName && name.Value != v.returnName && name.Value != TypeElement {
+		return name, nil
+	}
+
+	value, err := v.reservedNameHook(*name)
+	if err != nil {
+		var posErr *token.PosError
+		if errors.As(err, &posErr) {
+			return nil, err
+		}
+
+		return nil, token.NewPosError(name.Position, fmt.Sprintf("'%s' is a reserved name", name.Value)).
+			SetCause(err)
+	}
+
+	renamed := *name
+	renamed.Value = value
+
+	return &renamed, nil
+}
+
 // openForwardNode opens a new forwarding node for processing.
 func (v *Visitor) openForwardNode(name token.Identifier) error {
+	if err := v.startForwardRecording(name); err != nil {
+		return err
+	}
+
 	v.openNodes = append(v.openNodes, BlockNone)
 
 	return v.visitMe.OpenForward(name)
@@ -188,15 +429,48 @@ func (v *Visitor) setBlockType(blockType BlockType) error {
 // next returns the next token or (nil, io.EOF) if there are no more tokens.
 // Repeatedly calling this can be used to get all tokens by advancing the lexer.
 func (v *Visitor) next() (token.Token, error) {
+	var (
+		tok token.Token
+		err error
+	)
+
 	// Check the buffer for tokens
 	if len(v.tokenBuffer) > 0 {
 		twe := v.tokenBuffer[0]
-		v.tokenBuffer = v.tokenBuffer[1:] // pop token
+		// Pop the token. An emptied buffer is reset to reuse its memory for the next peek.
+		if len(v.tokenBuffer) == 1 {
+			v.tokenBuffer = v.tokenBuffer[:0]
+		} else {
+			v.tokenBuffer = v.tokenBuffer[1:]
+		}
 
-		return twe.tok, twe.err
+		tok, err = twe.tok, twe.err
+	} else {
+		tok, err = v.read()
+	}
+
+	if err == nil && tok != nil {
+		v.lastEnd = tok.Pos().End()
 	}
 
+	return tok, err
+}
+
+// read gets a new token from the lexer, or from the tokenTailBuffer once the lexer has no more tokens.
+// It does not look into the tokenBuffer, use next() or peek() for that.
+func (v *Visitor) read() (token.Token, error) {
+	if v.ctx != nil && v.tokensRead%contextCheckInterval == 0 {
+		if err := v.ctx.Err(); err != nil {
+			return nil, token.NewPosError(token.NewNode(v.lexer.Pos(), v.lexer.Pos()), "parsing stopped").SetCause(err)
+		}
+	}
+
+	v.tokensRead++
+
 	tok, err := v.lexer.Token()
+	if err == nil {
+		v.tokensLexed++
+	}
 
 	if errors.Is(err, io.EOF) {
 		// Check tail buffer for tokens that need to be appended
@@ -208,8 +482,8 @@ func (v *Visitor) next() (token.Token, error) {
 			// We fix that here, so that potential errors point to the right place.
 			if twe.tok != nil {
 				lexPos := v.lexer.Pos()
-				twe.tok.Pos().SetBegin(lexPos.File, lexPos.Line, lexPos.Col)
-				twe.tok.Pos().SetEnd(lexPos.File, lexPos.Line, lexPos.Col)
+				twe.tok.Pos().BeginPos = lexPos
+				twe.tok.Pos().EndPos = lexPos
 			}
 
 			return twe.tok, twe.err
@@ -230,7 +504,7 @@ func (v *Visitor) peek() (token.Token, error) {
 		return twe.tok, twe.err
 	}
 
-	tok, err := v.next()
+	tok, err := v.read()
 
 	// Store token+error for use in next()
 	v.tokenBuffer = append(v.tokenBuffer, tokenWithError{
@@ -262,7 +536,8 @@ func (v *Visitor) g1Node() error {
 		// Correctly set the forwarding mode.
 		if v.mode == token.G1LineForward || v.mode == token.G1Line {
 			if t.Forward {
-				return token.NewPosError(t.Pos(), "cannot forward nodes in G1 lines")
+				return token.NewPosError(t.Pos(), "cannot forward nodes in G1 lines").
+					SetCategory(token.ErrForwarding)
 			}
 		}
 
@@ -270,10 +545,11 @@ func (v *Visitor) g1Node() error {
 			isForwardingNode = true
 		} else {
 			isForwardingNode = t.Forward
+			v.forwardSkip = t.Skip
 		}
 	case *token.CharData:
 		if v.mode == token.G1LineForward {
-			if err := v.visitMe.TextForward(*t); err != nil {
+			if err := v.textForward(*t); err != nil {
 				return err
 			}
 		} else {
@@ -285,13 +561,23 @@ func (v *Visitor) g1Node() error {
 		return nil
 	case *token.G1Comment:
 		// Expect CharData as comment
+		delimiter := t.Position
+
 		tok, err = v.next()
 		if err != nil {
 			return err
 		}
 
 		if cd, ok := tok.(*token.CharData); ok {
-			err = v.visitMe.Comment(*cd)
+			comment := *cd
+			comment.Delimiter = delimiter
+			comment.CommentKind = token.CommentG1
+
+			if v.mode == token.G1Line || v.mode == token.G1LineForward {
+				comment.CommentKind = token.CommentG1Line
+			}
+
+			err = v.visitMe.Comment(comment)
 			if err != nil {
 				return err
 			}
@@ -299,12 +585,12 @@ func (v *Visitor) g1Node() error {
 			return nil
 		}
 
-		return token.NewPosError(
+		return token.NewSyntaxError(
 			tok.Pos(),
 			"expected a comment",
 		).SetCause(NewUnexpectedTokenError(tok, token.TokenCharData))
 	default:
-		return token.NewPosError(
+		return token.NewSyntaxError(
 			tok.Pos(),
 			"this token is not valid here",
 		).SetCause(NewUnexpectedTokenError(tok, token.TokenDefineElement, token.TokenCharData))
@@ -317,6 +603,13 @@ func (v *Visitor) g1Node() error {
 	}
 
 	if id, ok := tok.(*token.Identifier); ok {
+		// The first element is the root, which is the only one that is not part of the document.
+		if len(v.openNodes) > 0 {
+			if id, err = v.documentName(id); err != nil {
+				return err
+			}
+		}
+
 		if isForwardingNode {
 			if err := v.openForwardNode(*id); err != nil {
 				return err
@@ -327,7 +620,7 @@ func (v *Visitor) g1Node() error {
 			}
 		}
 	} else {
-		return token.NewPosError(
+		return token.NewSyntaxError(
 			tok.Pos(),
 			"this token is not valid here",
 		).SetCause(NewUnexpectedTokenError(tok, token.TokenIdentifier))
@@ -369,18 +662,8 @@ func (v *Visitor) g1Node() error {
 				// The block was closed
 				break collect
 			case *token.G2Preamble:
-				if v.mode == token.G1 {
-					// Parse a single G2 node
-					_, _ = v.next() // pop preamble
-					v.mode = token.G2
-
-					if err := v.g2Node(); err != nil {
-						return err
-					}
-
-					v.mode = token.G1
-				} else {
-					return token.NewPosError(tok.Pos(), "G2 node not allowed here")
+				if err := v.g2Section(); err != nil {
+					return err
 				}
 			default:
 				// Anything else must be another g1Node
@@ -397,7 +680,7 @@ func (v *Visitor) g1Node() error {
 		}
 
 		if tok.Type() != token.TokenBlockEnd {
-			return token.NewPosError(
+			return token.NewSyntaxError(
 				tok.Pos(),
 				"use a '}' here to close the element",
 			).SetCause(NewUnexpectedTokenError(tok, token.TokenBlockEnd))
@@ -434,11 +717,12 @@ func (v *Visitor) g1LineNodes() error {
 	if de, ok := tok.(*token.DefineElement); ok {
 		if de.Forward {
 			v.mode = token.G1LineForward
+			v.forwardSkip = de.Skip
 		} else {
 			v.mode = token.G1Line
 		}
 	} else {
-		return token.NewPosError(
+		return token.NewSyntaxError(
 			tok.Pos(),
 			"start of G1 line expected",
 		).SetCause(NewUnexpectedTokenError(tok, token.TokenDefineElement))
@@ -455,6 +739,14 @@ func (v *Visitor) g1LineNodes() error {
 			break
 		}
 
+		if tok != nil && tok.Type() == token.TokenG2Preamble {
+			if err := v.g2Section(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		// Read g1Nodes until we encounter G1LineEnd
 		err := v.g1Node()
 		if err != nil {
@@ -464,6 +756,31 @@ func (v *Visitor) g1LineNodes() error {
 
 	// Restore mode
 	v.mode = token.G2
+	v.forwardSkip = 0
+
+	return nil
+}
+
+// g2Section parses the single G2 node after a preamble, which may appear in G1 blocks and
+// G1 lines at any depth. The grammar mode is restored afterwards.
+func (v *Visitor) g2Section() error {
+	tok, err := v.next() // pop preamble
+	if err != nil {
+		return err
+	}
+
+	if v.mode == token.G1LineForward {
+		return token.NewSyntaxError(tok.Pos(), "G2 node not allowed here")
+	}
+
+	mode := v.mode
+	v.mode = token.G2
+
+	if err := v.g2Node(); err != nil {
+		return err
+	}
+
+	v.mode = mode
 
 	return nil
 }
@@ -495,7 +812,12 @@ func (v *Visitor) g2Node() error {
 
 	switch t := tok.(type) {
 	case *token.Identifier:
-		if err := v.openNode(*t); err != nil {
+		name, err := v.documentName(t)
+		if err != nil {
+			return err
+		}
+
+		if err := v.openNode(*name); err != nil {
 			return err
 		}
 	case *token.CharData:
@@ -508,7 +830,7 @@ func (v *Visitor) g2Node() error {
 
 		return nil
 	default:
-		return token.NewPosError(
+		return token.NewSyntaxError(
 			tok.Pos(),
 			"this token is not valid here",
 		).SetCause(NewUnexpectedTokenError(tok, token.TokenCharData, token.TokenIdentifier))
@@ -558,6 +880,12 @@ func (v *Visitor) g2Node() error {
 		// This is a G2Arrow after an identifier
 		// It ends the current element, but will not pop the token so that it can
 		// be parsed correctly later.
+	case *token.Colon:
+		if err := v.g2ParseColon(); err != nil {
+			return err
+		}
+
+		arrowAllowed = false
 	default:
 		err := v.g2Node()
 		if err != nil {
@@ -584,6 +912,9 @@ func (v *Visitor) g2Node() error {
 		if err := v.g2ParseArrow(); err != nil {
 			return err
 		}
+
+		// A comma may separate the return arrow from following siblings.
+		v.maybeEatComma()
 	}
 
 	return v.closeNode()
@@ -608,6 +939,8 @@ func (v *Visitor) g2EatComments() error {
 			return err
 		}
 
+		delimiter := *tok.Pos()
+
 		tok, err = v.next()
 		if err != nil {
 			return err
@@ -615,12 +948,16 @@ func (v *Visitor) g2EatComments() error {
 
 		// Expect CharData as comment
 		if cd, ok := tok.(*token.CharData); ok {
-			err = v.visitMe.Comment(*cd)
+			comment := *cd
+			comment.Delimiter = delimiter
+			comment.CommentKind = token.CommentG2
+
+			err = v.visitMe.Comment(comment)
 			if err != nil {
 				return err
 			}
 		} else {
-			return token.NewPosError(
+			return token.NewSyntaxError(
 				tok.Pos(),
 				"empty comment is not valid",
 			).SetCause(NewUnexpectedTokenError(tok, token.TokenCharData))
@@ -666,7 +1003,7 @@ func (v *Visitor) g2ParseBlock() error {
 			return err
 		}
 	default:
-		return token.NewPosError(tok.Pos(), "expected a BlockStart")
+		return token.NewSyntaxError(tok.Pos(), "expected a BlockStart")
 	}
 
 	// Parse children
@@ -724,11 +1061,12 @@ func correctClosingToken(blockType BlockType, tok token.Token) bool {
 // g2ParseArrow is used to parse the return arrow, which has special semantics.
 // It is used to append a "ret" element containing function return values to a
 // function definition. For this to work, the function must be defined as:
-//     name(...) -> [opt](...)
+//     name(...) -> [opt] [@key=value...] (...)
 // The "name" element will get a new child named "ret" appended that contains
 // all children in the block after "->". The block after name is optional.
 // The block "(...)" is required after the arrow, but can be any valid block with
-// or without a name.
+// or without a name. Attributes after the arrow are placed on the "ret" element,
+// or on the named element if there is a name.
 // After this method has been called the topmost element in openNodes will be a blockSpecial,
 // which you need to handle.
 func (v *Visitor) g2ParseArrow() error {
@@ -755,7 +1093,9 @@ func (v *Visitor) g2ParseArrow() error {
 				return err
 			}
 
-			name = tokName
+			if name, err = v.documentName(tokName); err != nil {
+				return err
+			}
 		}
 
 		// closeNode has a special mode, when blockSpecial is on the stack, see that method
@@ -767,8 +1107,17 @@ func (v *Visitor) g2ParseArrow() error {
 			return err
 		}
 
+		// Attributes belong to the "ret" element, or to the named element of a named return arrow.
+		if err := v.parseAttributes(false); err != nil {
+			return err
+		}
+
 		// Try parsing a block if there is one
 		tok, err = v.peek()
+		if _, ok := tok.(*token.Identifier); ok && name == nil {
+			return token.NewSyntaxError(tok.Pos(), "the name of a return arrow must precede its attributes")
+		}
+
 		if err == nil {
 			switch tok.(type) {
 			case *token.BlockStart, *token.GroupStart, *token.GenericStart:
@@ -787,10 +1136,82 @@ func (v *Visitor) g2ParseArrow() error {
 
 		return nil
 	default:
-		return token.NewPosError(tok.Pos(), "'->' expected")
+		return token.NewSyntaxError(tok.Pos(), "'->' expected")
 	}
 }
 
+// g2ParseColon parses a type annotation, which is written as:
+//     name: type
+// The type is a single element with optional attributes and an optional block, but unlike
+// other elements it does not nest following elements. It is placed into a "type" element,
+// that is appended to the current node. A comma after the type is optional.
+func (v *Visitor) g2ParseColon() error {
+	// Expect colon
+	tok, err := v.next()
+	if err != nil {
+		return err
+	}
+
+	colon, ok := tok.(*token.Colon)
+	if !ok {
+		return token.NewSyntaxError(tok.Pos(), "':' expected")
+	}
+
+	if err := v.openNode(token.Identifier{Position: colon.Position, Value: TypeElement}); err != nil {
+		return err
+	}
+
+	if err := v.g2EatComments(); err != nil {
+		return err
+	}
+
+	tok, err = v.next()
+	if err != nil {
+		return err
+	}
+
+	name, ok := tok.(*token.Identifier)
+	if !ok {
+		return token.NewSyntaxError(tok.Pos(), "this token is not valid here").
+			SetCause(NewUnexpectedTokenError(tok, token.TokenIdentifier))
+	}
+
+	if name, err = v.documentName(name); err != nil {
+		return err
+	}
+
+	if err := v.openNode(*name); err != nil {
+		return err
+	}
+
+	if err := v.parseAttributes(false); err != nil {
+		return err
+	}
+
+	tok, err = v.peek()
+	if err == nil {
+		switch tok.(type) {
+		case *token.BlockStart, *token.GroupStart, *token.GenericStart:
+			if err := v.g2ParseBlock(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Close the type and the "type" element.
+	if err := v.closeNode(); err != nil {
+		return err
+	}
+
+	if err := v.closeNode(); err != nil {
+		return err
+	}
+
+	v.maybeEatComma()
+
+	return nil
+}
+
 // parseAttributes eats consecutive attributes from the lexer.
 // wantForward specifies if regular or forwarding attributes should be parsed.
 // The function returns when a non-attribute is encountered. Should an attribute be parsed
@@ -807,7 +1228,7 @@ func (v *Visitor) parseAttributes(wantForward bool) error {
 
 		if attr, ok := tok.(*token.DefineAttribute); ok {
 			if wantForward && !attr.Forward {
-				return token.NewPosError(
+				return token.NewSyntaxError(
 					tok.Pos(),
 					"this should be a forward attribute or removed",
 				).SetCause(NewForwardAttrError())
@@ -837,6 +1258,8 @@ func (v *Visitor) parseAttributes(wantForward bool) error {
 
 		var attrValue token.CharData
 
+		skip := tok.(*token.DefineAttribute).Skip
+
 		// Read attribute key
 		tok, err = v.next()
 		if err != nil {
@@ -846,7 +1269,7 @@ func (v *Visitor) parseAttributes(wantForward bool) error {
 		if ident, ok := tok.(*token.Identifier); ok {
 			attrKey = *ident
 		} else {
-			return token.NewPosError(
+			return token.NewSyntaxError(
 				tok.Pos(),
 				"an identifier is required as an attribute key",
 			).SetCause(NewUnexpectedTokenError(tok, token.TokenIdentifier))
@@ -854,44 +1277,33 @@ func (v *Visitor) parseAttributes(wantForward bool) error {
 
 		// Read CharData enclosed in brackets as attribute value in G1.
 		// Read CharData after Assign in G2.
+		// Without either, the attribute is a flag with the value true.
+		tok, err = v.peek()
 
-		tok, err = v.next()
-		if err != nil {
-			return err
-		}
+		isFlag := err != nil ||
+			(isG1 && tok.Type() != token.TokenBlockStart) || (!isG1 && tok.Type() != token.TokenAssign)
+		if isFlag {
+			attrValue = token.CharData{Position: attrKey.Position, Value: "true", Kind: token.KindBool}
+		} else {
+			_, _ = v.next() // pop BlockStart or Assign
 
-		if isG1 {
-			if tok.Type() != token.TokenBlockStart {
-				return token.NewPosError(
-					tok.Pos(),
-					"attribute value must be enclosed in '{}'",
-				).SetCause(NewUnexpectedTokenError(tok, token.TokenBlockStart))
+			tok, err = v.next()
+			if err != nil {
+				return err
 			}
-		} else {
-			if tok.Type() != token.TokenAssign {
-				return token.NewPosError(
+
+			if cd, ok := tok.(*token.CharData); ok {
+				attrValue = *cd
+			} else {
+				return token.NewSyntaxError(
 					tok.Pos(),
-					"'=' is expected here",
-				).SetCause(NewUnexpectedTokenError(tok, token.TokenAssign))
+					"attribute value is required",
+				).SetCause(NewUnexpectedTokenError(tok, token.TokenCharData))
 			}
 		}
 
-		tok, err = v.next()
-		if err != nil {
-			return err
-		}
-
-		if cd, ok := tok.(*token.CharData); ok {
-			attrValue = *cd
-		} else {
-			return token.NewPosError(
-				tok.Pos(),
-				"attribute value is required",
-			).SetCause(NewUnexpectedTokenError(tok, token.TokenCharData))
-		}
-
 		if wantForward {
-			if err := v.visitMe.AttributeForward(attrKey, attrValue); err != nil {
+			if err := v.attributeForward(attrKey, attrValue, skip); err != nil {
 				return err
 			}
 		} else {
@@ -900,10 +1312,10 @@ func (v *Visitor) parseAttributes(wantForward bool) error {
 			}
 		}
 
-		if isG1 {
+		if isG1 && !isFlag {
 			tok, err = v.next()
 			if err == nil && tok.Type() != token.TokenBlockEnd {
-				return token.NewPosError(
+				return token.NewSyntaxError(
 					tok.Pos(),
 					"attribute value needs to be closed with '}'",
 				).SetCause(NewUnexpectedTokenError(tok, token.TokenBlockEnd))
@@ -0,0 +1,8 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package playground contains the backend of an interactive demo, which parses documents into the JSON
+// form of their tree, converts them into XML and reports their problems as diagnostics. Parse, ConvertXML
+// and Diagnose do the work, NewHandler serves them over HTTP and, in WebAssembly builds, Register
+// exposes them to JavaScript. All of them answer with a Response.
+package playground
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package playground
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golangee/dyml"
+)
+
+// DefaultMaxSourceSize is the size limit of documents sent to a handler from NewHandler,
+// unless another one is set with WithMaxSourceSize.
+const DefaultMaxSourceSize = 64 * dyml.KiB
+
+// HandlerOption configures a handler created by NewHandler.
+type HandlerOption func(h *handler)
+
+// WithMaxSourceSize sets the size limit of documents. Larger ones are rejected with
+// http.StatusRequestEntityTooLarge.
+func WithMaxSourceSize(size dyml.ByteSize) HandlerOption {
+	return func(h *handler) {
+		h.maxSize = size
+	}
+}
+
+// NewHandler creates an http.Handler, which expects the source of a document as body of POST requests
+// and answers with the JSON of a Response. These paths are served, use http.StripPrefix to mount the
+// handler below another path:
+//
+//  /parse        see Parse
+//  /xml          see ConvertXML
+//  /diagnostics  see Diagnose
+//
+// Invalid documents are answered with http.StatusOK, as their errors are part of the Response.
+func NewHandler(opts ...HandlerOption) http.Handler {
+	h := &handler{maxSize: DefaultMaxSourceSize}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// handler serves the operations of this package, see NewHandler.
+type handler struct {
+	maxSize dyml.ByteSize
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var operation func(src string) Response
+
+	switch r.URL.Path {
+	case "/parse":
+		operation = Parse
+	case "/xml":
+		operation = ConvertXML
+	case "/diagnostics":
+		operation = Diagnose
+	default:
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// One more byte than allowed is read to find out whether the document is too large.
+	src, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(h.maxSize)+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if len(src) > int(h.maxSize) {
+		http.Error(w, fmt.Sprintf("document exceeds the limit of %s", h.maxSize), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(encode(operation(string(src))))
+}
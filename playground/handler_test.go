@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package playground_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml"
+	. "github.com/golangee/dyml/playground"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		// want is contained in the body of the response.
+		want string
+	}{
+		{name: "parse", method: "POST", path: "/parse", body: "#a", status: http.StatusOK, want: `"tree":`},
+		{name: "xml", method: "POST", path: "/xml", body: "#a", status: http.StatusOK, want: `"xml":`},
+		{
+			name: "diagnostics", method: "POST", path: "/diagnostics", body: "#a {}",
+			status: http.StatusOK, want: "empty-blocks",
+		},
+		{name: "invalid document", method: "POST", path: "/parse", body: "#a {", status: http.StatusOK, want: `"error"`},
+		{name: "unknown path", method: "POST", path: "/yaml", body: "#a", status: http.StatusNotFound},
+		{name: "wrong method", method: "GET", path: "/parse", status: http.StatusMethodNotAllowed},
+		{
+			name: "too large", method: "POST", path: "/parse", body: strings.Repeat("#a ", 100),
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	handler := NewHandler(WithMaxSourceSize(256 * dyml.Byte))
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, but got %d: %s", test.status, w.Code, w.Body)
+			}
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var resp Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(w.Body.String(), test.want) {
+				t.Errorf("expected the response to contain '%s', but got %s", test.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package playground
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/lint"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Severities of diagnostics.
+const (
+	// SeverityError is used for documents that cannot be parsed.
+	SeverityError = "error"
+	// SeverityWarning is used for findings of the rules in package lint.
+	SeverityWarning = "warning"
+	// SeverityInfo is used for additional positions of an error, e.g. where a block was opened.
+	SeverityInfo = "info"
+)
+
+// Response is the result of an operation, which is sent as JSON.
+type Response struct {
+	// Tree is the parsed document in the form of parser.TreeNode.MarshalJSON, see Parse.
+	Tree *parser.TreeNode `json:"tree,omitempty"`
+	// XML is the converted document, see ConvertXML.
+	XML string `json:"xml,omitempty"`
+	// Diagnostics are the problems of the document, which is empty if there are none.
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Diagnostic is a problem in a document.
+type Diagnostic struct {
+	// Severity is one of SeverityError, SeverityWarning or SeverityInfo.
+	Severity string `json:"severity"`
+	// Rule is the name of the lint rule that reported a warning.
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+	// Range is the part of the document that the diagnostic is about, or nil if it is unknown.
+	Range *Range `json:"range,omitempty"`
+}
+
+// Range is a part of a document.
+type Range struct {
+	Begin Pos `json:"begin"`
+	End   Pos `json:"end"`
+}
+
+// Pos is a position in a document with a one-based line and column, which counts runes, and a byte offset.
+type Pos struct {
+	Line   int `json:"line"`
+	Col    int `json:"col"`
+	Offset int `json:"offset"`
+}
+
+// Parse parses src and returns its tree or the errors.
+func Parse(src string) Response {
+	tree, err := parser.NewParser("", strings.NewReader(src)).Parse()
+	if err != nil {
+		return Response{Diagnostics: errorDiagnostics(err)}
+	}
+
+	return Response{Tree: tree, Diagnostics: []Diagnostic{}}
+}
+
+// ConvertXML converts src into XML with encoder.XMLEncoder and returns it or the errors.
+func ConvertXML(src string) Response {
+	var buf bytes.Buffer
+
+	if err := encoder.NewXMLEncoder("", strings.NewReader(src), &buf).Encode(); err != nil {
+		return Response{Diagnostics: errorDiagnostics(err)}
+	}
+
+	return Response{XML: buf.String(), Diagnostics: []Diagnostic{}}
+}
+
+// Diagnose returns the errors of src, or the findings of lint.DefaultRules if it can be parsed.
+func Diagnose(src string) Response {
+	tree, err := parser.NewParser("", strings.NewReader(src)).Parse()
+	if err != nil {
+		return Response{Diagnostics: errorDiagnostics(err)}
+	}
+
+	diagnostics := []Diagnostic{}
+
+	for _, finding := range lint.Lint(tree, lint.DefaultRules()...) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Rule:     finding.Rule,
+			Message:  finding.Message,
+			Range:    newRange(finding.Range),
+		})
+	}
+
+	return Response{Diagnostics: diagnostics}
+}
+
+// errorDiagnostics returns a diagnostic for err and one for each additional detail of a token.PosError.
+func errorDiagnostics(err error) []Diagnostic {
+	var posErr *token.PosError
+	if !errors.As(err, &posErr) || len(posErr.Details) == 0 {
+		return []Diagnostic{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(posErr.Details))
+
+	for i, detail := range posErr.Details {
+		diagnostic := Diagnostic{Severity: SeverityInfo, Message: detail.Message}
+		if i == 0 {
+			diagnostic.Severity = SeverityError
+			diagnostic.Message = posErr.Error()
+		}
+
+		if detail.Node != nil {
+			diagnostic.Range = newRange(token.Position{BeginPos: detail.Node.Begin(), EndPos: detail.Node.End()})
+		}
+
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// newRange returns the Range of pos.
+func newRange(pos token.Position) *Range {
+	return &Range{
+		Begin: Pos{Line: pos.BeginPos.Line, Col: pos.BeginPos.Col, Offset: pos.BeginPos.Offset},
+		End:   Pos{Line: pos.EndPos.Line, Col: pos.EndPos.Col, Offset: pos.EndPos.Offset},
+	}
+}
+
+// encode returns the JSON of resp. Should that fail, the JSON of a Response with the error is returned.
+func encode(resp Response) []byte {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(Response{Diagnostics: errorDiagnostics(err)})
+	}
+
+	return data
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package playground_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/playground"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	resp := Parse("#a @k{v} {#b text}")
+	if len(resp.Diagnostics) != 0 || resp.Tree == nil {
+		t.Fatalf("expected a tree without diagnostics, but got %+v", resp)
+	}
+
+	if a := resp.Tree.Children[0]; a.Name != "a" || a.Attributes.Get("k").Value != "v" {
+		t.Errorf("expected the element a with attribute k, but got\n%s", resp.Tree.Dump())
+	}
+
+	resp = Parse("#a\n#b @x{1} @x{2}")
+	if resp.Tree != nil || len(resp.Diagnostics) == 0 {
+		t.Fatalf("expected only diagnostics, but got %+v", resp)
+	}
+
+	diagnostic := resp.Diagnostics[0]
+	if diagnostic.Severity != SeverityError || diagnostic.Range == nil || diagnostic.Range.Begin.Line != 2 {
+		t.Errorf("expected an error in line 2, but got %+v", diagnostic)
+	}
+}
+
+func TestConvertXML(t *testing.T) {
+	t.Parallel()
+
+	resp := ConvertXML("#a @k{v} {#b text}")
+	if len(resp.Diagnostics) != 0 || !strings.Contains(resp.XML, `<a k="v">`) {
+		t.Errorf("expected XML without diagnostics, but got %+v", resp)
+	}
+
+	if resp := ConvertXML("#a {"); resp.XML != "" || len(resp.Diagnostics) == 0 {
+		t.Errorf("expected only diagnostics, but got %+v", resp)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	resp := Diagnose("#a {}\n#b")
+	if len(resp.Diagnostics) != 1 {
+		t.Fatalf("expected one finding, but got %+v", resp.Diagnostics)
+	}
+
+	diagnostic := resp.Diagnostics[0]
+	if diagnostic.Severity != SeverityWarning || diagnostic.Rule != "empty-blocks" || diagnostic.Range.Begin.Line != 1 {
+		t.Errorf("expected a warning about the empty block in line 1, but got %+v", diagnostic)
+	}
+
+	if resp := Diagnose("#a {"); len(resp.Diagnostics) == 0 || resp.Diagnostics[0].Severity != SeverityError {
+		t.Errorf("expected an error, but got %+v", resp.Diagnostics)
+	}
+}
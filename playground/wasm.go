@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build js && wasm
+// +build js,wasm
+
+package playground
+
+import "syscall/js"
+
+// Register defines the JavaScript functions dymlParse, dymlXML and dymlDiagnose, which take the source
+// of a document and return the JSON of a Response as string, see Parse, ConvertXML and Diagnose.
+// Call it in the main function of a WebAssembly binary, which must keep running afterwards, e.g. with select {}.
+func Register() {
+	global := js.Global()
+	global.Set("dymlParse", jsFunc(Parse))
+	global.Set("dymlXML", jsFunc(ConvertXML))
+	global.Set("dymlDiagnose", jsFunc(Diagnose))
+}
+
+// jsFunc wraps operation into a JavaScript function.
+func jsFunc(operation func(src string) Response) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		src := ""
+		if len(args) > 0 {
+			src = args[0].String()
+		}
+
+		return string(encode(operation(src)))
+	})
+}
@@ -0,0 +1,6 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package preprocess contains optional stages that transform a parsed tree before it is used,
+// e.g. to resolve variables. They are applied to a tree explicitly or with an option while unmarshalling.
+package preprocess
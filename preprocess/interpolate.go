@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// InterpolateOption is used to configure Interpolate.
+type InterpolateOption func(i *interpolator)
+
+// WithFunc registers a function that is called for references like "${name:argument}" with
+// the argument, e.g. to read secrets from files with "${file:/run/secrets/db}".
+func WithFunc(name string, fn func(argument string) (string, error)) InterpolateOption {
+	return func(i *interpolator) {
+		i.funcs[name] = fn
+	}
+}
+
+// Interpolate returns a stage that replaces references like "${HOME}" in all texts and attribute
+// values with the value that lookup returns for the name, e.g. os.LookupEnv to use environment variables.
+// References like "${name:argument}" call the function registered with WithFunc instead.
+// Use "$${" for a literal "${". Unknown variables and functions as well as errors of functions
+// result in a positional error, so that configurations are never used with missing values.
+// Use it while unmarshalling with dyml.WithPreprocessor(preprocess.Interpolate(os.LookupEnv)).
+// Remember that G1 blocks end at the first "}", so references must be written as "${HOME\}" there.
+func Interpolate(lookup func(name string) (string, bool), opts ...InterpolateOption) func(tree *parser.TreeNode) error {
+	i := &interpolator{lookup: lookup, funcs: map[string]func(string) (string, error){}}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i.interpolate
+}
+
+// interpolator resolves references with a lookup function and registered functions.
+type interpolator struct {
+	lookup func(name string) (string, bool)
+	funcs  map[string]func(argument string) (string, error)
+}
+
+// interpolate replaces all references in node and its children.
+func (i *interpolator) interpolate(node *parser.TreeNode) error {
+	switch {
+	case node.IsText():
+		text, err := i.substitute(*node.Text, node.Range)
+		if err != nil {
+			return err
+		}
+
+		node.Text = &text
+
+		return nil
+	case node.IsComment():
+		return nil
+	}
+
+	for n := 0; n < node.Attributes.Len(); n++ {
+		attr := node.Attributes.GetAt(n)
+
+		value, err := i.substitute(attr.Value, attr.Range)
+		if err != nil {
+			return err
+		}
+
+		attr.Value = value
+	}
+
+	for _, child := range node.Children {
+		if err := i.interpolate(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// substitute replaces all references in s. pos is the position of s and used for errors.
+func (i *interpolator) substitute(s string, pos token.Position) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var result strings.Builder
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			result.WriteString(s)
+
+			break
+		}
+
+		// "$${" is an escaped "${".
+		if start > 0 && s[start-1] == '$' {
+			result.WriteString(s[:start-1])
+			result.WriteString("${")
+			s = s[start+2:]
+
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", token.NewPosError(pos, "reference is missing a closing '}'")
+		}
+
+		value, err := i.resolve(s[start+2:start+end], pos)
+		if err != nil {
+			return "", err
+		}
+
+		result.WriteString(s[:start])
+		result.WriteString(value)
+		s = s[start+end+1:]
+	}
+
+	return result.String(), nil
+}
+
+// resolve returns the value of a reference without "${" and "}". pos is used for errors.
+func (i *interpolator) resolve(reference string, pos token.Position) (string, error) {
+	if colon := strings.IndexByte(reference, ':'); colon >= 0 {
+		name, argument := reference[:colon], reference[colon+1:]
+
+		fn, ok := i.funcs[name]
+		if !ok {
+			return "", token.NewPosError(pos, fmt.Sprintf("function '%s' is not defined", name))
+		}
+
+		value, err := fn(argument)
+		if err != nil {
+			return "", token.NewPosError(pos, fmt.Sprintf("function '%s' failed", name)).SetCause(err)
+		}
+
+		return value, nil
+	}
+
+	value, ok := i.lookup(reference)
+	if !ok {
+		return "", token.NewPosError(pos, fmt.Sprintf("variable '%s' is not defined", reference))
+	}
+
+	return value, nil
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/parser"
+	. "github.com/golangee/dyml/preprocess"
+	"github.com/golangee/dyml/token"
+)
+
+var errNoSecret = errors.New("no such secret")
+
+func TestInterpolate(t *testing.T) {
+	variables := map[string]string{"HOST": "example.com", "PORT": "8080"}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+
+		return value, ok
+	}
+
+	secret := WithFunc("secret", func(argument string) (string, error) {
+		if argument == "db" {
+			return "hunter2", nil
+		}
+
+		return "", errNoSecret
+	})
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "g2 attributes and texts",
+			text: `#! server @url="https://${HOST}:${PORT}/" { password "${secret:db}" }`,
+			want: `#! server @url="https://example.com:8080/" { password "hunter2" }`,
+		},
+		{
+			name: "g1 with escaped brackets",
+			text: `#server @host{${HOST\}} {port ${PORT\}}`,
+			want: `#server @host{example.com} {port 8080}`,
+		},
+		{
+			name: "escaped reference",
+			text: `#! x "$${HOST} $ {}"`,
+			want: `#! x "${HOST} $ {}"`,
+		},
+		{
+			name: "comments are kept",
+			text: `#! x // ${MISSING}`,
+			want: `#! x // ${MISSING}`,
+		},
+		{
+			name:    "unknown variable",
+			text:    `#! x "${MISSING}"`,
+			wantErr: errors.New("variable 'MISSING' is not defined"),
+		},
+		{
+			name:    "unknown function",
+			text:    `#! x @key="${file:/etc/passwd}"`,
+			wantErr: errors.New("function 'file' is not defined"),
+		},
+		{
+			name:    "failing function",
+			text:    `#! x "${secret:other}"`,
+			wantErr: errNoSecret,
+		},
+		{
+			name:    "unterminated reference",
+			text:    `#! x "${HOST"`,
+			wantErr: errors.New("reference is missing a closing '}'"),
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = Interpolate(lookup, secret)(tree)
+			if test.wantErr != nil {
+				var posErr *token.PosError
+				if !errors.As(err, &posErr) {
+					t.Fatalf("expected a positional error, but got %v", err)
+				}
+
+				if !errors.Is(err, test.wantErr) && posErr.Details[0].Message != test.wantErr.Error() {
+					t.Errorf("expected error '%v', but got '%v'", test.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parser.NewParser(test.name, strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, change := range compare.Diff(want, tree) {
+				t.Error(change)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess
+
+import (
+	"fmt"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+const (
+	// AnchorAttribute is the name of the attribute that declares an anchor on a node.
+	AnchorAttribute = "anchor"
+	// RefElement is the name of elements that are replaced by a copy of an anchored node.
+	RefElement = "ref"
+	// RefAttribute is the attribute of a RefElement that names the anchor to copy.
+	RefAttribute = "to"
+)
+
+// References resolves anchors and references in the tree, similar to anchors in YAML.
+// Any element can declare an anchor with the "anchor" attribute. Elements that follow the
+// anchored element in document order can be replaced by a copy of it with a "ref" element:
+//
+//  #service @anchor{web} {#port{80}}
+//  #ref @to{web}
+//
+// The anchor attributes are removed from the tree, also from the copies. Declaring an anchor
+// twice or referencing an anchor that was not declared before results in a positional error.
+// As an anchor is only declared once the element is complete, it cannot reference itself.
+func References(tree *parser.TreeNode) error {
+	r := &referenceResolver{anchors: map[string]*parser.TreeNode{}}
+	if err := r.resolve(tree); err != nil {
+		return err
+	}
+
+	// The copies were placed into Children directly, so they do not know their parents yet.
+	tree.LinkParents()
+
+	return nil
+}
+
+// referenceResolver keeps track of all anchors that are declared while walking the tree.
+type referenceResolver struct {
+	anchors map[string]*parser.TreeNode
+}
+
+// resolve replaces all references in the children of node and records the anchor of node, if any.
+func (r *referenceResolver) resolve(node *parser.TreeNode) error {
+	for i, child := range node.Children {
+		if !child.IsNode() {
+			continue
+		}
+
+		if child.Name == RefElement {
+			to := child.Attributes.Get(RefAttribute)
+			if to == nil {
+				return token.NewPosError(child.Range, fmt.Sprintf("reference requires a '%s' attribute", RefAttribute))
+			}
+
+			anchored, ok := r.anchors[to.Value]
+			if !ok {
+				return token.NewPosError(to.Range, fmt.Sprintf("anchor '%s' is not declared", to.Value))
+			}
+
+			node.Children[i] = anchored.Clone()
+
+			continue
+		}
+
+		if err := r.resolve(child); err != nil {
+			return err
+		}
+	}
+
+	anchor := node.Attributes.Get(AnchorAttribute)
+	if anchor == nil {
+		return nil
+	}
+
+	if existing, ok := r.anchors[anchor.Value]; ok {
+		return token.NewPosError(anchor.Range, fmt.Sprintf("anchor '%s' is declared multiple times", anchor.Value),
+			token.NewErrDetail(existing.Range, "first declared here"))
+	}
+
+	r.anchors[anchor.Value] = node
+	node.Attributes.Remove(AnchorAttribute)
+
+	return nil
+}
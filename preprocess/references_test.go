@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/parser"
+	. "github.com/golangee/dyml/preprocess"
+	"github.com/golangee/dyml/token"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "copy",
+			text: `#service @anchor{web} @port{80} {#path{/}}#ref @to{web}`,
+			want: `#service @port{80} {#path{/}}#service @port{80} {#path{/}}`,
+		},
+		{
+			name: "nested anchors",
+			text: `#! endpoints {
+						endpoint @anchor="health" { path "/health" }
+						group @anchor="api" {
+							ref @to="health"
+						}
+					}
+					#! ref @to="api",`,
+			want: `#! endpoints {
+						endpoint { path "/health" }
+						group { endpoint { path "/health" } }
+					}
+					#! group { endpoint { path "/health" } }`,
+		},
+		{
+			name:    "undeclared anchor",
+			text:    `#ref @to{web}`,
+			wantErr: true,
+		},
+		{
+			name:    "reference before declaration",
+			text:    `#ref @to{web} #service @anchor{web}`,
+			wantErr: true,
+		},
+		{
+			name:    "self reference",
+			text:    `#service @anchor{web} {#ref @to{web}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate anchor",
+			text:    `#a @anchor{x} #b @anchor{x}`,
+			wantErr: true,
+		},
+		{
+			name:    "reference without target",
+			text:    `#ref`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = References(tree)
+			if test.wantErr {
+				var posErr *token.PosError
+				if !errors.As(err, &posErr) {
+					t.Errorf("expected a positional error, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parser.NewParser(test.name, strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, change := range compare.Diff(want, tree) {
+				t.Error(change)
+			}
+		})
+	}
+}
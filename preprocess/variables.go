@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// DefineElement is the name of elements that define variables.
+const DefineElement = "define"
+
+// Variables resolves variable definitions and references in the tree.
+// A variable is defined by an element named "define" with a "name" attribute and text as its value:
+//
+//  #define @name{host} {example.com}
+//
+// All text and attribute values that follow the definition in document order can reference
+// the variable with "$(host)". Parentheses are used, as curly brackets would close blocks in G1.
+// Use "$$(" for a literal "$(". Definitions may reference
+// variables that were defined before them and can be redefined.
+// All definitions are removed from the tree. Referencing an undefined variable results in
+// a positional error.
+func Variables(tree *parser.TreeNode) error {
+	r := &variableResolver{values: map[string]string{}}
+
+	return r.resolve(tree)
+}
+
+// variableResolver keeps track of all variables that are defined while walking the tree.
+type variableResolver struct {
+	values map[string]string
+}
+
+// resolve replaces all references in node and its children and removes definitions from them.
+func (r *variableResolver) resolve(node *parser.TreeNode) error {
+	switch {
+	case node.IsText():
+		text, err := r.substitute(*node.Text, node.Range)
+		if err != nil {
+			return err
+		}
+
+		node.Text = &text
+
+		return nil
+	case node.IsComment():
+		return nil
+	}
+
+	list := node.Attributes
+	for attr := list.Pop(); attr != nil; attr = list.Pop() {
+		value, err := r.substitute(attr.Value, attr.Range)
+		if err != nil {
+			return err
+		}
+
+		attr.Value = value
+		node.Attributes.Set(*attr)
+	}
+
+	children := node.Children[:0]
+
+	for _, child := range node.Children {
+		if child.IsNode() && child.Name == DefineElement {
+			if err := r.define(child); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := r.resolve(child); err != nil {
+			return err
+		}
+
+		children = append(children, child)
+	}
+
+	node.Children = children
+
+	return nil
+}
+
+// define stores the variable defined by node.
+func (r *variableResolver) define(node *parser.TreeNode) error {
+	name := node.Attributes.Get("name")
+	if name == nil {
+		return token.NewPosError(node.Range, "variable definition requires a 'name' attribute")
+	}
+
+	var value strings.Builder
+
+	for _, child := range node.Children {
+		switch {
+		case child.IsText():
+			text, err := r.substitute(*child.Text, child.Range)
+			if err != nil {
+				return err
+			}
+
+			value.WriteString(text)
+		case child.IsNode():
+			return token.NewPosError(child.Range, "variable definitions may only contain text")
+		}
+	}
+
+	r.values[name.Value] = value.String()
+
+	return nil
+}
+
+// substitute replaces all references in s. pos is the position of s and used for errors.
+func (r *variableResolver) substitute(s string, pos token.Position) (string, error) {
+	if !strings.Contains(s, "$(") {
+		return s, nil
+	}
+
+	var result strings.Builder
+
+	for {
+		start := strings.Index(s, "$(")
+		if start < 0 {
+			result.WriteString(s)
+
+			break
+		}
+
+		// "$$(" is an escaped "$(".
+		if start > 0 && s[start-1] == '$' {
+			result.WriteString(s[:start-1])
+			result.WriteString("$(")
+			s = s[start+2:]
+
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], ')')
+		if end < 0 {
+			return "", token.NewPosError(pos, "variable reference is missing a closing ')'")
+		}
+
+		name := s[start+2 : start+end]
+
+		value, ok := r.values[name]
+		if !ok {
+			return "", token.NewPosError(pos, fmt.Sprintf("variable '%s' is not defined", name))
+		}
+
+		result.WriteString(s[:start])
+		result.WriteString(value)
+		s = s[start+end+1:]
+	}
+
+	return result.String(), nil
+}
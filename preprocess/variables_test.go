@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package preprocess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/parser"
+	. "github.com/golangee/dyml/preprocess"
+	"github.com/golangee/dyml/token"
+)
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text and attributes",
+			text: `#define @name{host} {example.com}#server @url{https://$(host)/} {$(host):80}`,
+			want: `#server @url{https://example.com/} {example.com:80}`,
+		},
+		{
+			name: "g2 definitions",
+			text: `#! define @name="port" "8080"
+					#! server { port "$(port)" }`,
+			want: `#! server { port "8080" }`,
+		},
+		{
+			name: "nested definitions and redefinition",
+			text: `#define @name{a} {1}#define @name{b} {$(a)2}#define @name{a} {3}#x{$(a)$(b)}`,
+			want: `#x{312}`,
+		},
+		{
+			name: "escaped reference",
+			text: `#x{$$(not) $ {}`,
+			want: `#x{$(not) $ {}`,
+		},
+		{
+			name:    "undefined variable",
+			text:    `#x{$(missing)}`,
+			wantErr: true,
+		},
+		{
+			name:    "reference before definition",
+			text:    `#x{$(a)}#define @name{a} {1}`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated reference",
+			text:    `#x @key{$(a}`,
+			wantErr: true,
+		},
+		{
+			name:    "definition without name",
+			text:    `#define {1}`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser(test.name, strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = Variables(tree)
+			if test.wantErr {
+				var posErr *token.PosError
+				if !errors.As(err, &posErr) {
+					t.Errorf("expected a positional error, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parser.NewParser(test.name, strings.NewReader(test.want)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, change := range compare.Diff(want, tree) {
+				t.Error(change)
+			}
+		})
+	}
+}
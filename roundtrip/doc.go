@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package roundtrip generates random valid trees and checks with testing/quick that writing them
+// as dyml text and parsing that text again results in the same trees. Encoder authors can reuse it
+// to test their own writers:
+//
+//	func TestRoundTrip(t *testing.T) {
+//		roundtrip.Check(t, func(w io.Writer, tree *parser.TreeNode) error {
+//			return myencoder.New(w).Encode(tree)
+//		}, nil)
+//	}
+package roundtrip
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package roundtrip
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// Options limit the size of generated trees.
+type Options struct {
+	// MaxDepth is the maximum number of nested elements below the root.
+	MaxDepth int
+	// MaxChildren is the maximum number of children of an element.
+	MaxChildren int
+	// MaxAttributes is the maximum number of attributes of an element.
+	MaxAttributes int
+	// Comments enables comments within blocks. Comments on the top level are never generated,
+	// as their trailing newline is part of the comment. Neither are comments right after an element,
+	// as they belong to that element in G2.
+	Comments bool
+}
+
+// DefaultOptions returns the Options that are used by Tree.
+func DefaultOptions() Options {
+	return Options{
+		MaxDepth:      4,
+		MaxChildren:   4,
+		MaxAttributes: 3,
+		Comments:      true,
+	}
+}
+
+// blockTypes are the block types of generated elements with more than one child.
+//
+//nolint:gochecknoglobals // Generators only pick from it, it is never modified.
+var blockTypes = []parser.BlockType{parser.BlockNormal, parser.BlockGroup, parser.BlockGeneric}
+
+// textRunes are the characters of generated texts. They contain characters that need to be escaped
+// in some places and characters that are not ASCII.
+//
+//nolint:gochecknoglobals // Generators only pick from it, it is never modified.
+var textRunes = []rune("abcxyz019 .,;:-_#@{}()<>\"'\\/`äöü€→🙂")
+
+// RandomTree returns a random valid tree, whose root is a G1 element like the one created by the parser.
+//
+// All trees can be written as dyml text unambiguously: Elements without a block have at most one child,
+// as multiple children require a block, and attribute keys are unique within an element.
+// Names are random identifiers, texts and attribute values random strings.
+func RandomTree(r *rand.Rand, opts Options) *parser.TreeNode {
+	root := parser.NewNode(parser.DefaultRootName).Block(parser.BlockNormal)
+	root.Synthetic = true
+
+	for i := r.Intn(opts.MaxChildren + 1); i > 0; i-- {
+		if r.Intn(4) == 0 {
+			root.AddChildren(parser.NewStringNode(randomText(r)))
+		} else {
+			root.AddChildren(randomElement(r, opts, 1))
+		}
+	}
+
+	return root
+}
+
+// randomElement returns a random element with its children. depth is the depth of the element itself.
+func randomElement(r *rand.Rand, opts Options, depth int) *parser.TreeNode {
+	node := parser.NewNode(randomName(r))
+
+	keys := make(map[string]bool)
+
+	for i := r.Intn(opts.MaxAttributes + 1); i > 0; i-- {
+		key := randomName(r)
+		if keys[key] {
+			continue
+		}
+
+		keys[key] = true
+		node.AddAttribute(key, randomText(r))
+	}
+
+	if depth >= opts.MaxDepth {
+		return node
+	}
+
+	children := r.Intn(opts.MaxChildren + 1)
+	if children > 1 || r.Intn(3) == 0 {
+		node.Block(blockTypes[r.Intn(len(blockTypes))])
+	}
+
+	for i := 0; i < children; i++ {
+		afterElement := i > 0 && node.Children[i-1].IsNode()
+
+		switch n := r.Intn(6); {
+		case n == 0 && opts.Comments && node.BlockType != parser.BlockNone && !afterElement:
+			node.AddChildren(parser.NewStringCommentNode(randomName(r)))
+		case n <= 1:
+			node.AddChildren(parser.NewStringNode(randomText(r)))
+		default:
+			node.AddChildren(randomElement(r, opts, depth+1))
+		}
+	}
+
+	return node
+}
+
+// randomName returns a random identifier.
+func randomName(r *rand.Rand) string {
+	var sb strings.Builder
+
+	sb.WriteByte(byte('a' + r.Intn(26)))
+
+	for i := r.Intn(6); i > 0; i-- {
+		if r.Intn(4) == 0 {
+			sb.WriteString(strconv.Itoa(r.Intn(10)))
+		} else {
+			sb.WriteByte(byte('a' + r.Intn(26)))
+		}
+	}
+
+	return sb.String()
+}
+
+// randomText returns a random string of textRunes, which may be empty.
+func randomText(r *rand.Rand) string {
+	var sb strings.Builder
+
+	for i := r.Intn(12); i > 0; i-- {
+		sb.WriteRune(textRunes[r.Intn(len(textRunes))])
+	}
+
+	return sb.String()
+}
+
+// Tree is a random tree for testing/quick. Use it as the argument of a property:
+//
+//	quick.Check(func(tree roundtrip.Tree) bool { ... }, nil)
+type Tree struct {
+	*parser.TreeNode
+}
+
+// Generate implements quick.Generator. The size limits the number of children of each element.
+func (Tree) Generate(r *rand.Rand, size int) reflect.Value {
+	opts := DefaultOptions()
+	if size < opts.MaxChildren {
+		opts.MaxChildren = size
+	}
+
+	return reflect.ValueOf(Tree{RandomTree(r, opts)})
+}
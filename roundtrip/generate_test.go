@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package roundtrip_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/golangee/dyml/parser"
+	. "github.com/golangee/dyml/roundtrip"
+)
+
+func TestRandomTree(t *testing.T) {
+	t.Parallel()
+
+	opts := Options{MaxDepth: 2, MaxChildren: 3, MaxAttributes: 2}
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		tree := RandomTree(r, opts)
+
+		if tree.Name != "root" || tree.BlockType != parser.BlockNormal {
+			t.Fatalf("unexpected root:\n%s", tree.Dump())
+		}
+
+		checkGenerated(t, tree, opts, 0)
+	}
+}
+
+// checkGenerated checks that a generated node and its children stay within opts.
+func checkGenerated(t *testing.T, node *parser.TreeNode, opts Options, depth int) {
+	t.Helper()
+
+	if depth > opts.MaxDepth {
+		t.Fatalf("'%s' is deeper than %d", node.Name, opts.MaxDepth)
+	}
+
+	if len(node.Children) > opts.MaxChildren {
+		t.Fatalf("'%s' has %d children", node.Name, len(node.Children))
+	}
+
+	if node.Attributes.Len() > opts.MaxAttributes {
+		t.Fatalf("'%s' has %d attributes", node.Name, node.Attributes.Len())
+	}
+
+	if node.BlockType == parser.BlockNone && len(node.Children) > 1 {
+		t.Fatalf("'%s' has multiple children without a block", node.Name)
+	}
+
+	for _, child := range node.Children {
+		if child.IsComment() && !opts.Comments {
+			t.Fatalf("'%s' contains a comment", node.Name)
+		}
+
+		if child.IsNode() {
+			checkGenerated(t, child, opts, depth+1)
+		}
+	}
+}
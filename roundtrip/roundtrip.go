@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package roundtrip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/golangee/dyml/compare"
+	"github.com/golangee/dyml/parser"
+)
+
+// Encode writes tree as dyml text to w.
+type Encode func(w io.Writer, tree *parser.TreeNode) error
+
+// RoundTrip writes tree with encode and parses the text again. An error is returned if that fails
+// or the parsed tree differs from tree, it contains the written text.
+func RoundTrip(tree *parser.TreeNode, encode Encode) error {
+	var buf bytes.Buffer
+
+	if err := encode(&buf, tree); err != nil {
+		return fmt.Errorf("cannot encode tree: %w\n%s", err, tree.Dump())
+	}
+
+	got, err := parser.NewParser("roundtrip", bytes.NewReader(buf.Bytes())).Parse()
+	if err != nil {
+		return fmt.Errorf("cannot parse encoded text: %w\n%s", err, buf.String())
+	}
+
+	if !parser.Equal(tree, got, parser.NormalizeOptions{}) {
+		var changes strings.Builder
+
+		for _, change := range compare.Diff(tree, got) {
+			changes.WriteString(change.String() + "\n")
+		}
+
+		return fmt.Errorf("parsed tree differs from the encoded one:\n%sin text\n%s", changes.String(), buf.String())
+	}
+
+	return nil
+}
+
+// Check proves for random trees that parsing the text written by encode results in the same tree again.
+// config is passed to quick.Check and may be nil. The first tree that does not survive the round trip
+// fails the test.
+func Check(t *testing.T, encode Encode, config *quick.Config) {
+	t.Helper()
+
+	var failure error
+
+	property := func(tree Tree) bool {
+		failure = RoundTrip(tree.TreeNode, encode)
+
+		return failure == nil
+	}
+
+	if err := quick.Check(property, config); err != nil {
+		if failure != nil {
+			t.Fatal(failure)
+		}
+
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package roundtrip_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/golangee/dyml/encoder"
+	"github.com/golangee/dyml/parser"
+	. "github.com/golangee/dyml/roundtrip"
+)
+
+func encodeDyml(w io.Writer, tree *parser.TreeNode) error {
+	return encoder.NewDymlEncoder(w).Encode(tree)
+}
+
+func TestDymlEncoder(t *testing.T) {
+	t.Parallel()
+
+	Check(t, encodeDyml, &quick.Config{MaxCount: 500})
+}
+
+func TestRoundTripReportsDifferences(t *testing.T) {
+	t.Parallel()
+
+	tree := parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+		parser.NewNode("item").AddAttribute("key", "value"),
+	)
+
+	// This encoder forgets all attributes.
+	lossy := func(w io.Writer, tree *parser.TreeNode) error {
+		_, err := io.WriteString(w, "#! item")
+
+		return err
+	}
+
+	err := RoundTrip(tree, lossy)
+	if err == nil {
+		t.Fatal("expected an error for a lossy encoder")
+	}
+
+	if !strings.Contains(err.Error(), "root/item@key") {
+		t.Errorf("expected the error to name the lost attribute, got: %v", err)
+	}
+
+	if err := RoundTrip(tree, encodeDyml); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// CheckSingleChild returns the child element of node named name, which must exist exactly once,
+// just like the element of a field is read in strict mode. Otherwise an UnmarshalError is returned.
+func CheckSingleChild(node *parser.TreeNode, name string) (*parser.TreeNode, error) {
+	return singleChild(node, name, true, func(name, expected string) bool {
+		return name == expected
+	})
+}
+
+// CheckSingleText returns the text of node, which must contain exactly one text, just like primitive
+// values are read in strict mode. Otherwise an UnmarshalError is returned. Elements and comments in
+// node are ignored.
+func CheckSingleText(node *parser.TreeNode) (string, error) {
+	return singleText(node, true)
+}
+
+// CheckMapEntries checks that the children of node can be read as a map in strict mode: Every child is
+// an element, whose name is a key that is used only once, and which contains exactly one value.
+// Otherwise an UnmarshalError is returned.
+func CheckMapEntries(node *parser.TreeNode) error {
+	keys := map[string]bool{}
+
+	for _, keyNode := range nonCommentChildren(node) {
+		if !keyNode.IsNode() {
+			return NewUnmarshalError(node, "map key must be a node", nil)
+		}
+
+		if keys[keyNode.Name] {
+			return NewUnmarshalError(keyNode, fmt.Sprintf("map key '%s' defined multiple times", keyNode.Name), nil)
+		}
+
+		keys[keyNode.Name] = true
+
+		if err := checkMapValue(node, keyNode, keyNode.Name, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// singleChild returns the child element of node whose name matches name. In strict mode, the element
+// is required and must not be defined multiple times, otherwise the first one is returned or nil.
+func singleChild(node *parser.TreeNode, name string, strict bool,
+	match func(name, expected string) bool) (*parser.TreeNode, error) {
+	var child *parser.TreeNode
+
+	for _, c := range nonCommentChildren(node) {
+		if match(c.Name, name) {
+			if child == nil {
+				child = c
+
+				if !strict {
+					// We found a child and don't care if there are other ones in non-strict mode.
+					break
+				}
+			} else {
+				return nil, NewUnmarshalError(node, fmt.Sprintf("'%s' defined multiple times", name), nil)
+			}
+		}
+	}
+
+	if strict && child == nil {
+		return nil, NewUnmarshalError(node, fmt.Sprintf("child '%s' required", name), nil)
+	}
+
+	return child, nil
+}
+
+// singleText returns the text of node, or all of its texts concatenated. In strict mode, there must be
+// exactly one text.
+func singleText(node *parser.TreeNode, strict bool) (string, error) {
+	if node.IsText() {
+		return *node.Text, nil
+	}
+
+	foundAny := false
+
+	var text strings.Builder
+
+	for _, c := range nonCommentChildren(node) {
+		if c.IsText() {
+			if foundAny && strict {
+				return "", NewUnmarshalError(node, "multiple occurrences of text, where only one is allowed", nil)
+			}
+
+			foundAny = true
+
+			text.WriteString(*c.Text)
+		}
+	}
+
+	if strict && !foundAny {
+		return "", NewUnmarshalError(node, "text inside element required", nil)
+	}
+
+	return text.String(), nil
+}
+
+// checkMapValue returns an error, if keyNode in the map node contains no value, or more than one in strict mode.
+func checkMapValue(node, keyNode *parser.TreeNode, key interface{}, strict bool) error {
+	switch count := len(nonCommentChildren(keyNode)); {
+	case count == 0:
+		return NewUnmarshalError(node, fmt.Sprintf("no value in map for key '%v'", key), nil)
+	case strict && count != 1:
+		return NewUnmarshalError(node, fmt.Sprintf("key '%v' needs exactly one value", key), nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+func TestStrictChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		check   func(tree *parser.TreeNode) error
+		wantErr bool
+	}{
+		{
+			name: "single child",
+			text: "#a{x} #b",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleChild(tree, "a")
+
+				return err
+			},
+		},
+		{
+			name: "missing child",
+			text: "#b",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleChild(tree, "a")
+
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "child defined twice",
+			text: "#a #? comment\n#a",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleChild(tree, "a")
+
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "single text",
+			text: "#! a { \"x\" // comment\n}",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleText(tree.Children[0])
+
+				return err
+			},
+		},
+		{
+			name: "no text",
+			text: "#a{#b}",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleText(tree.Children[0])
+
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple texts",
+			text: "#a{x #b{z} y}",
+			check: func(tree *parser.TreeNode) error {
+				_, err := CheckSingleText(tree.Children[0])
+
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "map entries",
+			text: "#! m { a 1, b \"2\" }",
+			check: func(tree *parser.TreeNode) error {
+				return CheckMapEntries(tree.Children[0])
+			},
+		},
+		{
+			name: "map key defined twice",
+			text: "#! m { a 1, a 2 }",
+			check: func(tree *parser.TreeNode) error {
+				return CheckMapEntries(tree.Children[0])
+			},
+			wantErr: true,
+		},
+		{
+			name: "map key with two values",
+			text: "#! m { a (1, 2) }",
+			check: func(tree *parser.TreeNode) error {
+				return CheckMapEntries(tree.Children[0])
+			},
+			wantErr: true,
+		},
+		{
+			name: "map key without value",
+			text: "#! m { a }",
+			check: func(tree *parser.TreeNode) error {
+				return CheckMapEntries(tree.Children[0])
+			},
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser("", strings.NewReader(test.text)).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = test.check(tree)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, token.ErrType) {
+				t.Errorf("expected an error in the category ErrType, but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// textValue converts values of a type, which has its own text format, from and to text.
+type textValue struct {
+	// name describes the type in error messages.
+	name   string
+	parse  func(text string, value reflect.Value) error
+	format func(value reflect.Value) string
+}
+
+// textValues contains all types that are read from and written as text with their own format.
+// They can be used for elements and attributes, just like primitive types.
+//
+//nolint:gochecknoglobals // Registered once here and only read afterwards.
+var textValues = map[reflect.Type]textValue{
+	reflect.TypeOf(time.Duration(0)): {
+		name: "duration",
+		parse: func(text string, value reflect.Value) error {
+			d, err := time.ParseDuration(text)
+			if err != nil {
+				return err
+			}
+
+			value.SetInt(int64(d))
+
+			return nil
+		},
+		format: func(value reflect.Value) string {
+			return time.Duration(value.Int()).String()
+		},
+	},
+	reflect.TypeOf(ByteSize(0)): {
+		name: "byte size",
+		parse: func(text string, value reflect.Value) error {
+			size, err := ParseByteSize(text)
+			if err != nil {
+				return err
+			}
+
+			value.SetUint(uint64(size))
+
+			return nil
+		},
+		format: func(value reflect.Value) string {
+			return ByteSize(value.Uint()).String()
+		},
+	},
+	reflect.TypeOf(url.URL{}): {
+		name: "URL",
+		parse: func(text string, value reflect.Value) error {
+			u, err := url.Parse(text)
+			if err != nil {
+				return err
+			}
+
+			value.Set(reflect.ValueOf(*u))
+
+			return nil
+		},
+		format: func(value reflect.Value) string {
+			u := value.Interface().(url.URL) //nolint:forcetypeassert
+
+			return u.String()
+		},
+	},
+}
+
+// doTextValue parses the text of node into value, whose type must be in textValues.
+func (u *unmarshaler) doTextValue(node *parser.TreeNode, value reflect.Value, tv textValue) error {
+	text, err := getAsText(node)
+	if err != nil {
+		return NewUnmarshalError(node, fmt.Sprintf("%s required for '%s'", tv.name, value.Type().Name()), err)
+	}
+
+	if err := tv.parse(strings.TrimSpace(text), value); err != nil {
+		return NewUnmarshalError(node, fmt.Sprintf("'%s' is not a valid %s", text, tv.name), err)
+	}
+
+	return nil
+}
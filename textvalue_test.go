@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/golangee/dyml"
+)
+
+type textValues struct {
+	Timeout  time.Duration
+	Interval time.Duration `dyml:"interval,attr"`
+	Limit    ByteSize
+	Buffer   ByteSize `dyml:"buffer,attr"`
+	Endpoint *url.URL
+	Mirror   url.URL `dyml:"mirror,attr"`
+	Retries  []time.Duration
+}
+
+func TestUnmarshalTextValues(t *testing.T) {
+	t.Parallel()
+
+	input := `#! Timeout "30s"
+#! Limit "10MiB"
+#! Endpoint "https://example.com/api?x=1"
+#! Retries { "1s" "1m30s" }
+#! Settings @interval="5m" @buffer="1.5KB" @mirror="http://mirror.example.com"`
+
+	var value struct {
+		textValues
+		Settings textValues
+	}
+
+	if err := Unmarshal(strings.NewReader(input), &value, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if value.Timeout != 30*time.Second || value.Limit != 10*MiB || value.Endpoint.Host != "example.com" ||
+		value.Endpoint.RawQuery != "x=1" || !reflect.DeepEqual(value.Retries, []time.Duration{time.Second, 90 * time.Second}) {
+		t.Errorf("unexpected elements %+v", value.textValues)
+	}
+
+	if value.Settings.Interval != 5*time.Minute || value.Settings.Buffer != 1500 ||
+		value.Settings.Mirror.Host != "mirror.example.com" {
+		t.Errorf("unexpected attributes %+v", value.Settings)
+	}
+}
+
+func TestUnmarshalTextValueErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "duration element", text: `#Timeout{30 parsecs}`},
+		{name: "duration attribute", text: `#Settings @interval{often}`},
+		{name: "byte size element", text: `#Limit{10 MiBs}`},
+		{name: "byte size attribute", text: `#Settings @buffer{-1}`},
+		{name: "url element", text: `#! Endpoint "http://[::1"`},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var value struct {
+				textValues
+				Settings textValues
+			}
+
+			err := Unmarshal(strings.NewReader(test.text), &value, false)
+
+			var unmarshalErr UnmarshalError
+			if !errors.As(err, &unmarshalErr) {
+				t.Fatalf("expected an UnmarshalError, but got %v", err)
+			}
+
+			if unmarshalErr.Node.Range.BeginPos.Line != 1 {
+				t.Errorf("expected the error to have a position, but got %v", unmarshalErr.Node.Range)
+			}
+		})
+	}
+}
+
+func TestMarshalTextValues(t *testing.T) {
+	t.Parallel()
+
+	endpoint, _ := url.Parse("https://example.com/api")
+
+	var value struct {
+		Settings textValues
+	}
+
+	value.Settings = textValues{
+		Timeout:  90 * time.Second,
+		Interval: time.Minute,
+		Limit:    3 * GiB,
+		Buffer:   1500,
+		Endpoint: endpoint,
+		Mirror:   url.URL{Scheme: "http", Host: "mirror.example.com"},
+		Retries:  []time.Duration{time.Millisecond},
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, value); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"1m30s"`, `"3GiB"`, `"https://example.com/api"`, `@interval="1m0s"`,
+		`@buffer="1500B"`, `@mirror="http://mirror.example.com"`, `"1ms"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in\n%s", want, buf.String())
+		}
+	}
+
+	var got struct {
+		Settings textValues
+	}
+
+	if err := Unmarshal(&buf, &got, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("expected %+v but got %+v", value, got)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text    string
+		want    ByteSize
+		wantErr bool
+	}{
+		{text: "512", want: 512},
+		{text: "10MiB", want: 10 * MiB},
+		{text: "1.5 GB", want: 1500 * MB},
+		{text: "2kib", want: 2 * KiB},
+		{text: "0B", want: 0},
+		{text: "16EiB", wantErr: true},
+		{text: "10 parsecs", wantErr: true},
+		{text: "MiB", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseByteSize(test.text)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error %v, got %v", test.text, test.wantErr, err)
+
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%s: expected %d, got %d", test.text, test.want, got)
+		}
+
+		if !test.wantErr {
+			if again, _ := ParseByteSize(got.String()); again != got {
+				t.Errorf("%s: '%s' does not parse to the same size", test.text, got.String())
+			}
+		}
+	}
+}
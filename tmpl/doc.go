@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tmpl makes parsed documents usable as data in text/template and html/template,
+// so that reports can be rendered from a document without unmarshalling it into structs first.
+//
+//	// For the document
+//	//   #title Servers
+//	//   #server @host{a.example.com} {#port 80}
+//	//   #server @host{b.example.com} {#port 443}
+//	t := template.Must(template.New("report").Funcs(tmpl.FuncMap()).Parse(
+//	    `{{.title}}{{range .All "server"}}
+//	{{.Attr "host"}}:{{.port}}{{end}}`))
+//	root, err := tmpl.Parse("servers.dyml", file)
+//	...
+//	err = t.Execute(os.Stdout, root)
+package tmpl
@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package tmpl
+
+// FuncMap returns functions to work with Nodes in templates. It can be passed to Funcs of
+// text/template and html/template. The functions are the methods of Node as pipeline friendly functions:
+//
+//   - attr NODE KEY returns the value of an attribute, e.g. {{attr .server "port"}}.
+//   - hasAttr NODE KEY returns true if the attribute exists.
+//   - child NODE NAME returns the first child element with the name.
+//   - children NODE returns all child elements.
+//   - all NODE NAME returns all child elements with the name, e.g. {{range all . "item"}}.
+//   - text NODE returns the trimmed text of the element, e.g. {{.title | text | printf "%q"}}.
+//   - name NODE returns the name of the element.
+func FuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"attr":     Node.Attr,
+		"hasAttr":  Node.HasAttr,
+		"child":    Node.Child,
+		"children": Node.Children,
+		"all":      Node.All,
+		"text":     Node.Text,
+		"name":     Node.Name,
+	}
+}
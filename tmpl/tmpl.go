@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package tmpl
+
+import (
+	"io"
+	"strings"
+
+	"github.com/golangee/dyml/parser"
+)
+
+// treeKey is the key under which a Node keeps its element. It cannot be used in templates.
+type treeKey struct{}
+
+// Node makes an element usable as the data of a template. Its child elements can be accessed
+// by name with dot access, e.g. {{.server.port}}, which returns the first child with that name.
+// Should a child have the same name as a method, the method takes precedence, use Child instead.
+// Printing a Node prints its text, so that {{.title}} prints the text of the title element.
+// Accessing a child that does not exist returns no value, which is empty in {{if}} and {{with}}.
+type Node map[interface{}]interface{}
+
+// NewNode returns the Node for the given element.
+func NewNode(tree *parser.TreeNode) Node {
+	n := Node{treeKey{}: tree}
+
+	for _, child := range tree.Children {
+		if !child.IsNode() {
+			continue
+		}
+
+		if _, ok := n[child.Name]; !ok {
+			n[child.Name] = NewNode(child)
+		}
+	}
+
+	return n
+}
+
+// Parse parses the document in r and returns the Node for its root.
+func Parse(filename string, r io.Reader) (Node, error) {
+	tree, err := parser.NewParser(filename, r).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewNode(tree), nil
+}
+
+// Tree returns the element of this Node, or nil for a nil Node.
+func (n Node) Tree() *parser.TreeNode {
+	tree, _ := n[treeKey{}].(*parser.TreeNode)
+
+	return tree
+}
+
+// Name returns the name of the element.
+func (n Node) Name() string {
+	if tree := n.Tree(); tree != nil {
+		return tree.Name
+	}
+
+	return ""
+}
+
+// Attr returns the value of the attribute with the given key, or an empty string if it does not exist.
+func (n Node) Attr(key string) string {
+	tree := n.Tree()
+	if tree == nil {
+		return ""
+	}
+
+	if attr := tree.Attributes.Get(key); attr != nil {
+		return attr.Value
+	}
+
+	return ""
+}
+
+// HasAttr returns true if the element has an attribute with the given key.
+func (n Node) HasAttr(key string) bool {
+	tree := n.Tree()
+
+	return tree != nil && tree.Attributes.Get(key) != nil
+}
+
+// Attrs returns all attributes of the element, which can be used with {{range $key, $value := .Attrs}}.
+func (n Node) Attrs() map[string]string {
+	attrs := map[string]string{}
+
+	if tree := n.Tree(); tree != nil {
+		for i := 0; i < tree.Attributes.Len(); i++ {
+			attr := tree.Attributes.GetAt(i)
+			attrs[attr.Key] = attr.Value
+		}
+	}
+
+	return attrs
+}
+
+// Child returns the first child element with the given name, or nil if there is none.
+func (n Node) Child(name string) Node {
+	child, _ := n[name].(Node)
+
+	return child
+}
+
+// Children returns all child elements in document order. Texts and comments are not included.
+func (n Node) Children() []Node {
+	return n.children(func(*parser.TreeNode) bool { return true })
+}
+
+// All returns all child elements with the given name, e.g. for {{range .All "item"}}.
+func (n Node) All(name string) []Node {
+	return n.children(func(child *parser.TreeNode) bool { return child.Name == name })
+}
+
+// Text returns the trimmed text of the element and all its descendants. Comments are not included.
+func (n Node) Text() string {
+	var sb strings.Builder
+
+	if tree := n.Tree(); tree != nil {
+		writeText(&sb, tree)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// String returns the same as Text.
+func (n Node) String() string {
+	return n.Text()
+}
+
+// children returns the Nodes for the child elements that match the filter.
+func (n Node) children(filter func(child *parser.TreeNode) bool) []Node {
+	tree := n.Tree()
+	if tree == nil {
+		return nil
+	}
+
+	var result []Node
+
+	for _, child := range tree.Children {
+		if !child.IsNode() || !filter(child) {
+			continue
+		}
+
+		if first := n.Child(child.Name); first != nil && first.Tree() == child {
+			result = append(result, first)
+		} else {
+			result = append(result, NewNode(child))
+		}
+	}
+
+	return result
+}
+
+// writeText writes the texts of node and all its descendants to sb.
+func writeText(sb *strings.Builder, node *parser.TreeNode) {
+	if node.IsText() {
+		sb.WriteString(*node.Text)
+	}
+
+	for _, child := range node.Children {
+		writeText(sb, child)
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package tmpl_test
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/golangee/dyml/tmpl"
+)
+
+const servers = `#title Servers
+#? comments are not part of texts
+#server @host{a.example.com} {#port 80}
+#server @host{b.example.com} @tls{yes} {#port 443}
+#note{Some #b{bold}.}`
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "dot access", template: `{{.title}}: {{.server.port}}`, want: "Servers: 80"},
+		{name: "attr method", template: `{{.server.Attr "host"}}`, want: "a.example.com"},
+		{name: "missing attr", template: `[{{.server.Attr "tls"}}]`, want: "[]"},
+		{
+			name:     "range over named children",
+			template: `{{range .All "server"}}{{.Attr "host"}}:{{.port}}{{if .HasAttr "tls"}} tls{{end}};{{end}}`,
+			want:     "a.example.com:80;b.example.com:443 tls;",
+		},
+		{
+			name:     "range over children",
+			template: `{{range .Children}}{{.Name}} {{end}}`,
+			want:     "title server server note ",
+		},
+		{
+			name:     "range over attributes",
+			template: `{{range $i, $s := .All "server"}}{{range $k, $v := $s.Attrs}}{{$i}}{{$k}}={{$v}} {{end}}{{end}}`,
+			want:     "0host=a.example.com 1host=b.example.com 1tls=yes ",
+		},
+		{name: "nested text", template: `{{.note}}`, want: "Some bold."},
+		{name: "missing child", template: `{{with .missing}}x{{else}}none{{end}}`, want: "none"},
+		{
+			name:     "functions",
+			template: `{{attr (child . "server") "host"}} {{len (all . "server")}} {{.title | text | printf "%q"}}`,
+			want:     `a.example.com 2 "Servers"`,
+		},
+		{name: "child function", template: `{{name (child .note "b")}} {{len (children .)}}`, want: "b 4"},
+	}
+
+	root, err := tmpl.Parse("servers", strings.NewReader(servers))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tpl, err := template.New(test.name).Funcs(tmpl.FuncMap()).Parse(test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sb strings.Builder
+			if err := tpl.Execute(&sb, root); err != nil {
+				t.Fatal(err)
+			}
+
+			if sb.String() != test.want {
+				t.Errorf("expected '%s', but got '%s'", test.want, sb.String())
+			}
+		})
+	}
+}
+
+func TestHTMLTemplate(t *testing.T) {
+	t.Parallel()
+
+	root, err := tmpl.Parse("html", strings.NewReader(`#p{<b> & "quotes"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := htmltemplate.New("html").Funcs(tmpl.FuncMap()).Parse(`<p>{{.p}}</p><p>{{text .p}}</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, root); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "<p>&lt;b&gt; &amp; &#34;quotes&#34;</p><p>&lt;b&gt; &amp; &#34;quotes&#34;</p>"
+	if sb.String() != want {
+		t.Errorf("expected '%s', but got '%s'", want, sb.String())
+	}
+}
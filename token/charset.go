@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package token
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrCharset is the cause of all errors about input that cannot be transcoded to UTF-8.
+var ErrCharset = errors.New("cannot transcode input to UTF-8")
+
+// Charset is an encoding of the input that is detected by a CharsetReader.
+type Charset string
+
+const (
+	// UTF8 input is passed on unchanged.
+	UTF8 Charset = "UTF-8"
+	// UTF16LE is detected by its byte order mark FF FE.
+	UTF16LE Charset = "UTF-16LE"
+	// UTF16BE is detected by its byte order mark FE FF.
+	UTF16BE Charset = "UTF-16BE"
+	// Latin1 (ISO-8859-1) is assumed for input without a byte order mark that is not valid UTF-8.
+	Latin1 Charset = "ISO-8859-1"
+)
+
+// charsetProbeSize is the number of bytes at the beginning of the input that are checked for valid UTF-8.
+const charsetProbeSize = 4096
+
+// CharsetReader transcodes its input to UTF-8, so that the Lexer can read documents that were stored
+// in other encodings. UTF-16 is detected by its byte order mark, which is removed. Input without one
+// is UTF-8, unless its first 4 KiB are not valid UTF-8, in which case it is read as Latin-1.
+// Invalid UTF-16 results in an error that wraps ErrCharset.
+type CharsetReader struct {
+	src     *bufio.Reader
+	charset Charset
+	// detected is true once the charset has been detected, which happens on the first Read.
+	detected bool
+	// pending contains transcoded bytes that did not fit into the last Read.
+	pending []byte
+	// offset is the number of bytes read from src, which is used in errors.
+	offset int
+	// buf is reused for the transcoded chunks.
+	buf []byte
+	// err is returned once the pending bytes before it have been read.
+	err error
+}
+
+// NewCharsetReader creates a CharsetReader that reads from r.
+func NewCharsetReader(r io.Reader) *CharsetReader {
+	return &CharsetReader{src: bufio.NewReaderSize(r, charsetProbeSize)}
+}
+
+// Charset returns the detected encoding of the input. The input is peeked to detect it, if it has not been read yet.
+func (c *CharsetReader) Charset() (Charset, error) {
+	if err := c.detect(); err != nil {
+		return "", err
+	}
+
+	return c.charset, nil
+}
+
+// detect decides on the charset of the input.
+func (c *CharsetReader) detect() error {
+	if c.detected {
+		return nil
+	}
+
+	head, err := c.src.Peek(charsetProbeSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	c.detected = true
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		c.charset = UTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		c.charset = UTF16BE
+	case !validUTF8Prefix(head, err == nil):
+		c.charset = Latin1
+	default:
+		c.charset = UTF8
+	}
+
+	if c.charset == UTF16LE || c.charset == UTF16BE {
+		// The byte order mark is not part of the content.
+		_, _ = c.src.Discard(2)
+		c.offset = 2
+	}
+
+	return nil
+}
+
+// validUTF8Prefix returns true if b is valid UTF-8. If truncated is true, b is followed by more input,
+// so an incomplete rune at its end is valid.
+func validUTF8Prefix(b []byte, truncated bool) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return truncated && !utf8.FullRune(b)
+		}
+
+		b = b[size:]
+	}
+
+	return true
+}
+
+// Read reads transcoded UTF-8.
+func (c *CharsetReader) Read(p []byte) (int, error) {
+	if err := c.detect(); err != nil {
+		return 0, err
+	}
+
+	if c.charset == UTF8 {
+		return c.src.Read(p)
+	}
+
+	if len(c.pending) == 0 && c.err == nil {
+		c.fill()
+	}
+
+	if len(c.pending) == 0 {
+		return 0, c.err
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+
+	return n, nil
+}
+
+// fill transcodes the next chunk of input into pending. An error is kept in err, so that
+// the bytes that were transcoded before are read first.
+func (c *CharsetReader) fill() {
+	c.buf = c.buf[:0]
+
+	for len(c.buf) < charsetProbeSize {
+		r, err := c.readRune()
+		if err != nil {
+			c.err = err
+
+			break
+		}
+
+		c.buf = appendRune(c.buf, r)
+
+		if c.src.Buffered() == 0 {
+			// Do not block for more input, if some has already been transcoded.
+			break
+		}
+	}
+
+	c.pending = c.buf
+}
+
+// readRune reads a single rune in the detected charset other than UTF-8.
+func (c *CharsetReader) readRune() (rune, error) {
+	if c.charset == Latin1 {
+		b, err := c.src.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		c.offset++
+
+		// The first 256 code points of unicode are the ones of Latin-1.
+		return rune(b), nil
+	}
+
+	start := c.offset
+
+	unit, err := c.readUTF16()
+	if err != nil {
+		return 0, err
+	}
+
+	if !utf16.IsSurrogate(unit) {
+		return unit, nil
+	}
+
+	next, err := c.readUTF16()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	r := utf16.DecodeRune(unit, next)
+	if err != nil || r == utf8.RuneError {
+		return 0, fmt.Errorf("%w: unpaired %s surrogate at byte %d", ErrCharset, c.charset, start)
+	}
+
+	return r, nil
+}
+
+// readUTF16 reads a single code unit of UTF-16.
+func (c *CharsetReader) readUTF16() (rune, error) {
+	var unit [2]byte
+
+	n, err := io.ReadFull(c.src, unit[:])
+	c.offset += n
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("%w: %s input ends with an odd number of bytes", ErrCharset, c.charset)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if c.charset == UTF16LE {
+		return rune(unit[0]) | rune(unit[1])<<8, nil
+	}
+
+	return rune(unit[0])<<8 | rune(unit[1]), nil
+}
+
+// appendRune appends the UTF-8 encoding of r to b.
+func appendRune(b []byte, r rune) []byte {
+	var enc [utf8.UTFMax]byte
+
+	n := utf8.EncodeRune(enc[:], r)
+
+	return append(b, enc[:n]...)
+}
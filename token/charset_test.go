@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package token_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	. "github.com/golangee/dyml/token"
+)
+
+// encodeUTF16 returns s as UTF-16 with a byte order mark.
+func encodeUTF16(s string, bigEndian bool) []byte {
+	units := append([]uint16{0xFEFF}, utf16.Encode([]rune(s))...)
+
+	var b []byte
+
+	for _, unit := range units {
+		if bigEndian {
+			b = append(b, byte(unit>>8), byte(unit))
+		} else {
+			b = append(b, byte(unit), byte(unit>>8))
+		}
+	}
+
+	return b
+}
+
+func TestCharsetReader(t *testing.T) {
+	doc := "#grüße{🙂 €}"
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		charset Charset
+		wantErr bool
+	}{
+		{name: "utf-8", input: []byte(doc), want: doc, charset: UTF8},
+		{name: "utf-8 with bom", input: []byte("\uFEFF" + doc), want: "\uFEFF" + doc, charset: UTF8},
+		{name: "utf-16le", input: encodeUTF16(doc, false), want: doc, charset: UTF16LE},
+		{name: "utf-16be", input: encodeUTF16(doc, true), want: doc, charset: UTF16BE},
+		{name: "latin-1", input: []byte("#gr\xfc\xdfe{caf\xe9}"), want: "#grüße{café}", charset: Latin1},
+		{name: "odd utf-16", input: append(encodeUTF16(doc, false), 'x'), charset: UTF16LE, wantErr: true},
+		{name: "unpaired surrogate", input: []byte{0xFF, 0xFE, 'a', 0, 0x00, 0xD8, 'b', 0}, charset: UTF16LE, wantErr: true},
+		{name: "empty", input: nil, want: "", charset: UTF8},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			reader := NewCharsetReader(bytes.NewReader(test.input))
+
+			charset, err := reader.Charset()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if charset != test.charset {
+				t.Errorf("expected charset %s, but got %s", test.charset, charset)
+			}
+
+			got, err := io.ReadAll(reader)
+			if test.wantErr {
+				if !errors.Is(err, ErrCharset) {
+					t.Fatalf("expected an error wrapping ErrCharset, but got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("expected %q, but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCharsetReaderLatin1AfterProbe(t *testing.T) {
+	t.Parallel()
+
+	// Only the beginning of the input decides on the charset, so a late invalid byte is passed on.
+	input := strings.Repeat("a", 5000) + "\xe9"
+
+	got, err := io.ReadAll(NewCharsetReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != input {
+		t.Errorf("expected the input to be passed on unchanged")
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package token
+
+// Checkpoint is a position in the tokens of a Lexer, which the lexer can return to, see Lexer.Checkpoint.
+type Checkpoint struct {
+	index int
+}
+
+// lexedToken is a token that was read from the input, together with the state of the lexer before it.
+type lexedToken struct {
+	tok  Token
+	err  error
+	mode GrammarMode
+	pos  Pos
+}
+
+// lexToken reads the next token from the input and remembers the state before it.
+func (l *Lexer) lexToken() lexedToken {
+	t := lexedToken{mode: l.mode, pos: l.pos}
+	t.tok, t.err = l.lex()
+
+	return t
+}
+
+// PeekToken returns the next token without consuming it, so that the next call of PeekToken or Token
+// returns the same token again. The returned token must not be modified.
+func (l *Lexer) PeekToken() (Token, error) {
+	if l.replayPos == len(l.replay) {
+		l.replay = append(l.replay, l.lexToken())
+	}
+
+	t := l.replay[l.replayPos]
+
+	return t.tok, t.err
+}
+
+// Mode returns the grammar that the lexer is in before it reads the next token. The lexer switches
+// between the grammars by itself, e.g. to G2 after "#!", parsers can use the mode to interpret tokens.
+// If the next token was already read, see PeekToken, it is the mode before that token.
+func (l *Lexer) Mode() GrammarMode {
+	if l.replayPos < len(l.replay) {
+		return l.replay[l.replayPos].mode
+	}
+
+	return l.mode
+}
+
+// Checkpoint returns the current position in the tokens, so that parsers can backtrack with Restore,
+// e.g. to try another interpretation of the following tokens. The tokens read after the oldest checkpoint
+// are kept in memory, until every checkpoint has been passed to either Restore or Release.
+func (l *Lexer) Checkpoint() Checkpoint {
+	l.checkpoints++
+
+	return Checkpoint{index: l.index}
+}
+
+// Restore returns to the position of cp, so that Token returns the tokens after cp again, including
+// their errors. Mode, Pos and Warnings are not reset, as the input is not read again.
+// cp is released by Restore. It panics if the tokens after cp are not available anymore, because cp
+// was released before.
+func (l *Lexer) Restore(cp Checkpoint) {
+	back := l.index - cp.index
+	if l.checkpoints == 0 || back < 0 || back > l.replayPos {
+		panic("token: restore of a checkpoint that was already released")
+	}
+
+	l.replayPos -= back
+	l.index = cp.index
+
+	l.Release(cp)
+}
+
+// Release tells the lexer that cp is not needed anymore, so that the tokens before the position of
+// the remaining checkpoints are freed.
+func (l *Lexer) Release(cp Checkpoint) {
+	if l.checkpoints == 0 || cp.index > l.index {
+		panic("token: release of a checkpoint that was already released")
+	}
+
+	l.checkpoints--
+	l.trimReplay()
+}
+
+// trimReplay frees the tokens that were returned already, once no checkpoint needs them anymore.
+func (l *Lexer) trimReplay() {
+	if l.checkpoints > 0 || l.replayPos == 0 {
+		return
+	}
+
+	n := copy(l.replay, l.replay[l.replayPos:])
+	for i := n; i < len(l.replay); i++ {
+		l.replay[i] = lexedToken{}
+	}
+
+	l.replay = l.replay[:n]
+	l.replayPos = 0
+}
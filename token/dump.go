@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package token
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dump reads all tokens from r and writes one line for each of them to w, e.g. to find out how
+// a document is lexed when debugging grammar issues. A line contains the range, the type and the value
+// of a token, if it has one:
+//
+//	1:1-1:2     DefineElement
+//	1:2-1:7     Identifier       "hello"
+//
+// Values are quoted like Go strings. CharData values that are numbers or booleans are followed by their
+// kind and forwarding definitions by "forward". Lexer errors stop the dump and are returned.
+func Dump(r io.Reader, w io.Writer, opts ...LexerOption) error {
+	lexer := NewLexer("", r, opts...)
+	out := bufio.NewWriter(w)
+
+	for {
+		tok, err := lexer.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			_ = out.Flush()
+
+			return err
+		}
+
+		if _, err := out.WriteString(dumpLine(tok)); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// dumpLine returns the line that Dump writes for tok, including the newline.
+func dumpLine(tok Token) string {
+	pos := tok.Pos()
+	rng := fmt.Sprintf("%d:%d-%d:%d", pos.BeginPos.Line, pos.BeginPos.Col, pos.EndPos.Line, pos.EndPos.Col)
+	line := fmt.Sprintf("%-11s %-16s", rng, strings.TrimPrefix(string(tok.Type()), "Token"))
+
+	switch tok := tok.(type) {
+	case *Identifier:
+		line += fmt.Sprintf(" %q", tok.Value)
+	case *CharData:
+		line += fmt.Sprintf(" %q", tok.Value)
+		if tok.Kind != KindString {
+			line += " " + tok.Kind.String()
+		}
+	case *DefineElement:
+		if tok.Forward {
+			line += " forward"
+		}
+
+		if tok.Skip > 0 {
+			line += fmt.Sprintf(" skip=%d", tok.Skip)
+		}
+	case *DefineAttribute:
+		if tok.Forward {
+			line += " forward"
+		}
+
+		if tok.Skip > 0 {
+			line += fmt.Sprintf(" skip=%d", tok.Skip)
+		}
+	}
+
+	return strings.TrimRight(line, " ") + "\n"
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package token_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/token"
+)
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		text    string
+		opts    []LexerOption
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "g1 forward",
+			text: "##x @a{b}",
+			want: `1:1-1:3     DefineElement    forward
+1:3-1:4     Identifier       "x"
+1:5-1:6     DefineAttribute
+1:6-1:7     Identifier       "a"
+1:7-1:8     BlockStart
+1:8-1:9     CharData         "b"
+1:9-1:10    BlockEnd
+`,
+		},
+		{
+			name: "g1 multi-level forward",
+			text: "###x @@@@a{b}",
+			want: `1:1-1:4     DefineElement    forward skip=1
+1:4-1:5     Identifier       "x"
+1:6-1:10    DefineAttribute  forward skip=2
+1:10-1:11   Identifier       "a"
+1:11-1:12   BlockStart
+1:12-1:13   CharData         "b"
+1:13-1:14   BlockEnd
+`,
+		},
+		{
+			name: "g2 values",
+			text: "#! a @@k=5 \"s\"",
+			want: `1:1-1:3     G2Preamble
+1:4-1:5     Identifier       "a"
+1:6-1:8     DefineAttribute  forward
+1:8-1:9     Identifier       "k"
+1:9-1:10    Assign
+1:10-1:11   CharData         "5" number
+1:12-1:15   CharData         "s"
+`,
+		},
+		{
+			name:    "error after tokens",
+			text:    "#name{too long}",
+			opts:    []LexerOption{WithMaxTokenLength(4)},
+			want:    "1:1-1:2     DefineElement\n1:2-1:6     Identifier       \"name\"\n1:6-1:7     BlockStart\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Dump(strings.NewReader(test.text), &buf, test.opts...)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			if buf.String() != test.want {
+				t.Errorf("expected\n%s\nbut got\n%s", test.want, buf.String())
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package token
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,6 +13,17 @@ import (
 	"strings"
 )
 
+// Categories of errors, which can be checked with errors.Is, so that callers can react to kinds of errors
+// without matching their messages. Errors caused by the environment, like failing readers, have no category.
+var (
+	// ErrSyntax is the category of errors about input that is not valid dyml.
+	ErrSyntax = errors.New("syntax error")
+	// ErrForwarding is the category of errors about forwarded nodes and attributes that cannot be applied.
+	ErrForwarding = errors.New("forwarding error")
+	// ErrType is the category of errors about values that do not fit the Go type they are unmarshalled into.
+	ErrType = errors.New("type error")
+)
+
 type ErrDetail struct {
 	Node    Node
 	Message string
@@ -29,6 +41,9 @@ type PosError struct {
 	Details []ErrDetail
 	Cause   error
 	Hint    string
+	// Category is one of ErrSyntax, ErrForwarding or ErrType, or nil if the error does not belong to
+	// any of them. errors.Is reports whether the error is in a category, see Is.
+	Category error
 }
 
 // NewPosError creates a new PosError with the given root cause and optional details.
@@ -44,6 +59,11 @@ func NewPosError(node Node, msg string, details ...ErrDetail) *PosError {
 	}
 }
 
+// NewSyntaxError creates a new PosError like NewPosError in the category ErrSyntax.
+func NewSyntaxError(node Node, msg string, details ...ErrDetail) *PosError {
+	return NewPosError(node, msg, details...).SetCategory(ErrSyntax)
+}
+
 func (p *PosError) SetCause(err error) *PosError {
 	p.Cause = err
 
@@ -56,6 +76,17 @@ func (p *PosError) SetHint(str string) *PosError {
 	return p
 }
 
+func (p *PosError) SetCategory(category error) *PosError {
+	p.Category = category
+
+	return p
+}
+
+// Is returns true if target is the Category of this error. The Cause is checked by errors.Is as well.
+func (p *PosError) Is(target error) bool {
+	return p.Category != nil && target == p.Category
+}
+
 func (p *PosError) Unwrap() error {
 	return p.Cause
 }
@@ -76,6 +107,34 @@ func (p *PosError) Error() string {
 	return p.firstDetail().Message + ": " + p.Cause.Error()
 }
 
+// LimitError is used as the cause of a PosError, when the input exceeds a configured limit.
+type LimitError struct {
+	// Limit describes the limit that was exceeded, e.g. "nesting depth".
+	Limit string
+	// Max is the configured maximum.
+	Max int
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds the limit of %d", e.Limit, e.Max)
+}
+
+// NewLimitError creates a PosError at node, which is caused by exceeding the given limit.
+func NewLimitError(node Node, limit string, max int) *PosError {
+	return NewPosError(node, "limit exceeded").SetCause(LimitError{Limit: limit, Max: max})
+}
+
+// Warning describes a suspicious part of the input, that does not stop parsing, so that tools can show it
+// as a soft diagnostic.
+type Warning struct {
+	Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Begin().String() + ": " + w.Message
+}
+
 // src tries to load the source code based on the given file name. If it fails, the empty string is returned.
 func src(fname string) string {
 	buf, err := ioutil.ReadFile(fname)
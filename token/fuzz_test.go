@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.18
+// +build go1.18
+
+package token_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml/token"
+)
+
+// fuzzSeeds is a small corpus that covers most of the grammar.
+//
+//nolint:gochecknoglobals // Shared by all fuzz targets and never modified.
+var fuzzSeeds = []string{
+	"",
+	"hello world",
+	`#title Chapter \#1 @id{c1} {#b{bold} text}`,
+	"##forward @@key{value} #item",
+	"#? comment\n#! g2 { item @key=\"value\" (a, b) -> (c) <d> }",
+	"#! g2 {\n// comment\n## doc\n@@x=\"y\" func x(a int) -> (int, error)\n}",
+	"#! raw `multi\nline` \"\\u00e4\\n\"",
+	"#x{\\u12}",
+	"#grüße{ünïcödé}",
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		lexer := NewLexer("fuzz", strings.NewReader(text))
+
+		// Every token consumes at least one byte, so there cannot be more tokens than bytes.
+		for i := 0; i <= len(text); i++ {
+			tok, err := lexer.Token()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			if tok == nil {
+				t.Fatal("lexer returned neither a token nor an error")
+			}
+		}
+
+		t.Fatal("lexer did not terminate")
+	})
+}
@@ -4,26 +4,40 @@
 package token
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"unicode/utf8"
 )
 
 // gText parses a text sequence until next rune is in stopAt or EOF.
+// Backslashes escape the stopAt characters and backslashes themselves.
+// The escape sequences \n, \t and \uXXXX can be used for newlines, tabs and arbitrary unicode characters.
 func (l *Lexer) gText(stopAt string) (*CharData, error) {
 	startPos := l.Pos()
 
-	var tmp bytes.Buffer
+	tmp := l.scratch()
 
 	// Keep track of whether the last read char is a '\' to properly escape backslashes
 	// and the stopAt characters.
 	isEscaping := false
+	// escapeStart is the position of the '\' that started the current escape sequence.
+	var escapeStart Pos
 
 	for {
+		if err := l.checkTokenLength(tmp, startPos); err != nil {
+			return nil, err
+		}
+
+		beforeRune := l.Pos()
+
 		r, err := l.nextR()
 		if errors.Is(err, io.EOF) {
+			if isEscaping {
+				return nil, NewSyntaxError(NewNode(escapeStart, l.Pos()), "incomplete escape sequence")
+			}
+
 			if tmp.Len() == 0 {
 				return nil, io.EOF
 			}
@@ -36,15 +50,33 @@ func (l *Lexer) gText(stopAt string) (*CharData, error) {
 		}
 
 		if isEscaping {
-			// The last character was a backslash, only backslashes and stopAt characters may follow.
-			if strings.ContainsRune(stopAt, r) || r == '\\' {
+			isEscaping = false
+
+			// The last character was a backslash, only backslashes, stopAt characters
+			// and the known escape sequences may follow.
+			switch {
+			case strings.ContainsRune(stopAt, r) || r == '\\':
 				// The character was correctly escaped and should be emitted as-is.
 				tmp.WriteRune(r)
-
-				isEscaping = false
-			} else {
+			case r == 'n':
+				tmp.WriteRune('\n')
+			case r == 't':
+				tmp.WriteRune('\t')
+			case r == 'u':
+				unicodeRune, err := l.gUnicodeEscape(escapeStart)
+				if err != nil {
+					return nil, err
+				}
+
+				tmp.WriteRune(unicodeRune)
+			case r == ' ' || r == '\t':
+				// This is most likely a typo, so the backslash is kept instead of failing.
+				l.warn(NewNode(escapeStart, l.Pos()), "whitespace after '\\' is not an escape sequence")
+				tmp.WriteRune('\\')
+				tmp.WriteRune(r)
+			default:
 				// Escaping happened, but nothing valid to escape was found!
-				return nil, NewPosError(l.node(), fmt.Sprintf("'%c' may not be escaped here", r))
+				return nil, NewSyntaxError(NewNode(escapeStart, l.Pos()), fmt.Sprintf("'%c' may not be escaped here", r))
 			}
 		} else {
 			// We are not currently expecting an escaped char, proceed normally.
@@ -56,6 +88,7 @@ func (l *Lexer) gText(stopAt string) (*CharData, error) {
 			} else if r == '\\' {
 				// Enter escape mode and not emit this backslash.
 				isEscaping = true
+				escapeStart = beforeRune
 			} else {
 				// Any other normal character
 				tmp.WriteRune(r)
@@ -64,18 +97,55 @@ func (l *Lexer) gText(stopAt string) (*CharData, error) {
 	}
 
 	text := &CharData{}
-	text.Value = tmp.String()
+	text.Value = l.charData(tmp.Bytes())
 	text.Position.BeginPos = startPos
 	text.Position.EndPos = l.pos
 
 	return text, nil
 }
 
+// gUnicodeEscape reads the four hex digits following a '\u' and returns the rune they encode.
+// escapeStart is the position of the '\' and only used for errors.
+func (l *Lexer) gUnicodeEscape(escapeStart Pos) (rune, error) {
+	var codePoint rune
+
+	for i := 0; i < 4; i++ {
+		r, err := l.nextR()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		var digit rune
+
+		switch {
+		case err != nil:
+			return 0, NewSyntaxError(NewNode(escapeStart, l.Pos()), "'\\u' requires four hex digits")
+		case r >= '0' && r <= '9':
+			digit = r - '0'
+		case r >= 'a' && r <= 'f':
+			digit = r - 'a' + 10
+		case r >= 'A' && r <= 'F':
+			digit = r - 'A' + 10
+		default:
+			return 0, NewSyntaxError(NewNode(escapeStart, l.Pos()), fmt.Sprintf("'%c' is not a hex digit in '\\u' escape", r))
+		}
+
+		codePoint = codePoint<<4 | digit
+	}
+
+	if !utf8.ValidRune(codePoint) {
+		return 0, NewSyntaxError(NewNode(escapeStart, l.Pos()),
+			fmt.Sprintf("'\\u%04X' is not a valid unicode character", codePoint))
+	}
+
+	return codePoint, nil
+}
+
 func (l *Lexer) g1LineEnd() (*G1LineEnd, error) {
 	startPos := l.Pos()
 
 	if r, _ := l.nextR(); r != '\n' {
-		return nil, NewPosError(l.node(), "expected newline")
+		return nil, NewSyntaxError(l.node(), "expected newline")
 	}
 
 	lineEnd := &G1LineEnd{}
@@ -91,11 +161,11 @@ func (l *Lexer) g1CommentStart() (*G1Comment, error) {
 
 	// Eat '#?' from input
 	if r, _ := l.nextR(); r != '#' {
-		return nil, NewPosError(l.node(), "expected '#?' for comment")
+		return nil, NewSyntaxError(l.node(), "expected '#?' for comment")
 	}
 
 	if r, _ := l.nextR(); r != '?' {
-		return nil, NewPosError(l.node(), "expected '#?' for comment")
+		return nil, NewSyntaxError(l.node(), "expected '#?' for comment")
 	}
 
 	comment := &G1Comment{}
@@ -5,7 +5,11 @@ package token
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 // g2Preamble reads the '#!' preamble of G2 grammars.
@@ -14,11 +18,11 @@ func (l *Lexer) g2Preamble() (*G2Preamble, error) {
 
 	// Eat '#!' from input
 	if r, _ := l.nextR(); r != '#' {
-		return nil, NewPosError(l.node(), "expected '#' in g2 mode")
+		return nil, NewSyntaxError(l.node(), "expected '#' in g2 mode")
 	}
 
 	if r, _ := l.nextR(); r != '!' {
-		return nil, NewPosError(l.node(), "expected '!' in g2 mode")
+		return nil, NewSyntaxError(l.node(), "expected '!' in g2 mode")
 	}
 
 	preamble := &G2Preamble{}
@@ -34,11 +38,11 @@ func (l *Lexer) g2Arrow() (*G2Arrow, error) {
 
 	// Eat '->' from input
 	if r, _ := l.nextR(); r != '-' {
-		return nil, NewPosError(l.node(), "expected '-'")
+		return nil, NewSyntaxError(l.node(), "expected '-'")
 	}
 
 	if r, _ := l.nextR(); r != '>' {
-		return nil, NewPosError(l.node(), "expected '>'")
+		return nil, NewSyntaxError(l.node(), "expected '>'")
 	}
 
 	arrow := &G2Arrow{}
@@ -48,17 +52,27 @@ func (l *Lexer) g2Arrow() (*G2Arrow, error) {
 	return arrow, nil
 }
 
-// g2CharData reads a "quoted string".
+// g2CharData reads a "quoted string" or a `raw string`.
 func (l *Lexer) g2CharData() (*CharData, error) {
 	startPos := l.Pos()
 
 	// Eat starting '"'
 	r, _ := l.nextR()
+	if r == '`' {
+		l.prevR()
+
+		return l.g2RawCharData()
+	}
+
 	if r != '"' {
-		return nil, NewPosError(l.node(), "expected '\"'")
+		return nil, NewSyntaxError(l.node(), "expected '\"'")
 	}
 
 	text, err := l.gText("\"")
+	if errors.Is(err, io.EOF) {
+		return nil, NewSyntaxError(NewNode(startPos, l.Pos()), "string is not terminated")
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +80,7 @@ func (l *Lexer) g2CharData() (*CharData, error) {
 	// Eat closing '"'
 	r, _ = l.nextR()
 	if r != '"' {
-		return nil, NewPosError(l.node(), "expected '\"'")
+		return nil, NewSyntaxError(l.node(), "expected '\"'")
 	}
 
 	chardata := &CharData{}
@@ -77,6 +91,191 @@ func (l *Lexer) g2CharData() (*CharData, error) {
 	return chardata, nil
 }
 
+// g2Literal reads an unquoted attribute value, which must be a number or a boolean.
+func (l *Lexer) g2Literal() (*CharData, error) {
+	literal, err := l.g2LiteralText()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case literal.Value == "true" || literal.Value == "false":
+		literal.Kind = KindBool
+	case isNumber(literal.Value):
+		literal.Kind = KindNumber
+	default:
+		return nil, NewSyntaxError(literal, "attribute values must be quoted strings, numbers or booleans").
+			SetHint(fmt.Sprintf("use \"%s\" for a string", literal.Value))
+	}
+
+	return literal, nil
+}
+
+// g2Number reads a number literal, which is used as text if the lexer was created WithLiterals.
+func (l *Lexer) g2Number() (*CharData, error) {
+	literal, err := l.g2LiteralText()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNumber(literal.Value) {
+		return nil, NewSyntaxError(literal, fmt.Sprintf("'%s' is not a valid number", literal.Value)).
+			SetHint(fmt.Sprintf("use \"%s\" for a string", literal.Value))
+	}
+
+	literal.Kind = KindNumber
+
+	return literal, nil
+}
+
+// g2NegativeIdent reads a '-' followed by an identifier that starts with a digit, so that negative
+// numbers like -5 or -1.5 can be used like all other numbers, e.g. as map keys or slice elements.
+// Identifiers that are not numbers, like -1abc, are not allowed.
+func (l *Lexer) g2NegativeIdent() (*Identifier, error) {
+	startPos := l.Pos()
+
+	r, err := l.nextR()
+	if err != nil {
+		return nil, err
+	}
+
+	if r != '-' {
+		return nil, NewSyntaxError(l.node(), "expected '-'")
+	}
+
+	ident, err := l.gIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	ident.Value = l.intern([]byte("-" + ident.Value))
+	ident.Position.BeginPos = startPos
+
+	if !isNumber(ident.Value) {
+		return nil, NewSyntaxError(ident, fmt.Sprintf("'%s' is not a valid number", ident.Value)).
+			SetHint(fmt.Sprintf("use \"%s\" for a string", ident.Value))
+	}
+
+	return ident, nil
+}
+
+// g2BoolLiteral returns a boolean literal, if the identifier is true or false.
+func g2BoolLiteral(ident *Identifier) (*CharData, bool) {
+	if ident.Value != "true" && ident.Value != "false" {
+		return nil, false
+	}
+
+	return &CharData{Position: ident.Position, Value: ident.Value, Kind: KindBool}, true
+}
+
+// afterIdentChar returns true if the rune in front of the next one belongs to an identifier.
+// A sign only starts a number after a separator, so that x-1 is not read as x followed by -1.
+func (l *Lexer) afterIdentChar() bool {
+	return l.bufPos > 0 && l.gIdentChar(l.buf[l.bufPos-1].r)
+}
+
+// isDigit returns true for the ASCII digits, which start a number literal.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// g2LiteralText reads the characters of an unquoted literal, without checking its value.
+func (l *Lexer) g2LiteralText() (*CharData, error) {
+	startPos := l.Pos()
+
+	tmp := l.scratch()
+
+	for {
+		r, err := l.nextR()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.+-", r) {
+			l.prevR()
+
+			break
+		}
+
+		tmp.WriteRune(r)
+
+		if err := l.checkTokenLength(tmp, startPos); err != nil {
+			return nil, err
+		}
+	}
+
+	literal := &CharData{}
+	literal.Position.BeginPos = startPos
+	literal.Position.EndPos = l.pos
+	literal.Value = l.intern(tmp.Bytes())
+
+	return literal, nil
+}
+
+// isNumber returns true if s is a decimal integer or floating point number, like -1, 8080, 1.5 or 1e-6.
+func isNumber(s string) bool {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+
+	// Requiring a leading digit excludes words like "inf", this excludes hexadecimal numbers and underscores.
+	if strings.ContainsAny(digits, "xXpP_") {
+		return false
+	}
+
+	// Numbers that are too large for a float64 are still numbers, unmarshalling will report them.
+	_, err := strconv.ParseFloat(s, 64)
+
+	return err == nil || errors.Is(err, strconv.ErrRange)
+}
+
+// g2RawCharData reads a `raw string`, which may span multiple lines.
+// All characters up to the closing '`' are taken as-is, there are no escape sequences.
+func (l *Lexer) g2RawCharData() (*CharData, error) {
+	startPos := l.Pos()
+
+	// Eat starting '`'
+	r, _ := l.nextR()
+	if r != '`' {
+		return nil, NewSyntaxError(l.node(), "expected '`'")
+	}
+
+	tmp := l.scratch()
+
+	for {
+		if err := l.checkTokenLength(tmp, startPos); err != nil {
+			return nil, err
+		}
+
+		r, err := l.nextR()
+		if errors.Is(err, io.EOF) {
+			return nil, NewSyntaxError(NewNode(startPos, l.Pos()), "raw string is not terminated")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if r == '`' {
+			break
+		}
+
+		tmp.WriteRune(r)
+	}
+
+	chardata := &CharData{}
+	chardata.Position.BeginPos = startPos
+	chardata.Position.EndPos = l.pos
+	chardata.Value = l.charData(tmp.Bytes())
+
+	return chardata, nil
+}
+
 // g2Assign reads the '=' in an attribute definition.
 func (l *Lexer) g2Assign() (*Assign, error) {
 	startPos := l.Pos()
@@ -87,7 +286,7 @@ func (l *Lexer) g2Assign() (*Assign, error) {
 	}
 
 	if r != '=' {
-		return nil, NewPosError(l.node(), "expected '=' (attribute definition)")
+		return nil, NewSyntaxError(l.node(), "expected '=' (attribute definition)")
 	}
 
 	assign := &Assign{}
@@ -107,7 +306,7 @@ func (l *Lexer) g2Comma() (*Comma, error) {
 	}
 
 	if r != ',' {
-		return nil, NewPosError(l.node(), "expected ','")
+		return nil, NewSyntaxError(l.node(), "expected ','")
 	}
 
 	comma := &Comma{}
@@ -127,7 +326,7 @@ func (l *Lexer) g2Semicolon() (*Semicolon, error) {
 	}
 
 	if r != ';' {
-		return nil, NewPosError(l.node(), "expected ','")
+		return nil, NewSyntaxError(l.node(), "expected ','")
 	}
 
 	semicolon := &Semicolon{}
@@ -137,6 +336,26 @@ func (l *Lexer) g2Semicolon() (*Semicolon, error) {
 	return semicolon, nil
 }
 
+// g2Colon reads ':' which introduces a type annotation.
+func (l *Lexer) g2Colon() (*Colon, error) {
+	startPos := l.Pos()
+
+	r, err := l.nextR()
+	if err != nil {
+		return nil, err
+	}
+
+	if r != ':' {
+		return nil, NewSyntaxError(l.node(), "expected ':'")
+	}
+
+	colon := &Colon{}
+	colon.Position.BeginPos = startPos
+	colon.Position.EndPos = l.pos
+
+	return colon, nil
+}
+
 // g2GroupStart reads the '(' that marks the start of a group.
 func (l *Lexer) g2GroupStart() (*GroupStart, error) {
 	startPos := l.Pos()
@@ -147,7 +366,7 @@ func (l *Lexer) g2GroupStart() (*GroupStart, error) {
 	}
 
 	if r != '(' {
-		return nil, NewPosError(l.node(), "expected '('")
+		return nil, NewSyntaxError(l.node(), "expected '('")
 	}
 
 	groupStart := &GroupStart{}
@@ -167,7 +386,7 @@ func (l *Lexer) g2GroupEnd() (*GroupEnd, error) {
 	}
 
 	if r != ')' {
-		return nil, NewPosError(l.node(), "expected ')'")
+		return nil, NewSyntaxError(l.node(), "expected ')'")
 	}
 
 	groupEnd := &GroupEnd{}
@@ -187,7 +406,7 @@ func (l *Lexer) g2GenericStart() (*GenericStart, error) {
 	}
 
 	if r != '<' {
-		return nil, NewPosError(l.node(), "expected '<'")
+		return nil, NewSyntaxError(l.node(), "expected '<'")
 	}
 
 	genericStart := &GenericStart{}
@@ -207,7 +426,7 @@ func (l *Lexer) g2GenericEnd() (*GenericEnd, error) {
 	}
 
 	if r != '>' {
-		return nil, NewPosError(l.node(), "expected '>'")
+		return nil, NewSyntaxError(l.node(), "expected '>'")
 	}
 
 	genericEnd := &GenericEnd{}
@@ -225,7 +444,7 @@ func (l *Lexer) g2CommentStart() (*G2Comment, error) {
 	for i := 0; i < 2; i++ {
 		r, _ := l.nextR()
 		if r != '/' {
-			return nil, NewPosError(l.node(), "expected '//' for line comment")
+			return nil, NewSyntaxError(l.node(), "expected '//' for line comment")
 		}
 	}
 
@@ -4,10 +4,10 @@
 package token
 
 import (
-	"bytes"
 	"errors"
 	"io"
 	"strings"
+	"unicode"
 )
 
 // gBlockStart reads the '{' that marks the start of a block.
@@ -20,7 +20,7 @@ func (l *Lexer) gBlockStart() (*BlockStart, error) {
 	}
 
 	if r != '{' {
-		return nil, NewPosError(l.node(), "expected '{'")
+		return nil, NewSyntaxError(l.node(), "expected '{'")
 	}
 
 	blockStart := &BlockStart{}
@@ -40,7 +40,7 @@ func (l *Lexer) gBlockEnd() (*BlockEnd, error) {
 	}
 
 	if r != '}' {
-		return nil, NewPosError(l.node(), "expected '}'")
+		return nil, NewSyntaxError(l.node(), "expected '}'")
 	}
 
 	blockEnd := &BlockEnd{}
@@ -74,7 +74,21 @@ func (l *Lexer) gSkipWhitespace(dontSkip ...rune) error {
 	}
 }
 
-// gIdent parses an identifier, which is a dot separated sequence of [a-zA-Z0-9_].
+// gSkipTextWhitespace skips whitespace like gSkipWhitespace, unless the lexer is in G1 and keeps the
+// whitespace as text, see WithPreserveWhitespace. In a G1 line the newline is kept, as it ends the line.
+func (l *Lexer) gSkipTextWhitespace(dontSkip ...rune) error {
+	switch {
+	case l.preserveWhitespace && l.mode == G1:
+		return nil
+	case l.mode == G1Line:
+		return l.gSkipWhitespace(append(dontSkip, '\n')...)
+	}
+
+	return l.gSkipWhitespace(dontSkip...)
+}
+
+// gIdent parses an identifier, which is a sequence of identifier characters separated by '.' or '::',
+// so that qualified names like "http.server" or "std::vector" are a single identifier.
 func (l *Lexer) gIdent() (*Identifier, error) {
 	startPos := l.Pos()
 
@@ -82,9 +96,13 @@ func (l *Lexer) gIdent() (*Identifier, error) {
 	// This is true at the start and after a '.'.
 	requireChar := true
 
-	var tmp bytes.Buffer
+	tmp := l.scratch()
 
 	for {
+		if err := l.checkTokenLength(tmp, startPos); err != nil {
+			return nil, err
+		}
+
 		r, err := l.nextR()
 		if errors.Is(err, io.EOF) {
 			if tmp.Len() == 0 {
@@ -102,10 +120,15 @@ func (l *Lexer) gIdent() (*Identifier, error) {
 			requireChar = false
 			// Require a character
 			if !l.gIdentChar(r) {
-				return nil, NewPosError(l.node(), "expected identifier")
+				return nil, NewSyntaxError(l.node(), "expected identifier")
 			}
 		} else if r == '.' {
 			// After a dot we require another identifier.
+			requireChar = true
+		} else if r == ':' && l.gQualifierColon() {
+			// '::' separates qualifiers just like a dot, the second colon has already been read.
+			tmp.WriteRune(r)
+
 			requireChar = true
 		} else if l.gIdentChar(r) {
 			// Okay, will be added to the buffer later
@@ -120,19 +143,63 @@ func (l *Lexer) gIdent() (*Identifier, error) {
 	}
 
 	if tmp.Len() == 0 {
-		return nil, NewPosError(l.node(), "expected identifier")
+		return nil, NewSyntaxError(l.node(), "expected identifier")
 	}
 
 	ident := &Identifier{}
-	ident.Value = tmp.String()
+	ident.Value = l.intern(tmp.Bytes())
 	ident.Position.BeginPos = startPos
 	ident.Position.EndPos = l.pos
 
 	return ident, nil
 }
 
-// gIdentChar is any character of an identifier: [a-zA-Z0-9_].
+// gQualifierColon is called after a ':' in an identifier and reads a second ':', if it is followed by
+// an identifier character. Otherwise nothing is read and false is returned, so that a single ':' still
+// starts a type annotation.
+func (l *Lexer) gQualifierColon() bool {
+	r, err := l.nextR()
+	if err != nil {
+		return false
+	}
+
+	if r != ':' {
+		l.prevR()
+
+		return false
+	}
+
+	next, err := l.nextR()
+	if err != nil {
+		l.prevR()
+
+		return false
+	}
+
+	l.prevR()
+
+	if !l.gIdentChar(next) {
+		l.prevR()
+
+		return false
+	}
+
+	return true
+}
+
+// gIdentChar is any character of an identifier, as decided by the lexer's policy.
 func (l *Lexer) gIdentChar(r rune) bool {
+	return l.identChar(r)
+}
+
+// UnicodeIdentChar allows unicode letters, digits and marks as well as '_' in identifiers.
+// This is the default policy of the lexer.
+func UnicodeIdentChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc)
+}
+
+// ASCIIIdentChar only allows [a-zA-Z0-9_] in identifiers.
+func ASCIIIdentChar(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || (r == '_')
 }
 
@@ -146,7 +213,7 @@ func (l *Lexer) gDefineAttribute() (*DefineAttribute, error) {
 	}
 
 	if r != '@' {
-		return nil, NewPosError(l.node(), "expected '@' (attribute definition)")
+		return nil, NewSyntaxError(l.node(), "expected '@' (attribute definition)")
 	}
 
 	attr := &DefineAttribute{}
@@ -155,7 +222,19 @@ func (l *Lexer) gDefineAttribute() (*DefineAttribute, error) {
 	r, err = l.nextR()
 	if r == '@' {
 		attr.Forward = true
-	} else if err == nil {
+
+		// Each additional '@' passes over one more element.
+		for {
+			r, err = l.nextR()
+			if r != '@' {
+				break
+			}
+
+			attr.Skip++
+		}
+	}
+
+	if r != '@' && err == nil {
 		l.prevR()
 	}
 
@@ -175,7 +254,7 @@ func (l *Lexer) gDefineElement() (*DefineElement, error) {
 	}
 
 	if r != '#' {
-		return nil, NewPosError(l.node(), "expected '#' (element definition)")
+		return nil, NewSyntaxError(l.node(), "expected '#' (element definition)")
 	}
 
 	define := &DefineElement{}
@@ -184,7 +263,19 @@ func (l *Lexer) gDefineElement() (*DefineElement, error) {
 	r, err = l.nextR()
 	if r == '#' {
 		define.Forward = true
-	} else if err == nil {
+
+		// Each additional '#' passes over one more element.
+		for {
+			r, err = l.nextR()
+			if r != '#' {
+				break
+			}
+
+			define.Skip++
+		}
+	}
+
+	if r != '#' && err == nil {
 		l.prevR()
 	}
 
@@ -1,8 +1,8 @@
 // SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
 // SPDX-License-Identifier: Apache-2.0
 
-// Generate token identifiers for all structs in parser2/token.go and
-// place them in parser2/token.gen.go.
+// Generate token identifiers for all structs in token/token.go and
+// place them in token/token.gen.go.
 //nolint
 package main
 
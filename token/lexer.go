@@ -5,6 +5,7 @@ package token
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -58,10 +59,13 @@ type Token interface {
 type Type string
 
 type runeWithPos struct {
-	r    rune
-	line int32
-	col  int32
-	off  int32
+	r        rune
+	line     int32
+	col      int32
+	utf16Col int32
+	off      int32
+	// size is the number of bytes of the rune in the input, which is 2 for a '\n' read from "\r\n".
+	size int8
 }
 
 // Lexer can be used to get individual tokens.
@@ -69,6 +73,8 @@ type Lexer struct {
 	r      *bufio.Reader
 	buf    []runeWithPos
 	bufPos int
+	// tmp is reused by the token functions to collect runes, see scratch.
+	tmp bytes.Buffer
 	// pos is the current lexer position.
 	// It is the position of the rune that would be read next by nextR.
 	pos  Pos
@@ -78,30 +84,190 @@ type Lexer struct {
 	// brackets have occurred. For an open bracket we add one, for a closed bracket we
 	// remove one. When the counter then reaches 0 we switch back to G1.
 	g2BracketCounter uint
+	// g1LineBrackets contains for each G2 section that was started within a G1 line the g2BracketCounter
+	// of the G2 section around that line. The lexer returns to the G1 line once the nested section is closed.
+	g1LineBrackets []uint
+	// identChar decides which characters may be used in identifiers.
+	identChar func(r rune) bool
+	// strings contains all interned strings, see intern.
+	strings map[string]string
+	// internCharData is the maximum length of CharData values that get interned.
+	internCharData int
+	// maxTokenLength is the maximum length of identifiers and CharData in bytes, or 0 if unlimited.
+	maxTokenLength int
+	// literals is true if numbers and booleans in G2 are CharData instead of identifiers.
+	literals bool
+	// tabWidth is the distance between tab stops for columns, or 0 if a tab is a single column.
+	tabWidth int
+	// byteColumns is true if columns count bytes instead of runes.
+	byteColumns bool
+	// preserveWhitespace is true if whitespace after brackets in G1 is kept as CharData.
+	preserveWhitespace bool
+	// warnings are collected while reading tokens, see Warnings.
+	warnings []Warning
+	// replay contains tokens that were read from the input, but are returned again, see PeekToken and
+	// Checkpoint. replayPos is the index of the next token to return, the ones before it are only kept
+	// for checkpoints.
+	replay    []lexedToken
+	replayPos int
+	// index is the number of tokens before the one that Token returns next.
+	index int
+	// checkpoints is the number of checkpoints that were neither restored nor released.
+	checkpoints int
+}
+
+// LexerOption can be passed to NewLexer to configure the lexer.
+type LexerOption func(l *Lexer)
+
+// WithIdentChar sets the policy for characters in identifiers, which defaults to UnicodeIdentChar.
+// Identifiers are always split at '.', so the policy should not accept it. Accepting other characters
+// that have a meaning in dyml, like '#', '@' or brackets, will lead to surprising results.
+func WithIdentChar(isIdentChar func(r rune) bool) LexerOption {
+	return func(l *Lexer) {
+		l.identChar = isIdentChar
+	}
+}
+
+// WithCharDataInterning lets the lexer intern CharData values with up to maxLen bytes, like
+// identifiers always are. Equal values then share their memory, which reduces the memory usage
+// for documents that repeat short texts or attribute values often.
+// Longer values are unlikely to be repeated and are always copied.
+func WithCharDataInterning(maxLen int) LexerOption {
+	return func(l *Lexer) {
+		l.internCharData = maxLen
+	}
+}
+
+// WithMaxTokenLength limits the length of identifiers and CharData values to maxLen bytes.
+// Longer tokens result in a PosError caused by a LimitError, before they are read completely.
+func WithMaxTokenLength(maxLen int) LexerOption {
+	return func(l *Lexer) {
+		l.maxTokenLength = maxLen
+	}
+}
+
+// WithLiterals lets the lexer read unquoted numbers and booleans in G2, like 8080, -1.5 or true,
+// as CharData with KindNumber or KindBool instead of identifiers, so that "#! port 8080" is an
+// element with a text. Tokens that start with a digit must then be valid numbers.
+// Without it, numbers and booleans are identifiers, which is needed to use them as map keys like
+// "#! m { 1 first }".
+func WithLiterals() LexerOption {
+	return func(l *Lexer) {
+		l.literals = true
+	}
+}
+
+// WithTabWidth lets a tab advance the column to the next tab stop, with width columns between
+// the stops, so that columns match the ones displayed by editors. Without it, a tab is a single column.
+// Pos.UTF16Col is not affected, as a tab is always a single code unit.
+func WithTabWidth(width int) LexerOption {
+	return func(l *Lexer) {
+		l.tabWidth = width
+	}
+}
+
+// WithByteColumns lets columns count the bytes of the UTF-8 encoded input instead of runes,
+// which is what some tools expect. Pos.UTF16Col is not affected.
+func WithByteColumns() LexerOption {
+	return func(l *Lexer) {
+		l.byteColumns = true
+	}
+}
+
+// WithPreserveWhitespace lets the lexer keep the whitespace after brackets in G1 and after the end of
+// G2, which is skipped otherwise. It becomes part of the following text or a text of its own, e.g. the
+// newline between "#a{x}" and "#b" on the next line.
+// Whitespace after names and attributes separates them from the following token and is still skipped.
+func WithPreserveWhitespace() LexerOption {
+	return func(l *Lexer) {
+		l.preserveWhitespace = true
+	}
 }
 
 // NewLexer creates a new instance, ready to start parsing.
-func NewLexer(filename string, r io.Reader) *Lexer {
+func NewLexer(filename string, r io.Reader, opts ...LexerOption) *Lexer {
 	l := &Lexer{}
 	l.r = bufio.NewReader(r)
+	l.strings = map[string]string{}
+	l.init(filename, opts)
+
+	return l
+}
+
+// Reset lets the lexer read from r like a new Lexer created by NewLexer with the same arguments, but it keeps
+// its buffers, so that lexing many small inputs needs fewer allocations. Interned strings are forgotten.
+func (l *Lexer) Reset(filename string, r io.Reader, opts ...LexerOption) {
+	reader, buf, tmp, interned := l.r, l.buf[:0], l.tmp, l.strings
+
+	reader.Reset(r)
+	tmp.Reset()
+
+	for s := range interned {
+		delete(interned, s)
+	}
+
+	// Tokens of the previous input are not kept alive by the unused part of the replay buffer.
+	replay := l.replay[:cap(l.replay)]
+	for i := range replay {
+		replay[i] = lexedToken{}
+	}
+
+	*l = Lexer{r: reader, buf: buf, tmp: tmp, replay: replay[:0], strings: interned}
+	l.init(filename, opts)
+}
+
+// init sets the initial position and state and applies opts.
+func (l *Lexer) init(filename string, opts []LexerOption) {
 	l.pos.File = filename
 	l.pos.Line = 1
 	l.pos.Col = 1
+	l.pos.UTF16Col = 1
 	l.want = WantNothing
+	l.identChar = UnicodeIdentChar
 
-	return l
+	for _, opt := range opts {
+		opt(l)
+	}
 }
 
 // Token returns the next dyml token in the input stream.
 // At the end of the input stream, Token returns nil, io.EOF.
-// The lexer start of in G1 mode. Should a user of a Lexer detect a token that
-// indicates a mode change, it is THEIR responsibility to change the lexer's
-// mode accordingly.
+// The lexer starts in G1 mode and switches between the grammars by itself, see Mode.
+// Tokens that were peeked or that are read again after Restore are returned from a buffer.
 func (l *Lexer) Token() (Token, error) {
+	if l.replayPos < len(l.replay) {
+		t := l.replay[l.replayPos]
+		l.replayPos++
+		l.index++
+		l.trimReplay()
+
+		return t.tok, t.err
+	}
+
+	l.index++
+
+	// Without checkpoints, tokens are not needed again and do not have to be buffered.
+	if l.checkpoints == 0 {
+		return l.lex()
+	}
+
+	t := l.lexToken()
+	l.replay = append(l.replay, t)
+	l.replayPos++
+
+	return t.tok, t.err
+}
+
+// lex reads the next token from the input.
+func (l *Lexer) lex() (Token, error) {
 	// Peek the first two runes.
 	// The second one is only used to detect the g2 grammar.
 	r1, err := l.nextR()
 	if err != nil {
+		if errors.Is(err, io.EOF) && (l.want == WantG1AttributeCharData || l.want == WantG1AttributeEnd) {
+			return nil, NewSyntaxError(l.node(), "attribute value is not terminated with '}'")
+		}
+
 		return nil, err
 	}
 
@@ -129,7 +295,16 @@ func (l *Lexer) Token() (Token, error) {
 			_ = l.gSkipWhitespace()
 		}
 
-		l.want = WantG1AttributeStart
+		// An attribute without a value is a flag, so nothing more belongs to it.
+		l.want = WantNothing
+
+		if r, nextErr := l.nextR(); nextErr == nil {
+			l.prevR()
+
+			if r == '{' {
+				l.want = WantG1AttributeStart
+			}
+		}
 
 		return tok, err
 	case WantG1AttributeStart:
@@ -197,15 +372,25 @@ func (l *Lexer) Token() (Token, error) {
 			l.want = WantG1AttributeIdent
 		} else if r1 == '{' {
 			tok, err = l.gBlockStart()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else if r1 == '}' {
 			tok, err = l.gBlockEnd()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else {
 			tok, err = l.gText("#}")
 		}
 	case G1Line:
-		if r1 == '\n' {
+		if l.want == WantCommentLine && r1 != '\n' {
+			// A comment in a G1 line ends with the line.
+			tok, err = l.gText("\n")
+			l.want = WantNothing
+		} else if r1 == '#' && r2 == '!' {
+			tok, err = l.g2Preamble()
+			l.g1LineBrackets = append(l.g1LineBrackets, l.g2BracketCounter)
+			l.g2BracketCounter = 0
+			l.mode = G2
+			_ = l.gSkipWhitespace()
+		} else if r1 == '\n' {
 			// Newline marks the end of this G1Line. Switch back to G2.
 			tok, err = l.g1LineEnd()
 			l.want = WantNothing
@@ -215,6 +400,10 @@ func (l *Lexer) Token() (Token, error) {
 			tok, err = l.gIdent()
 			l.want = WantNothing
 			_ = l.gSkipWhitespace('\n')
+		} else if r1 == '#' && r2 == '?' {
+			tok, err = l.g1CommentStart()
+			l.want = WantCommentLine
+			_ = l.gSkipWhitespace('\n')
 		} else if r1 == '#' {
 			tok, err = l.gDefineElement()
 			l.want = WantIdentifier
@@ -236,7 +425,12 @@ func (l *Lexer) Token() (Token, error) {
 			l.want = WantNothing
 			_ = l.gSkipWhitespace()
 		} else if l.want == WantG2AttributeValue {
-			tok, err = l.g2CharData()
+			if r1 == '"' || r1 == '`' {
+				tok, err = l.g2CharData()
+			} else {
+				tok, err = l.g2Literal()
+			}
+
 			l.want = WantNothing
 			_ = l.gSkipWhitespace()
 		} else if r1 == '{' {
@@ -247,7 +441,7 @@ func (l *Lexer) Token() (Token, error) {
 			tok, err = l.gBlockEnd()
 			l.g2BracketCounter--
 			l.checkSwitchToG1()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else if r1 == '(' {
 			tok, err = l.g2GroupStart()
 			l.g2BracketCounter++
@@ -256,7 +450,7 @@ func (l *Lexer) Token() (Token, error) {
 			tok, err = l.g2GroupEnd()
 			l.g2BracketCounter--
 			l.checkSwitchToG1()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else if r1 == '<' {
 			tok, err = l.g2GenericStart()
 			l.g2BracketCounter++
@@ -265,11 +459,11 @@ func (l *Lexer) Token() (Token, error) {
 			tok, err = l.g2GenericEnd()
 			l.g2BracketCounter--
 			l.checkSwitchToG1()
-			_ = l.gSkipWhitespace()
-		} else if r1 == '"' {
+			_ = l.gSkipTextWhitespace()
+		} else if r1 == '"' || r1 == '`' {
 			tok, err = l.g2CharData()
 			l.checkSwitchToG1()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else if r1 == '@' {
 			tok, err = l.gDefineAttribute()
 		} else if r1 == '#' {
@@ -284,10 +478,13 @@ func (l *Lexer) Token() (Token, error) {
 		} else if r1 == ',' {
 			tok, err = l.g2Comma()
 			l.checkSwitchToG1()
-			_ = l.gSkipWhitespace()
+			_ = l.gSkipTextWhitespace()
 		} else if r1 == ';' {
 			tok, err = l.g2Semicolon()
 			l.checkSwitchToG1()
+			_ = l.gSkipTextWhitespace()
+		} else if r1 == ':' {
+			tok, err = l.g2Colon()
 			_ = l.gSkipWhitespace()
 		} else if r1 == '/' {
 			tok, err = l.g2CommentStart()
@@ -296,11 +493,26 @@ func (l *Lexer) Token() (Token, error) {
 		} else if r1 == '-' && r2 == '>' {
 			tok, err = l.g2Arrow()
 			_ = l.gSkipWhitespace()
+		} else if l.literals && (isDigit(r1) || ((r1 == '+' || r1 == '-') && isDigit(r2) && !l.afterIdentChar())) {
+			tok, err = l.g2Number()
+			l.checkSwitchToG1()
+			_ = l.gSkipTextWhitespace()
+		} else if r1 == '-' && isDigit(r2) && !l.afterIdentChar() {
+			tok, err = l.g2NegativeIdent()
+			_ = l.gSkipWhitespace()
 		} else if l.gIdentChar(r1) {
 			tok, err = l.gIdent()
-			_ = l.gSkipWhitespace()
+			if err == nil && l.literals {
+				if literal, ok := g2BoolLiteral(tok.(*Identifier)); ok { //nolint:forcetypeassert
+					tok = literal
+
+					l.checkSwitchToG1()
+				}
+			}
+
+			_ = l.gSkipTextWhitespace()
 		} else {
-			return nil, NewPosError(l.node(), fmt.Sprintf("unexpected char '%c'", r1))
+			return nil, NewSyntaxError(l.node(), fmt.Sprintf("unexpected char '%c'", r1))
 		}
 	default:
 		return nil, fmt.Errorf("lexer is in unknown mode (%d), this is a bug", l.mode)
@@ -323,10 +535,21 @@ func (l *Lexer) Token() (Token, error) {
 }
 
 // checkSwitchToG1 will check the bracketCounter and, if it is 0, set the lexer's mode to G1.
+// A G2 section that was started within a G1 line returns to that line instead.
 func (l *Lexer) checkSwitchToG1() {
-	if l.g2BracketCounter == 0 {
-		l.mode = G1
+	if l.g2BracketCounter != 0 {
+		return
+	}
+
+	if last := len(l.g1LineBrackets) - 1; last >= 0 {
+		l.g2BracketCounter = l.g1LineBrackets[last]
+		l.g1LineBrackets = l.g1LineBrackets[:last]
+		l.mode = G1Line
+
+		return
 	}
+
+	l.mode = G1
 }
 
 // nextR reads the next rune and updates the position.
@@ -334,61 +557,163 @@ func (l *Lexer) nextR() (rune, error) {
 	if l.bufPos < len(l.buf) {
 		r := l.buf[l.bufPos]
 		l.bufPos++
-		l.pos.Line = int(r.line)
-		// col needs to be incremented so that the lexer points to the next rune.
-		l.pos.Col = int(r.col) + 1
-		l.pos.Offset = int(r.off) + 1
-
-		if r.r == '\n' {
-			l.pos.Line++
-			l.pos.Col = 1
-		}
+		l.setPos(r)
+		// The position needs to be advanced so that the lexer points to the next rune.
+		l.advance(r.r, int(r.size))
 
 		return r.r, nil
 	}
 
-	r, size, err := l.r.ReadRune()
+	r, size, err := l.readRune()
 	if r == unicode.ReplacementChar {
-		return r, NewPosError(l.node(), "invalid unicode sequence")
+		return r, NewSyntaxError(l.node(), "invalid unicode sequence")
 	}
 
 	if err != nil {
-		return r, NewPosError(l.node(), "unable to read next rune").SetCause(err)
+		posErr := NewPosError(l.node(), "unable to read next rune").SetCause(err)
+
+		// The end of the input is only reported where more input is required, so it is a syntax error.
+		if errors.Is(err, io.EOF) {
+			posErr.SetCategory(ErrSyntax)
+		}
+
+		return r, posErr
 	}
 
 	l.buf = append(l.buf, runeWithPos{
-		r:    r,
-		line: int32(l.pos.Line),
-		col:  int32(l.pos.Col),
-		off:  int32(l.pos.Offset),
+		r:        r,
+		line:     int32(l.pos.Line),
+		col:      int32(l.pos.Col),
+		utf16Col: int32(l.pos.UTF16Col),
+		off:      int32(l.pos.Offset),
+		size:     int8(size),
 	})
 	l.bufPos++
 
 	// Should the buffer get longer than maxBufferSize we will remove the first element from it.
+	// The elements are moved instead of reslicing, so that the buffer does not need to grow again.
 	if len(l.buf) > maxBufferSize {
-		l.buf = l.buf[1:]
+		copy(l.buf, l.buf[1:])
+		l.buf = l.buf[:len(l.buf)-1]
 		l.bufPos = len(l.buf)
 	}
 
+	l.advance(r, size)
+
+	return r, err
+}
+
+// byteOrderMark is skipped at the beginning of the input.
+const byteOrderMark = '\uFEFF'
+
+// readRune reads the next rune from the input. A byte order mark at the beginning of the input is
+// skipped and "\r\n" is read as a single '\n' with a size of 2, so that documents written by Windows
+// editors result in the same tokens and lines as others.
+func (l *Lexer) readRune() (rune, int, error) {
+	r, size, err := l.r.ReadRune()
+	if r == byteOrderMark && l.pos.Offset == 0 && err == nil {
+		l.pos.Offset += size
+		r, size, err = l.r.ReadRune()
+	}
+
+	if r == '\r' && err == nil {
+		next, nextSize, nextErr := l.r.ReadRune()
+		if nextErr == nil && next == '\n' {
+			return next, size + nextSize, nil
+		}
+
+		if nextErr == nil {
+			_ = l.r.UnreadRune()
+		}
+	}
+
+	return r, size, err
+}
+
+// advance moves the position behind the rune r, which is size bytes long.
+func (l *Lexer) advance(r rune, size int) {
 	l.pos.Offset += size
-	l.pos.Col++
 
 	if r == '\n' {
 		l.pos.Line++
 		l.pos.Col = 1
+		l.pos.UTF16Col = 1
+
+		return
 	}
 
-	return r, err
+	switch {
+	case r == '\t' && l.tabWidth > 0:
+		l.pos.Col += l.tabWidth - (l.pos.Col-1)%l.tabWidth
+	case l.byteColumns:
+		l.pos.Col += size
+	default:
+		l.pos.Col++
+	}
+
+	// Runes outside the basic multilingual plane are encoded as a surrogate pair.
+	if r > 0xFFFF {
+		l.pos.UTF16Col += 2
+	} else {
+		l.pos.UTF16Col++
+	}
+}
+
+// setPos sets the position to the one of a buffered rune.
+func (l *Lexer) setPos(r runeWithPos) {
+	l.pos.Line = int(r.line)
+	l.pos.Col = int(r.col)
+	l.pos.UTF16Col = int(r.utf16Col)
+	l.pos.Offset = int(r.off)
+}
+
+// checkTokenLength returns an error if the runes collected in tmp for the token that started
+// at startPos exceed the maximum token length.
+func (l *Lexer) checkTokenLength(tmp *bytes.Buffer, startPos Pos) error {
+	if l.maxTokenLength > 0 && tmp.Len() > l.maxTokenLength {
+		return NewLimitError(NewNode(startPos, l.pos), "token length", l.maxTokenLength)
+	}
+
+	return nil
+}
+
+// scratch returns an empty buffer for collecting the runes of a token. The buffer is shared,
+// so its content must be copied before the next call to scratch.
+func (l *Lexer) scratch() *bytes.Buffer {
+	l.tmp.Reset()
+
+	return &l.tmp
+}
+
+// intern returns the content of b as a string. Equal contents result in the same string,
+// so that repeated names and values only need to be allocated once per Lexer.
+func (l *Lexer) intern(b []byte) string {
+	// The compiler does not allocate the string for a map lookup.
+	if s, ok := l.strings[string(b)]; ok {
+		return s
+	}
+
+	s := string(b)
+	l.strings[s] = s
+
+	return s
+}
+
+// charData returns the content of b as a string for a CharData value,
+// which is interned depending on WithCharDataInterning.
+func (l *Lexer) charData(b []byte) string {
+	if len(b) <= l.internCharData {
+		return l.intern(b)
+	}
+
+	return string(b)
 }
 
 // prevR unreads the current rune. panics if out of balance with nextR or if it was called
 // more than maxBufferSize times in succession.
 func (l *Lexer) prevR() {
 	l.bufPos--
-	r := l.buf[l.bufPos]
-	l.pos.Line = int(r.line)
-	l.pos.Col = int(r.col)
-	l.pos.Offset = int(r.off)
+	l.setPos(l.buf[l.bufPos])
 }
 
 // node returns a fake node for positional errors.
@@ -397,6 +722,24 @@ func (l *Lexer) node() Node {
 }
 
 // Pos returns the current position of the token parser.
+// If the next token was already read, see PeekToken, it is the position before that token.
 func (l *Lexer) Pos() Pos {
+	if l.replayPos < len(l.replay) {
+		return l.replay[l.replayPos].pos
+	}
+
 	return l.pos
 }
+
+// Warnings returns the suspicious parts of the input that have been read so far, in the order they were found.
+func (l *Lexer) Warnings() []Warning {
+	return l.warnings
+}
+
+// warn records a Warning at node.
+func (l *Lexer) warn(node Node, msg string) {
+	l.warnings = append(l.warnings, Warning{
+		Position: Position{BeginPos: node.Begin(), EndPos: node.End()},
+		Message:  msg,
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"unsafe"
 
 	. "github.com/golangee/dyml/token"
 )
@@ -52,8 +53,58 @@ func TestLexer(t *testing.T) {
 		},
 
 		{
-			name:    "Whitespace after backslash",
-			text:    `#book @id{my-book\ } @author{Torben\}`,
+			name: "escape sequences",
+			text: `tab\tnew\nline \u00FC\u4e16`,
+			want: NewTestSet().
+				CharData("tab\tnew\nline \u00FC\u4e16"),
+		},
+
+		{
+			name:    "invalid escape sequence",
+			text:    `hello \x`,
+			wantErr: true,
+		},
+
+		{
+			name:    "unicode escape with too few digits",
+			text:    `#!{"\u12"}`,
+			wantErr: true,
+		},
+
+		{
+			name:    "unicode escape with invalid digits",
+			text:    `\u12G4`,
+			wantErr: true,
+		},
+
+		{
+			name:    "unicode escape of surrogate",
+			text:    `\uD800`,
+			wantErr: true,
+		},
+
+		{
+			name:    "backslash at end of input",
+			text:    `hello\`,
+			wantErr: true,
+		},
+
+		{
+			name: "Whitespace after backslash",
+			text: `#book @id{my-book\ }`,
+			want: NewTestSet().
+				DefineElement(false).
+				Identifier("book").
+				DefineAttribute(false).
+				Identifier("id").
+				BlockStart().
+				CharData(`my-book\ `).
+				BlockEnd(),
+		},
+
+		{
+			name:    "Escaped block end at end of input",
+			text:    `#book @author{Torben\}`,
 			wantErr: true,
 		},
 
@@ -240,6 +291,16 @@ func TestLexer(t *testing.T) {
 				BlockEnd(),
 		},
 
+		{
+			name: "g2 with escape sequences",
+			text: `#! a @key="\t\u0041" "line\nbreak"`,
+			want: NewTestSet().
+				G2Preamble().
+				Identifier("a").
+				DefineAttribute(false).Identifier("key").Assign().CharData("\tA").
+				CharData("line\nbreak"),
+		},
+
 		{
 			name: "g2 with attributes",
 			text: `#!{x @key="value" @@num="5" y}`,
@@ -316,6 +377,46 @@ func TestLexer(t *testing.T) {
 				BlockEnd(),
 		},
 
+		{
+			name: "g2 in g1 line",
+			text: `#!{
+						# #! a {b} c
+						d
+					}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				DefineElement(false).
+				G2Preamble().
+				Identifier("a").
+				BlockStart().
+				Identifier("b").
+				BlockEnd().
+				CharData("c").
+				G1LineEnd().
+				Identifier("d").
+				BlockEnd(),
+		},
+
+		{
+			name: "comment in g1 line",
+			text: `#!{
+						# #a #? a #b comment
+						c
+					}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				DefineElement(false).
+				DefineElement(false).
+				Identifier("a").
+				G1Comment().
+				CharData("a #b comment").
+				G1LineEnd().
+				Identifier("c").
+				BlockEnd(),
+		},
+
 		{
 			name: "g2 mixed with multiple g1 lines",
 			text: `#!{
@@ -371,6 +472,51 @@ func TestLexer(t *testing.T) {
 				BlockEnd(),
 		},
 
+		{
+			name: "g2 with typed attribute values",
+			text: `#!{x @port=8080 @ratio=-1.5e3 @@on=true @name="8080"}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("x").
+				DefineAttribute(false).Identifier("port").Assign().Value("8080", KindNumber).
+				DefineAttribute(false).Identifier("ratio").Assign().Value("-1.5e3", KindNumber).
+				DefineAttribute(true).Identifier("on").Assign().Value("true", KindBool).
+				DefineAttribute(false).Identifier("name").Assign().Value("8080", KindString).
+				BlockEnd(),
+		},
+
+		{
+			name:    "g2 with unquoted word as attribute value",
+			text:    `#!{x @key=value}`,
+			wantErr: true,
+		},
+
+		{
+			name:    "g2 with hex number as attribute value",
+			text:    `#!{x @key=0x10}`,
+			wantErr: true,
+		},
+
+		{
+			name: "g2 with type annotations",
+			text: `#!{name: string, list:list<int>}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("name").
+				Colon().
+				Identifier("string").
+				Comma().
+				Identifier("list").
+				Colon().
+				Identifier("list").
+				GenericStart().
+				Identifier("int").
+				GenericEnd().
+				BlockEnd(),
+		},
+
 		{
 			name: "g2 with simple groups",
 			text: `#!{ ( ) < >()<> }`,
@@ -494,6 +640,72 @@ func TestLexer(t *testing.T) {
 				BlockEnd(),
 		},
 
+		{
+			name: "unicode identifiers",
+			text: "#grüße #!世界{ café.ñ, مرحبا, नमस्ते; }",
+			want: NewTestSet().
+				DefineElement(false).
+				Identifier("grüße").
+				G2Preamble().
+				Identifier("世界").
+				BlockStart().
+				Identifier("café.ñ").Comma().
+				Identifier("مرحبا").Comma().
+				Identifier("नमस्ते").Semicolon().
+				BlockEnd(),
+		},
+
+		{
+			name: "g2 negative numbers",
+			text: `#!{a -5, b -1.5 {-2 -3}, f() -> (x)}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("a").Identifier("-5").Comma().
+				Identifier("b").Identifier("-1.5").BlockStart().Identifier("-2").Identifier("-3").BlockEnd().Comma().
+				Identifier("f").GroupStart().GroupEnd().G2Arrow().GroupStart().Identifier("x").GroupEnd().
+				BlockEnd(),
+		},
+
+		{
+			name:    "g2 negative number with letters",
+			text:    `#!{g {-1abc}}`,
+			wantErr: true,
+		},
+
+		{
+			name:    "g2 negative number with two dots",
+			text:    `#!{g {-1.2.3}}`,
+			wantErr: true,
+		},
+
+		{
+			name:    "g2 negative number with qualifier",
+			text:    `#!{g {-1::x}}`,
+			wantErr: true,
+		},
+
+		{
+			name:    "g2 negative number without separator",
+			text:    `#!{g {x-1}}`,
+			wantErr: true,
+		},
+
+		{
+			name: "qualified identifiers",
+			text: `#!{http::server, std::vector<a.b::c>, name::x: ns::type, single:type} #a::b`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("http::server").Comma().
+				Identifier("std::vector").GenericStart().Identifier("a.b::c").GenericEnd().Comma().
+				Identifier("name::x").Colon().Identifier("ns::type").Comma().
+				Identifier("single").Colon().Identifier("type").
+				BlockEnd().
+				DefineElement(false).
+				Identifier("a::b"),
+		},
+
 		{
 			name:    "bad identifier double dots",
 			text:    "#abc..def",
@@ -512,6 +724,39 @@ func TestLexer(t *testing.T) {
 				DefineAttribute(false).Identifier("color").Assign().CharData("green").
 				Semicolon(),
 		},
+
+		{
+			name: "g2 raw strings",
+			text: "#! code @lang=`go` `func main() {\n\tprint(\"#hello\\n\")\n}` text,",
+			want: NewTestSet().
+				G2Preamble().
+				Identifier("code").
+				DefineAttribute(false).Identifier("lang").Assign().CharData("go").
+				CharData("func main() {\n\tprint(\"#hello\\n\")\n}").
+				CharData("text,"),
+		},
+
+		{
+			name: "g2 empty raw string",
+			text: "#!{a ``}",
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("a").
+				CharData("").
+				BlockEnd(),
+		},
+
+		{
+			name:    "unterminated raw string",
+			text:    "#!{a `text}",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated string at end of input",
+			text:    "#!\"",
+			wantErr: true,
+		},
 	}
 
 	t.Parallel()
@@ -554,6 +799,350 @@ func TestLexer(t *testing.T) {
 	}
 }
 
+func TestLexerEscapeErrorPosition(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseTokens("#!{\n\t\"ok \\u12x4\"}")
+
+	var posErr *PosError
+	if !errors.As(err, &posErr) {
+		t.Fatalf("expected a PosError, but got %v", err)
+	}
+
+	// The error should span the escape sequence, beginning at the backslash.
+	want := newTestPositions(2, 6, 2, 11)[0]
+	got := Position{BeginPos: posErr.Details[0].Node.Begin(), EndPos: posErr.Details[0].Node.End()}
+	if !comparePos(want, got) {
+		t.Errorf("expected error at %v, but got %v", want, got)
+	}
+}
+
+func TestLexerASCIIIdentChar(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("ascii", bytes.NewBufferString("#grüße"), WithIdentChar(ASCIIIdentChar))
+
+	if _, err := lexer.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	ident, err := lexer.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ident.(*Identifier).Value != "gr" {
+		t.Errorf("expected identifier to stop at the first non-ascii character, but got '%s'", ident.(*Identifier).Value)
+	}
+}
+
+func TestLexerColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts []LexerOption
+		// wantCol and wantUTF16Col are the columns of the identifier at the end of text.
+		wantCol, wantUTF16Col int
+	}{
+		{name: "runes", text: "ä🙂\t#x", wantCol: 5, wantUTF16Col: 6},
+		{name: "tab width", text: "\t#x", opts: []LexerOption{WithTabWidth(4)}, wantCol: 6, wantUTF16Col: 3},
+		{name: "tab to next stop", text: "ab\t#x", opts: []LexerOption{WithTabWidth(4)}, wantCol: 6, wantUTF16Col: 5},
+		{name: "tab at stop", text: "abcd\t#x", opts: []LexerOption{WithTabWidth(4)}, wantCol: 10, wantUTF16Col: 7},
+		{name: "bytes", text: "ä🙂#x", opts: []LexerOption{WithByteColumns()}, wantCol: 8, wantUTF16Col: 5},
+		{name: "next line", text: "🙂\n\t#x", opts: []LexerOption{WithTabWidth(8)}, wantCol: 10, wantUTF16Col: 3},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens, err := parseTokens(test.text, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := tokens[len(tokens)-1].Pos().BeginPos
+			if got.Col != test.wantCol || got.UTF16Col != test.wantUTF16Col {
+				t.Errorf("expected col %d and UTF-16 col %d, but got %d and %d",
+					test.wantCol, test.wantUTF16Col, got.Col, got.UTF16Col)
+			}
+		})
+	}
+}
+
+func TestLexerWindowsInput(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		// want is the same document with "\n" line endings and without a byte order mark.
+		want string
+		// offset is the number of bytes that the last token of text is behind the one of want.
+		offset int
+	}{
+		{
+			name:   "g1 crlf",
+			text:   "#a{x}\r\n#b text\r\nmore",
+			want:   "#a{x}\n#b text\nmore",
+			offset: 1,
+		},
+		{
+			name:   "g2 crlf",
+			text:   "#! g {\r\n  # line\r\n  item @k=\"v\",\r\n  `raw\r\ntext`\r\n}",
+			want:   "#! g {\n  # line\n  item @k=\"v\",\n  `raw\ntext`\n}",
+			offset: 5,
+		},
+		{
+			name:   "byte order mark",
+			text:   "\uFEFF#item{x}",
+			want:   "#item{x}",
+			offset: 3,
+		},
+		{
+			name: "single carriage return",
+			text: "#a{x\ry}",
+			want: "#a{x\ry}",
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTokens(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := parseTokens(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("expected %d tokens, but got %d", len(want), len(got))
+			}
+
+			for i := range got {
+				if got[i].Type() != want[i].Type() || !comparePos(*got[i].Pos(), *want[i].Pos()) {
+					t.Errorf("token %d: expected %s at %v, but got %s at %v",
+						i, want[i].Type(), *want[i].Pos(), got[i].Type(), *got[i].Pos())
+				}
+
+				if cd, ok := got[i].(*CharData); ok && cd.Value != want[i].(*CharData).Value {
+					t.Errorf("token %d: expected %q, but got %q", i, want[i].(*CharData).Value, cd.Value)
+				}
+			}
+
+			last := len(got) - 1
+			if diff := got[last].Pos().BeginPos.Offset - want[last].Pos().BeginPos.Offset; diff != test.offset {
+				t.Errorf("expected the last token to be %d bytes behind, but got %d", test.offset, diff)
+			}
+		})
+	}
+}
+
+func TestLexerLiterals(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *TestSet
+		wantErr bool
+	}{
+		{
+			name: "numbers and booleans",
+			text: `#!{port 8080, ratio -1.5e3, on true, off false, name "8080", x1 +2}`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("port").Value("8080", KindNumber).Comma().
+				Identifier("ratio").Value("-1.5e3", KindNumber).Comma().
+				Identifier("on").Value("true", KindBool).Comma().
+				Identifier("off").Value("false", KindBool).Comma().
+				Identifier("name").Value("8080", KindString).Comma().
+				Identifier("x1").Value("+2", KindNumber).
+				BlockEnd(),
+		},
+		{
+			name: "identifiers are unchanged",
+			text: `#!{truth f(x) -> (y)} #1`,
+			want: NewTestSet().
+				G2Preamble().
+				BlockStart().
+				Identifier("truth").
+				Identifier("f").GroupStart().Identifier("x").GroupEnd().
+				G2Arrow().GroupStart().Identifier("y").GroupEnd().
+				BlockEnd().
+				DefineElement(false).Identifier("1"),
+		},
+		{
+			name:    "invalid number",
+			text:    `#!{version 1.2.3}`,
+			wantErr: true,
+		},
+		{
+			name:    "sign without separator",
+			text:    `#!{x-1}`,
+			wantErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, test := range tests {
+		tt := test
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens, err := parseTokens(tt.text, WithLiterals())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.want.Assert(t, tokens)
+		})
+	}
+}
+
+func TestLexerInterning(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("interning", bytes.NewBufferString("#a @k{v} #a @k{v} #b @k{long} #c @k{long}"),
+		WithCharDataInterning(1))
+
+	values := map[string][]string{}
+
+	for {
+		tok, err := lexer.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch tok := tok.(type) {
+		case *Identifier:
+			values[tok.Value] = append(values[tok.Value], tok.Value)
+		case *CharData:
+			values[tok.Value] = append(values[tok.Value], tok.Value)
+		}
+	}
+
+	for _, value := range []string{"a", "k", "v"} {
+		if found := values[value]; len(found) < 2 || stringData(found[0]) != stringData(found[1]) {
+			t.Errorf("expected '%s' to be interned", value)
+		}
+	}
+
+	if found := values["long"]; len(found) != 2 || stringData(found[0]) == stringData(found[1]) {
+		t.Error("expected 'long' not to be interned")
+	}
+}
+
+// stringData returns a pointer to the bytes of s, which is the first word of a string.
+// unsafe.StringData would need Go 1.20.
+func stringData(s string) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&s))
+}
+
+func TestLexerCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("checkpoint", bytes.NewBufferString("#a #! b { c }"))
+
+	// rest reads all remaining tokens and returns their types together with the mode before them.
+	rest := func() []string {
+		var types []string
+
+		for {
+			mode := lexer.Mode()
+
+			tok, err := lexer.Token()
+			if errors.Is(err, io.EOF) {
+				return types
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			types = append(types, fmt.Sprintf("%d %s", mode, tok.Type()))
+		}
+	}
+
+	peeked, err := lexer.PeekToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pos := lexer.Pos(); pos.Offset != 0 {
+		t.Errorf("expected the position before the peeked token, but got %v", pos)
+	}
+
+	if tok, _ := lexer.Token(); tok != peeked {
+		t.Errorf("expected the peeked token %s, but got %s", toString(peeked), toString(tok))
+	}
+
+	outer := lexer.Checkpoint()
+
+	if _, err := lexer.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := lexer.Checkpoint()
+	want := []string{
+		fmt.Sprintf("%d %s", G1, TokenG2Preamble),
+		fmt.Sprintf("%d %s", G2, TokenIdentifier),
+		fmt.Sprintf("%d %s", G2, TokenBlockStart),
+		fmt.Sprintf("%d %s", G2, TokenIdentifier),
+		fmt.Sprintf("%d %s", G2, TokenBlockEnd),
+	}
+
+	if got := rest(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the tokens and modes %v, but got %v", want, got)
+	}
+
+	lexer.Restore(inner)
+
+	if got := rest(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the same tokens after restoring, but got %v instead of %v", got, want)
+	}
+
+	lexer.Restore(outer)
+
+	if tok, _ := lexer.Token(); tok.Type() != TokenIdentifier {
+		t.Errorf("expected the identifier after the outer checkpoint, but got %s", toString(tok))
+	}
+
+	if got := rest(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the same tokens after restoring, but got %v instead of %v", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when restoring a released checkpoint")
+		}
+	}()
+
+	lexer.Restore(outer)
+}
+
 // test utils
 
 type TestSet struct {
@@ -784,6 +1373,35 @@ func (ts *TestSet) Comma() *TestSet {
 	return ts
 }
 
+// Value checks for CharData with the given value and kind.
+func (ts *TestSet) Value(value string, kind ValueKind) *TestSet {
+	ts.checker = append(ts.checker, func(t Token) error {
+		if cd, ok := t.(*CharData); ok {
+			if cd.Value != value || cd.Kind != kind {
+				return fmt.Errorf("Value: expected %s '%s' but got %s '%s'", kind, value, cd.Kind, cd.Value)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("Value: unexpected type '%v'", reflect.TypeOf(t))
+	})
+
+	return ts
+}
+
+func (ts *TestSet) Colon() *TestSet {
+	ts.checker = append(ts.checker, func(t Token) error {
+		if _, ok := t.(*Colon); ok {
+			return nil
+		}
+
+		return fmt.Errorf("Colon: unexpected type '%v': %s", reflect.TypeOf(t), toString(t))
+	})
+
+	return ts
+}
+
 func (ts *TestSet) Semicolon() *TestSet {
 	ts.checker = append(ts.checker, func(t Token) error {
 		if _, ok := t.(*Semicolon); ok {
@@ -851,12 +1469,12 @@ func comparePos(a, b Position) bool {
 		a.End().Col == b.End().Col && a.End().Line == b.End().Line
 }
 
-func newTestLexer(text string) *Lexer {
-	return NewLexer("lexer_test.go", bytes.NewBuffer([]byte(text)))
+func newTestLexer(text string, opts ...LexerOption) *Lexer {
+	return NewLexer("lexer_test.go", bytes.NewBuffer([]byte(text)), opts...)
 }
 
-func parseTokens(text string) ([]Token, error) {
-	dec := newTestLexer(text)
+func parseTokens(text string, opts ...LexerOption) ([]Token, error) {
+	dec := newTestLexer(text, opts...)
 
 	var res []Token
 
@@ -884,3 +1502,40 @@ func toString(i interface{}) string {
 
 	return string(buf)
 }
+
+func TestLexerReset(t *testing.T) {
+	t.Parallel()
+
+	// tokens returns all tokens of lexer with their positions.
+	tokens := func(lexer *Lexer) []string {
+		var all []string
+
+		for {
+			tok, err := lexer.Token()
+			if errors.Is(err, io.EOF) {
+				return all
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			all = append(all, fmt.Sprintf("%s %s %v", tok.Pos(), tok.Type(), tok))
+		}
+	}
+
+	lexer := NewLexer("first", bytes.NewBufferString("#! a { b 1 }"), WithLiterals())
+	if _, err := lexer.PeekToken(); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens(lexer)
+
+	input := "#a{text} #! b { c }"
+	lexer.Reset("second", bytes.NewBufferString(input))
+
+	want := tokens(NewLexer("second", bytes.NewBufferString(input)))
+	if got := tokens(lexer); !reflect.DeepEqual(want, got) {
+		t.Errorf("expected the tokens of a new lexer\n%v\nbut got\n%v", want, got)
+	}
+}
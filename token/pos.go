@@ -22,7 +22,11 @@ type Pos struct {
 	// Line denotes the one-based line number in the denoted File.
 	Line int
 	// Col denotes the one-based column number in the denoted Line.
+	// It counts runes by default, see WithTabWidth and WithByteColumns for other units.
 	Col int
+	// UTF16Col is the one-based column number in UTF-16 code units, as it is used by the
+	// Language Server Protocol. It is 0 if unknown, e.g. for positions that were not created by a Lexer.
+	UTF16Col int
 	// Offset in bytes
 	Offset int
 }
@@ -17,6 +17,7 @@ const (
 	TokenG1LineEnd       Type = "TokenG1LineEnd"
 	TokenComma           Type = "TokenComma"
 	TokenSemicolon       Type = "TokenSemicolon"
+	TokenColon           Type = "TokenColon"
 	TokenG1Comment       Type = "TokenG1Comment"
 	TokenG2Comment       Type = "TokenG2Comment"
 	TokenG2Arrow         Type = "TokenG2Arrow"
@@ -142,6 +143,14 @@ func (t *Semicolon) Pos() *Position {
 	return &t.Position
 }
 
+func (t *Colon) Type() Type {
+	return TokenColon
+}
+
+func (t *Colon) Pos() *Position {
+	return &t.Position
+}
+
 func (t *G1Comment) Type() Type {
 	return TokenG1Comment
 }
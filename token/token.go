@@ -11,6 +11,75 @@ import "strings"
 type CharData struct {
 	Position
 	Value string
+	// Kind describes how the value was written. Only unquoted attribute values in G2, and
+	// unquoted texts in G2 if the lexer was created WithLiterals, can be a number or a boolean.
+	Kind ValueKind
+	// CommentKind is set by the parser.Visitor for the text of comments and tells how they were written.
+	CommentKind CommentKind
+	// Delimiter is the position of the '#?' or '//' that started a comment.
+	Delimiter Position
+}
+
+// ValueKind is a hint about the type of a CharData value.
+type ValueKind int
+
+const (
+	// KindString is used for text, quoted and raw strings.
+	KindString ValueKind = iota
+	// KindNumber is used for unquoted numbers, like 8080, -1.5 or 1e6.
+	KindNumber
+	// KindBool is used for unquoted true and false.
+	KindBool
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// CommentKind describes how a comment was written, so that it can be written again in the same way.
+type CommentKind int
+
+const (
+	// CommentNone is used for everything that is not a comment.
+	CommentNone CommentKind = iota
+	// CommentG1 is used for comments started with '#?' in G1.
+	CommentG1
+	// CommentG2 is used for line comments started with '//' in G2.
+	CommentG2
+	// CommentG1Line is used for comments started with '#?' in a G1 line, which end with the line.
+	CommentG1Line
+)
+
+func (k CommentKind) String() string {
+	switch k {
+	case CommentG1:
+		return "g1"
+	case CommentG2:
+		return "g2"
+	case CommentG1Line:
+		return "g1line"
+	default:
+		return "none"
+	}
+}
+
+// Delimiter returns the characters that start a comment of this kind, or "" for CommentNone.
+func (k CommentKind) Delimiter() string {
+	switch k {
+	case CommentG1, CommentG1Line:
+		return "#?"
+	case CommentG2:
+		return "//"
+	default:
+		return ""
+	}
 }
 
 func (t *CharData) String() string {
@@ -103,12 +172,18 @@ type G2Preamble struct {
 type DefineElement struct {
 	Position
 	Forward bool
+	// Skip is the number of following elements that a forwarded element passes over,
+	// one for each additional '#', e.g. 1 for '###'.
+	Skip int
 }
 
 // DefineAttribute is the '@' before the name of an attribute.
 type DefineAttribute struct {
 	Position
 	Forward bool
+	// Skip is the number of following elements that a forwarded attribute passes over,
+	// one for each additional '@', e.g. 1 for '@@@'.
+	Skip int
 }
 
 // Assign is the '=' in G2 attribute definitions.
@@ -131,6 +206,11 @@ type Semicolon struct {
 	Position
 }
 
+// Colon ':' separates an element from its type annotation in G2.
+type Colon struct {
+	Position
+}
+
 // G1Comment is a '#?' that indicates a comment in G1.
 type G1Comment struct {
 	Position
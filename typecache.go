@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structField is the precompiled information about a struct field, which is needed to marshal or unmarshal it.
+type structField struct {
+	index int
+	// goName is the name of the field in the struct.
+	goName string
+	// name is the name of the element or attribute in the document, which can be changed by the first tag.
+	name string
+	// renamed is true if the name is set by the first tag, so that naming strategies do not apply.
+	renamed bool
+	// tags are the comma separated values of the dyml tag, nil if there is no tag.
+	tags []string
+	// as is set by the second tag.
+	as unmarshalType
+	// invalidAs is the second tag, should it not be a known kind of field.
+	invalidAs string
+	// embedded is true for anonymous fields without a rename tag and for squashed fields,
+	// whose fields belong to the surrounding struct.
+	embedded bool
+	// squash is true for fields with a 'squash' or 'flatten' tag, which must be structs.
+	squash   bool
+	exported bool
+	// filtered is true for renamed slices and arrays, which consist of all elements with the new name.
+	filtered bool
+	// oneOf contains the allowed values of the field, if it has a 'oneof=...' tag.
+	oneOf []string
+	// base64 is true for []byte fields with a 'base64' tag, which are read from and written as base64 text.
+	base64 bool
+}
+
+// oneOfOption is the prefix of the tag option with the allowed values of a field, e.g. "oneof=debug info".
+const oneOfOption = "oneof="
+
+// isOption returns true if the tag is an option, which can be used instead of the kind of field
+// as the second tag.
+func isOption(tag string) bool {
+	return strings.HasPrefix(tag, oneOfOption) || tag == base64Option
+}
+
+// structType is the precompiled information about a struct type.
+type structType struct {
+	fields []structField
+	// elements are all fields of the struct or embedded structs that read elements.
+	elements []structField
+}
+
+// structTypeCache maps a reflect.Type of a struct to its *structType.
+//
+//nolint:gochecknoglobals // Shared by all unmarshalers, like the type cache of encoding/json.
+var structTypeCache sync.Map
+
+// cachedStructType returns the information about the struct type t, which is only computed once per type.
+func cachedStructType(t reflect.Type) *structType {
+	if info, ok := structTypeCache.Load(t); ok {
+		return info.(*structType)
+	}
+
+	info := &structType{fields: compileStructFields(t)}
+	info.elements = collectElements(t, nil, map[reflect.Type]bool{})
+
+	cached, _ := structTypeCache.LoadOrStore(t, info)
+
+	return cached.(*structType)
+}
+
+// collectElements appends all fields of the struct type t that read elements to elements.
+// visited contains the embedded structs that were already processed, as they might embed themselves.
+func collectElements(t reflect.Type, elements []structField, visited map[reflect.Type]bool) []structField {
+	visited[t] = true
+
+	for _, field := range compileStructFields(t) {
+		fieldType := indirectType(t.Field(field.index).Type)
+
+		switch {
+		case field.embedded && fieldType.Kind() == reflect.Struct:
+			if !visited[fieldType] {
+				elements = collectElements(fieldType, elements, visited)
+			}
+		case field.as == unmarshalNormal || field.as == unmarshalRaw:
+			elements = append(elements, field)
+		}
+	}
+
+	return elements
+}
+
+// compileStructFields parses the tags of all fields of the struct type t.
+func compileStructFields(t reflect.Type) []structField {
+	fields := make([]structField, t.NumField())
+
+	for i := range fields {
+		fieldType := t.Field(i)
+
+		field := structField{
+			index:    i,
+			goName:   fieldType.Name,
+			name:     fieldType.Name,
+			as:       unmarshalNormal,
+			embedded: fieldType.Anonymous && !hasRenameTag(fieldType),
+			exported: fieldType.PkgPath == "",
+		}
+
+		if structTag, ok := fieldType.Tag.Lookup("dyml"); ok {
+			field.tags = strings.Split(structTag, ",")
+
+			if len(field.tags[0]) > 0 {
+				field.name = field.tags[0]
+				field.renamed = true
+			}
+
+			for _, option := range field.tags[1:] {
+				switch {
+				case strings.HasPrefix(option, oneOfOption):
+					field.oneOf = strings.Fields(strings.TrimPrefix(option, oneOfOption))
+				case option == base64Option:
+					field.base64 = true
+				}
+			}
+
+			if len(field.tags) > 1 && !isOption(field.tags[1]) {
+				switch field.tags[1] {
+				case "attr":
+					field.as = unmarshalAttribute
+				case "inner":
+					field.as = unmarshalInner
+				case "any":
+					field.as = unmarshalAny
+				case "raw":
+					field.as = unmarshalRaw
+				case "children":
+					field.as = unmarshalChildren
+				case "squash", "flatten":
+					field.squash = true
+					field.embedded = true
+				case "":
+					field.as = unmarshalNormal
+				default:
+					field.invalidAs = field.tags[1]
+				}
+			}
+		}
+
+		field.filtered = isSliceOrArray(indirectType(fieldType.Type)) && len(field.tags) > 0 && len(field.tags[0]) > 0 &&
+			!field.base64
+		fields[i] = field
+	}
+
+	return fields
+}
+
+// rawTypeCache maps a reflect.Type to whether values of it contain 'raw' fields.
+//
+//nolint:gochecknoglobals // Shared by all unmarshalers, just like structTypeCache.
+var rawTypeCache sync.Map
+
+// hasRawFields returns true if values of type t contain fields with a 'raw' tag, which need the source text.
+func hasRawFields(t reflect.Type) bool {
+	if raw, ok := rawTypeCache.Load(t); ok {
+		return raw.(bool)
+	}
+
+	raw := findRawFields(t, map[reflect.Type]bool{})
+	rawTypeCache.Store(t, raw)
+
+	return raw
+}
+
+// findRawFields returns true if values of type t contain fields with a 'raw' tag.
+// visited contains all types that were already checked, as types might contain themselves.
+func findRawFields(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findRawFields(t.Elem(), visited)
+	case reflect.Struct:
+		for _, field := range cachedStructType(t).fields {
+			if field.as == unmarshalRaw || findRawFields(t.Field(field.index).Type, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -7,9 +7,18 @@ type Attribute struct {
 	Key   string
 	Value string
 	Range token.Position
+	// Kind is a hint whether the value was written as a string, number or boolean.
+	Kind token.ValueKind
 }
 
 // AttributeList is a list to hold attributes.
+// Attributes keep the order in which they were added. For parsed nodes this is the source order,
+// where forwarded attributes come before the attributes of the node itself. Setting an attribute
+// that already exists keeps its position. Keys are unique in lists built by Set, but not by Add.
+//
+// Copies of an AttributeList share memory, so use Clone before modifying a copy with Add or Set.
+// Pop and Remove never write to shared memory, so a copy may be consumed with Pop without
+// changing the original.
 type AttributeList struct {
 	attributes []Attribute
 }
@@ -24,6 +33,49 @@ func (l *AttributeList) Len() int {
 	return len(l.attributes)
 }
 
+// Clone returns a copy of the list, that does not share memory with the original.
+func (l AttributeList) Clone() AttributeList {
+	if len(l.attributes) == 0 {
+		return AttributeList{}
+	}
+
+	return AttributeList{attributes: append([]Attribute(nil), l.attributes...)}
+}
+
+// GetAt returns the attribute at index i, or nil if i is out of range.
+func (l *AttributeList) GetAt(i int) *Attribute {
+	if i < 0 || i >= len(l.attributes) {
+		return nil
+	}
+
+	return &l.attributes[i]
+}
+
+// Keys returns the keys of all attributes in order.
+func (l *AttributeList) Keys() []string {
+	keys := make([]string, 0, len(l.attributes))
+	for _, attr := range l.attributes {
+		keys = append(keys, attr.Key)
+	}
+
+	return keys
+}
+
+// Remove deletes the first attribute with the given key and keeps the order of the others.
+// Returns true if an attribute was removed.
+func (l *AttributeList) Remove(key string) bool {
+	for i := range l.attributes {
+		if l.attributes[i].Key == key {
+			// The full slice expression makes append allocate, so that copies of the list are not modified.
+			l.attributes = append(l.attributes[:i:i], l.attributes[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
 // Add the given attribute to the list.
 func (l *AttributeList) Add(attr Attribute) {
 	l.attributes = append(l.attributes, attr)
@@ -48,8 +100,7 @@ func (l *AttributeList) Set(attr Attribute) bool {
 	//nolint:ifshort
 	existing := l.Get(attr.Key)
 	if existing != nil {
-		existing.Key = attr.Key
-		existing.Value = attr.Value
+		*existing = attr
 
 		return true
 	}
@@ -59,11 +110,25 @@ func (l *AttributeList) Set(attr Attribute) bool {
 	return false
 }
 
+// GetAll returns all attributes with the given key in order, which are multiple ones only in lists
+// built by Add.
+func (l *AttributeList) GetAll(key string) []*Attribute {
+	var result []*Attribute
+
+	for i := range l.attributes {
+		if l.attributes[i].Key == key {
+			result = append(result, &l.attributes[i])
+		}
+	}
+
+	return result
+}
+
 // Get returns an attribute for a given key, or nil if it does not exist.
 func (l *AttributeList) Get(key string) *Attribute {
-	for _, a := range l.attributes {
-		if a.Key == key {
-			return &a
+	for i := range l.attributes {
+		if l.attributes[i].Key == key {
+			return &l.attributes[i]
 		}
 	}
 
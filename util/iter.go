@@ -0,0 +1,20 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.23
+// +build go1.23
+
+package util
+
+import "iter"
+
+// All returns an iterator over copies of all attributes in order.
+func (l *AttributeList) All() iter.Seq[Attribute] {
+	return func(yield func(Attribute) bool) {
+		for _, attr := range l.attributes {
+			if !yield(attr) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+)
+
+// Validator can be implemented by structs to check their values once they have been unmarshalled,
+// e.g. that a port is within a valid range or that two fields are not set at the same time.
+// ValidateDyml is called for every struct that is read from an element, after all of its fields
+// have been set, so nested structs are validated before the structs that contain them.
+// Its error is returned as a token.PosError at the range of the element, which is caused by it.
+type Validator interface {
+	ValidateDyml() error
+}
+
+// validate calls ValidateDyml of value, if it is a struct that implements Validator.
+// node is the element that value was read from.
+func (u *unmarshaler) validate(node *parser.TreeNode, value reflect.Value) error {
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var validator Validator
+
+	switch {
+	case value.CanAddr() && value.Addr().Type().Implements(validatorType):
+		validator = value.Addr().Interface().(Validator) //nolint:forcetypeassert
+	case value.CanInterface() && value.Type().Implements(validatorType):
+		validator = value.Interface().(Validator) //nolint:forcetypeassert
+	default:
+		return nil
+	}
+
+	if err := validator.ValidateDyml(); err != nil {
+		return token.NewPosError(node.Range, fmt.Sprintf("invalid '%s'", value.Type())).SetCause(err)
+	}
+
+	return nil
+}
+
+// validatorType is the type of the Validator interface.
+//
+//nolint:gochecknoglobals // A reflect.Type cannot be a constant.
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
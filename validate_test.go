@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package dyml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/golangee/dyml"
+	"github.com/golangee/dyml/token"
+)
+
+var errInvalidPort = errors.New("port out of range")
+
+type validatedServer struct {
+	Host string `dyml:"host,attr"`
+	Port int    `dyml:"port"`
+}
+
+func (s *validatedServer) ValidateDyml() error {
+	if s.Port <= 0 || s.Port > 65535 {
+		return errInvalidPort
+	}
+
+	return nil
+}
+
+type validatedLimits struct {
+	Min int `dyml:"min,attr"`
+	Max int `dyml:"max,attr"`
+}
+
+// ValidateDyml has a value receiver, so that it is also called for values that are not addressable.
+func (l validatedLimits) ValidateDyml() error {
+	if l.Min > l.Max {
+		return errors.New("min is larger than max")
+	}
+
+	return nil
+}
+
+type validatedConfig struct {
+	Servers []validatedServer `dyml:"server"`
+	Limits  *validatedLimits  `dyml:"limits"`
+	Name    string            `dyml:"name"`
+}
+
+func (c *validatedConfig) ValidateDyml() error {
+	if len(c.Servers) == 0 {
+		return errors.New("at least one server is required")
+	}
+
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr error
+		// line is the line of the element that failed validation.
+		line int
+	}{
+		{
+			name: "valid",
+			text: "#server @host{a} {#port 80}\n#limits @min{1} @max{2}",
+		},
+		{
+			name:    "invalid nested struct",
+			text:    "#name x\n#server @host{a} {#port 80}\n#server @host{b} {#port 0}",
+			wantErr: errInvalidPort,
+			line:    3,
+		},
+		{
+			name: "value receiver",
+			text: "#server @host{a} {#port 80}\n#limits @min{3} @max{2}",
+			line: 2,
+		},
+		{
+			name: "root",
+			text: "#name x",
+			line: 1,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var config validatedConfig
+
+			err := Unmarshal(strings.NewReader(test.text), &config, false)
+			if test.line == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			var posErr *token.PosError
+			if !errors.As(err, &posErr) {
+				t.Fatalf("expected a PosError, but got %v", err)
+			}
+
+			if line := posErr.Details[0].Node.Begin().Line; line != test.line {
+				t.Errorf("expected the error in line %d, but got %d: %v", test.line, line, err)
+			}
+
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("expected an error wrapping '%v', but got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+type orderedInner struct {
+	Name string `dyml:"name,attr"`
+	// validated is set by ValidateDyml.
+	validated bool
+}
+
+func (i *orderedInner) ValidateDyml() error {
+	i.validated = true
+
+	return nil
+}
+
+type orderedOuter struct {
+	Inner []orderedInner `dyml:"inner"`
+	// innerFirst is set by ValidateDyml, if all inner structs were validated before.
+	innerFirst bool
+}
+
+func (o *orderedOuter) ValidateDyml() error {
+	o.innerFirst = true
+
+	for _, inner := range o.Inner {
+		o.innerFirst = o.innerFirst && inner.validated
+	}
+
+	return nil
+}
+
+func TestValidatorOrder(t *testing.T) {
+	t.Parallel()
+
+	var outer orderedOuter
+	if err := Unmarshal(strings.NewReader("#inner @name{a} #inner @name{b}"), &outer, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outer.Inner) != 2 || !outer.innerFirst {
+		t.Errorf("expected nested structs to be validated first, but got %+v", outer)
+	}
+}
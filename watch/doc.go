@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watch reloads dyml configuration files when they change.
+//
+//	watcher := watch.NewWatcher("config.dyml", func() interface{} { return &Config{} })
+//	for update := range watcher.Watch(ctx) {
+//	    if update.Err != nil {
+//	        log.Println(update.Err) // keep the previous configuration
+//	        continue
+//	    }
+//	    apply(update.Value.(*Config))
+//	}
+package watch
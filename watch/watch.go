@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/golangee/dyml"
+)
+
+// DefaultInterval is the time between two checks for changes, unless WithInterval is used.
+const DefaultInterval = time.Second
+
+// Update is delivered whenever the watched file was loaded.
+type Update struct {
+	// Value is the newly unmarshalled value. It is nil if Err is set.
+	Value interface{}
+	// Err tells why the file could not be read, parsed, unmarshalled or validated.
+	// The previous value should be kept in this case.
+	Err error
+}
+
+// Option can be passed to NewWatcher to configure a Watcher.
+type Option func(w *Watcher)
+
+// WithInterval sets how often the file is checked for changes.
+func WithInterval(interval time.Duration) Option {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithFS reads the file from fsys instead of the operating system.
+func WithFS(fsys fs.FS) Option {
+	return func(w *Watcher) {
+		w.fsys = fsys
+	}
+}
+
+// WithStrict unmarshals in strict mode, see dyml.Unmarshal.
+func WithStrict() Option {
+	return func(w *Watcher) {
+		w.strict = true
+	}
+}
+
+// WithUnmarshalOptions passes the given options to the dyml.Decoder, e.g. to add preprocessors.
+func WithUnmarshalOptions(opts ...dyml.UnmarshalOption) Option {
+	return func(w *Watcher) {
+		w.unmarshalOptions = append(w.unmarshalOptions, opts...)
+	}
+}
+
+// WithValidator checks every unmarshalled value with validate. Values for which an error is returned
+// are not delivered, the error is delivered instead.
+func WithValidator(validate func(value interface{}) error) Option {
+	return func(w *Watcher) {
+		w.validate = validate
+	}
+}
+
+// Watcher reloads a dyml file whenever its content changes and unmarshals it into a new value.
+// Changes are detected by polling, so that it works for every file system.
+// A Watcher must not be used by multiple goroutines at the same time.
+type Watcher struct {
+	filename         string
+	newValue         func() interface{}
+	interval         time.Duration
+	fsys             fs.FS
+	strict           bool
+	unmarshalOptions []dyml.UnmarshalOption
+	validate         func(value interface{}) error
+
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	loaded  bool
+}
+
+// NewWatcher creates a Watcher for the file with the given name. newValue must return a pointer
+// to a new value to unmarshal into, e.g. func() interface{} { return &Config{} }.
+func NewWatcher(filename string, newValue func() interface{}, opts ...Option) *Watcher {
+	w := &Watcher{
+		filename: filename,
+		newValue: newValue,
+		interval: DefaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Load reads and unmarshals the file once.
+func (w *Watcher) Load(ctx context.Context) (interface{}, error) {
+	info, err := w.stat()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := w.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	w.remember(info, data)
+
+	return w.unmarshal(ctx, data)
+}
+
+// Run loads the file and calls fn with the result. Afterwards fn is called whenever the content
+// of the file changes, until ctx is done. Errors of fn are not handled, Run returns ctx.Err().
+func (w *Watcher) Run(ctx context.Context, fn func(update Update)) error {
+	value, err := w.Load(ctx)
+	fn(Update{Value: value, Err: err})
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if update, changed := w.poll(ctx); changed {
+				fn(update)
+			}
+		}
+	}
+}
+
+// Watch works like Run, but delivers the updates over the returned channel. The channel is closed
+// once ctx is done. Updates are not dropped, so the channel must be read.
+func (w *Watcher) Watch(ctx context.Context) <-chan Update {
+	updates := make(chan Update)
+
+	go func() {
+		defer close(updates)
+
+		_ = w.Run(ctx, func(update Update) {
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	return updates
+}
+
+// poll checks if the file changed and reloads it. Files whose modification time and size did not change
+// are not read again. Files that cannot be read are reported once, until they can be read again.
+func (w *Watcher) poll(ctx context.Context) (Update, bool) {
+	info, err := w.stat()
+	if err == nil && w.loaded && w.unchanged(info) {
+		return Update{}, false
+	}
+
+	var data []byte
+	if err == nil {
+		data, err = w.readFile()
+	}
+
+	if err != nil {
+		if !w.loaded {
+			return Update{}, false
+		}
+
+		w.loaded = false
+
+		return Update{Err: err}, true
+	}
+
+	if w.loaded && sha256.Sum256(data) == w.hash {
+		w.remember(info, data)
+
+		return Update{}, false
+	}
+
+	w.remember(info, data)
+
+	value, err := w.unmarshal(ctx, data)
+
+	return Update{Value: value, Err: err}, true
+}
+
+// unchanged returns true if the modification time and size of the file are the same as when it was last read.
+// File systems without modification times are always considered to be changed.
+func (w *Watcher) unchanged(info fs.FileInfo) bool {
+	return !info.ModTime().IsZero() && info.ModTime().Equal(w.modTime) && info.Size() == w.size
+}
+
+// remember stores the state of the file, which was read with the given content.
+func (w *Watcher) remember(info fs.FileInfo, data []byte) {
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	w.hash = sha256.Sum256(data)
+	w.loaded = true
+}
+
+// unmarshal parses data into a new value and validates it.
+func (w *Watcher) unmarshal(ctx context.Context, data []byte) (interface{}, error) {
+	value := w.newValue()
+
+	decoder := dyml.NewDecoder(w.filename, bytes.NewReader(data), w.strict, w.unmarshalOptions...)
+	if err := decoder.DecodeContext(ctx, value); err != nil {
+		return nil, err
+	}
+
+	if w.validate != nil {
+		if err := w.validate(value); err != nil {
+			return nil, fmt.Errorf("invalid configuration in '%s': %w", w.filename, err)
+		}
+	}
+
+	return value, nil
+}
+
+// stat returns information about the watched file.
+func (w *Watcher) stat() (fs.FileInfo, error) {
+	if w.fsys != nil {
+		return fs.Stat(w.fsys, w.filename)
+	}
+
+	return os.Stat(w.filename)
+}
+
+// readFile returns the content of the watched file.
+func (w *Watcher) readFile() ([]byte, error) {
+	if w.fsys != nil {
+		return fs.ReadFile(w.fsys, w.filename)
+	}
+
+	return os.ReadFile(w.filename)
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package watch_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golangee/dyml/watch"
+)
+
+type config struct {
+	Port int `dyml:"port"`
+}
+
+func newConfig() interface{} {
+	return &config{}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "config.dyml")
+	// Files are replaced atomically, so that the watcher never reads a partially written file.
+	write := func(text string) {
+		if err := os.WriteFile(filename+".tmp", []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Rename(filename+".tmp", filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("#port 80")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := watch.NewWatcher(filename, newConfig, watch.WithInterval(time.Millisecond),
+		watch.WithValidator(func(value interface{}) error {
+			if value.(*config).Port == 0 {
+				return errors.New("port must not be 0")
+			}
+
+			return nil
+		}))
+	updates := watcher.Watch(ctx)
+
+	next := func() watch.Update {
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(5 * time.Second):
+			t.Fatal("no update received")
+
+			return watch.Update{}
+		}
+	}
+
+	if update := next(); update.Err != nil || update.Value.(*config).Port != 80 {
+		t.Fatalf("expected port 80, got %+v", update)
+	}
+
+	write("#port{not a number}")
+
+	if update := next(); update.Err == nil {
+		t.Fatalf("expected an error for an invalid port, got %+v", update)
+	}
+
+	write("#port 8080")
+
+	if update := next(); update.Err != nil || update.Value.(*config).Port != 8080 {
+		t.Fatalf("expected port 8080, got %+v", update)
+	}
+
+	write("#port 0")
+
+	if update := next(); update.Err == nil {
+		t.Fatalf("expected a validation error, got %+v", update)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if update := next(); !errors.Is(update.Err, os.ErrNotExist) {
+		t.Fatalf("expected an error for a missing file, got %+v", update)
+	}
+
+	write("#port 443")
+
+	if update := next(); update.Err != nil || update.Value.(*config).Port != 443 {
+		t.Fatalf("expected port 443, got %+v", update)
+	}
+
+	cancel()
+
+	for range updates {
+		// Drain the channel until it is closed.
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{"config.dyml": &fstest.MapFile{Data: []byte("#port 80")}}
+
+	value, err := watch.NewWatcher("config.dyml", newConfig, watch.WithFS(fsys)).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if port := value.(*config).Port; port != 80 {
+		t.Errorf("expected port 80, got %d", port)
+	}
+
+	if _, err := watch.NewWatcher("missing.dyml", newConfig, watch.WithFS(fsys)).Load(context.Background()); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,7 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wire contains a compact and versioned binary encoding of parsed trees, which can be passed
+// between services, e.g. as the bytes of a protobuf message or google.protobuf.Any, without parsing
+// the document again. Use Marshal and Unmarshal to convert between trees and their encoding.
+package wire
@@ -0,0 +1,313 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	"github.com/golangee/dyml/util"
+)
+
+// Version is the version of the encoding written by Marshal. It is the first byte of every encoded tree
+// and is increased whenever the encoding changes, so that Unmarshal can reject or convert older data.
+const Version = 1
+
+// ErrInvalid is the cause of all errors about data that is not a valid encoding of a tree.
+var ErrInvalid = errors.New("invalid wire encoding")
+
+// The kind of node is the first byte of every encoded node.
+const (
+	kindElement byte = iota
+	kindText
+	kindComment
+)
+
+// blockTypes contains the block types in the order of their encoding.
+//
+//nolint:gochecknoglobals // The encoding of block types, which must never change.
+var blockTypes = []parser.BlockType{parser.BlockNone, parser.BlockNormal, parser.BlockGroup, parser.BlockGeneric}
+
+// maxDepth limits the nesting of decoded nodes, so that hostile data cannot exhaust the stack.
+const maxDepth = 10000
+
+// Marshal encodes tree. The encoding starts with the Version byte, followed by the length of the
+// encoded root node as an unsigned varint and the node itself.
+// A node is a byte for its kind followed by its content:
+// Elements have their name, a byte for the block type, the number of attributes, each with key, value
+// and a byte for their token.ValueKind, and the number of children followed by the children.
+// Texts have their value and a byte for their token.ValueKind, comments only their value.
+// Numbers are unsigned varints and strings are prefixed with their length in bytes.
+// Ranges and metadata are not encoded.
+func Marshal(tree *parser.TreeNode) ([]byte, error) {
+	payload, err := appendNode(nil, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(payload)+1+binary.MaxVarintLen64)
+	data = append(data, Version)
+	data = appendUvarint(data, uint64(len(payload)))
+
+	return append(data, payload...), nil
+}
+
+// appendNode appends the encoding of node and its children to b.
+func appendNode(b []byte, node *parser.TreeNode) ([]byte, error) {
+	switch {
+	case node.IsText():
+		b = append(b, kindText)
+		b = appendString(b, *node.Text)
+
+		return append(b, byte(node.TextKind)), nil
+	case node.IsComment():
+		b = append(b, kindComment)
+
+		return appendString(b, *node.Comment), nil
+	}
+
+	blockType := -1
+
+	for i, bt := range blockTypes {
+		if bt == node.BlockType {
+			blockType = i
+		}
+	}
+
+	if blockType < 0 {
+		return nil, fmt.Errorf("cannot encode block type '%s' of element '%s'", node.BlockType, node.Name)
+	}
+
+	b = append(b, kindElement)
+	b = appendString(b, node.Name)
+	b = append(b, byte(blockType))
+
+	b = appendUvarint(b, uint64(node.Attributes.Len()))
+	for i := 0; i < node.Attributes.Len(); i++ {
+		attr := node.Attributes.GetAt(i)
+		b = appendString(b, attr.Key)
+		b = appendString(b, attr.Value)
+		b = append(b, byte(attr.Kind))
+	}
+
+	b = appendUvarint(b, uint64(len(node.Children)))
+
+	for _, child := range node.Children {
+		var err error
+		if b, err = appendNode(b, child); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// appendString appends s prefixed with its length.
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+
+	return append(b, s...)
+}
+
+// appendUvarint appends v as an unsigned varint.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(buf[:], v)
+
+	return append(b, buf[:n]...)
+}
+
+// Unmarshal decodes a tree that was encoded by Marshal. Data that is not a complete encoding,
+// e.g. because it was truncated, results in an error that wraps ErrInvalid.
+func Unmarshal(data []byte) (*parser.TreeNode, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: no data", ErrInvalid)
+	}
+
+	if data[0] != Version {
+		return nil, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalid, data[0], Version)
+	}
+
+	d := decoder{data: data, offset: 1}
+
+	length, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length != uint64(len(d.data)-d.offset) {
+		return nil, fmt.Errorf("%w: expected %d bytes, but got %d", ErrInvalid, length, len(d.data)-d.offset)
+	}
+
+	tree, err := d.node(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.offset != len(d.data) {
+		return nil, fmt.Errorf("%w: %d bytes after the root node", ErrInvalid, len(d.data)-d.offset)
+	}
+
+	return tree, nil
+}
+
+// decoder reads the encoding of a tree.
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+// node decodes a node and its children at the given depth.
+func (d *decoder) node(depth int) (*parser.TreeNode, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nodes are nested deeper than %d", ErrInvalid, maxDepth)
+	}
+
+	kind, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := d.string()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case kindText:
+		textKind, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		node := parser.NewStringNode(value)
+		node.TextKind = token.ValueKind(textKind)
+
+		return node, nil
+	case kindComment:
+		return parser.NewStringCommentNode(value), nil
+	case kindElement:
+		return d.element(value, depth)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind of node %d at byte %d", ErrInvalid, kind, d.offset-1)
+	}
+}
+
+// element decodes the content of an element with the given name.
+func (d *decoder) element(name string, depth int) (*parser.TreeNode, error) {
+	node := parser.NewNode(name)
+
+	blockType, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	if int(blockType) >= len(blockTypes) {
+		return nil, fmt.Errorf("%w: unknown block type %d at byte %d", ErrInvalid, blockType, d.offset-1)
+	}
+
+	node.BlockType = blockTypes[blockType]
+
+	count, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < count; i++ {
+		var attr util.Attribute
+
+		if attr.Key, err = d.string(); err != nil {
+			return nil, err
+		}
+
+		if attr.Value, err = d.string(); err != nil {
+			return nil, err
+		}
+
+		kind, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+
+		attr.Kind = token.ValueKind(kind)
+		node.Attributes.Add(attr)
+	}
+
+	if count, err = d.count(); err != nil {
+		return nil, err
+	}
+
+	if count > 0 {
+		node.Children = make([]*parser.TreeNode, 0, count)
+	}
+
+	for i := 0; i < count; i++ {
+		child, err := d.node(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		node.AddChildren(child)
+	}
+
+	return node, nil
+}
+
+// byte reads a single byte.
+func (d *decoder) byte() (byte, error) {
+	if d.offset >= len(d.data) {
+		return 0, fmt.Errorf("%w: unexpected end of data", ErrInvalid)
+	}
+
+	b := d.data[d.offset]
+	d.offset++
+
+	return b, nil
+}
+
+// uvarint reads an unsigned varint.
+func (d *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.offset:])
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: invalid number at byte %d", ErrInvalid, d.offset)
+	}
+
+	d.offset += n
+
+	return v, nil
+}
+
+// count reads the number of following attributes or nodes. As each of them needs at least
+// one byte, a count larger than the remaining data is invalid, which prevents huge allocations.
+func (d *decoder) count() (int, error) {
+	start := d.offset
+
+	v, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+
+	if v > uint64(len(d.data)-d.offset) {
+		return 0, fmt.Errorf("%w: count %d at byte %d exceeds the data", ErrInvalid, v, start)
+	}
+
+	return int(v), nil
+}
+
+// string reads a string prefixed with its length.
+func (d *decoder) string() (string, error) {
+	length, err := d.count()
+	if err != nil {
+		return "", err
+	}
+
+	s := string(d.data[d.offset : d.offset+length])
+	d.offset += length
+
+	return s, nil
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: © 2021 The dyml authors <https://github.com/golangee/dyml/blob/main/AUTHORS>
+// SPDX-License-Identifier: Apache-2.0
+
+package wire_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golangee/dyml/parser"
+	"github.com/golangee/dyml/token"
+	. "github.com/golangee/dyml/wire"
+)
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		`#? comment
+		#book @id{1} @title{A long title} {
+			#chapter Some text #b{bold}
+		}`,
+		`#! g2 {
+			fn @public=true @@order=2 Run(x int) -> result (int, error)
+			name: string
+			port 8080
+		}`,
+		strings.Repeat("#item{text} ", 300),
+	}
+
+	t.Parallel()
+
+	for _, in := range inputs {
+		input := in
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := parser.NewParser("", strings.NewReader(input),
+				parser.WithLexerOptions(token.WithLiterals())).Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := Marshal(tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Unmarshal(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !parser.Equal(tree, got, parser.NormalizeOptions{}) {
+				t.Errorf("expected\n%s\nbut got\n%s", tree.Dump(), got.Dump())
+			}
+
+			if !sameKinds(tree, got) {
+				t.Error("expected the same kinds of texts and attribute values")
+			}
+		})
+	}
+}
+
+// sameKinds returns true if the texts and attributes of both trees have the same token.ValueKind.
+func sameKinds(a, b *parser.TreeNode) bool {
+	if a.TextKind != b.TextKind {
+		return false
+	}
+
+	for i := 0; i < a.Attributes.Len(); i++ {
+		if a.Attributes.GetAt(i).Kind != b.Attributes.GetAt(i).Kind {
+			return false
+		}
+	}
+
+	for i := range a.Children {
+		if !sameKinds(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestMarshalStable(t *testing.T) {
+	t.Parallel()
+
+	tree := parser.NewNode("root").Block(parser.BlockNormal).AddChildren(
+		parser.NewNode("a").AddAttribute("k", "v").AddChildren(parser.NewStringNode("x")),
+		parser.NewStringCommentNode("c"),
+	)
+
+	data, err := Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		Version, 27,
+		0, 4, 'r', 'o', 'o', 't', 1, 0, 2,
+		0, 1, 'a', 0, 1, 1, 'k', 1, 'v', 0, 1,
+		1, 1, 'x', 0,
+		2, 1, 'c',
+	}
+
+	if !bytes.Equal(data, want) {
+		t.Errorf("expected %v, but got %v", want, data)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	t.Parallel()
+
+	tree, err := parser.NewParser("", strings.NewReader("#? c\n#a @k{v} {#b{text}}")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string][]byte{
+		"wrong version": append([]byte{Version + 1}, data[1:]...),
+		"trailing data": append(append([]byte{}, data...), 0),
+		"huge count":    {Version, 12, 0, 1, 'a', 0, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F, 0, 0, 0},
+		"block type":    {Version, 5, 0, 1, 'a', 9, 0},
+	}
+
+	for i := 0; i < len(data); i++ {
+		invalid[fmt.Sprintf("truncated to %d bytes", i)] = data[:i]
+	}
+
+	for name, data := range invalid {
+		if _, err := Unmarshal(data); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected an error wrapping ErrInvalid, but got %v", name, err)
+		}
+	}
+}